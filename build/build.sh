@@ -10,6 +10,8 @@ if ! which go > /dev/null; then
 fi
 
 GIT_COMMIT=${SOURCE_GIT_COMMIT:-$(git rev-parse HEAD)}
+# The semantic version is left empty if the commit is not tagged with one.
+SEMVER=${SOURCE_GIT_TAG:-$(git describe --tags --exact-match 2>/dev/null || true)}
 
 BIN_DIR="$(pwd)/build/_output/bin"
 mkdir -p ${BIN_DIR}
@@ -17,4 +19,4 @@ PROJECT_NAME="marketplace-operator"
 REPO_PATH="github.com/operator-framework/operator-marketplace/"
 BUILD_PATH="${REPO_PATH}/cmd/manager"
 echo "building "${PROJECT_NAME}"..."
-go build -ldflags "-X '${REPO_PATH}pkg/version.GitCommit=${GIT_COMMIT}'" -o ${BIN_DIR}/${PROJECT_NAME} $BUILD_PATH
+go build -ldflags "-X '${REPO_PATH}pkg/version.GitCommit=${GIT_COMMIT}' -X '${REPO_PATH}pkg/version.SemVer=${SEMVER}'" -o ${BIN_DIR}/${PROJECT_NAME} $BUILD_PATH
@@ -2,46 +2,75 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/operator-framework/operator-marketplace/pkg/certificateauthority"
+	operatorconfig "github.com/operator-framework/operator-marketplace/pkg/config"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	apiconfigv1 "github.com/openshift/api/config/v1"
+	imagev1 "github.com/openshift/api/image/v1"
 	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
 
 	"github.com/operator-framework/operator-marketplace/pkg/apis"
 	configv1 "github.com/operator-framework/operator-marketplace/pkg/apis/config/v1"
 	apiutils "github.com/operator-framework/operator-marketplace/pkg/apis/operators/shared"
 	"github.com/operator-framework/operator-marketplace/pkg/controller"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/backoff"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/catalogaffinity"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/catalogauth"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/catalogsource"
 	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
 	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/health"
+	"github.com/operator-framework/operator-marketplace/pkg/httpserver"
+	"github.com/operator-framework/operator-marketplace/pkg/leader"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
 	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/operator-framework/operator-marketplace/pkg/preflight"
 	"github.com/operator-framework/operator-marketplace/pkg/signals"
 	"github.com/operator-framework/operator-marketplace/pkg/status"
 	sourceCommit "github.com/operator-framework/operator-marketplace/pkg/version"
 
+	appsv1 "k8s.io/api/apps/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kruntime "k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 const (
@@ -50,10 +79,172 @@ const (
 	defaultRetryPeriod                 = 30 * time.Second
 	defaultRenewDeadline               = 60 * time.Second
 	defaultLeaseDuration               = 90 * time.Second
+
+	// leaderHealthzGracePeriod is how long past the renew deadline a leader
+	// may go without renewing its lease before it reports itself unhealthy.
+	leaderHealthzGracePeriod = 45 * time.Second
+
+	// metricsServiceName is the name of the Service exposing the metrics of
+	// the marketplace replicas.
+	metricsServiceName = "marketplace-operator-metrics"
+
+	// logFormatText and logFormatJSON are the supported log formats.
+	logFormatText = "text"
+	logFormatJSON = "json"
+
+	// operatorLoggerName is the logger name of the lines logged by the
+	// operator in the json log format.
+	operatorLoggerName = "marketplace-operator"
+
+	// defaultGracefulShutdownTimeout is the default time given to the
+	// in-flight reconciles to complete on shutdown.
+	defaultGracefulShutdownTimeout = 30 * time.Second
+
+	// defaultSyncPeriod is the default interval at which every watched object
+	// is reconciled again, restoring the modifications whose events were
+	// missed or filtered out.
+	defaultSyncPeriod = 10 * time.Minute
+
+	// defaultsRetryInitialDelay and defaultsRetryMaxDelay bound the backoff
+	// the default CatalogSources are populated again with when they can not
+	// be populated, in case the defaults are laid out again.
+	defaultsRetryInitialDelay = 10 * time.Second
+	defaultsRetryMaxDelay     = 5 * time.Minute
+
+	// minSyncPeriod is the shortest sync period accepted, so that the
+	// operator does not reconcile every object over and over.
+	minSyncPeriod = time.Minute
+
+	// defaultKubeAPIQPS and defaultKubeAPIBurst are the client-side rate
+	// limits of the requests to the apiserver, the ones controller-runtime
+	// defaults to.
+	defaultKubeAPIQPS   = 20
+	defaultKubeAPIBurst = 30
 )
 
-func init() {
-	log.SetLogger(zap.New())
+// setRateLimits sets the client-side rate limits of the clients created from
+// cfg to qps requests per second, with bursts of up to burst requests.
+func setRateLimits(cfg *rest.Config, qps float64, burst int) error {
+	if qps <= 0 {
+		return fmt.Errorf("invalid -kube-api-qps %v, must be positive", qps)
+	}
+	if burst <= 0 || float64(burst) < qps {
+		return fmt.Errorf("invalid -kube-api-burst %d, must be positive and at least -kube-api-qps %v", burst, qps)
+	}
+	cfg.QPS = float32(qps)
+	cfg.Burst = burst
+	// The rate limiter of cfg, if any, would take precedence over QPS and
+	// Burst.
+	cfg.RateLimiter = nil
+	return nil
+}
+
+// inClusterConfig returns the config of the service account mounted in the
+// operator pod, it is replaced in tests.
+var inClusterConfig = rest.InClusterConfig
+
+// getConfig returns the config to talk to the apiserver with, and whether the
+// operator runs in-cluster with a mounted service account. The kubeconfig file
+// at the given path is used out-of-cluster, and ignored with a warning
+// in-cluster. The config is resolved by controller-runtime if kubeconfig is
+// empty.
+func getConfig(logger logrus.FieldLogger, kubeconfig string) (*rest.Config, bool, error) {
+	serviceAccountConfig, err := inClusterConfig()
+	inCluster := err == nil
+	if kubeconfig == "" {
+		cfg, err := config.GetConfig()
+		return cfg, inCluster, err
+	}
+	if inCluster {
+		logger.Warnf("ignoring -kubeconfig %s, running in-cluster with a mounted service account", kubeconfig)
+		return serviceAccountConfig, true, nil
+	}
+	cfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load -kubeconfig %s: %v", kubeconfig, err)
+	}
+	return cfg, false, nil
+}
+
+// setLogFormat configures logger to emit logs in the given format.
+func setLogFormat(logger *logrus.Logger, format string) error {
+	switch format {
+	case logFormatText:
+		logger.SetFormatter(&logrus.TextFormatter{})
+	case logFormatJSON:
+		logger.SetFormatter(&logrus.JSONFormatter{})
+		logger.AddHook(logging.NameHook(operatorLoggerName))
+	default:
+		return fmt.Errorf("unsupported log format %q, must be %s or %s", format, logFormatText, logFormatJSON)
+	}
+	return nil
+}
+
+// isFlagSet returns true if the flag with the given name was passed on the
+// command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// newHealthMux returns the mux serving the health checks, and the status page
+// on /status. The http.DefaultServeMux is not used, as net/http/pprof registers
+// its handlers on it.
+func newHealthMux(liveness, readiness, statusPage http.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", liveness)
+	mux.Handle("/readyz", readiness)
+	mux.Handle("/status", statusPage)
+	return mux
+}
+
+// listenHealth binds the listener serving the health checks and the status
+// page on addr. It returns a nil Server if addr is empty.
+func listenHealth(addr string, liveness, readiness, statusPage http.Handler) (*httpserver.Server, error) {
+	if addr == "" {
+		return nil, nil
+	}
+	server, err := httpserver.Listen(httpserver.New(addr, newHealthMux(liveness, readiness, statusPage)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to serve the health checks on -healthz-addr %s: %v", addr, err)
+	}
+	return server, nil
+}
+
+// newPprofMux returns the mux serving the net/http/pprof handlers.
+func newPprofMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// operatorOwnerReference returns a reference to the operator Deployment with
+// the given name in namespace. Only the metadata of the Deployment is read, so
+// that the apps API does not need to be in the scheme.
+func operatorOwnerReference(ctx context.Context, reader client.Reader, namespace, name string) (metav1.OwnerReference, error) {
+	if name == "" {
+		return metav1.OwnerReference{}, errors.New("OPERATOR_NAME is not set")
+	}
+	deployment := &metav1.PartialObjectMetadata{}
+	deployment.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, deployment); err != nil {
+		return metav1.OwnerReference{}, fmt.Errorf("failed to get Deployment %s/%s: %v", namespace, name, err)
+	}
+	return metav1.OwnerReference{
+		APIVersion: appsv1.SchemeGroupVersion.String(),
+		Kind:       "Deployment",
+		Name:       deployment.Name,
+		UID:        deployment.UID,
+	}, nil
 }
 
 func printVersion() {
@@ -67,9 +258,11 @@ func setupScheme() *kruntime.Scheme {
 	utilruntime.Must(apis.AddToScheme(scheme))
 	utilruntime.Must(olmv1alpha1.AddToScheme(scheme))
 	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(networkingv1.AddToScheme(scheme))
 
 	if configv1.IsAPIAvailable() {
 		utilruntime.Must(apiconfigv1.AddToScheme(scheme))
+		utilruntime.Must(imagev1.AddToScheme(scheme))
 	}
 
 	return scheme
@@ -82,22 +275,135 @@ func main() {
 		clusterOperatorName     string
 		tlsKeyPath              string
 		tlsCertPath             string
+		metricsAuthToken        string
+		metricsPort             int
+		metricsAddr             string
+		tlsMinVersion           string
+		tlsCipherSuites         string
+		tlsClientCA             string
+		latencyUpdateInterval   time.Duration
 		leaderElectionNamespace string
-		pprofAddress            string
+		watchNamespace          string
+		watchNamespaceSelector  string
+		webhookPort             int
+		webhookCertDir          string
+		oauthEndpoints          string
+		oauthClientSecret       string
+		pprofAddr               string
+		healthzAddr             string
+		apiServerFailures       int
+		enforceImmutableSpec    bool
+		resolveImageDigests     bool
+		imageMirrorPrefixes     string
+		gracefulShutdownTimeout time.Duration
+		exposeCatalogs          bool
+		catalogIngressClass     string
+		catalogIngressDomain    string
+		messageTemplateCM       string
+		maxConcurrentReconciles int
+		syncPeriod              time.Duration
+		defaultsURLs            string
+		defaultsURLTimeout      time.Duration
+		rolloutBatchSize        int
+		rolloutWaitTimeout      time.Duration
+		alertAfter              time.Duration
+		conditionHistorySize    int
+		statusUpdateRetries     int
+		conditionDecayTimeout   time.Duration
+		statusResyncInterval    time.Duration
+		alertWebhookURL         string
+		alertWebhookFormat      string
+		alertRoutingKey         string
 		version                 bool
+		validateDefaults        bool
 		loglvl                  string
+		logFormat               string
+		kubeAPILogLevel         int
+		kubeAPIQPS              float64
+		kubeAPIBurst            int
+		configFile              string
 	)
 	flag.StringVar(&clusterOperatorName, "clusterOperatorName", "", "configures the name of the OpenShift ClusterOperator that should reflect this operator's status, or the empty string to disable ClusterOperator updates")
 	flag.StringVar(&defaults.Dir, "defaultsDir", "", "configures the directory where the default CatalogSources are stored")
+	flag.StringVar(&defaults.ConfigMap, "defaults-configmap", "", "configures the namespace/name of a ConfigMap whose data keys are the default CatalogSource manifests, taking the place of -defaultsDir while it exists. The ConfigMap must be readable by the operator")
+	flag.StringVar(&defaultsURLs, "defaults-url", "", "configures a comma-separated list of HTTP or HTTPS URLs, each serving a default CatalogSource manifest, merged with the ones of -defaultsDir. A URL takes precedence over -defaultsDir and the URLs before it for the CatalogSource it defines. Can not be combined with -defaults-configmap")
+	flag.DurationVar(&defaultsURLTimeout, "defaults-url-timeout", defaults.DefaultHTTPLoaderTimeout, "Time given to the request for each -defaults-url to complete")
+	flag.DurationVar(&defaults.CanaryTimeout, "canary-timeout", 0, "Time given to the canary CatalogSource created to upgrade the image of a default CatalogSource to become ready, after which the upgrade is aborted. The images are upgraded in place if it is 0")
+	flag.IntVar(&rolloutBatchSize, "defaults-rollout-batch-size", 0, "Number of default CatalogSources created, updated or deleted at a time when the definitions change on startup, the new ones first and the removed ones last, waiting for the default CatalogSources to be ready between each batch. Every change is applied at once if it is 0")
+	flag.DurationVar(&rolloutWaitTimeout, "defaults-rollout-wait-timeout", defaults.DefaultRolloutWaitTimeout, "Time given to the default CatalogSources to become ready between the batches of -defaults-rollout-batch-size, after which the rollout carries on with the next batch")
+	flag.StringVar(&defaults.PatchDir, "defaults-patch-dir", "", "configures the directory where the strategic merge patches of the default CatalogSources are stored, each named after the CatalogSource it applies to")
 	flag.BoolVar(&version, "version", false, "displays marketplace source commit info.")
-	flag.StringVar(&pprofAddress, "pprof-address", ":6060", "Address to serve pprof endpoints on.")
+	flag.BoolVar(&validateDefaults, "validate-defaults", false, "validates the default CatalogSources of -defaultsDir, prints the result of each file and exits with status 1 if any is invalid, without connecting to a cluster")
+	flag.StringVar(&healthzAddr, "healthz-addr", ":8080", "host:port to serve the /healthz and /readyz endpoints on. The liveness and readiness probes of the operator Deployment must target this port, and must be removed if it is empty, which disables the health listener")
+	flag.IntVar(&apiServerFailures, "healthz-apiserver-failure-threshold", health.DefaultAPIServerFailureThreshold, "Number of consecutive failed checks of the connectivity to the API server, run every 30s, after which /healthz fails and kubelet restarts the operator. 0 never fails /healthz on an API server outage")
+	flag.StringVar(&pprofAddr, "pprof-addr", "", "host:port to serve the pprof endpoints on, on a listener of their own. An empty value disables the pprof listener")
+	flag.StringVar(&pprofAddr, "pprof-address", "", "Deprecated: use -pprof-addr")
 	flag.StringVar(&tlsKeyPath, "tls-key", "", "Path to use for private key (requires tls-cert)")
 	flag.StringVar(&tlsCertPath, "tls-cert", "", "Path to use for certificate (requires tls-key)")
+	flag.StringVar(&metricsAuthToken, "metrics-auth-token", "", "If set, requests to the metrics endpoint must present this value as a bearer token")
+	flag.IntVar(&metricsPort, "metrics-port", 0, "Port to serve the metrics on, defaults to 8081 when serving over https and 8383 otherwise")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "host:port to serve the metrics on, takes precedence over -metrics-port. An empty value disables the metrics listener")
+	flag.StringVar(&tlsMinVersion, "tls-min-version", metrics.DefaultTLSMinVersion, "Minimum TLS version accepted by the metrics endpoint, one of VersionTLS10, VersionTLS11, VersionTLS12 or VersionTLS13")
+	flag.StringVar(&tlsCipherSuites, "tls-cipher-suites", "", "Comma-separated list of the IANA names of the cipher suites accepted by the metrics endpoint up to TLS 1.2. Defaults to the Go recommended cipher suites")
+	flag.DurationVar(&latencyUpdateInterval, "latency-percentile-update-interval", metrics.DefaultLatencyPercentileUpdateInterval, "Interval at which the P50, P95 and P99 reconcile latency gauges are computed from the reconcile duration histogram")
+	flag.StringVar(&tlsClientCA, "tls-client-ca", "", "Path to a CA bundle the metrics clients must present a certificate signed by (requires tls-key and tls-cert)")
 	flag.StringVar(&leaderElectionNamespace, "leader-namespace", "openshift-marketplace", "configures the namespace that will contain the leader election lock")
+	flag.IntVar(&webhookPort, "webhook-port", 0, "port to serve the CatalogSource admission webhooks on, the webhooks are disabled if it is 0")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "", "configures the directory holding the tls.crt and tls.key serving certificate of the admission webhooks, defaults to the controller-runtime directory")
+	flag.StringVar(&oauthEndpoints, "oauth-registry-endpoint", "", "configures a comma-separated list of <registry>=<token endpoint URL> pairs. A pull Secret holding an OAuth bearer token of each registry is kept fresh, and referenced by the default CatalogSources whose image is pulled from the registry")
+	flag.StringVar(&oauthClientSecret, "oauth-client-secret", "", "configures the name of the Secret, in the operator namespace, holding the client_id and client_secret the -oauth-registry-endpoint tokens are requested with")
+	flag.StringVar(&watchNamespace, "watch-namespace", "", "configures the operator namespace when running out-of-cluster, in place of the WATCH_NAMESPACE environment variable. Ignored in-cluster")
+	flag.StringVar(&watchNamespaceSelector, "watch-namespace-selector", "", "configures a label selector of the namespaces watched along with the operator namespace, in place of every namespace. The namespaces are selected on startup")
+	flag.BoolVar(&enforceImmutableSpec, "enforce-immutable-spec", false, "reverts modifications of the default CatalogSources spec that were not made by the operator")
+	flag.BoolVar(&resolveImageDigests, "resolve-image-digests", false, "pins the default CatalogSources to the digest their image tag points to when the defaults are populated, from the ImageStreams on OpenShift or from the registry. The tags are resolved again when the defaults are populated again")
+	flag.StringVar(&imageMirrorPrefixes, "image-mirror-prefix", "", "configures a comma-separated list of <source>=<mirror> repository prefixes, as in registry.redhat.io=mirror.corp.example.com. The images of the default CatalogSources starting with a source are rewritten to start with its mirror, keeping their tag or digest")
+	flag.BoolVar(&exposeCatalogs, "expose-catalogs-externally", false, "creates an Ingress for the grpc Service of each default CatalogSource")
+	flag.StringVar(&catalogIngressClass, "catalog-ingress-class", "", "configures the class of the catalog Ingresses, the cluster default class is used if empty")
+	flag.StringVar(&catalogIngressDomain, "catalog-ingress-domain", "", "configures the domain the hosts of the catalog Ingresses are allocated in, as <name>-<namespace>.<domain>")
+	flag.DurationVar(&gracefulShutdownTimeout, "graceful-shutdown-timeout", defaultGracefulShutdownTimeout, "Time given to the in-flight reconciles to complete on shutdown")
+	flag.StringVar(&messageTemplateCM, "message-template-configmap", "", "configures the name of the ConfigMap, in the operator namespace, overriding the templates of the default CatalogSource condition messages, keyed by condition reason")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1, "Number of reconciles each controller runs concurrently")
+	flag.DurationVar(&syncPeriod, "sync-period", defaultSyncPeriod, "Interval at which every default CatalogSource is reconciled again, even without a change event. Must be at least 1m")
+	flag.IntVar(&conditionHistorySize, "condition-history-size", status.DefaultConditionHistorySize, "Number of transitions of each ClusterOperator condition kept in the condition history, persisted in the "+status.ConditionHistoryConfigMapName+" ConfigMap of the operator namespace")
+	flag.IntVar(&statusUpdateRetries, "status-update-retries", status.DefaultStatusUpdateRetries, "Number of times a ClusterOperator status update failing with a conflict or because of rate limiting is retried, with an exponential backoff")
+	flag.DurationVar(&statusResyncInterval, "status-resync-interval", 0, "Interval at which the ClusterOperator status is re-synced, restoring the conditions edited by hand without waiting for a change of the operator status. Re-syncs are disabled if 0")
+	flag.DurationVar(&conditionDecayTimeout, "condition-decay-timeout", status.DefaultConditionDecayTimeout, "Time after which the failure conditions of the default CatalogSources, and their failures listed in the Degraded condition of the ClusterOperator, are cleared if no new failure was observed. Must be 0, to never clear them, or at least the maximum retry delay of "+backoff.DefaultMaxDelay.String())
+	flag.DurationVar(&alertAfter, "alert-after", status.DefaultAlertAfter, "Time the ClusterOperator has to be Degraded for before an alert is sent to -alert-webhook-url")
+	flag.StringVar(&alertWebhookURL, "alert-webhook-url", "", "URL of the webhook the alerts are sent to when the ClusterOperator stays Degraded, alerting is disabled if empty")
+	flag.StringVar(&alertWebhookFormat, "alert-webhook-format", status.WebhookFormatSlack, "Format of the alerts sent to -alert-webhook-url, either slack or pagerduty")
+	flag.StringVar(&alertRoutingKey, "alert-pagerduty-routing-key", "", "Integration key of the PagerDuty service the alerts are sent to, required by the pagerduty format")
 	flag.StringVar(&loglvl, "level", "info", "Sets level of logger with default verbosity info level. See https://github.com/sirupsen/logrus for other verbosity levels.")
+	flag.IntVar(&kubeAPILogLevel, "kube-api-log-level", -1, "Sets the klog verbosity of client-go and the other Kubernetes libraries. It follows -level if negative: 0 at info, 4 at debug and 10 at trace. The lines logged are still filtered by -level")
+	flag.Float64Var(&kubeAPIQPS, "kube-api-qps", defaultKubeAPIQPS, "Number of requests per second the clients of the operator send to the apiserver before throttling them, must be positive")
+	flag.IntVar(&kubeAPIBurst, "kube-api-burst", defaultKubeAPIBurst, "Number of requests the clients of the operator send to the apiserver in a burst above -kube-api-qps, must be at least -kube-api-qps")
+	flag.StringVar(&logFormat, "log-format", logFormatText, "Sets the format of the logs, either text or json.")
+	// The kubeconfig flag is registered by controller-runtime.
+	flag.Lookup(config.KubeconfigFlagName).Usage = "Path to the kubeconfig used when running out-of-cluster. Ignored in-cluster, where the mounted service account is used"
+	flag.StringVar(&configFile, "config", "", "Path to a YAML file setting the flags, keyed by their camelCase names. Flags passed on the command line take precedence.")
 	flag.Parse()
 	logger := logrus.New()
 
+	// Read the flags that were not passed on the command line from the
+	// config file
+	if configFile != "" {
+		fileConfig, err := operatorconfig.Load(configFile)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if err := fileConfig.Apply(flag.CommandLine); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	// Set the log format of both the operator and the packages logging
+	// through the standard logger
+	for _, l := range []*logrus.Logger{logger, logrus.StandardLogger()} {
+		if err := setLogFormat(l, logFormat); err != nil {
+			logger.Error(err)
+			os.Exit(1)
+		}
+	}
+
 	// Set verbosity level
 	parsedLevel, err := logrus.ParseLevel(loglvl)
 	if err != nil {
@@ -106,28 +412,168 @@ func main() {
 	}
 	logger.SetLevel(parsedLevel)
 
+	// Route the logs of controller-runtime and client-go through the logger
+	// of the operator, so that they share its format and level
+	log.SetLogger(logging.NewLogger(logger).WithName("controller-runtime"))
+	followLevel := kubeAPILogLevel < 0
+	if followLevel {
+		kubeAPILogLevel = logging.KlogVerbosity(parsedLevel)
+	}
+	if err := logging.RedirectKlog(logger, kubeAPILogLevel); err != nil {
+		logger.Fatalf("invalid -kube-api-log-level: %v", err)
+	}
+
+	// The level can be changed at runtime by setting MARKETPLACE_LOG_LEVEL
+	// and sending a SIGHUP
+	signals.OnReload(func() {
+		logger.SetLevel(logrus.GetLevel())
+		if followLevel {
+			if err := logging.SetKlogVerbosity(logging.KlogVerbosity(logrus.GetLevel())); err != nil {
+				logger.Error(err)
+			}
+		}
+	})
+
 	// Check if version flag was set
 	if version {
 		logger.Infof("%s", sourceCommit.String())
 		os.Exit(0)
 	}
 
+	if validateDefaults {
+		os.Exit(runDefaultsValidation(os.Stdout, defaults.Dir))
+	}
+
+	if maxConcurrentReconciles < 1 {
+		logger.Fatalf("invalid -max-concurrent-reconciles %d, must be at least 1", maxConcurrentReconciles)
+	}
+	if defaultsURLs != "" && defaults.ConfigMap != "" {
+		logger.Fatal("-defaults-url can not be combined with -defaults-configmap")
+	}
+	if syncPeriod < minSyncPeriod {
+		logger.Fatalf("invalid -sync-period %s, must be at least %s", syncPeriod, minSyncPeriod)
+	}
+	if webhookPort < 0 || webhookPort > 65535 {
+		logger.Fatalf("invalid -webhook-port %d", webhookPort)
+	}
+	if conditionHistorySize < 1 {
+		logger.Fatalf("invalid -condition-history-size %d, must be at least 1", conditionHistorySize)
+	}
+	if statusUpdateRetries < 0 {
+		logger.Fatalf("invalid -status-update-retries %d, must not be negative", statusUpdateRetries)
+	}
+	if statusResyncInterval < 0 {
+		logger.Fatalf("invalid -status-resync-interval %s, must not be negative", statusResyncInterval)
+	}
+	if conditionDecayTimeout != 0 && conditionDecayTimeout < backoff.DefaultMaxDelay {
+		logger.Fatalf("invalid -condition-decay-timeout %s, must be 0 or at least %s", conditionDecayTimeout, backoff.DefaultMaxDelay)
+	}
+	if rolloutBatchSize < 0 {
+		logger.Fatalf("invalid -defaults-rollout-batch-size %d, must not be negative", rolloutBatchSize)
+	}
+	if rolloutWaitTimeout <= 0 {
+		logger.Fatalf("invalid -defaults-rollout-wait-timeout %s, must be positive", rolloutWaitTimeout)
+	}
+	if apiServerFailures < 0 {
+		logger.Fatalf("invalid -healthz-apiserver-failure-threshold %d, must not be negative", apiServerFailures)
+	}
+	if defaults.CanaryTimeout < 0 {
+		logger.Fatalf("invalid -canary-timeout %s, must not be negative", defaults.CanaryTimeout)
+	}
+	imageMirrors, err := defaults.ParseImageMirrors(imageMirrorPrefixes)
+	if err != nil {
+		logger.Fatalf("invalid -image-mirror-prefix: %v", err)
+	}
+	oauthRegistryEndpoints, err := catalogauth.ParseEndpoints(oauthEndpoints)
+	if err != nil {
+		logger.Fatalf("invalid -oauth-registry-endpoint: %v", err)
+	}
+	if len(oauthRegistryEndpoints) > 0 && oauthClientSecret == "" {
+		logger.Fatal("-oauth-registry-endpoint requires -oauth-client-secret")
+	}
+	for registry := range oauthRegistryEndpoints {
+		defaults.RegistrySecrets[registry] = catalogauth.SecretName(registry)
+	}
+
+	// The metrics are served on all interfaces on the -metrics-port unless
+	// -metrics-addr is given, even if empty.
+	if !isFlagSet("metrics-addr") {
+		metricsAddr = net.JoinHostPort("", strconv.Itoa(metricsPort))
+	}
+	minVersion, err := metrics.TLSVersion(tlsMinVersion)
+	if err != nil {
+		logger.Fatalf("invalid -tls-min-version: %v", err)
+	}
+	var cipherSuiteNames []string
+	if tlsCipherSuites != "" {
+		cipherSuiteNames = strings.Split(tlsCipherSuites, ",")
+	}
+	cipherSuites, err := metrics.CipherSuites(cipherSuiteNames)
+	if err != nil {
+		logger.Fatalf("invalid -tls-cipher-suites: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(signals.Context())
+	defer cancel()
+
+	// The HTTP servers are shut down once ctx is done, main waits for the
+	// in-flight requests to complete before it exits.
+	var servers sync.WaitGroup
+	serve := func(name string, server *httpserver.Server) {
+		servers.Add(1)
+		go func() {
+			defer servers.Done()
+			if err := server.Serve(ctx); err != nil {
+				logger.Fatalf("failed to serve %s: %v", name, err)
+			}
+		}()
+	}
+
 	// set TLS to serve metrics over a secure channel if cert is provided
 	// cert is provided by default by the marketplace-trusted-ca volume mounted as part of the marketplace-operator deployment
-	if err := metrics.ServePrometheus(tlsCertPath, tlsKeyPath); err != nil {
+	metricsServer, err := metrics.ServePrometheus(metrics.ServeOptions{
+		CertPath:        tlsCertPath,
+		KeyPath:         tlsKeyPath,
+		AuthToken:       metricsAuthToken,
+		Addr:            metricsAddr,
+		TLSMinVersion:   minVersion,
+		TLSCipherSuites: cipherSuites,
+		ClientCAPath:    tlsClientCA,
+	})
+	if err != nil {
 		logger.Fatalf("failed to serve prometheus metrics: %s", err)
 	}
+	if metricsServer != nil {
+		serve("the prometheus metrics", metricsServer)
+	}
 
-	namespace, err := apiutils.GetWatchNamespace()
+	// Get a config to talk to the apiserver
+	cfg, inCluster, err := getConfig(logger, flag.Lookup(config.KubeconfigFlagName).Value.String())
 	if err != nil {
-		logger.Fatalf("failed to get watch namespace: %v", err)
+		logger.Fatal(err)
 	}
 
-	// Get a config to talk to the apiserver
-	cfg, err := config.GetConfig()
+	if watchNamespace != "" {
+		if inCluster {
+			logger.Warnf("ignoring -watch-namespace %s, running in-cluster", watchNamespace)
+		} else {
+			apiutils.SetWatchNamespaces([]string{watchNamespace})
+		}
+	}
+	namespace, err := apiutils.GetWatchNamespace()
 	if err != nil {
+		logger.Fatalf("failed to get watch namespace: %v", err)
+	}
+	// The writes of the operator are recorded under a field manager of its
+	// namespace, so that another operator instance managing the default
+	// CatalogSources is detected.
+	cfg.UserAgent = catalogsource.FieldManager(namespace) + "/" + sourceCommit.GitCommit
+	// The manager and the leader election clientset are both created from
+	// cfg.
+	if err := setRateLimits(cfg, kubeAPIQPS, kubeAPIBurst); err != nil {
 		logger.Fatal(err)
 	}
+	logger.Infof("limiting the requests to the apiserver to %v per second, with bursts of %d", cfg.QPS, cfg.Burst)
 
 	// Set OpenShift config API availability
 	if err := configv1.SetConfigAPIAvailability(cfg); err != nil {
@@ -137,42 +583,165 @@ func main() {
 	logger.Info("setting up scheme")
 	scheme := setupScheme()
 
+	// Only the namespaces matching the selector are watched, along with the
+	// namespace of the operator that holds its own objects.
+	var watchNamespaces []string
+	if watchNamespaceSelector != "" {
+		reader, err := client.New(cfg, client.Options{Scheme: scheme})
+		if err != nil {
+			logger.Fatal(err)
+		}
+		selected, err := apiutils.GetWatchNamespacesBySelector(context.TODO(), reader, watchNamespaceSelector)
+		if err != nil {
+			logger.Fatalf("invalid -watch-namespace-selector: %v", err)
+		}
+		watchNamespaces = []string{namespace}
+		for _, ns := range selected {
+			if ns != namespace {
+				watchNamespaces = append(watchNamespaces, ns)
+			}
+		}
+		apiutils.SetWatchNamespaces(watchNamespaces)
+		logger.Infof("watching namespaces %s", strings.Join(watchNamespaces, ", "))
+	}
+
+	// Fail early with a clear message rather than letting the manager block
+	// on, or panic over, a missing API or permission.
+	logger.Info("running pre-flight checks")
+	if err := preflight.RunChecks(cfg, scheme); err != nil {
+		logger.Fatalf("pre-flight checks failed: %v", err)
+	}
+
 	// Even though we are asking to watch all namespaces, we only handle events
 	// from the operator's namespace. The reason for watching all namespaces is
 	// watch for CatalogSources in targetNamespaces being deleted and recreate
-	// them.
+	// them. Only the namespaces selected by -watch-namespace-selector are
+	// watched if it is set.
 	//
 	// Note(tflannag): Setting the `MetricsBindAddress` to `0` here disables the
 	// metrics listener from controller-runtime. Previously, this was disabled by
 	// default in <v0.2.0, but it's now enabled by default and the default port
-	// conflicts with the same port we bind for the health checks.
-	mgr, err := manager.New(cfg, manager.Options{
-		Metrics:          metricsserver.Options{BindAddress: "0"},
-		PprofBindAddress: pprofAddress,
-		Scheme:           scheme,
-		Cache: cache.Options{
-			ByObject: map[client.Object]cache.ByObject{
-				&corev1.ConfigMap{}: {
-					Field: fields.SelectorFromSet(fields.Set{
-						"metadata.namespace": namespace,
-						"metadata.name":      certificateauthority.TrustedCaConfigMapName,
-					}),
-				},
-			},
+	// conflicts with the same port we bind for the health checks. The
+	// controller-runtime metrics are served on the marketplace metrics
+	// endpoint instead.
+	cacheByObject := map[client.Object]cache.ByObject{
+		&corev1.ConfigMap{}: {
+			Field: fields.SelectorFromSet(fields.Set{
+				"metadata.namespace": namespace,
+				"metadata.name":      certificateauthority.TrustedCaConfigMapName,
+			}),
+		},
+		// Only the OLM operator pods are watched, to co-locate the
+		// default CatalogSource pods with them.
+		&corev1.Pod{}: {
+			Namespaces: map[string]cache.Config{catalogaffinity.OLMNamespace: {}},
+			Label: labels.SelectorFromSet(labels.Set{
+				catalogaffinity.OLMPodLabelKey: catalogaffinity.OLMPodLabelValue,
+			}),
 		},
+	}
+	// Only the Secrets of the operator namespace are read, for the
+	// registry OAuth tokens.
+	cacheByObject[&corev1.Secret{}] = cache.ByObject{
+		Namespaces: map[string]cache.Config{namespace: {}},
+	}
+	// Only the Ingresses exposing the default CatalogSources are watched.
+	cacheByObject[&networkingv1.Ingress{}] = cache.ByObject{
+		Namespaces: map[string]cache.Config{namespace: {}},
+	}
+	if configv1.IsAPIAvailable() {
+		// Only the ImageStreams the default CatalogSources may refer to are
+		// watched, to reconcile them when their tags are pushed.
+		cacheByObject[&imagev1.ImageStream{}] = cache.ByObject{
+			Namespaces: map[string]cache.Config{catalogsource.ImageStreamNamespace: {}},
+		}
+		// Only the ClusterVersion reporting the cluster upgrades is watched.
+		cacheByObject[&apiconfigv1.ClusterVersion{}] = cache.ByObject{
+			Field: fields.SelectorFromSet(fields.Set{"metadata.name": catalogsource.ClusterVersionName}),
+		}
+	}
+	cacheOptions := cache.Options{ByObject: cacheByObject, SyncPeriod: &syncPeriod}
+	if len(watchNamespaces) > 0 {
+		cacheOptions.DefaultNamespaces = make(map[string]cache.Config, len(watchNamespaces))
+		for _, ns := range watchNamespaces {
+			cacheOptions.DefaultNamespaces[ns] = cache.Config{}
+		}
+	}
+	mgr, err := manager.New(cfg, manager.Options{
+		Metrics: metricsserver.Options{BindAddress: "0"},
+		Scheme:  scheme,
+		Cache:   cacheOptions,
+		// The webhook server is only started if the webhooks are enabled.
+		WebhookServer: ctrlwebhook.NewServer(ctrlwebhook.Options{Port: webhookPort, CertDir: webhookCertDir}),
+		// The manager waits for its runnables for the same time main waits
+		// for the manager.
+		GracefulShutdownTimeout: &gracefulShutdownTimeout,
 	})
 	if err != nil {
 		logger.Fatal(err)
 	}
 
 	logger.Info("setting up health checks")
-	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	})
-	go http.ListenAndServe(":8080", nil)
+	// The /healthz endpoint fails if the leader is unable to renew its lease,
+	// while /readyz only reports ready on the replica that holds the leader
+	// lock. The adaptor only becomes active once the election is running, and
+	// its timeout is counted from the end of the lease duration.
+	leaderHealthz := leaderelection.NewLeaderHealthzAdaptor(defaultRenewDeadline + leaderHealthzGracePeriod - defaultLeaseDuration)
+	// The /healthz endpoint also fails once the API server could not be
+	// reached for a few consecutive checks, as happens with an expired
+	// service account token.
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		logger.Fatal(fmt.Errorf("failed to initialize the discovery client: %v", err))
+	}
+	apiServerHealthz := health.NewAPIServerChecker(health.VersionCheck(discoveryClient.RESTClient()),
+		health.DefaultAPIServerCheckInterval, apiServerFailures)
+	go apiServerHealthz.Run(ctx)
+	// The /readyz endpoint also waits for the default CatalogSources to be
+	// populated and, when reporting to a ClusterOperator, for its status to
+	// be first set.
+	readiness := &health.Readiness{}
+	defaultsPopulated := readiness.Register("defaults-populated")
+	// The webhook Service only routes the admission requests to the ready
+	// replica, which must then be serving the webhooks of its manager.
+	var webhooksServing *health.Precondition
+	if webhookPort != 0 {
+		webhooksServing = readiness.Register("webhooks-serving")
+	}
+	reporterOptions := []status.ReporterOption{status.WithConditionHistorySize(conditionHistorySize), status.WithStatusUpdateRetries(statusUpdateRetries), status.WithConditionDecayTimeout(conditionDecayTimeout), status.WithCommit(sourceCommit.GitCommit)}
+	if clusterOperatorName != "" {
+		clusterOperatorReported := readiness.Register("clusteroperator-reported")
+		reporterOptions = append(reporterOptions, status.WithOnReported(func() { clusterOperatorReported.Set(true) }))
+	}
+	// The status page reads from the apiserver, as the cache only runs on
+	// the leader.
+	statusPage := status.NewStatusPage(mgr.GetAPIReader(), namespace, clusterOperatorName, os.Getenv("RELEASE_VERSION"), readiness.IsLeader)
+	healthServer, err := listenHealth(healthzAddr, health.NewLiveness(leaderHealthz, apiServerHealthz), readiness, statusPage)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if healthServer != nil {
+		serve("the health checks", healthServer)
+	} else {
+		logger.Info("-healthz-addr is empty, the health listener is disabled")
+	}
 
-	ctx, cancel := context.WithCancel(signals.Context())
-	defer cancel()
+	if pprofAddr != "" {
+		server := httpserver.New(pprofAddr, newPprofMux())
+		// The CPU profiles and traces are written for as long as they are
+		// requested.
+		server.WriteTimeout = 0
+		pprofServer, err := httpserver.Listen(server)
+		if err != nil {
+			logger.Fatalf("failed to serve pprof: %v", err)
+		}
+		logger.Infof("serving pprof on %s", pprofAddr)
+		serve("pprof", pprofServer)
+	}
+
+	// The latency percentiles are computed on every replica, like the other
+	// marketplace metrics are served.
+	go metrics.NewLatencyPercentileGauge(latencyUpdateInterval).Start(ctx)
 
 	run := func(ctx context.Context) {
 		stopCh := ctx.Done()
@@ -180,30 +749,174 @@ func main() {
 		var statusReporter status.Reporter = &status.NoOpReporter{}
 		if clusterOperatorName != "" {
 			logger.Info("setting up the marketplace clusteroperator status reporter")
-			statusReporter, err = status.NewReporter(cfg, mgr, namespace, clusterOperatorName, os.Getenv("RELEASE_VERSION"), stopCh)
+			statusReporter, err = status.NewReporter(cfg, mgr, namespace, clusterOperatorName, os.Getenv("RELEASE_VERSION"), stopCh, reporterOptions...)
 			if err != nil {
 				logger.Fatal(err)
 			}
+			// The status is re-synced by the reporter itself, before
+			// the alerting wrapper that starts its checks on each call.
+			if statusResyncInterval > 0 {
+				logger.Infof("re-syncing the clusteroperator status every %s", statusResyncInterval)
+				statusReporter = status.NewPeriodicReporter(statusReporter, statusResyncInterval, stopCh)
+			}
+			if alertWebhookURL != "" {
+				logger.Infof("alerting when the clusteroperator is degraded for %s", alertAfter)
+				sender, err := status.NewWebhookSender(alertWebhookFormat, alertWebhookURL, alertRoutingKey)
+				if err != nil {
+					logger.Fatalf("invalid alert webhook: %v", err)
+				}
+				statusReporter, err = status.NewAlertingReporter(cfg, statusReporter, clusterOperatorName, alertAfter, sender, stopCh)
+				if err != nil {
+					logger.Fatal(err)
+				}
+			}
 		}
 
 		// Populate the global default CatalogSource definitions and config
-		if err := defaults.PopulateGlobals(); err != nil {
+		var populateOptions []defaults.Option
+		if owner, err := operatorOwnerReference(ctx, mgr.GetAPIReader(), namespace, os.Getenv("OPERATOR_NAME")); err != nil {
+			logger.Warnf("the default CatalogSources will not be owned by the operator Deployment: %v", err)
+		} else {
+			populateOptions = append(populateOptions, defaults.WithOwnerReference(owner))
+		}
+		if len(imageMirrors) > 0 {
+			populateOptions = append(populateOptions, defaults.WithImageMirrors(imageMirrors))
+		}
+		if resolveImageDigests {
+			// The ImageStreams are only available on OpenShift.
+			var imageStreams client.Reader
+			if configv1.IsAPIAvailable() {
+				imageStreams = mgr.GetAPIReader()
+			}
+			resolver := defaults.NewCatalogSourceImageDigestResolver(imageStreams, catalogsource.ImageStreamNamespace, defaults.DefaultDigestResolveTimeout)
+			populateOptions = append(populateOptions, defaults.WithDigestResolver(resolver))
+		}
+		populateGlobals := func() (defaults.PopulationResult, error) {
+			return defaults.PopulateGlobals(populateOptions...)
+		}
+		if defaults.ConfigMap != "" {
+			populateGlobals = func() (defaults.PopulationResult, error) {
+				return defaults.PopulateGlobalsFromConfigMap(ctx, mgr.GetAPIReader(), defaults.ConfigMap, populateOptions...)
+			}
+		}
+		// The defaults are reloaded when the defaults directory changes, it
+		// is not read if the defaults are read from a ConfigMap.
+		var reloadLoader defaults.Loader = defaults.DirLoader{Dir: defaults.Dir}
+		if defaultsURLs != "" {
+			loaders := []defaults.Loader{defaults.DirLoader{Dir: defaults.Dir}}
+			for _, url := range strings.Split(defaultsURLs, ",") {
+				loaders = append(loaders, defaults.NewHTTPLoader(url, defaultsURLTimeout))
+			}
+			loader := defaults.NewMultiSourceLoader(loaders...)
+			populateGlobals = func() (defaults.PopulationResult, error) {
+				return defaults.PopulateGlobalsFrom(ctx, loader, populateOptions...)
+			}
+			reloadLoader = loader
+		}
+		reloadDefaultsDir := defaults.Dir
+		if defaults.ConfigMap != "" {
+			reloadDefaultsDir = ""
+		}
+		reloadDefaults := func(ctx context.Context) error {
+			population, err := defaults.ReloadGlobalsFrom(ctx, reloadLoader, populateOptions...)
+			logPopulationResult(logger, population)
+			return err
+		}
+
+		// start reporting the marketplace clusteroperator status before
+		// populating the defaults, so that a failure to populate them is
+		// reported while they are retried
+		logger.Info("starting the marketplace clusteroperator status reporter")
+		statusReportingDoneCh := statusReporter.StartReporting()
+
+		// The default CatalogSources whose definition was removed, for
+		// instance by an upgrade of the operator, are pruned once the defaults
+		// are populated, through the apiserver as the cache is not started
+		// yet.
+		pruneClient, err := client.New(cfg, client.Options{Scheme: scheme})
+		if err != nil {
 			logger.Fatal(err)
 		}
+		populateAndPrune := func() (defaults.PopulationResult, error) {
+			population, err := populateGlobals()
+			if err == nil {
+				if err := defaults.PruneObsoleteGlobals(ctx, pruneClient); err != nil {
+					logger.Errorf("error pruning the obsolete default CatalogSources: %v", err)
+				}
+			}
+			return population, err
+		}
+
+		// The controllers all manage the default CatalogSources, they are
+		// only set up once the defaults are populated.
+		if err := populateDefaults(ctx, logger, populateAndPrune, statusReporter, wait.Backoff{
+			Duration: defaultsRetryInitialDelay,
+			Factor:   2,
+			Jitter:   0.1,
+			Steps:    math.MaxInt32,
+			Cap:      defaultsRetryMaxDelay,
+		}); err != nil {
+			<-statusReportingDoneCh
+			return
+		}
+		defaultsPopulated.Set(true)
 
 		logger.Info("setting up controllers")
-		if err := controller.AddToManager(mgr, options.ControllerOptions{}); err != nil {
+		if err := controller.AddToManager(mgr, options.ControllerOptions{
+			EnforceImmutableSpec:       enforceImmutableSpec,
+			Namespace:                  namespace,
+			Version:                    os.Getenv("RELEASE_VERSION"),
+			ExposeCatalogsExternally:   exposeCatalogs,
+			CatalogIngressClass:        catalogIngressClass,
+			CatalogIngressDomain:       catalogIngressDomain,
+			MessageTemplateConfigMap:   messageTemplateCM,
+			MaxConcurrentReconciles:    maxConcurrentReconciles,
+			FailureReporter:            statusReporter,
+			EnableWebhooks:             webhookPort != 0,
+			OAuthRegistryEndpoints:     oauthRegistryEndpoints,
+			OAuthClientSecret:          oauthClientSecret,
+			DefaultsRolloutBatchSize:   rolloutBatchSize,
+			DefaultsRolloutWaitTimeout: rolloutWaitTimeout,
+			DefaultsDir:                reloadDefaultsDir,
+			ReloadDefaults:             reloadDefaults,
+			ConditionDecayTimeout:      conditionDecayTimeout,
+		}); err != nil {
 			logger.Fatal(err)
 		}
 
-		// start reporting the marketplace clusteroperator status reporting before
-		// starting the manager instance as mgr.Start is blocking
-		logger.Info("starting the marketplace clusteroperator status reporter")
-		statusReportingDoneCh := statusReporter.StartReporting()
+		go func() {
+			if mgr.GetCache().WaitForCacheSync(ctx) {
+				readiness.SetCacheSynced(true)
+			}
+		}()
+		if webhooksServing != nil {
+			go func() {
+				started := mgr.GetWebhookServer().StartedChecker()
+				if err := wait.PollUntilContextCancel(ctx, time.Second, true, func(context.Context) (bool, error) {
+					return started(nil) == nil, nil
+				}); err == nil {
+					webhooksServing.Set(true)
+				}
+			}()
+		}
 
 		logger.Info("starting manager")
-		if err := mgr.Start(ctx); err != nil {
-			logger.WithError(err).Error("unable to run manager")
+		managerDone := make(chan struct{})
+		go func() {
+			defer close(managerDone)
+			if err := mgr.Start(ctx); err != nil {
+				logger.WithError(err).Error("unable to run manager")
+			}
+		}()
+
+		// Do not wait for the in-flight reconciles forever on shutdown, the
+		// leader lock would otherwise never be released.
+		shutdownCtx, stopShutdown := signals.ShutdownContext(ctx, gracefulShutdownTimeout)
+		defer stopShutdown()
+		select {
+		case <-managerDone:
+		case <-shutdownCtx.Done():
+			logger.Warnf("the manager did not stop within the graceful shutdown timeout of %s", gracefulShutdownTimeout)
 		}
 
 		// Wait for ClusterOperator status reporting routine to close the statusReportingDoneCh channel.
@@ -215,42 +928,76 @@ func main() {
 		logger.Fatal(fmt.Errorf("failed to initialize the kubernetes clientset: %v", err))
 	}
 
-	id := os.Getenv("POD_NAME")
-	if id == "" {
-		logger.Warn("failed to determine $POD_NAME falling back to hostname")
-		id, err = os.Hostname()
-		if err != nil {
-			logger.Fatal(err)
-		}
+	// The leader aggregates the metrics of every replica behind the metrics
+	// Service so that a single scrape covers active-passive deployments.
+	aggregator, err := metrics.NewAggregator(client.CoreV1(), namespace, metricsServiceName,
+		tlsCertPath, tlsKeyPath, metricsAuthToken, readiness.IsLeader)
+	if err != nil {
+		logger.Fatalf("failed to aggregate the metrics of the replicas: %v", err)
 	}
+	metrics.ServeAggregated(aggregator, metricsAuthToken)
+
+	id, err := leader.Identity(ctx, client.CoreV1(), namespace)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("using leader election identity %s", id)
+	metrics.SetLeaderStatus(id, false)
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events(leaderElectionNamespace)})
+	defer eventBroadcaster.Shutdown()
+	eventRecorder := eventBroadcaster.NewRecorder(scheme, corev1.EventSource{Component: id})
 
 	rl, err := resourcelock.New(resourcelock.LeasesResourceLock, leaderElectionNamespace, defaultLeaderElectionConfigMapName, client.CoreV1(), client.CoordinationV1(), resourcelock.ResourceLockConfig{
 		Identity:      id,
-		EventRecorder: record.NewBroadcaster().NewRecorder(scheme, corev1.EventSource{Component: id}),
+		EventRecorder: eventRecorder,
 	})
 	if err != nil {
 		logger.Fatal(err)
 	}
 
-	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+	// Leadership transitions are recorded as Events on the lock object so
+	// that they outlive the operator logs.
+	transitions := leader.NewTransitionRecorder(eventRecorder, &corev1.ObjectReference{
+		APIVersion: coordinationv1.SchemeGroupVersion.String(),
+		Kind:       "Lease",
+		Namespace:  leaderElectionNamespace,
+		Name:       defaultLeaderElectionConfigMapName,
+	}, id)
+
+	// The lock is released on shutdown, but only once the manager and the
+	// status reporter have stopped so that the next leader does not reconcile
+	// the default CatalogSources concurrently with this replica.
+	coordinator := leader.NewCoordinator(ctx)
+
+	leaderelection.RunOrDie(coordinator.Context(), leaderelection.LeaderElectionConfig{
 		Lock:            rl,
 		ReleaseOnCancel: true,
 		LeaseDuration:   defaultLeaseDuration,
 		RenewDeadline:   defaultRenewDeadline,
 		RetryPeriod:     defaultRetryPeriod,
+		WatchDog:        leaderHealthz,
 		Callbacks: leaderelection.LeaderCallbacks{
 			OnStartedLeading: func(ctx context.Context) {
 				logger.Infof("became leader: %s", id)
-				run(ctx)
+				readiness.SetLeader(true)
+				metrics.SetLeaderStatus(id, true)
+				transitions.StartedLeading()
+				coordinator.Lead(ctx, run)
 			},
 			OnStoppedLeading: func() {
 				logger.Warnf("leader election lost for %s identity", id)
+				readiness.SetLeader(false)
+				metrics.SetLeaderStatus(id, false)
+				transitions.StoppedLeading()
 				// Stop the controller just in case this doesn't coincide with container stop
 				// e.g. scale > 1 (which we don't support today and would require the ability
 				// to start/stop reconciliation dynamically)
 				cancel()
 			},
 			OnNewLeader: func(identity string) {
+				transitions.NewLeader(identity)
 				if identity == id {
 					return
 				}
@@ -258,4 +1005,104 @@ func main() {
 			},
 		},
 	})
+
+	servers.Wait()
+}
+
+// runDefaultsValidation validates the default CatalogSources of dir, then
+// populates them as the operator does, and writes the result of each file to
+// w. It returns the exit status of the validation, 0 if every file is valid
+// and 1 otherwise.
+func runDefaultsValidation(w io.Writer, dir string) int {
+	if dir == "" {
+		fmt.Fprintln(w, "FAIL: -defaultsDir is not set")
+		return 1
+	}
+	results, err := defaults.ValidateDir(dir)
+	if err != nil {
+		fmt.Fprintf(w, "FAIL %s: %s\n", dir, describeDefaultsError(err))
+		return 1
+	}
+
+	invalid := 0
+	for _, result := range results {
+		if result.Err != nil {
+			invalid++
+			fmt.Fprintf(w, "FAIL %s: %v\n", result.Path, result.Err)
+			continue
+		}
+		fmt.Fprintf(w, "PASS %s: CatalogSource %s\n", result.Path, result.Name)
+	}
+	// The checks spanning the files, such as the dependency cycles, are
+	// only made when the definitions are populated.
+	if invalid == 0 {
+		if _, err := defaults.PopulateGlobals(); err != nil {
+			fmt.Fprintf(w, "FAIL %s: %s\n", dir, describeDefaultsError(err))
+			return 1
+		}
+	}
+	fmt.Fprintf(w, "%d of %d default CatalogSource files valid\n", len(results)-invalid, len(results))
+	if invalid > 0 {
+		return 1
+	}
+	return 0
+}
+
+// describeDefaultsError returns the description of the error populating the
+// default CatalogSources.
+func describeDefaultsError(err error) string {
+	var defaultsErr *defaults.DefaultsError
+	if errors.As(err, &defaultsErr) {
+		switch defaultsErr.Kind {
+		case defaults.FilesystemError:
+			return fmt.Sprintf("unable to read the default CatalogSources from %s: %v", defaultsErr.Path, defaultsErr.Cause)
+		case defaults.ValidationError:
+			return fmt.Sprintf("invalid default CatalogSource in %s: %v", defaultsErr.Path, defaultsErr.Cause)
+		case defaults.APIError:
+			return fmt.Sprintf("unable to get the default CatalogSources ConfigMap %s: %v", defaultsErr.Path, defaultsErr.Cause)
+		case defaults.HTTPError:
+			return fmt.Sprintf("unable to fetch the default CatalogSource from %s: %v", defaultsErr.Path, defaultsErr.Cause)
+		}
+	}
+	return err.Error()
+}
+
+// populateDefaults populates the default CatalogSources with populate,
+// retrying on the retry backoff until they are populated or ctx is done. The
+// result of each attempt is set on reporter, which reports the ClusterOperator
+// Degraded until the defaults are populated.
+func populateDefaults(ctx context.Context, logger *logrus.Logger, populate func() (defaults.PopulationResult, error), reporter status.Reporter, retry wait.Backoff) error {
+	for {
+		population, err := populate()
+		logPopulationResult(logger, population)
+		reporter.SetDefaultsError(err)
+		if err == nil {
+			return nil
+		}
+
+		delay := retry.Step()
+		logger.Errorf("%s, retrying in %s", describeDefaultsError(err), delay.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// logPopulationResult logs the default CatalogSources of each category of the
+// population result.
+func logPopulationResult(logger *logrus.Logger, result defaults.PopulationResult) {
+	if len(result.Created) > 0 {
+		logger.Infof("default CatalogSources created: %s", strings.Join(result.Created, ", "))
+	}
+	if len(result.Updated) > 0 {
+		logger.Infof("default CatalogSources updated: %s", strings.Join(result.Updated, ", "))
+	}
+	if len(result.Skipped) > 0 {
+		logger.Debugf("default CatalogSources unchanged: %s", strings.Join(result.Skipped, ", "))
+	}
+	if len(result.Failed) > 0 {
+		logger.Errorf("default CatalogSources failed to populate: %s", strings.Join(result.Failed, ", "))
+	}
 }
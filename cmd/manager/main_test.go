@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/status"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+func TestSetLogFormat(t *testing.T) {
+	output := func(t *testing.T, format string) string {
+		t.Helper()
+		var buf bytes.Buffer
+		logger := logrus.New()
+		logger.SetOutput(&buf)
+		require.NoError(t, setLogFormat(logger, format))
+		logger.WithField("catalogsource", "redhat-operators").Info("sample")
+		return buf.String()
+	}
+
+	t.Run("text", func(t *testing.T) {
+		line := output(t, logFormatText)
+		assert.Contains(t, line, `level=info msg=sample catalogsource=redhat-operators`)
+		assert.False(t, json.Valid([]byte(line)))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		entry := map[string]string{}
+		require.NoError(t, json.Unmarshal([]byte(output(t, logFormatJSON)), &entry))
+		assert.Equal(t, "info", entry["level"])
+		assert.Equal(t, "sample", entry["msg"])
+		assert.Equal(t, "redhat-operators", entry["catalogsource"])
+		assert.Equal(t, operatorLoggerName, entry["logger"])
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		assert.Error(t, setLogFormat(logrus.New(), "xml"))
+	})
+}
+
+func TestPprofMux(t *testing.T) {
+	get := func(t *testing.T, handler http.Handler, path string) int {
+		t.Helper()
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	assert.Equal(t, http.StatusOK, get(t, newPprofMux(), "/debug/pprof/heap"))
+
+	statusPage := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	healthMux := newHealthMux(ok, ok, statusPage)
+	assert.Equal(t, http.StatusOK, get(t, healthMux, "/healthz"))
+	assert.Equal(t, http.StatusOK, get(t, healthMux, "/readyz"))
+	assert.Equal(t, http.StatusAccepted, get(t, healthMux, "/status"))
+	assert.Equal(t, http.StatusNotFound, get(t, healthMux, "/debug/pprof/heap"), "the health port does not serve pprof")
+	assert.Equal(t, http.StatusNotFound, get(t, healthMux, "/debug/pprof/"))
+}
+
+func TestListenHealth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server, err := listenHealth("", ok, ok, ok)
+	require.NoError(t, err)
+	assert.Nil(t, server, "an empty address disables the health listener")
+
+	bound, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer bound.Close()
+	_, err = listenHealth(bound.Addr().String(), ok, ok, ok)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "-healthz-addr "+bound.Addr().String())
+}
+
+func TestSetRateLimits(t *testing.T) {
+	cfg := &rest.Config{Host: "https://127.0.0.1:6443", RateLimiter: flowcontrol.NewFakeAlwaysRateLimiter()}
+	require.NoError(t, setRateLimits(cfg, 50, 100))
+	assert.Equal(t, float32(50), cfg.QPS)
+	assert.Equal(t, 100, cfg.Burst)
+
+	// The manager client and the leader election clientset are both created
+	// from the config.
+	clientset, err := kubernetes.NewForConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, float32(50), clientset.CoreV1().RESTClient().GetRateLimiter().QPS())
+	mgr, err := manager.New(cfg, manager.Options{Metrics: metricsserver.Options{BindAddress: "0"}})
+	require.NoError(t, err)
+	assert.Equal(t, float32(50), mgr.GetConfig().QPS)
+	assert.Equal(t, 100, mgr.GetConfig().Burst)
+
+	for _, limits := range []struct {
+		qps   float64
+		burst int
+	}{{0, 10}, {-1, 10}, {10, 0}, {10, 5}} {
+		assert.Error(t, setRateLimits(&rest.Config{}, limits.qps, limits.burst), "qps %v burst %d", limits.qps, limits.burst)
+	}
+}
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:6443
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+current-context: dev
+users:
+- name: dev
+  user:
+    token: dev-token
+`
+
+func TestGetConfig(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(testKubeconfig), 0600))
+	defer func(original func() (*rest.Config, error)) { inClusterConfig = original }(inClusterConfig)
+
+	t.Run("out-of-cluster", func(t *testing.T) {
+		inClusterConfig = func() (*rest.Config, error) { return nil, rest.ErrNotInCluster }
+		logger, hook := logtest.NewNullLogger()
+
+		cfg, inCluster, err := getConfig(logger, kubeconfig)
+		require.NoError(t, err)
+		assert.False(t, inCluster)
+		assert.Equal(t, "https://dev.example.com:6443", cfg.Host)
+		assert.Equal(t, "dev-token", cfg.BearerToken)
+		assert.Empty(t, hook.AllEntries())
+
+		_, _, err = getConfig(logger, filepath.Join(t.TempDir(), "missing"))
+		assert.Error(t, err)
+	})
+
+	t.Run("in-cluster", func(t *testing.T) {
+		inClusterConfig = func() (*rest.Config, error) {
+			return &rest.Config{Host: "https://172.30.0.1:443", BearerToken: "service-account-token"}, nil
+		}
+		logger, hook := logtest.NewNullLogger()
+
+		cfg, inCluster, err := getConfig(logger, kubeconfig)
+		require.NoError(t, err)
+		assert.True(t, inCluster)
+		assert.Equal(t, "https://172.30.0.1:443", cfg.Host, "the kubeconfig is ignored in-cluster")
+		assert.Equal(t, "service-account-token", cfg.BearerToken)
+		require.NotNil(t, hook.LastEntry())
+		assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+		assert.Contains(t, hook.LastEntry().Message, "ignoring -kubeconfig")
+	})
+}
+
+// defaultsErrorRecorder is a status.Reporter recording the errors populating
+// the default CatalogSources it is given.
+type defaultsErrorRecorder struct {
+	status.NoOpReporter
+	errs []error
+}
+
+func (r *defaultsErrorRecorder) SetDefaultsError(err error) {
+	r.errs = append(r.errs, err)
+}
+
+func TestPopulateDefaultsRetries(t *testing.T) {
+	invalid := &defaults.DefaultsError{Kind: defaults.ValidationError, Path: "/defaults/redhat-operators.yaml", Cause: errors.New("error converting YAML to JSON")}
+	attempts := 0
+	populate := func() (defaults.PopulationResult, error) {
+		attempts++
+		if attempts < 3 {
+			return defaults.PopulationResult{Failed: []string{"redhat-operators"}}, invalid
+		}
+		return defaults.PopulationResult{Created: []string{"redhat-operators"}}, nil
+	}
+	logger, hook := logtest.NewNullLogger()
+	reporter := &defaultsErrorRecorder{}
+
+	require.NoError(t, populateDefaults(context.TODO(), logger, populate, reporter, wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 10}))
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, []error{invalid, invalid, nil}, reporter.errs, "the failures are reported until the defaults are populated")
+	var retries int
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.ErrorLevel && entry.Message != "default CatalogSources failed to populate: redhat-operators" {
+			assert.Contains(t, entry.Message, "invalid default CatalogSource in /defaults/redhat-operators.yaml: error converting YAML to JSON, retrying in ")
+			retries++
+		}
+	}
+	assert.Equal(t, 2, retries)
+
+	// The retries stop with ctx.
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	err := populateDefaults(ctx, logger, func() (defaults.PopulationResult, error) { return defaults.PopulationResult{}, invalid }, &defaultsErrorRecorder{}, wait.Backoff{Duration: time.Hour})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRunDefaultsValidation(t *testing.T) {
+	previous := defaults.Dir
+	t.Cleanup(func() {
+		defaults.Dir = previous
+		_, err := defaults.PopulateGlobals()
+		require.NoError(t, err)
+	})
+
+	var report bytes.Buffer
+	shipped := filepath.Join("..", "..", "defaults")
+	defaults.Dir = shipped
+	assert.Equal(t, 0, runDefaultsValidation(&report, shipped), report.String())
+	assert.Contains(t, report.String(), "PASS "+filepath.Join(shipped, "01_redhat_operators.cr.yaml")+": CatalogSource redhat-operators")
+	assert.NotContains(t, report.String(), "FAIL")
+
+	dir := t.TempDir()
+	for name, manifest := range map[string]string{
+		"redhat-operators.yaml": "apiVersion: operators.coreos.com/v1alpha1\nkind: CatalogSource\nmetadata:\n  name: redhat-operators\n  namespace: openshift-marketplace\nspec:\n  sourceType: grpc\n  image: quay.io/example/redhat-operators:latest\n",
+		"broken.yaml":           "kind: [CatalogSource",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(manifest), 0644))
+	}
+	defaults.Dir = dir
+	report.Reset()
+	assert.Equal(t, 1, runDefaultsValidation(&report, dir))
+	assert.Contains(t, report.String(), "FAIL "+filepath.Join(dir, "broken.yaml")+": unable to decode the manifest")
+	assert.Contains(t, report.String(), "PASS "+filepath.Join(dir, "redhat-operators.yaml"))
+	assert.Contains(t, report.String(), "1 of 2 default CatalogSource files valid")
+
+	report.Reset()
+	assert.Equal(t, 1, runDefaultsValidation(&report, ""))
+	assert.Contains(t, report.String(), "-defaultsDir is not set")
+}
@@ -0,0 +1,54 @@
+package shared
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// ManagedByLabelKey is the well-known label naming the tool managing an
+	// object.
+	ManagedByLabelKey = "app.kubernetes.io/managed-by"
+
+	// VersionLabelKey is the well-known label holding the version of the tool
+	// managing an object.
+	VersionLabelKey = "app.kubernetes.io/version"
+
+	// ManagedLabelKey is the label, set to "true", of every object the
+	// marketplace operator manages.
+	ManagedLabelKey = "marketplace.operator.openshift.io/managed"
+
+	// maxLabelValueLength is the maximum length of a label value.
+	maxLabelValueLength = 63
+)
+
+// invalidLabelValueChars matches the characters that are not allowed in a
+// label value.
+var invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// ManagedLabels returns the labels of the objects managed by the tool of the
+// given name and version. The managed-by and version labels are omitted if
+// name or version is empty. The characters of version that are not allowed in
+// a label value, such as the + of the semver build metadata, are replaced
+// with an underscore.
+func ManagedLabels(name, version string) map[string]string {
+	labels := map[string]string{ManagedLabelKey: "true"}
+	if value := labelValue(name); value != "" {
+		labels[ManagedByLabelKey] = value
+	}
+	if value := labelValue(version); value != "" {
+		labels[VersionLabelKey] = value
+	}
+	return labels
+}
+
+// labelValue returns value as a valid label value: at most 63 characters,
+// each alphanumeric or one of -_., beginning and ending with an alphanumeric
+// character.
+func labelValue(value string) string {
+	value = invalidLabelValueChars.ReplaceAllString(value, "_")
+	if len(value) > maxLabelValueLength {
+		value = value[:maxLabelValueLength]
+	}
+	return strings.Trim(value, "._-")
+}
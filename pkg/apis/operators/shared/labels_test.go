@@ -0,0 +1,78 @@
+package shared
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestManagedLabels(t *testing.T) {
+	tests := []struct {
+		description string
+		name        string
+		version     string
+		expected    map[string]string
+	}{
+		{
+			description: "name and version",
+			name:        "marketplace-operator",
+			version:     "4.18.0",
+			expected: map[string]string{
+				ManagedLabelKey:   "true",
+				ManagedByLabelKey: "marketplace-operator",
+				VersionLabelKey:   "4.18.0",
+			},
+		},
+		{
+			description: "empty name",
+			version:     "4.18.0",
+			expected:    map[string]string{ManagedLabelKey: "true", VersionLabelKey: "4.18.0"},
+		},
+		{
+			description: "empty version",
+			name:        "marketplace-operator",
+			expected:    map[string]string{ManagedLabelKey: "true", ManagedByLabelKey: "marketplace-operator"},
+		},
+		{
+			description: "semver with build metadata",
+			name:        "marketplace-operator",
+			version:     "1.2.3-rc.1+build.5",
+			expected: map[string]string{
+				ManagedLabelKey:   "true",
+				ManagedByLabelKey: "marketplace-operator",
+				VersionLabelKey:   "1.2.3-rc.1_build.5",
+			},
+		},
+		{
+			description: "version with a leading v and a trailing build separator",
+			name:        "marketplace-operator",
+			version:     "v4.18.0+",
+			expected: map[string]string{
+				ManagedLabelKey:   "true",
+				ManagedByLabelKey: "marketplace-operator",
+				VersionLabelKey:   "v4.18.0",
+			},
+		},
+		{
+			description: "version made of invalid characters only",
+			name:        "marketplace-operator",
+			version:     "+",
+			expected:    map[string]string{ManagedLabelKey: "true", ManagedByLabelKey: "marketplace-operator"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ManagedLabels(tt.name, tt.version))
+		})
+	}
+}
+
+func TestManagedLabelsAreValid(t *testing.T) {
+	for _, version := range []string{"4.18.0-0.nightly-2025-01-01-000000+" + strings.Repeat("a", 80), "-1.0.0-", "1.0.0+20250101/amd64"} {
+		for key, value := range ManagedLabels("marketplace-operator", version) {
+			assert.Empty(t, validation.IsValidLabelValue(value), "invalid value %q of label %s for version %q", value, key, version)
+		}
+	}
+}
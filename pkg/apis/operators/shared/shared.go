@@ -1,17 +1,34 @@
 package shared
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// watchNamespaces overrides the namespaces returned by GetWatchNamespace if it
+// is not empty.
+var watchNamespaces []string
+
 // GetWatchNamespace returns the Namespace the operator should be watching for changes
 // Note: the marketplace-operator YAML manifest deployed by the CVO specifies the
 // $WATCH_NAMESPACE as an environment variable using the downward API.
 // Source: https://sdk.operatorframework.io/docs/building-operators/golang/operator-scope/
+//
+// The namespaces set with SetWatchNamespaces are returned instead, joined
+// with a comma.
 func GetWatchNamespace() (string, error) {
+	if len(watchNamespaces) > 0 {
+		return strings.Join(watchNamespaces, ","), nil
+	}
+
 	// WatchNamespaceEnvVar is the constant for env variable WATCH_NAMESPACE
 	// which specifies the Namespace to watch.
 	// An empty value means the operator is running with cluster scope.
@@ -24,6 +41,57 @@ func GetWatchNamespace() (string, error) {
 	return ns, nil
 }
 
+// SetWatchNamespaces overrides the namespaces returned by GetWatchNamespace.
+// The override is removed if namespaces is empty.
+func SetWatchNamespaces(namespaces []string) {
+	watchNamespaces = namespaces
+}
+
+// ParseNamespaceSelector parses the label selector of the namespaces to watch.
+// An empty selector is rejected, as it would select every namespace.
+func ParseNamespaceSelector(selector string) (labels.Selector, error) {
+	if strings.TrimSpace(selector) == "" {
+		return nil, fmt.Errorf("the namespace selector must not be empty")
+	}
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespace selector %q: %v", selector, err)
+	}
+	return parsed, nil
+}
+
+// GetWatchNamespacesBySelector returns the sorted names of the namespaces
+// matching the given label selector. The namespaces being deleted are not
+// returned.
+func GetWatchNamespacesBySelector(ctx context.Context, reader client.Reader, selector string) ([]string, error) {
+	parsed, err := ParseNamespaceSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := &corev1.NamespaceList{}
+	if err := reader.List(ctx, namespaces, client.MatchingLabelsSelector{Selector: parsed}); err != nil {
+		return nil, fmt.Errorf("failed to list the namespaces matching %q: %v", selector, err)
+	}
+	return filterNamespaces(namespaces.Items, parsed), nil
+}
+
+// filterNamespaces returns the sorted names of the namespaces that match
+// selector and are not being deleted.
+func filterNamespaces(namespaces []corev1.Namespace, selector labels.Selector) []string {
+	var names []string
+	for _, namespace := range namespaces {
+		if !namespace.DeletionTimestamp.IsZero() || namespace.Status.Phase == corev1.NamespaceTerminating {
+			continue
+		}
+		if !selector.Matches(labels.Set(namespace.Labels)) {
+			continue
+		}
+		names = append(names, namespace.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // EnsureFinalizer ensures that the object's finalizer is included
 // in the ObjectMeta Finalizers slice. If it already exists, no state change occurs.
 // If it doesn't, the finalizer is appended to the slice.
@@ -69,17 +137,19 @@ func HasFinalizer(objectMeta *metav1.ObjectMeta, expectedFinalizer string) bool
 	return finalizerExists
 }
 
-// IsObjectInOtherNamespace returns true if the namespace is not the watched
-// namespace of the operator. An false, error will be returned if there was an
-// error getting the watched namespace.
+// IsObjectInOtherNamespace returns true if the namespace is not one of the
+// watched namespaces of the operator. An false, error will be returned if
+// there was an error getting the watched namespaces.
 func IsObjectInOtherNamespace(namespace string) (bool, error) {
 	watchNamespace, err := GetWatchNamespace()
 	if err != nil {
 		return false, err
 	}
 
-	if namespace != watchNamespace {
-		return true, nil
+	for _, watched := range strings.Split(watchNamespace, ",") {
+		if namespace == watched {
+			return false, nil
+		}
 	}
-	return false, nil
+	return true, nil
 }
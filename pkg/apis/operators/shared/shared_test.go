@@ -0,0 +1,101 @@
+package shared
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func namespace(name string, labels map[string]string) corev1.Namespace {
+	return corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestParseNamespaceSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		matches  labels.Set
+		valid    bool
+	}{
+		{selector: "tenant=true", matches: labels.Set{"tenant": "true"}, valid: true},
+		{selector: "tenant in (a, b),!excluded", matches: labels.Set{"tenant": "b"}, valid: true},
+		{selector: "tenant", matches: labels.Set{"tenant": "any"}, valid: true},
+		{selector: ""},
+		{selector: "   "},
+		{selector: "=true"},
+		{selector: "tenant in (a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector, err := ParseNamespaceSelector(tt.selector)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, selector.Matches(tt.matches))
+		})
+	}
+}
+
+func TestFilterNamespaces(t *testing.T) {
+	selector, err := ParseNamespaceSelector("tenant=true")
+	require.NoError(t, err)
+	terminating := namespace("tenant-c", map[string]string{"tenant": "true"})
+	terminating.Status.Phase = corev1.NamespaceTerminating
+	deleted := namespace("tenant-d", map[string]string{"tenant": "true"})
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+
+	names := filterNamespaces([]corev1.Namespace{
+		namespace("tenant-b", map[string]string{"tenant": "true"}),
+		namespace("tenant-a", map[string]string{"tenant": "true"}),
+		namespace("other", map[string]string{"tenant": "false"}),
+		namespace("unlabeled", nil),
+		terminating,
+		deleted,
+	}, selector)
+	assert.Equal(t, []string{"tenant-a", "tenant-b"}, names)
+}
+
+func TestGetWatchNamespacesBySelector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	a, b, other := namespace("tenant-a", map[string]string{"tenant": "true"}), namespace("tenant-b", map[string]string{"tenant": "true"}), namespace("other", nil)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&b, &a, &other).Build()
+
+	names, err := GetWatchNamespacesBySelector(context.TODO(), c, "tenant=true")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tenant-a", "tenant-b"}, names)
+
+	_, err = GetWatchNamespacesBySelector(context.TODO(), c, "")
+	assert.Error(t, err)
+}
+
+func TestSetWatchNamespaces(t *testing.T) {
+	t.Setenv("WATCH_NAMESPACE", "openshift-marketplace")
+	t.Cleanup(func() { SetWatchNamespaces(nil) })
+
+	SetWatchNamespaces([]string{"openshift-marketplace", "tenant-a"})
+	namespaces, err := GetWatchNamespace()
+	require.NoError(t, err)
+	assert.Equal(t, "openshift-marketplace,tenant-a", namespaces)
+
+	other, err := IsObjectInOtherNamespace("tenant-a")
+	require.NoError(t, err)
+	assert.False(t, other)
+	other, err = IsObjectInOtherNamespace("tenant-b")
+	require.NoError(t, err)
+	assert.True(t, other)
+
+	SetWatchNamespaces(nil)
+	namespaces, err = GetWatchNamespace()
+	require.NoError(t, err)
+	assert.Equal(t, "openshift-marketplace", namespaces)
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Config is the operator configuration read from a YAML file. Its keys mirror
+// the names of the command line flags, and only the keys present in the file
+// are applied. Values passed on the command line take precedence over the
+// file.
+type Config struct {
+	ClusterOperatorName              *string  `json:"clusterOperatorName,omitempty"`
+	DefaultsDir                      *string  `json:"defaultsDir,omitempty"`
+	DefaultsConfigMap                *string  `json:"defaultsConfigMap,omitempty"`
+	DefaultsPatchDir                 *string  `json:"defaultsPatchDir,omitempty"`
+	DefaultsURL                      []string `json:"defaultsURL,omitempty"`
+	DefaultsURLTimeout               *string  `json:"defaultsURLTimeout,omitempty"`
+	HealthzAddr                      *string  `json:"healthzAddr,omitempty"`
+	HealthzAPIServerFailureThreshold *int     `json:"healthzAPIServerFailureThreshold,omitempty"`
+	PprofAddr                        *string  `json:"pprofAddr,omitempty"`
+	PprofAddress                     *string  `json:"pprofAddress,omitempty"`
+	TLSKey                           *string  `json:"tlsKey,omitempty"`
+	TLSCert                          *string  `json:"tlsCert,omitempty"`
+	MetricsAuthToken                 *string  `json:"metricsAuthToken,omitempty"`
+	MetricsPort                      *int     `json:"metricsPort,omitempty"`
+	MetricsAddr                      *string  `json:"metricsAddr,omitempty"`
+	TLSMinVersion                    *string  `json:"tlsMinVersion,omitempty"`
+	TLSCipherSuites                  []string `json:"tlsCipherSuites,omitempty"`
+	TLSClientCA                      *string  `json:"tlsClientCA,omitempty"`
+	LatencyPercentileUpdateInterval  *string  `json:"latencyPercentileUpdateInterval,omitempty"`
+	LeaderNamespace                  *string  `json:"leaderNamespace,omitempty"`
+	Kubeconfig                       *string  `json:"kubeconfig,omitempty"`
+	WatchNamespace                   *string  `json:"watchNamespace,omitempty"`
+	WatchNamespaceSelector           *string  `json:"watchNamespaceSelector,omitempty"`
+	WebhookPort                      *int     `json:"webhookPort,omitempty"`
+	WebhookCertDir                   *string  `json:"webhookCertDir,omitempty"`
+	OAuthRegistryEndpoint            []string `json:"oauthRegistryEndpoint,omitempty"`
+	OAuthClientSecret                *string  `json:"oauthClientSecret,omitempty"`
+	EnforceImmutableSpec             *bool    `json:"enforceImmutableSpec,omitempty"`
+	ResolveImageDigests              *bool    `json:"resolveImageDigests,omitempty"`
+	ImageMirrorPrefix                []string `json:"imageMirrorPrefix,omitempty"`
+	GracefulShutdownTimeout          *string  `json:"gracefulShutdownTimeout,omitempty"`
+	ExposeCatalogsExternally         *bool    `json:"exposeCatalogsExternally,omitempty"`
+	CatalogIngressClass              *string  `json:"catalogIngressClass,omitempty"`
+	CatalogIngressDomain             *string  `json:"catalogIngressDomain,omitempty"`
+	MessageTemplateConfigMap         *string  `json:"messageTemplateConfigMap,omitempty"`
+	MaxConcurrentReconciles          *int     `json:"maxConcurrentReconciles,omitempty"`
+	SyncPeriod                       *string  `json:"syncPeriod,omitempty"`
+	CanaryTimeout                    *string  `json:"canaryTimeout,omitempty"`
+	DefaultsRolloutBatchSize         *int     `json:"defaultsRolloutBatchSize,omitempty"`
+	DefaultsRolloutWaitTimeout       *string  `json:"defaultsRolloutWaitTimeout,omitempty"`
+	AlertAfter                       *string  `json:"alertAfter,omitempty"`
+	ConditionHistorySize             *int     `json:"conditionHistorySize,omitempty"`
+	StatusUpdateRetries              *int     `json:"statusUpdateRetries,omitempty"`
+	ConditionDecayTimeout            *string  `json:"conditionDecayTimeout,omitempty"`
+	StatusResyncInterval             *string  `json:"statusResyncInterval,omitempty"`
+	AlertWebhookURL                  *string  `json:"alertWebhookURL,omitempty"`
+	AlertWebhookFormat               *string  `json:"alertWebhookFormat,omitempty"`
+	AlertPagerDutyRoutingKey         *string  `json:"alertPagerDutyRoutingKey,omitempty"`
+	Level                            *string  `json:"level,omitempty"`
+	LogFormat                        *string  `json:"logFormat,omitempty"`
+	KubeAPILogLevel                  *int     `json:"kubeAPILogLevel,omitempty"`
+	KubeAPIQPS                       *float64 `json:"kubeAPIQPS,omitempty"`
+	KubeAPIBurst                     *int     `json:"kubeAPIBurst,omitempty"`
+}
+
+// Load reads the Config from the YAML file at path. Unknown keys are rejected
+// so that a misspelled key does not go unnoticed.
+func Load(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// Apply sets the flags of fs to the values of the Config. Flags that were set
+// on the command line keep their value. fs must have been parsed.
+func (c *Config) Apply(fs *flag.FlagSet) error {
+	passed := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		passed[f.Name] = true
+	})
+
+	for name, value := range c.flagValues() {
+		if passed[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value for %s in config file: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// flagValues returns the values present in the Config keyed by flag name.
+func (c *Config) flagValues() map[string]string {
+	values := map[string]string{}
+	setString := func(name string, value *string) {
+		if value != nil {
+			values[name] = *value
+		}
+	}
+	setString("clusterOperatorName", c.ClusterOperatorName)
+	setString("defaultsDir", c.DefaultsDir)
+	setString("canary-timeout", c.CanaryTimeout)
+	setString("defaults-configmap", c.DefaultsConfigMap)
+	setString("defaults-patch-dir", c.DefaultsPatchDir)
+	setString("defaults-url-timeout", c.DefaultsURLTimeout)
+	setString("defaults-rollout-wait-timeout", c.DefaultsRolloutWaitTimeout)
+	setString("healthz-addr", c.HealthzAddr)
+	setString("pprof-addr", c.PprofAddr)
+	if c.PprofAddr == nil {
+		setString("pprof-address", c.PprofAddress)
+	}
+	setString("tls-key", c.TLSKey)
+	setString("tls-cert", c.TLSCert)
+	setString("metrics-auth-token", c.MetricsAuthToken)
+	setString("metrics-addr", c.MetricsAddr)
+	setString("tls-min-version", c.TLSMinVersion)
+	setString("tls-client-ca", c.TLSClientCA)
+	setString("latency-percentile-update-interval", c.LatencyPercentileUpdateInterval)
+	setString("leader-namespace", c.LeaderNamespace)
+	setString("kubeconfig", c.Kubeconfig)
+	setString("watch-namespace", c.WatchNamespace)
+	setString("watch-namespace-selector", c.WatchNamespaceSelector)
+	setString("webhook-cert-dir", c.WebhookCertDir)
+	setString("oauth-client-secret", c.OAuthClientSecret)
+	setString("level", c.Level)
+	setString("log-format", c.LogFormat)
+	setString("graceful-shutdown-timeout", c.GracefulShutdownTimeout)
+	setString("catalog-ingress-class", c.CatalogIngressClass)
+	setString("catalog-ingress-domain", c.CatalogIngressDomain)
+	setString("message-template-configmap", c.MessageTemplateConfigMap)
+	setString("sync-period", c.SyncPeriod)
+	setString("alert-after", c.AlertAfter)
+	setString("condition-decay-timeout", c.ConditionDecayTimeout)
+	setString("status-resync-interval", c.StatusResyncInterval)
+	setString("alert-webhook-url", c.AlertWebhookURL)
+	setString("alert-webhook-format", c.AlertWebhookFormat)
+	setString("alert-pagerduty-routing-key", c.AlertPagerDutyRoutingKey)
+	if c.DefaultsURL != nil {
+		values["defaults-url"] = strings.Join(c.DefaultsURL, ",")
+	}
+	if c.ImageMirrorPrefix != nil {
+		values["image-mirror-prefix"] = strings.Join(c.ImageMirrorPrefix, ",")
+	}
+	if c.OAuthRegistryEndpoint != nil {
+		values["oauth-registry-endpoint"] = strings.Join(c.OAuthRegistryEndpoint, ",")
+	}
+	if c.TLSCipherSuites != nil {
+		values["tls-cipher-suites"] = strings.Join(c.TLSCipherSuites, ",")
+	}
+	if c.MetricsPort != nil {
+		values["metrics-port"] = strconv.Itoa(*c.MetricsPort)
+	}
+	if c.HealthzAPIServerFailureThreshold != nil {
+		values["healthz-apiserver-failure-threshold"] = strconv.Itoa(*c.HealthzAPIServerFailureThreshold)
+	}
+	if c.MaxConcurrentReconciles != nil {
+		values["max-concurrent-reconciles"] = strconv.Itoa(*c.MaxConcurrentReconciles)
+	}
+	if c.DefaultsRolloutBatchSize != nil {
+		values["defaults-rollout-batch-size"] = strconv.Itoa(*c.DefaultsRolloutBatchSize)
+	}
+	if c.KubeAPILogLevel != nil {
+		values["kube-api-log-level"] = strconv.Itoa(*c.KubeAPILogLevel)
+	}
+	if c.ConditionHistorySize != nil {
+		values["condition-history-size"] = strconv.Itoa(*c.ConditionHistorySize)
+	}
+	if c.StatusUpdateRetries != nil {
+		values["status-update-retries"] = strconv.Itoa(*c.StatusUpdateRetries)
+	}
+	if c.KubeAPIQPS != nil {
+		values["kube-api-qps"] = strconv.FormatFloat(*c.KubeAPIQPS, 'f', -1, 64)
+	}
+	if c.KubeAPIBurst != nil {
+		values["kube-api-burst"] = strconv.Itoa(*c.KubeAPIBurst)
+	}
+	if c.WebhookPort != nil {
+		values["webhook-port"] = strconv.Itoa(*c.WebhookPort)
+	}
+	if c.EnforceImmutableSpec != nil {
+		values["enforce-immutable-spec"] = strconv.FormatBool(*c.EnforceImmutableSpec)
+	}
+	if c.ResolveImageDigests != nil {
+		values["resolve-image-digests"] = strconv.FormatBool(*c.ResolveImageDigests)
+	}
+	if c.ExposeCatalogsExternally != nil {
+		values["expose-catalogs-externally"] = strconv.FormatBool(*c.ExposeCatalogsExternally)
+	}
+	return values
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfig writes content to a config file in a temporary directory and
+// returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestLoadMalformedYAML(t *testing.T) {
+	for name, content := range map[string]string{
+		"invalid syntax": "level: [debug",
+		"wrong type":     "metricsPort: eighty",
+		"unknown key":    "logLevel: debug",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, content))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApplyFlagOverride(t *testing.T) {
+	path := writeConfig(t, `
+clusterOperatorName: marketplace
+defaultsDir: /defaults
+level: debug
+metricsPort: 9443
+enforceImmutableSpec: true
+`)
+	config, err := Load(path)
+	require.NoError(t, err)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	clusterOperatorName := fs.String("clusterOperatorName", "", "")
+	defaultsDir := fs.String("defaultsDir", "", "")
+	level := fs.String("level", "info", "")
+	metricsPort := fs.Int("metrics-port", 0, "")
+	enforceImmutableSpec := fs.Bool("enforce-immutable-spec", false, "")
+	leaderNamespace := fs.String("leader-namespace", "openshift-marketplace", "")
+	require.NoError(t, fs.Parse([]string{"-level=warn", "-defaultsDir="}))
+
+	require.NoError(t, config.Apply(fs))
+	assert.Equal(t, "marketplace", *clusterOperatorName)
+	assert.Equal(t, "", *defaultsDir, "a flag passed on the command line wins, even if empty")
+	assert.Equal(t, "warn", *level, "a flag passed on the command line wins")
+	assert.Equal(t, 9443, *metricsPort)
+	assert.True(t, *enforceImmutableSpec)
+	assert.Equal(t, "openshift-marketplace", *leaderNamespace, "flags missing from the file keep their default")
+}
+
+func TestApplyCipherSuites(t *testing.T) {
+	path := writeConfig(t, `
+tlsMinVersion: VersionTLS13
+tlsCipherSuites:
+- TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+- TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384
+`)
+	config, err := Load(path)
+	require.NoError(t, err)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	tlsMinVersion := fs.String("tls-min-version", "VersionTLS12", "")
+	tlsCipherSuites := fs.String("tls-cipher-suites", "", "")
+	require.NoError(t, fs.Parse(nil))
+
+	require.NoError(t, config.Apply(fs))
+	assert.Equal(t, "VersionTLS13", *tlsMinVersion)
+	assert.Equal(t, "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", *tlsCipherSuites)
+}
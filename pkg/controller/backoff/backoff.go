@@ -0,0 +1,72 @@
+package backoff
+
+import (
+	"math/rand"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// DefaultBaseDelay is the delay before the first retry of a request that
+	// failed.
+	DefaultBaseDelay = 5 * time.Millisecond
+
+	// DefaultMaxDelay is the longest delay between two retries of a request,
+	// so that the requests failing during an outage of the API server are
+	// still retried every few minutes once it is back.
+	DefaultMaxDelay = 5 * time.Minute
+
+	// DefaultJitter is the fraction of the delay that is randomly added to
+	// it, so that the requests that failed together are not retried together.
+	DefaultJitter = 0.2
+)
+
+// JitteredRateLimiter doubles the delay before retrying a request on each of
+// its successive failures, and adds a random jitter to every delay.
+type JitteredRateLimiter struct {
+	failures workqueue.TypedRateLimiter[reconcile.Request]
+	maxDelay time.Duration
+	jitter   float64
+	// random returns a number in [0.0,1.0), it must be safe for concurrent
+	// use.
+	random func() float64
+}
+
+var _ workqueue.TypedRateLimiter[reconcile.Request] = &JitteredRateLimiter{}
+
+// NewJitteredRateLimiter returns a JitteredRateLimiter waiting baseDelay
+// before the first retry of a request, up to maxDelay between two retries.
+// Up to jitter times the delay is randomly added to it, the delay never
+// exceeds maxDelay though.
+func NewJitteredRateLimiter(baseDelay, maxDelay time.Duration, jitter float64) *JitteredRateLimiter {
+	return &JitteredRateLimiter{
+		failures: workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](baseDelay, maxDelay),
+		maxDelay: maxDelay,
+		jitter:   jitter,
+		random:   rand.Float64,
+	}
+}
+
+// When records a failure of the request and returns how long to wait before
+// retrying it.
+func (r *JitteredRateLimiter) When(request reconcile.Request) time.Duration {
+	delay := r.failures.When(request)
+	delay += time.Duration(r.jitter * r.random() * float64(delay))
+	if delay > r.maxDelay {
+		return r.maxDelay
+	}
+	return delay
+}
+
+// Forget resets the delay of the request, once it succeeded.
+func (r *JitteredRateLimiter) Forget(request reconcile.Request) {
+	r.failures.Forget(request)
+}
+
+// NumRequeues returns the number of times the request failed since it was
+// last forgotten.
+func (r *JitteredRateLimiter) NumRequeues(request reconcile.Request) int {
+	return r.failures.NumRequeues(request)
+}
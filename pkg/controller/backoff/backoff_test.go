@@ -0,0 +1,45 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestJitteredRateLimiter(t *testing.T) {
+	r := NewJitteredRateLimiter(10*time.Millisecond, 500*time.Millisecond, 0.5)
+	// The jitter is at its largest, so that it must not make a delay shorter
+	// than the previous one.
+	r.random = func() float64 { return 0.99 }
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: "redhat-operators"}}
+	other := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: "certified-operators"}}
+
+	var previous time.Duration
+	for i := 0; i < 6; i++ {
+		delay := r.When(request)
+		assert.Greater(t, delay, previous, "failure %d", i+1)
+		previous = delay
+	}
+	assert.Equal(t, 6, r.NumRequeues(request))
+	assert.Equal(t, 500*time.Millisecond, r.When(request), "the delay is capped, jitter included")
+
+	r.random = func() float64 { return 0 }
+	assert.Equal(t, 10*time.Millisecond, r.When(other), "the failures are counted per request")
+
+	r.Forget(request)
+	assert.Equal(t, 0, r.NumRequeues(request))
+	assert.Equal(t, 10*time.Millisecond, r.When(request))
+}
+
+func TestJitteredRateLimiterJitter(t *testing.T) {
+	r := NewJitteredRateLimiter(100*time.Millisecond, time.Minute, DefaultJitter)
+	for i := 0; i < 100; i++ {
+		request := reconcile.Request{NamespacedName: types.NamespacedName{Name: string(rune('a' + i%26)), Namespace: string(rune('a' + i/26))}}
+		delay := r.When(request)
+		assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		assert.Less(t, delay, 120*time.Millisecond)
+	}
+}
@@ -0,0 +1,196 @@
+package catalogaffinity
+
+import (
+	"context"
+	"fmt"
+
+	mktconfig "github.com/operator-framework/operator-marketplace/pkg/apis/config/v1"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// controllerName is the name of the controller, it is used to identify its
+	// metrics.
+	controllerName = "catalogaffinity-controller"
+
+	// OLMNamespace is the namespace the OLM operator is deployed in.
+	OLMNamespace = "openshift-operator-lifecycle-manager"
+
+	// OLMPodLabelKey and OLMPodLabelValue identify the OLM operator pods.
+	OLMPodLabelKey   = "app"
+	OLMPodLabelValue = "olm-operator"
+
+	// hostnameLabel is the well known node label used to prefer the node
+	// the OLM operator is running on.
+	hostnameLabel = "kubernetes.io/hostname"
+
+	// preferredWeight is the weight of the preferred scheduling term.
+	preferredWeight = 100
+)
+
+// Add creates a new catalog affinity Controller and adds it to the Manager.
+// The Manager will set fields on the Controller and Start it when the Manager
+// is Started.
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	return add(mgr, newReconciler(mgr), o.ControllerRuntimeOptions())
+}
+
+// newReconciler returns a new ReconcileCatalogAffinity.
+func newReconciler(mgr manager.Manager) *ReconcileCatalogAffinity {
+	return &ReconcileCatalogAffinity{
+		client: mgr.GetClient(),
+		reader: mgr.GetAPIReader(),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler, opts controller.Options) error {
+	// The OLM operator is only known to run in its own namespace on OpenShift.
+	if !mktconfig.IsAPIAvailable() {
+		return nil
+	}
+
+	return builder.ControllerManagedBy(mgr).
+		Named(controllerName).
+		For(&corev1.Pod{}).
+		WithEventFilter(getPredicateFunctions()).
+		WithOptions(opts).
+		Complete(metrics.NewInstrumentedReconciler(controllerName, r))
+}
+
+// getPredicateFunctions returns the predicate functions used to identify the
+// OLM operator pods.
+func getPredicateFunctions() predicate.Funcs {
+	isOLMPod := func(obj client.Object) bool {
+		return obj.GetNamespace() == OLMNamespace && obj.GetLabels()[OLMPodLabelKey] == OLMPodLabelValue
+	}
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isOLMPod(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			// Only a change in scheduling, or in whether the pod runs,
+			// affects the pod that is preferred.
+			oldPod, newPod := e.ObjectOld.(*corev1.Pod), e.ObjectNew.(*corev1.Pod)
+			return isOLMPod(newPod) &&
+				(oldPod.Spec.NodeName != newPod.Spec.NodeName ||
+					oldPod.Status.Phase != newPod.Status.Phase ||
+					oldPod.DeletionTimestamp.IsZero() != newPod.DeletionTimestamp.IsZero())
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			// The preferred pod may be the one that went away.
+			return isOLMPod(e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+var _ reconcile.Reconciler = &ReconcileCatalogAffinity{}
+
+// ReconcileCatalogAffinity reconciles the OLM operator pods and prefers
+// scheduling the default CatalogSource pods on the same node to reduce the
+// gRPC latency of package resolution.
+type ReconcileCatalogAffinity struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	// reader reads directly from the apiserver so that Nodes do not need to
+	// be cached.
+	reader client.Reader
+}
+
+// Reconcile reads the node the preferred OLM operator pod is scheduled on and
+// updates the default CatalogSources to prefer that node. When several OLM
+// operator pods run, whichever pod is reconciled, the same one is preferred so
+// that the affinity does not move from node to node.
+func (r *ReconcileCatalogAffinity) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	logging.FromContext(ctx).Infof("Reconciling OLM pod %s/%s", request.Namespace, request.Name)
+
+	pods := &corev1.PodList{}
+	if err := r.client.List(ctx, pods, client.InNamespace(OLMNamespace), client.MatchingLabels{OLMPodLabelKey: OLMPodLabelValue}); err != nil {
+		return reconcile.Result{}, err
+	}
+	pod := preferredPod(pods.Items)
+	if pod == nil {
+		// No OLM operator pod runs yet, an update will follow. The node
+		// preferred until then is kept.
+		return reconcile.Result{}, nil
+	}
+
+	node := &corev1.Node{}
+	if err := r.reader.Get(ctx, client.ObjectKey{Name: pod.Spec.NodeName}, node); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	hostname, ok := node.Labels[hostnameLabel]
+	if !ok {
+		hostname = node.Name
+	}
+
+	if defaults.SetPreferredNodeAffinity(nodeAffinityFor(hostname)) {
+		logging.FromContext(ctx).Infof("[affinity] Preferring node %s for the default CatalogSources", hostname)
+	}
+
+	catsrcDefinitions := defaults.GetGlobalCatalogSourceDefinitions()
+	result := defaults.New(catsrcDefinitions, operatorhub.GetSingleton().Get()).EnsureAll(ctx, r.client)
+	if len(result) != 0 {
+		for name, err := range result {
+			logging.FromContext(ctx).Errorf("[affinity] Error applying node affinity to CatalogSource %s - %v", name, err)
+		}
+		return reconcile.Result{}, fmt.Errorf("failed to apply node affinity to %d default CatalogSources", len(result))
+	}
+	return reconcile.Result{}, nil
+}
+
+// preferredPod returns the oldest of the given pods that is scheduled, running
+// and not being deleted, or nil if there is none. Pods created at the same
+// time are ordered by name.
+func preferredPod(pods []corev1.Pod) *corev1.Pod {
+	var preferred *corev1.Pod
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if preferred == nil ||
+			pod.CreationTimestamp.Before(&preferred.CreationTimestamp) ||
+			(pod.CreationTimestamp.Equal(&preferred.CreationTimestamp) && pod.Name < preferred.Name) {
+			preferred = pod
+		}
+	}
+	return preferred
+}
+
+// nodeAffinityFor returns a node affinity preferring the node with the given
+// hostname.
+func nodeAffinityFor(hostname string) *corev1.NodeAffinity {
+	return &corev1.NodeAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+			{
+				Weight: preferredWeight,
+				Preference: corev1.NodeSelectorTerm{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{
+							Key:      hostnameLabel,
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{hostname},
+						},
+					},
+				},
+			},
+		},
+	}
+}
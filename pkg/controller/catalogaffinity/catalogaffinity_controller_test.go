@@ -0,0 +1,165 @@
+package catalogaffinity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const catsrcManifest = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: redhat-operators
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: quay.io/example/redhat-operators:latest
+`
+
+var created = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// olmPod returns an OLM operator pod created age minutes after the others.
+func olmPod(name, nodeName string, phase corev1.PodPhase, age int) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         OLMNamespace,
+			Labels:            map[string]string{OLMPodLabelKey: OLMPodLabelValue},
+			CreationTimestamp: metav1.NewTime(created.Add(time.Duration(age) * time.Minute)),
+		},
+		Spec:   corev1.PodSpec{NodeName: nodeName},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func node(name string) *corev1.Node {
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   name,
+		Labels: map[string]string{hostnameLabel: name + ".example.com"},
+	}}
+}
+
+// setup populates the default CatalogSources and returns a reconciler backed
+// by an in-memory cluster containing the given objects.
+func setup(t *testing.T, objs ...client.Object) (*ReconcileCatalogAffinity, client.Client) {
+	t.Helper()
+	defaultstest.Populate(t, map[string]string{"redhat-operators.yaml": catsrcManifest})
+	t.Cleanup(func() { defaults.SetPreferredNodeAffinity(nil) })
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &ReconcileCatalogAffinity{client: c, reader: c}, c
+}
+
+func reconcilePod(t *testing.T, r *ReconcileCatalogAffinity, name string) {
+	t.Helper()
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: OLMNamespace, Name: name}})
+	require.NoError(t, err)
+}
+
+// preferredHostname returns the hostname the default CatalogSource prefers, or
+// the empty string if it prefers none.
+func preferredHostname(t *testing.T, c client.Client) string {
+	t.Helper()
+	catsrc := &olmv1alpha1.CatalogSource{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: "openshift-marketplace", Name: "redhat-operators"}, catsrc)
+	if err != nil {
+		require.NoError(t, client.IgnoreNotFound(err))
+		return ""
+	}
+	if catsrc.Spec.GrpcPodConfig == nil || catsrc.Spec.GrpcPodConfig.Affinity == nil {
+		return ""
+	}
+	terms := catsrc.Spec.GrpcPodConfig.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	require.Len(t, terms, 1)
+	return terms[0].Preference.MatchExpressions[0].Values[0]
+}
+
+func TestPredicate(t *testing.T) {
+	pred := getPredicateFunctions()
+	pending := olmPod("olm-operator-a", "", corev1.PodPending, 0)
+	scheduled := olmPod("olm-operator-a", "node-a", corev1.PodPending, 0)
+	running := olmPod("olm-operator-a", "node-a", corev1.PodRunning, 0)
+	deleting := running.DeepCopy()
+	deleting.DeletionTimestamp = &metav1.Time{Time: created}
+	relabeled := running.DeepCopy()
+	relabeled.Labels["app"] = "catalog-operator"
+
+	assert.True(t, pred.Create(event.CreateEvent{Object: running}))
+	assert.False(t, pred.Create(event.CreateEvent{Object: relabeled}), "only the OLM operator pods are reconciled")
+	assert.True(t, pred.Update(event.UpdateEvent{ObjectOld: pending, ObjectNew: scheduled}), "the pod was scheduled")
+	assert.True(t, pred.Update(event.UpdateEvent{ObjectOld: scheduled, ObjectNew: running}), "the pod started running")
+	assert.True(t, pred.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: deleting}), "the pod is being deleted")
+	assert.False(t, pred.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: running}))
+	assert.True(t, pred.Delete(event.DeleteEvent{Object: running}))
+	assert.False(t, pred.Delete(event.DeleteEvent{Object: relabeled}))
+}
+
+func TestPreferredPod(t *testing.T) {
+	deleting := olmPod("olm-operator-deleting", "node-a", corev1.PodRunning, 0)
+	deleting.DeletionTimestamp = &metav1.Time{Time: created}
+	pods := []corev1.Pod{
+		*olmPod("olm-operator-newest", "node-c", corev1.PodRunning, 3),
+		*olmPod("olm-operator-b", "node-b", corev1.PodRunning, 2),
+		*olmPod("olm-operator-a", "node-a", corev1.PodRunning, 2),
+		*olmPod("olm-operator-pending", "node-a", corev1.PodPending, 1),
+		*olmPod("olm-operator-unscheduled", "", corev1.PodPending, 0),
+		*deleting,
+	}
+
+	assert.Equal(t, "olm-operator-a", preferredPod(pods).Name, "the oldest running pod is preferred, then the first by name")
+	assert.Equal(t, "olm-operator-newest", preferredPod(pods[:1]).Name)
+	assert.Nil(t, preferredPod(pods[3:]), "no pod is running")
+	assert.Nil(t, preferredPod(nil))
+}
+
+func TestReconcilePrefersTheSameNodeForEveryPod(t *testing.T) {
+	older := olmPod("olm-operator-older", "node-a", corev1.PodRunning, 0)
+	newer := olmPod("olm-operator-newer", "node-b", corev1.PodRunning, 1)
+	r, c := setup(t, older, newer, node("node-a"), node("node-b"))
+
+	// Each OLM operator pod is reconciled on its own events, the affinity
+	// does not follow whichever was reconciled last.
+	reconcilePod(t, r, newer.Name)
+	assert.Equal(t, "node-a.example.com", preferredHostname(t, c))
+	reconcilePod(t, r, older.Name)
+	assert.Equal(t, "node-a.example.com", preferredHostname(t, c))
+	reconcilePod(t, r, newer.Name)
+	assert.Equal(t, "node-a.example.com", preferredHostname(t, c))
+
+	// Once the preferred pod goes away, the remaining one is preferred.
+	require.NoError(t, c.Delete(context.TODO(), older))
+	reconcilePod(t, r, older.Name)
+	assert.Equal(t, "node-b.example.com", preferredHostname(t, c))
+}
+
+func TestReconcileWaitsForARunningPod(t *testing.T) {
+	r, c := setup(t, olmPod("olm-operator-a", "node-a", corev1.PodPending, 0), node("node-a"))
+
+	reconcilePod(t, r, "olm-operator-a")
+	assert.Empty(t, preferredHostname(t, c), "the default CatalogSources are not ensured for a pod that does not run")
+}
+
+func TestReconcileFallsBackToTheNodeName(t *testing.T) {
+	unlabeled := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+	r, c := setup(t, olmPod("olm-operator-a", "node-a", corev1.PodRunning, 0), unlabeled)
+
+	reconcilePod(t, r, "olm-operator-a")
+	assert.Equal(t, "node-a", preferredHostname(t, c))
+}
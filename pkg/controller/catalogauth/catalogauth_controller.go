@@ -0,0 +1,358 @@
+package catalogauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// controllerName is the name of the controller, it is used to identify its
+	// metrics.
+	controllerName = "catalogauth-controller"
+
+	// RegistryLabel is the label of the pull Secrets holding the OAuth token
+	// of a registry, its value is the registry host.
+	RegistryLabel = "marketplace.operator.openshift.io/oauth-registry"
+
+	// TokenExpiryAnnotation is the annotation of the pull Secrets recording
+	// when their token expires, in RFC 3339 format.
+	TokenExpiryAnnotation = "marketplace.operator.openshift.io/token-expiry"
+
+	// tokenLifetimeAnnotation is the annotation of the pull Secrets recording
+	// the lifetime of their token.
+	tokenLifetimeAnnotation = "marketplace.operator.openshift.io/token-lifetime"
+
+	// TokenUsername is the user name the OAuth token is presented with to the
+	// registry.
+	TokenUsername = "oauth2accesstoken"
+
+	// ClientIDKey and ClientSecretKey are the keys of the client credentials
+	// in the -oauth-client-secret Secret.
+	ClientIDKey     = "client_id"
+	ClientSecretKey = "client_secret"
+
+	// maxRefreshMargin is the longest time before its expiry a token is
+	// refreshed. The tokens living less than twice as long are refreshed
+	// halfway through their life.
+	maxRefreshMargin = 5 * time.Minute
+
+	// tokenRequestTimeout is the time given to a token endpoint to respond.
+	tokenRequestTimeout = 30 * time.Second
+
+	// maxTokenResponseSize bounds the size of the token endpoint responses.
+	maxTokenResponseSize = 1 << 20
+)
+
+// invalidNameChars matches the characters that are not allowed in the name
+// of a Secret.
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// SecretName returns the name of the pull Secret holding the OAuth token of
+// the given registry.
+func SecretName(registry string) string {
+	name := "marketplace-oauth-" + strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(registry), "-"), "-")
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-")
+	}
+	return name
+}
+
+// ParseEndpoints parses the comma-separated list of <registry>=<token endpoint
+// URL> pairs of -oauth-registry-endpoint.
+func ParseEndpoints(value string) (map[string]string, error) {
+	endpoints := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		registry, endpoint, ok := strings.Cut(pair, "=")
+		if !ok || registry == "" {
+			return nil, fmt.Errorf("invalid registry endpoint %q, must be <registry>=<token endpoint URL>", pair)
+		}
+		parsed, err := url.Parse(endpoint)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid token endpoint %q of registry %s, must be an http or https URL", endpoint, registry)
+		}
+		if _, ok := endpoints[registry]; ok {
+			return nil, fmt.Errorf("registry %s has several token endpoints", registry)
+		}
+		endpoints[registry] = endpoint
+	}
+	return endpoints, nil
+}
+
+// Add creates a new catalog auth Controller and adds it to the Manager if
+// registries are configured with -oauth-registry-endpoint. The Manager will
+// set fields on the Controller and Start it when the Manager is Started.
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	if len(o.OAuthRegistryEndpoints) == 0 {
+		return nil
+	}
+	r := newReconciler(mgr.GetClient(), o)
+	return add(mgr, r, r.initialEvents(), o.ControllerRuntimeOptions())
+}
+
+// newReconciler returns a new ReconcileCatalogAuth.
+func newReconciler(c client.Client, o options.ControllerOptions) *ReconcileCatalogAuth {
+	registries := make(map[string]string, len(o.OAuthRegistryEndpoints))
+	for registry := range o.OAuthRegistryEndpoints {
+		registries[SecretName(registry)] = registry
+	}
+	return &ReconcileCatalogAuth{
+		client:       c,
+		namespace:    o.Namespace,
+		endpoints:    o.OAuthRegistryEndpoints,
+		registries:   registries,
+		clientSecret: o.OAuthClientSecret,
+		httpClient:   &http.Client{Timeout: tokenRequestTimeout},
+		now:          time.Now,
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler. The
+// pull Secret of each registry is reconciled on startup, then whenever it is
+// modified or deleted.
+func add(mgr manager.Manager, r reconcile.Reconciler, initial <-chan event.GenericEvent, opts controller.Options) error {
+	return builder.ControllerManagedBy(mgr).
+		Named(controllerName).
+		For(&corev1.Secret{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			_, ok := obj.GetLabels()[RegistryLabel]
+			return ok
+		}))).
+		WatchesRawSource(source.Channel(initial, &handler.EnqueueRequestForObject{})).
+		WithOptions(opts).
+		Complete(metrics.NewInstrumentedReconciler(controllerName, r))
+}
+
+var _ reconcile.Reconciler = &ReconcileCatalogAuth{}
+
+// ReconcileCatalogAuth keeps a pull Secret holding a fresh OAuth bearer token
+// for each registry configured with -oauth-registry-endpoint. The tokens are
+// requested with the OAuth client credentials grant, and the default
+// CatalogSources reference the Secret of the registry of their image.
+type ReconcileCatalogAuth struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+
+	// namespace is the namespace of the pull Secrets and of clientSecret.
+	namespace string
+	// endpoints holds the token endpoint URL of each registry.
+	endpoints map[string]string
+	// registries holds the registry of each pull Secret name.
+	registries map[string]string
+	// clientSecret is the name of the Secret holding the client credentials.
+	clientSecret string
+
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// initialEvents returns a closed channel holding an event for the pull Secret
+// of each registry, so that the Secrets that do not exist yet are created.
+func (r *ReconcileCatalogAuth) initialEvents() <-chan event.GenericEvent {
+	names := make([]string, 0, len(r.registries))
+	for name := range r.registries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	events := make(chan event.GenericEvent, len(names))
+	for _, name := range names {
+		events <- event.GenericEvent{Object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: r.namespace}}}
+	}
+	close(events)
+	return events
+}
+
+// Reconcile refreshes the token of a pull Secret once it is about to expire,
+// and requeues the Secret for its next refresh.
+func (r *ReconcileCatalogAuth) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	registry, ok := r.registries[request.Name]
+	if !ok || request.Namespace != r.namespace {
+		return reconcile.Result{}, nil
+	}
+	logging.FromContext(ctx).Debugf("Reconciling the OAuth token of registry %s", registry)
+
+	secret := &corev1.Secret{}
+	err := r.client.Get(ctx, request.NamespacedName, secret)
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	if err == nil {
+		if refresh, ok := refreshAt(secret, registry); ok && r.now().Before(refresh) {
+			return reconcile.Result{RequeueAfter: refresh.Sub(r.now())}, nil
+		}
+	}
+
+	token, lifetime, err := r.requestToken(ctx, r.endpoints[registry])
+	if err != nil {
+		logging.FromContext(ctx).Errorf("[catalogauth] Error refreshing the OAuth token of registry %s - %v", registry, err)
+		return reconcile.Result{}, err
+	}
+	expiry := r.now().Add(lifetime)
+	if err := r.ensureSecret(ctx, request.NamespacedName, registry, token, expiry, lifetime); err != nil {
+		return reconcile.Result{}, err
+	}
+	metrics.SetRegistryTokenExpiry(registry, expiry)
+	logging.FromContext(ctx).Infof("[catalogauth] Refreshed the OAuth token of registry %s, valid until %s", registry, expiry.UTC().Format(time.RFC3339))
+	return reconcile.Result{RequeueAfter: lifetime - refreshMargin(lifetime)}, nil
+}
+
+// refreshAt returns when the token of secret must be refreshed, and false if
+// the Secret does not hold a token of registry the operator requested.
+func refreshAt(secret *corev1.Secret, registry string) (time.Time, bool) {
+	if secret.Type != corev1.SecretTypeDockerConfigJson || len(secret.Data[corev1.DockerConfigJsonKey]) == 0 {
+		return time.Time{}, false
+	}
+	expiry, err := time.Parse(time.RFC3339, secret.Annotations[TokenExpiryAnnotation])
+	if err != nil {
+		return time.Time{}, false
+	}
+	metrics.SetRegistryTokenExpiry(registry, expiry)
+	lifetime, err := time.ParseDuration(secret.Annotations[tokenLifetimeAnnotation])
+	if err != nil {
+		lifetime = 2 * maxRefreshMargin
+	}
+	return expiry.Add(-refreshMargin(lifetime)), true
+}
+
+// refreshMargin returns how long before its expiry a token of the given
+// lifetime is refreshed.
+func refreshMargin(lifetime time.Duration) time.Duration {
+	if lifetime < 2*maxRefreshMargin {
+		return lifetime / 2
+	}
+	return maxRefreshMargin
+}
+
+// tokenResponse is the response of an OAuth token endpoint.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// requestToken requests a token from endpoint with the client credentials
+// grant, and returns it along with its lifetime.
+func (r *ReconcileCatalogAuth) requestToken(ctx context.Context, endpoint string) (string, time.Duration, error) {
+	credentials := &corev1.Secret{}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: r.namespace, Name: r.clientSecret}, credentials); err != nil {
+		return "", 0, fmt.Errorf("failed to get the OAuth client credentials: %v", err)
+	}
+	clientID, clientSecret := string(credentials.Data[ClientIDKey]), string(credentials.Data[ClientSecretKey])
+	if clientID == "" || clientSecret == "" {
+		return "", 0, fmt.Errorf("Secret %s/%s must hold the %s and %s keys", r.namespace, r.clientSecret, ClientIDKey, ClientSecretKey)
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenResponseSize))
+	if err != nil {
+		return "", 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token endpoint %s responded %s", endpoint, resp.Status)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", 0, fmt.Errorf("invalid response of token endpoint %s: %v", endpoint, err)
+	}
+	if token.AccessToken == "" || token.ExpiresIn <= 0 {
+		return "", 0, fmt.Errorf("token endpoint %s returned no access_token or expires_in", endpoint)
+	}
+	return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, nil
+}
+
+// ensureSecret creates or updates the pull Secret of registry with the given
+// token.
+func (r *ReconcileCatalogAuth) ensureSecret(ctx context.Context, key types.NamespacedName, registry, token string, expiry time.Time, lifetime time.Duration) error {
+	config, err := dockerConfigJSON(registry, token)
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.client, secret, func() error {
+		if secret.Labels == nil {
+			secret.Labels = make(map[string]string)
+		}
+		secret.Labels[RegistryLabel] = labelValue(registry)
+		if secret.Annotations == nil {
+			secret.Annotations = make(map[string]string)
+		}
+		secret.Annotations[TokenExpiryAnnotation] = expiry.UTC().Format(time.RFC3339)
+		secret.Annotations[tokenLifetimeAnnotation] = lifetime.String()
+		secret.Type = corev1.SecretTypeDockerConfigJson
+		secret.Data = map[string][]byte{corev1.DockerConfigJsonKey: config}
+		return nil
+	})
+	return err
+}
+
+// dockerConfigJSON returns the kubernetes.io/dockerconfigjson content
+// presenting token to registry.
+func dockerConfigJSON(registry, token string) ([]byte, error) {
+	type auth struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+	return json.Marshal(map[string]map[string]auth{
+		"auths": {
+			registry: {
+				Username: TokenUsername,
+				Password: token,
+				Auth:     base64.StdEncoding.EncodeToString([]byte(TokenUsername + ":" + token)),
+			},
+		},
+	})
+}
+
+// labelValue returns registry as a label value, in which the port separator
+// is not allowed.
+func labelValue(registry string) string {
+	value := strings.ReplaceAll(registry, ":", "_")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return strings.Trim(value, "._-")
+}
@@ -0,0 +1,154 @@
+package catalogauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// tokenExpiry returns the token expiry time remaining reported for the given
+// registry.
+func tokenExpiry(t *testing.T, registry string) (float64, bool) {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "marketplace_registry_token_expiry_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "registry" && label.GetValue() == registry {
+					return metric.GetGauge().GetValue(), true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestReconcileRefreshesToken(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "marketplace" || secret != "s3cr3t" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	credentials := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "oauth-client", Namespace: "openshift-marketplace"},
+		Data:       map[string][]byte{ClientIDKey: []byte("marketplace"), ClientSecretKey: []byte("s3cr3t")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(credentials).Build()
+	r := newReconciler(c, options.ControllerOptions{
+		Namespace:              "openshift-marketplace",
+		OAuthRegistryEndpoints: map[string]string{"registry.example.com:5000": server.URL},
+		OAuthClientSecret:      "oauth-client",
+	})
+	now := time.Now().Truncate(time.Second)
+	r.now = func() time.Time { return now }
+
+	events := r.initialEvents()
+	var names []string
+	for e := range events {
+		names = append(names, e.Object.GetName())
+	}
+	require.Equal(t, []string{"marketplace-oauth-registry-example-com-5000"}, names)
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: names[0]}}
+
+	// The Secret is created with a fresh token.
+	result, err := r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour-maxRefreshMargin, result.RequeueAfter)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+	secret := &corev1.Secret{}
+	require.NoError(t, c.Get(context.TODO(), request.NamespacedName, secret))
+	assert.Equal(t, corev1.SecretTypeDockerConfigJson, secret.Type)
+	assert.Equal(t, "registry.example.com_5000", secret.Labels[RegistryLabel])
+	var config map[string]map[string]map[string]string
+	require.NoError(t, json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config))
+	assert.Equal(t, "token", config["auths"]["registry.example.com:5000"]["password"])
+	assert.Equal(t, TokenUsername, config["auths"]["registry.example.com:5000"]["username"])
+	remaining, ok := tokenExpiry(t, "registry.example.com:5000")
+	require.True(t, ok)
+	assert.InDelta(t, time.Until(now.Add(time.Hour)).Seconds(), remaining, 5)
+
+	// The token is not requested again while it is fresh.
+	now = now.Add(30 * time.Minute)
+	result, err = r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	assert.Equal(t, 25*time.Minute, result.RequeueAfter)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+
+	// The token is refreshed once it is about to expire.
+	now = now.Add(26 * time.Minute)
+	_, err = r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests))
+	require.NoError(t, c.Get(context.TODO(), request.NamespacedName, secret))
+	assert.Equal(t, now.Add(time.Hour).UTC().Format(time.RFC3339), secret.Annotations[TokenExpiryAnnotation])
+
+	// Invalid client credentials fail the reconcile.
+	credentials.Data[ClientSecretKey] = []byte("wrong")
+	require.NoError(t, c.Update(context.TODO(), credentials))
+	now = now.Add(time.Hour)
+	_, err = r.Reconcile(context.TODO(), request)
+	assert.Error(t, err)
+}
+
+func TestRefreshMargin(t *testing.T) {
+	assert.Equal(t, maxRefreshMargin, refreshMargin(time.Hour))
+	assert.Equal(t, 2*time.Minute, refreshMargin(4*time.Minute))
+}
+
+func TestParseEndpoints(t *testing.T) {
+	endpoints, err := ParseEndpoints("registry.example.com=https://auth.example.com/token, quay.io=http://auth.quay.io/oauth")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"registry.example.com": "https://auth.example.com/token",
+		"quay.io":              "http://auth.quay.io/oauth",
+	}, endpoints)
+
+	endpoints, err = ParseEndpoints("")
+	require.NoError(t, err)
+	assert.Empty(t, endpoints)
+
+	for _, value := range []string{
+		"registry.example.com",
+		"=https://auth.example.com/token",
+		"registry.example.com=ftp://auth.example.com",
+		"registry.example.com=https://a.example.com,registry.example.com=https://b.example.com",
+	} {
+		_, err := ParseEndpoints(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestSecretName(t *testing.T) {
+	assert.Equal(t, "marketplace-oauth-registry-example-com-5000", SecretName("Registry.Example.com:5000"))
+}
@@ -0,0 +1,179 @@
+package catalogingress
+
+import (
+	"context"
+	"fmt"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// controllerName is the name of the controller, it is used to identify its
+	// metrics.
+	controllerName = "catalogingress-controller"
+
+	// grpcPortName is the name of the port of the Service OLM creates for a
+	// grpc CatalogSource, which is named after the CatalogSource.
+	grpcPortName = "grpc"
+)
+
+// Add creates a new catalog Ingress Controller and adds it to the Manager if
+// the catalogs are exposed externally. The Manager will set fields on the
+// Controller and Start it when the Manager is Started.
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	if !o.ExposeCatalogsExternally {
+		return nil
+	}
+	return add(mgr, newReconciler(mgr, o.CatalogIngressClass, o.CatalogIngressDomain), o.ControllerRuntimeOptions())
+}
+
+// newReconciler returns a new ReconcileCatalogIngress.
+func newReconciler(mgr manager.Manager, ingressClass, domain string) *ReconcileCatalogIngress {
+	return &ReconcileCatalogIngress{
+		client:       mgr.GetClient(),
+		scheme:       mgr.GetScheme(),
+		ingressClass: ingressClass,
+		domain:       domain,
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler, opts controller.Options) error {
+	return builder.ControllerManagedBy(mgr).
+		Named(controllerName).
+		For(&olmv1alpha1.CatalogSource{}).
+		Owns(&networkingv1.Ingress{}).
+		WithEventFilter(getPredicateFunctions()).
+		WithOptions(opts).
+		Complete(metrics.NewInstrumentedReconciler(controllerName, r))
+}
+
+// getPredicateFunctions returns the predicate functions used to identify the
+// default CatalogSources and the Ingresses, named after them, that expose
+// them.
+func getPredicateFunctions() predicate.Funcs {
+	defaultCatalogsources := defaults.GetGlobalCatalogSourceDefinitions()
+	isDefault := func(obj client.Object) bool {
+		def, ok := defaultCatalogsources[obj.GetName()]
+		return ok && def.Namespace == obj.GetNamespace()
+	}
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isDefault(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isDefault(e.ObjectNew)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isDefault(e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+var _ reconcile.Reconciler = &ReconcileCatalogIngress{}
+
+// ReconcileCatalogIngress exposes the grpc Service of each default
+// CatalogSource through an Ingress, so that tools running outside of the
+// cluster can consume the catalog.
+type ReconcileCatalogIngress struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+	// ingressClass is the class of the Ingresses, the cluster default class
+	// is used if empty.
+	ingressClass string
+	// domain, if not empty, is the domain the hosts of the Ingresses are
+	// allocated in.
+	domain string
+}
+
+// Reconcile creates or updates the Ingress of an enabled default CatalogSource
+// and deletes the Ingress of a disabled one. The Ingress is owned by the
+// CatalogSource so that it is garbage collected along with it.
+func (r *ReconcileCatalogIngress) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	logging.FromContext(ctx).Debugf("Reconciling the Ingress of CatalogSource %s/%s", request.Namespace, request.Name)
+
+	catsrc := &olmv1alpha1.CatalogSource{}
+	if err := r.client.Get(ctx, request.NamespacedName, catsrc); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      catsrc.Name,
+			Namespace: catsrc.Namespace,
+		},
+	}
+	disabled := operatorhub.GetSingleton().Get()[catsrc.Name]
+	if disabled || !catsrc.DeletionTimestamp.IsZero() || catsrc.Spec.SourceType != olmv1alpha1.SourceTypeGrpc {
+		if err := r.client.Delete(ctx, ingress); err != nil && !k8sErrors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.client, ingress, func() error {
+		ingress.Spec = r.ingressSpec(catsrc)
+		return controllerutil.SetControllerReference(catsrc, ingress, r.scheme)
+	})
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if result != controllerutil.OperationResultNone {
+		logging.FromContext(ctx).Infof("[ingress] Ingress for CatalogSource %s %s", catsrc.Name, result)
+	}
+	return reconcile.Result{}, nil
+}
+
+// ingressSpec returns the spec of the Ingress routing to the grpc Service of
+// the CatalogSource.
+func (r *ReconcileCatalogIngress) ingressSpec(catsrc *olmv1alpha1.CatalogSource) networkingv1.IngressSpec {
+	pathType := networkingv1.PathTypePrefix
+	rule := networkingv1.IngressRule{
+		IngressRuleValue: networkingv1.IngressRuleValue{
+			HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{{
+					Path:     "/",
+					PathType: &pathType,
+					Backend: networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: catsrc.Name,
+							Port: networkingv1.ServiceBackendPort{Name: grpcPortName},
+						},
+					},
+				}},
+			},
+		},
+	}
+	if r.domain != "" {
+		rule.Host = fmt.Sprintf("%s-%s.%s", catsrc.Name, catsrc.Namespace, r.domain)
+	}
+
+	spec := networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{rule}}
+	if r.ingressClass != "" {
+		ingressClass := r.ingressClass
+		spec.IngressClassName = &ingressClass
+	}
+	return spec
+}
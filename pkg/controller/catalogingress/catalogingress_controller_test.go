@@ -0,0 +1,142 @@
+package catalogingress
+
+import (
+	"context"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	catsrcName      = "redhat-operators"
+	catsrcNamespace = "openshift-marketplace"
+	catsrcManifest  = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: redhat-operators
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: quay.io/example/redhat-operators:latest
+`
+)
+
+// setup populates the default CatalogSources and returns a reconciler backed
+// by an in-memory cluster containing the default CatalogSource.
+func setup(t *testing.T, ingressClass, domain string) (*ReconcileCatalogIngress, client.Client) {
+	t.Helper()
+	defaultstest.Populate(t, map[string]string{catsrcName + ".yaml": catsrcManifest})
+
+	desired, ok := defaults.GetDesiredCatalogSource(catsrcName)
+	require.True(t, ok)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	require.NoError(t, networkingv1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&desired).Build()
+	return &ReconcileCatalogIngress{
+		client:       c,
+		scheme:       scheme,
+		ingressClass: ingressClass,
+		domain:       domain,
+	}, c
+}
+
+func reconcileCatsrc(t *testing.T, r *ReconcileCatalogIngress) {
+	t.Helper()
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: catsrcNamespace, Name: catsrcName},
+	})
+	require.NoError(t, err)
+}
+
+func getIngress(t *testing.T, c client.Client) (*networkingv1.Ingress, error) {
+	t.Helper()
+	ingress := &networkingv1.Ingress{}
+	err := c.Get(context.TODO(), client.ObjectKey{Namespace: catsrcNamespace, Name: catsrcName}, ingress)
+	return ingress, err
+}
+
+func TestReconcileCreatesIngress(t *testing.T) {
+	r, c := setup(t, "openshift-default", "apps.example.com")
+	reconcileCatsrc(t, r)
+
+	ingress, err := getIngress(t, c)
+	require.NoError(t, err)
+	require.NotNil(t, ingress.Spec.IngressClassName)
+	assert.Equal(t, "openshift-default", *ingress.Spec.IngressClassName)
+	require.Len(t, ingress.Spec.Rules, 1)
+	rule := ingress.Spec.Rules[0]
+	assert.Equal(t, "redhat-operators-openshift-marketplace.apps.example.com", rule.Host)
+	require.Len(t, rule.HTTP.Paths, 1)
+	backend := rule.HTTP.Paths[0].Backend.Service
+	assert.Equal(t, catsrcName, backend.Name)
+	assert.Equal(t, "grpc", backend.Port.Name)
+
+	require.Len(t, ingress.OwnerReferences, 1)
+	assert.Equal(t, "CatalogSource", ingress.OwnerReferences[0].Kind)
+	assert.Equal(t, catsrcName, ingress.OwnerReferences[0].Name)
+
+	// A modified Ingress is reverted.
+	ingress.Spec.Rules[0].Host = "modified.example.com"
+	require.NoError(t, c.Update(context.TODO(), ingress))
+	reconcileCatsrc(t, r)
+	ingress, err = getIngress(t, c)
+	require.NoError(t, err)
+	assert.Equal(t, "redhat-operators-openshift-marketplace.apps.example.com", ingress.Spec.Rules[0].Host)
+}
+
+func TestReconcileWithoutClassAndDomain(t *testing.T) {
+	r, c := setup(t, "", "")
+	reconcileCatsrc(t, r)
+
+	ingress, err := getIngress(t, c)
+	require.NoError(t, err)
+	assert.Nil(t, ingress.Spec.IngressClassName, "the cluster default class is used")
+	assert.Empty(t, ingress.Spec.Rules[0].Host)
+}
+
+func TestReconcileDeletesIngressOfDisabledSource(t *testing.T) {
+	r, c := setup(t, "", "")
+	reconcileCatsrc(t, r)
+	_, err := getIngress(t, c)
+	require.NoError(t, err)
+
+	operatorhub.GetSingleton().Set(configv1.OperatorHubSpec{
+		Sources: []configv1.HubSource{{Name: catsrcName, Disabled: true}},
+	})
+	reconcileCatsrc(t, r)
+	_, err = getIngress(t, c)
+	assert.True(t, k8sErrors.IsNotFound(err))
+
+	// Reconciling again once the Ingress is gone is a no-op.
+	reconcileCatsrc(t, r)
+}
+
+func TestPredicate(t *testing.T) {
+	setup(t, "", "")
+	pred := getPredicateFunctions()
+
+	desired, _ := defaults.GetDesiredCatalogSource(catsrcName)
+	assert.True(t, pred.Create(event.CreateEvent{Object: &desired}))
+	elsewhere := desired.DeepCopy()
+	elsewhere.Namespace = "default"
+	assert.False(t, pred.Create(event.CreateEvent{Object: elsewhere}), "only the default CatalogSources are exposed")
+	custom := desired.DeepCopy()
+	custom.Name = "custom"
+	assert.False(t, pred.Create(event.CreateEvent{Object: custom}))
+}
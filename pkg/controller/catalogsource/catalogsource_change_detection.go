@@ -0,0 +1,50 @@
+package catalogsource
+
+import (
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// detectUnexpectedSpecMutation returns true if the spec of the default
+// CatalogSource no longer matches the hash the operator recorded when it last
+// applied it, which means it was modified by someone else. The mutation is
+// logged and counted, the spec is then restored when the CatalogSource is
+// ensured.
+//
+// The CatalogSources that are not defaults, being deleted or that the
+// operator never applied a spec hash on are not checked.
+func detectUnexpectedSpecMutation(catsrc *olmv1alpha1.CatalogSource) bool {
+	if _, ok := defaults.GetGlobalCatalogSourceDefinitions()[catsrc.Name]; !ok {
+		return false
+	}
+	if !catsrc.DeletionTimestamp.IsZero() {
+		return false
+	}
+	if !defaults.IsSpecModified(catsrc) {
+		return false
+	}
+
+	log.Warnf("[catalogsource] Security: the spec of CatalogSource %s was modified by someone other than the operator (last modified by %s)",
+		catsrc.Name, lastManager(catsrc))
+	metrics.IncUnexpectedSpecMutations(catsrc.Name)
+	return true
+}
+
+// lastManager returns the field manager of the latest write to the
+// CatalogSource, or "unknown" if the managed fields are not recorded.
+func lastManager(catsrc *olmv1alpha1.CatalogSource) string {
+	manager := "unknown"
+	var latest *metav1.Time
+	for _, entry := range catsrc.ManagedFields {
+		if entry.Time == nil || entry.Manager == "" {
+			continue
+		}
+		if latest == nil || entry.Time.After(latest.Time) {
+			manager, latest = entry.Manager, entry.Time
+		}
+	}
+	return manager
+}
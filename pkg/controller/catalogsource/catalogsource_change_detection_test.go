@@ -0,0 +1,101 @@
+package catalogsource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// unexpectedSpecMutations returns the number of unexpected spec mutations
+// reported for the given default CatalogSource.
+func unexpectedSpecMutations(t *testing.T, name string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "marketplace_unexpected_spec_mutations_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == name {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestReconcileDetectsUnexpectedSpecMutation(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+	defaultstest.Populate(t, map[string]string{"redhat-operators.yaml": catsrcManifest})
+
+	// A CatalogSource applied before the spec hash was recorded is not
+	// reported.
+	legacy := &olmv1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "redhat-operators", Namespace: "openshift-marketplace"},
+		Spec:       olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: "quay.io/example/other:latest"},
+	}
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(legacy).WithStatusSubresource(legacy).Build()
+	templates, err := NewMessageTemplates(nil)
+	require.NoError(t, err)
+	r := &ReconcileCatalogSource{client: c, templates: templates, now: time.Now, versions: NewResourceVersionCache()}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: "redhat-operators"}}
+	key := client.ObjectKey{Namespace: "openshift-marketplace", Name: "redhat-operators"}
+
+	_, err = r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, unexpectedSpecMutations(t, "redhat-operators"))
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), key, catsrc))
+	assert.Equal(t, defaults.SpecHash(&catsrc.Spec), catsrc.Annotations[defaults.SpecHashAnnotationKey],
+		"the hash of the spec applied is recorded")
+
+	// A spec modified by someone else is reported, then restored.
+	catsrc.Spec.Image = "quay.io/example/other:latest"
+	require.NoError(t, c.Update(context.TODO(), catsrc))
+	_, err = r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, unexpectedSpecMutations(t, "redhat-operators"))
+	require.NoError(t, c.Get(context.TODO(), key, catsrc))
+	desired, ok := defaults.GetDesiredCatalogSource("redhat-operators")
+	require.True(t, ok)
+	assert.Equal(t, desired.Spec.Image, catsrc.Spec.Image)
+
+	// Modifications of the metadata only are not reported.
+	catsrc.Labels["team"] = "catalogs"
+	require.NoError(t, c.Update(context.TODO(), catsrc))
+	_, err = r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, unexpectedSpecMutations(t, "redhat-operators"))
+}
+
+func TestLastManager(t *testing.T) {
+	catsrc := &olmv1alpha1.CatalogSource{}
+	assert.Equal(t, "unknown", lastManager(catsrc))
+
+	earlier, later := metav1.NewTime(time.Unix(100, 0)), metav1.NewTime(time.Unix(200, 0))
+	catsrc.ManagedFields = []metav1.ManagedFieldsEntry{
+		{Manager: "kubectl-edit", Time: &later},
+		{Manager: "marketplace-operator", Time: &earlier},
+		{Manager: "no-time"},
+	}
+	assert.Equal(t, "kubectl-edit", lastManager(catsrc))
+}
@@ -0,0 +1,136 @@
+package catalogsource
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// conditionDecayCheckInterval is the interval at which the failure conditions
+// of the CatalogSources are checked for decay.
+const conditionDecayCheckInterval = time.Minute
+
+// failureObservations records when each CatalogSource was last observed
+// failing to be ensured. It is safe for concurrent use.
+type failureObservations struct {
+	mu       sync.Mutex
+	observed map[types.NamespacedName]time.Time
+}
+
+// newFailureObservations returns an empty failureObservations.
+func newFailureObservations() *failureObservations {
+	return &failureObservations{observed: make(map[types.NamespacedName]time.Time)}
+}
+
+// Observe records that the CatalogSource key was observed failing at t.
+func (o *failureObservations) Observe(key types.NamespacedName, t time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.observed[key] = t
+}
+
+// Forget removes the observation of the CatalogSource key, if any.
+func (o *failureObservations) Forget(key types.NamespacedName) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.observed, key)
+}
+
+// lastObserved returns when the CatalogSource key was last observed failing.
+// A CatalogSource that was never observed, such as one whose condition was
+// set before the operator restarted, is recorded as observed at t so that its
+// condition only decays once the timeout elapses from then.
+func (o *failureObservations) lastObserved(key types.NamespacedName, t time.Time) time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	observed, ok := o.observed[key]
+	if !ok {
+		o.observed[key] = t
+		return t
+	}
+	return observed
+}
+
+// observeFailure records the result of ensuring the default CatalogSource of
+// request, so that its failure condition is only cleared once no failure was
+// observed for the decay timeout.
+func (r *ReconcileCatalogSource) observeFailure(request reconcile.Request, ensureErr error) {
+	if r.observations == nil {
+		return
+	}
+	if ensureErr != nil {
+		r.observations.Observe(request.NamespacedName, r.now())
+		return
+	}
+	r.observations.Forget(request.NamespacedName)
+}
+
+// conditionDecay clears the EnsureFailed conditions of the CatalogSources
+// that were not observed failing for its timeout, such as the ones that are
+// no longer reconciled because they are no longer defined. It is run by the
+// Manager on the leader only.
+type conditionDecay struct {
+	client       client.Client
+	timeout      time.Duration
+	observations *failureObservations
+	now          func() time.Time
+	interval     time.Duration
+}
+
+// newConditionDecay returns a conditionDecay clearing the EnsureFailed
+// conditions through client once no failure was recorded in observations for
+// timeout.
+func newConditionDecay(client client.Client, timeout time.Duration, observations *failureObservations) *conditionDecay {
+	return &conditionDecay{
+		client:       client,
+		timeout:      timeout,
+		observations: observations,
+		now:          time.Now,
+		interval:     conditionDecayCheckInterval,
+	}
+}
+
+// Start clears the stale failure conditions every interval until ctx is done.
+func (d *conditionDecay) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, d.clearStale, d.interval)
+	return nil
+}
+
+// clearStale removes the EnsureFailed condition of every CatalogSource that
+// was not observed failing for the timeout. A CatalogSource still failing is
+// reconciled on the update and gets its condition back.
+func (d *conditionDecay) clearStale(ctx context.Context) {
+	catsrcs := &olmv1alpha1.CatalogSourceList{}
+	if err := d.client.List(ctx, catsrcs); err != nil {
+		logging.FromContext(ctx).Errorf("[catalogsource] Error listing the CatalogSources to clear their stale conditions - %v", err)
+		return
+	}
+	now := d.now()
+	for i := range catsrcs.Items {
+		catsrc := &catsrcs.Items[i]
+		condition := meta.FindStatusCondition(catsrc.Status.Conditions, EnsuredConditionType)
+		if condition == nil || condition.Status != metav1.ConditionFalse {
+			continue
+		}
+		key := types.NamespacedName{Namespace: catsrc.Namespace, Name: catsrc.Name}
+		if now.Sub(d.observations.lastObserved(key, now)) < d.timeout {
+			continue
+		}
+		meta.RemoveStatusCondition(&catsrc.Status.Conditions, EnsuredConditionType)
+		if err := d.client.Status().Update(ctx, catsrc); err != nil {
+			logging.FromContext(ctx).Errorf("[catalogsource] Error clearing the stale %s condition of CatalogSource %s - %v", EnsuredConditionType, key, err)
+			continue
+		}
+		d.observations.Forget(key)
+		logging.FromContext(ctx).Infof("[catalogsource] Cleared the %s condition of CatalogSource %s, no failure was observed for %s", EnsuredConditionType, key, d.timeout)
+	}
+}
@@ -0,0 +1,82 @@
+package catalogsource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestConditionDecayClearsStaleFailures(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	catsrc := func(name string, status metav1.ConditionStatus) *olmv1alpha1.CatalogSource {
+		reason := EnsuredConditionReason
+		if status == metav1.ConditionFalse {
+			reason = EnsureFailedConditionReason
+		}
+		return &olmv1alpha1.CatalogSource{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openshift-marketplace"},
+			Status: olmv1alpha1.CatalogSourceStatus{Conditions: []metav1.Condition{{
+				Type: EnsuredConditionType, Status: status, Reason: reason, LastTransitionTime: metav1.NewTime(now.Add(-time.Hour)),
+			}}},
+		}
+	}
+	failing, unobserved, ensured := catsrc("redhat-operators", metav1.ConditionFalse), catsrc("certified-operators", metav1.ConditionFalse), catsrc("community-operators", metav1.ConditionTrue)
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(failing, unobserved, ensured).WithStatusSubresource(failing, unobserved, ensured).Build()
+
+	observations := newFailureObservations()
+	r := &ReconcileCatalogSource{now: func() time.Time { return now }, observations: observations}
+	d := newConditionDecay(c, 30*time.Minute, observations)
+	d.now = func() time.Time { return now }
+	condition := func(catsrc *olmv1alpha1.CatalogSource) *metav1.Condition {
+		cluster := &olmv1alpha1.CatalogSource{}
+		require.NoError(t, c.Get(context.TODO(), client.ObjectKeyFromObject(catsrc), cluster))
+		return meta.FindStatusCondition(cluster.Status.Conditions, EnsuredConditionType)
+	}
+
+	r.observeFailure(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: "redhat-operators"}}, assert.AnError)
+	now = now.Add(20 * time.Minute)
+	d.clearStale(context.TODO())
+	assert.NotNil(t, condition(failing))
+	assert.NotNil(t, condition(unobserved), "the decay of a condition never observed starts when it is first checked")
+
+	now = now.Add(10 * time.Minute)
+	d.clearStale(context.TODO())
+	assert.Nil(t, condition(failing), "a condition not observed failing for the timeout is cleared")
+	assert.NotNil(t, condition(unobserved))
+	assert.NotNil(t, condition(ensured))
+
+	now = now.Add(20 * time.Minute)
+	d.clearStale(context.TODO())
+	assert.Nil(t, condition(unobserved))
+	assert.NotNil(t, condition(ensured), "the Ensured conditions never decay")
+}
+
+func TestObserveFailureForgetsEnsured(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	observations := newFailureObservations()
+	r := &ReconcileCatalogSource{now: func() time.Time { return now }, observations: observations}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: "redhat-operators"}}
+
+	r.observeFailure(request, assert.AnError)
+	assert.Equal(t, now, observations.lastObserved(request.NamespacedName, now.Add(time.Hour)))
+
+	r.observeFailure(request, nil)
+	later := now.Add(time.Hour)
+	assert.Equal(t, later, observations.lastObserved(request.NamespacedName, later), "the observation is forgotten once the CatalogSource is ensured")
+
+	// The observations are not recorded without a decay timeout.
+	(&ReconcileCatalogSource{now: time.Now}).observeFailure(request, assert.AnError)
+}
@@ -0,0 +1,144 @@
+package catalogsource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// EnsuredConditionType is the type of the condition the operator sets on
+	// the default CatalogSources to report whether they were ensured.
+	EnsuredConditionType = "MarketplaceEnsured"
+
+	// EnsuredConditionReason is the reason of the condition set on a default
+	// CatalogSource that the operator ensured.
+	EnsuredConditionReason = "Ensured"
+
+	// EnsureFailedConditionReason is the reason of the condition set on a
+	// default CatalogSource that the operator failed to ensure.
+	EnsureFailedConditionReason = "EnsureFailed"
+)
+
+// defaultMessageTemplates are the templates of the condition messages, keyed
+// by the reason of the condition. They are used for the reasons that are not
+// overridden in the message template ConfigMap.
+var defaultMessageTemplates = map[string]string{
+	EnsuredConditionReason:      "The default CatalogSource {{.SourceName}} is in the desired state since {{.Since}}",
+	EnsureFailedConditionReason: "Failed to ensure the default CatalogSource {{.SourceName}} since {{.Since}}: {{.Error}}",
+}
+
+// MessageTemplateData holds the variables available to the condition message
+// templates.
+type MessageTemplateData struct {
+	// SourceName is the name of the default CatalogSource.
+	SourceName string
+	// Error is the error that caused the condition, it is empty if there is
+	// none.
+	Error string
+	// Since is the time, in RFC 3339 format, the condition last transitioned.
+	Since string
+}
+
+// MessageTemplates formats the condition messages of the default
+// CatalogSources using Go templates, so that they can be localized or
+// customized without rebuilding the operator.
+type MessageTemplates struct {
+	templates map[string]*template.Template
+}
+
+// NewMessageTemplates returns the MessageTemplates parsed from overrides, keyed
+// by condition reason, on top of the default templates. An error is returned
+// if a template can not be parsed or the reason is unknown.
+func NewMessageTemplates(overrides map[string]string) (*MessageTemplates, error) {
+	m := &MessageTemplates{templates: make(map[string]*template.Template)}
+	for reason, text := range defaultMessageTemplates {
+		m.templates[reason] = template.Must(template.New(reason).Option("missingkey=error").Parse(text))
+	}
+	for reason, text := range overrides {
+		if _, ok := defaultMessageTemplates[reason]; !ok {
+			return nil, fmt.Errorf("unknown condition reason %q", reason)
+		}
+		t, err := template.New(reason).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message template for condition reason %s: %v", reason, err)
+		}
+		m.templates[reason] = t
+	}
+	return m, nil
+}
+
+// Render returns the message of the condition with the given reason. If the
+// template fails to execute the message falls back to the default template,
+// as a condition must never be left without a message.
+func (m *MessageTemplates) Render(reason string, data MessageTemplateData) string {
+	var message bytes.Buffer
+	err := m.templates[reason].Execute(&message, data)
+	if err == nil {
+		return message.String()
+	}
+	log.Warnf("[catalogsource] Falling back to the default message template for condition reason %s - %v", reason, err)
+
+	message.Reset()
+	template.Must(template.New(reason).Parse(defaultMessageTemplates[reason])).Execute(&message, data)
+	return message.String()
+}
+
+// LoadMessageTemplates returns the MessageTemplates overridden by the data of
+// the ConfigMap with the given namespace and name. The default templates are
+// returned if name is empty or the ConfigMap does not exist.
+func LoadMessageTemplates(ctx context.Context, reader client.Reader, namespace, name string) (*MessageTemplates, error) {
+	if name == "" {
+		return NewMessageTemplates(nil)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, configMap)
+	if k8sErrors.IsNotFound(err) {
+		log.Warnf("[catalogsource] Message template ConfigMap %s/%s not found, using the default condition messages", namespace, name)
+		return NewMessageTemplates(nil)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get message template ConfigMap %s/%s: %v", namespace, name, err)
+	}
+
+	templates, err := NewMessageTemplates(configMap.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message template ConfigMap %s/%s: %v", namespace, name, err)
+	}
+	log.Infof("[catalogsource] Loaded the condition message templates from ConfigMap %s/%s", namespace, name)
+	return templates, nil
+}
+
+// ensuredCondition returns the EnsuredConditionType condition reporting the
+// result of ensuring the default CatalogSource with the given name. The time
+// the condition last transitioned is carried over from conditions if its
+// status is unchanged.
+func (m *MessageTemplates) ensuredCondition(conditions []metav1.Condition, name string, ensureErr error, now time.Time) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               EnsuredConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             EnsuredConditionReason,
+		LastTransitionTime: metav1.NewTime(now),
+	}
+	data := MessageTemplateData{SourceName: name}
+	if ensureErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = EnsureFailedConditionReason
+		data.Error = ensureErr.Error()
+	}
+	if existing := meta.FindStatusCondition(conditions, EnsuredConditionType); existing != nil && existing.Status == condition.Status {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	}
+	data.Since = condition.LastTransitionTime.UTC().Format(time.RFC3339)
+	condition.Message = m.Render(condition.Reason, data)
+	return condition
+}
@@ -0,0 +1,115 @@
+package catalogsource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/operator-framework/operator-marketplace/pkg/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestMessageTemplates(t *testing.T) {
+	templates, err := NewMessageTemplates(map[string]string{
+		EnsureFailedConditionReason: "Le CatalogSource {{.SourceName}} n'a pas pu être appliqué depuis {{.Since}} : {{.Error}}",
+	})
+	require.NoError(t, err)
+	data := MessageTemplateData{SourceName: "redhat-operators", Error: "forbidden", Since: "2026-10-14T08:00:00Z"}
+	assert.Equal(t, "Le CatalogSource redhat-operators n'a pas pu être appliqué depuis 2026-10-14T08:00:00Z : forbidden",
+		templates.Render(EnsureFailedConditionReason, data))
+	assert.Equal(t, "The default CatalogSource redhat-operators is in the desired state since 2026-10-14T08:00:00Z",
+		templates.Render(EnsuredConditionReason, data), "the reasons that are not overridden use the default template")
+
+	_, err = NewMessageTemplates(map[string]string{EnsuredConditionReason: "{{.SourceName"})
+	assert.Error(t, err, "a malformed template is rejected")
+	_, err = NewMessageTemplates(map[string]string{"Unknown": "{{.SourceName}}"})
+	assert.Error(t, err, "a template for an unknown reason is rejected")
+
+	templates, err = NewMessageTemplates(map[string]string{EnsuredConditionReason: "{{.Unknown}}"})
+	require.NoError(t, err)
+	assert.Equal(t, "The default CatalogSource redhat-operators is in the desired state since 2026-10-14T08:00:00Z",
+		templates.Render(EnsuredConditionReason, data), "a template failing to execute falls back to the default")
+}
+
+func TestLoadMessageTemplates(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "messages", Namespace: "openshift-marketplace"},
+			Data:       map[string]string{EnsuredConditionReason: "{{.SourceName}} ok"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "openshift-marketplace"},
+			Data:       map[string]string{EnsuredConditionReason: "{{"},
+		},
+	).Build()
+	data := MessageTemplateData{SourceName: "redhat-operators"}
+
+	templates, err := LoadMessageTemplates(context.TODO(), reader, "openshift-marketplace", "messages")
+	require.NoError(t, err)
+	assert.Equal(t, "redhat-operators ok", templates.Render(EnsuredConditionReason, data))
+
+	templates, err = LoadMessageTemplates(context.TODO(), reader, "openshift-marketplace", "missing")
+	require.NoError(t, err, "a missing ConfigMap falls back to the defaults")
+	assert.Contains(t, templates.Render(EnsuredConditionReason, data), "The default CatalogSource redhat-operators")
+
+	_, err = LoadMessageTemplates(context.TODO(), reader, "openshift-marketplace", "invalid")
+	assert.Error(t, err)
+}
+
+func TestReconcileSetsEnsuredCondition(t *testing.T) {
+	defaultstest.Populate(t, map[string]string{"redhat-operators.yaml": catsrcManifest})
+
+	desired, ok := defaults.GetDesiredCatalogSource("redhat-operators")
+	require.True(t, ok)
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&desired).WithStatusSubresource(&desired).Build()
+
+	templates, err := NewMessageTemplates(map[string]string{EnsuredConditionReason: "{{.SourceName}} ok since {{.Since}}"})
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+	failures := &status.NoOpReporter{}
+	r := &ReconcileCatalogSource{client: c, templates: templates, now: func() time.Time { return now }, versions: NewResourceVersionCache(), failures: failures}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: "redhat-operators"}}
+	getCondition := func() *metav1.Condition {
+		catsrc := &olmv1alpha1.CatalogSource{}
+		require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: "openshift-marketplace", Name: "redhat-operators"}, catsrc))
+		return meta.FindStatusCondition(catsrc.Status.Conditions, EnsuredConditionType)
+	}
+
+	_, err = r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	condition := getCondition()
+	require.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, EnsuredConditionReason, condition.Reason)
+	assert.Equal(t, "redhat-operators ok since 2026-10-14T08:00:00Z", condition.Message)
+	assert.Empty(t, failures.RecordedFailures(), "an ensured CatalogSource is not reported failing")
+
+	// The time is carried over while the status does not change.
+	now = now.Add(time.Hour)
+	_, err = r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "redhat-operators ok since 2026-10-14T08:00:00Z", getCondition().Message)
+
+	require.NoError(t, r.setEnsuredCondition(context.TODO(), request, errors.New("forbidden")))
+	condition = getCondition()
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, EnsureFailedConditionReason, condition.Reason)
+	assert.Equal(t, "Failed to ensure the default CatalogSource redhat-operators since 2026-10-14T09:00:00Z: forbidden", condition.Message)
+}
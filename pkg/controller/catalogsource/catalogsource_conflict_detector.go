@@ -0,0 +1,123 @@
+package catalogsource
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// FieldManagerPrefix prefixes the field manager of every marketplace
+	// operator instance.
+	FieldManagerPrefix = "marketplace-operator"
+
+	// fieldManagerConflictReason is the reason of the events reporting a
+	// default CatalogSource managed by another operator instance.
+	fieldManagerConflictReason = "FieldManagerConflict"
+)
+
+// FieldManager returns the field manager of the marketplace operator running
+// in namespace, so that the writes of the operator instances deployed in
+// different namespaces can be told apart in the managedFields of the objects.
+func FieldManager(namespace string) string {
+	return FieldManagerPrefix + "-" + namespace
+}
+
+// conflictDetector detects the default CatalogSources whose spec is also
+// managed by another marketplace operator instance, as happens when the
+// operator is deployed twice. Each conflict is reported once, with a warning
+// event and a metric, until the competing managers change.
+type conflictDetector struct {
+	recorder record.EventRecorder
+	// manager is the field manager of this operator instance.
+	manager string
+
+	mu sync.Mutex
+	// reported holds the competing managers last reported for each
+	// CatalogSource.
+	reported map[types.NamespacedName]string
+}
+
+// newConflictDetector returns a conflictDetector reporting the CatalogSources
+// managed by others than manager through recorder.
+func newConflictDetector(recorder record.EventRecorder, manager string) *conflictDetector {
+	return &conflictDetector{
+		recorder: recorder,
+		manager:  manager,
+		reported: make(map[types.NamespacedName]string),
+	}
+}
+
+// detect reports the marketplace operator instances other than this one that
+// manage fields of the spec of catsrc, and returns their field managers.
+func (d *conflictDetector) detect(ctx context.Context, catsrc *olmv1alpha1.CatalogSource) []string {
+	competing := competingManagers(catsrc, d.manager)
+	key := types.NamespacedName{Namespace: catsrc.Namespace, Name: catsrc.Name}
+	joined := strings.Join(competing, ", ")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(competing) == 0 {
+		delete(d.reported, key)
+		return nil
+	}
+	if d.reported[key] == joined {
+		return competing
+	}
+	d.reported[key] = joined
+
+	logging.FromContext(ctx).Warnf("[catalogsource] CatalogSource %s is managed by both %s and %s, another marketplace operator may be deployed",
+		catsrc.Name, d.manager, joined)
+	d.recorder.Eventf(catsrc, corev1.EventTypeWarning, fieldManagerConflictReason,
+		"The default CatalogSource %s is managed by both %s and %s, only one marketplace operator should be deployed",
+		catsrc.Name, d.manager, joined)
+	metrics.IncFieldManagerConflicts(catsrc.Name)
+	return competing
+}
+
+// competingManagers returns the sorted marketplace operator field managers,
+// other than manager, owning fields of the spec of catsrc. The bare
+// FieldManagerPrefix the operator wrote with before the managers were
+// qualified with the namespace is not competing, as its fields are kept by
+// the CatalogSources applied before an upgrade.
+func competingManagers(catsrc *olmv1alpha1.CatalogSource, manager string) []string {
+	seen := make(map[string]bool)
+	var competing []string
+	for _, entry := range catsrc.ManagedFields {
+		if entry.Manager == manager || seen[entry.Manager] || !strings.HasPrefix(entry.Manager, FieldManagerPrefix+"-") {
+			continue
+		}
+		if entry.FieldsV1 == nil {
+			continue
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if _, ok := fields["f:spec"]; !ok {
+			continue
+		}
+		seen[entry.Manager] = true
+		competing = append(competing, entry.Manager)
+	}
+	sort.Strings(competing)
+	return competing
+}
+
+// detectConflicts reports the other marketplace operator instances managing
+// the default CatalogSource, if conflicts are detected.
+func (r *ReconcileCatalogSource) detectConflicts(ctx context.Context, catsrc *olmv1alpha1.CatalogSource) {
+	if r.conflicts == nil || !catsrc.DeletionTimestamp.IsZero() {
+		return
+	}
+	r.conflicts.detect(ctx, catsrc)
+}
@@ -0,0 +1,97 @@
+package catalogsource
+
+import (
+	"context"
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// fieldManagerConflicts returns the number of field manager conflicts
+// reported for the given default CatalogSource.
+func fieldManagerConflicts(t *testing.T, name string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "marketplace_field_manager_conflicts_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == name {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// managedFields returns a managedFields entry of manager owning the given
+// raw fields.
+func managedFields(manager, raw string) metav1.ManagedFieldsEntry {
+	return metav1.ManagedFieldsEntry{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate, FieldsV1: &metav1.FieldsV1{Raw: []byte(raw)}}
+}
+
+func TestCompetingManagers(t *testing.T) {
+	own := FieldManager("openshift-marketplace")
+	spec := `{"f:spec":{"f:image":{}}}`
+	catsrc := &olmv1alpha1.CatalogSource{}
+	catsrc.ManagedFields = []metav1.ManagedFieldsEntry{
+		managedFields(own, spec),
+		// The managers that are not marketplace operator instances, or the
+		// bare manager of the operator before an upgrade, are not
+		// competing.
+		managedFields("kubectl-edit", spec),
+		managedFields(FieldManagerPrefix, spec),
+		// Nor are the instances managing the metadata or status only.
+		managedFields(FieldManager("other"), `{"f:metadata":{"f:labels":{}}}`),
+		{Manager: FieldManager("status-only"), Subresource: "status"},
+		managedFields(FieldManager("zz-marketplace"), spec),
+		managedFields(FieldManager("custom-marketplace"), spec),
+		managedFields(FieldManager("custom-marketplace"), `{"f:spec":{"f:priority":{}}}`),
+	}
+	assert.Equal(t, []string{FieldManager("custom-marketplace"), FieldManager("zz-marketplace")}, competingManagers(catsrc, own))
+}
+
+func TestConflictDetectorReportsOnce(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+	recorder := record.NewFakeRecorder(10)
+	d := newConflictDetector(recorder, FieldManager("openshift-marketplace"))
+	catsrc := &olmv1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "certified-operators", Namespace: "openshift-marketplace"},
+	}
+	catsrc.ManagedFields = []metav1.ManagedFieldsEntry{
+		managedFields(FieldManager("openshift-marketplace"), `{"f:spec":{}}`),
+		managedFields(FieldManager("custom-marketplace"), `{"f:spec":{}}`),
+	}
+	previous := fieldManagerConflicts(t, "certified-operators")
+
+	assert.Equal(t, []string{FieldManager("custom-marketplace")}, d.detect(context.TODO(), catsrc))
+	require.Len(t, recorder.Events, 1)
+	event := <-recorder.Events
+	assert.Contains(t, event, "Warning "+fieldManagerConflictReason)
+	assert.Contains(t, event, FieldManager("openshift-marketplace"))
+	assert.Contains(t, event, FieldManager("custom-marketplace"))
+	assert.Equal(t, previous+1, fieldManagerConflicts(t, "certified-operators"))
+
+	// The same conflict is not reported again.
+	d.detect(context.TODO(), catsrc)
+	assert.Empty(t, recorder.Events)
+	assert.Equal(t, previous+1, fieldManagerConflicts(t, "certified-operators"))
+
+	// A conflict resolved then detected again is reported again.
+	resolved := catsrc.DeepCopy()
+	resolved.ManagedFields = resolved.ManagedFields[:1]
+	assert.Empty(t, d.detect(context.TODO(), resolved))
+	d.detect(context.TODO(), catsrc)
+	assert.Len(t, recorder.Events, 1)
+	assert.Equal(t, previous+2, fieldManagerConflicts(t, "certified-operators"))
+}
@@ -2,48 +2,101 @@ package catalogsource
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	configv1 "github.com/openshift/api/config/v1"
+	imagev1 "github.com/openshift/api/image/v1"
 	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
 
+	mktconfig "github.com/operator-framework/operator-marketplace/pkg/apis/config/v1"
 	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
 	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
 	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	"github.com/operator-framework/operator-marketplace/pkg/status"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
 
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
+// controllerName is the name of the controller, it is used to identify its
+// metrics.
+const controllerName = "catalogsource-controller"
+
 // Add creates a new CatalogSource Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
-func Add(mgr manager.Manager, _ options.ControllerOptions) error {
-	return add(mgr, newReconciler(mgr))
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	// The templates are read once, a change to the ConfigMap is picked up
+	// when the operator restarts.
+	templates, err := LoadMessageTemplates(context.TODO(), mgr.GetAPIReader(), o.Namespace, o.MessageTemplateConfigMap)
+	if err != nil {
+		log.Errorf("[catalogsource] Using the default condition messages - %v", err)
+		templates, _ = NewMessageTemplates(nil)
+	}
+	r := newReconciler(mgr, templates, o.FailureReporter)
+	r.conflicts = newConflictDetector(mgr.GetEventRecorderFor(controllerName), FieldManager(o.Namespace))
+	var reloads <-chan event.GenericEvent
+	if o.DefaultsDir != "" && o.ReloadDefaults != nil {
+		reloader := newDefaultsReloader(mgr.GetClient(), o.DefaultsDir, o.ReloadDefaults, r.versions)
+		if err := mgr.Add(reloader); err != nil {
+			return err
+		}
+		reloads = reloader.events
+	}
+	if o.ConditionDecayTimeout > 0 {
+		r.observations = newFailureObservations()
+		if err := mgr.Add(newConditionDecay(mgr.GetClient(), o.ConditionDecayTimeout, r.observations)); err != nil {
+			return err
+		}
+	}
+	return add(mgr, r, r.versions, r.pushes, reloads, o.ControllerRuntimeOptions())
 }
 
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+func newReconciler(mgr manager.Manager, templates *MessageTemplates, failures status.FailureReporter) *ReconcileCatalogSource {
 	client := mgr.GetClient()
 	return &ReconcileCatalogSource{
-		client: client,
+		client:    client,
+		templates: templates,
+		now:       time.Now,
+		reader:    mgr.GetAPIReader(),
+		versions:  NewResourceVersionCache(),
+		pushes:    newPushedImages(),
+		failures:  failures,
 	}
 }
 
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
-	defaultCatalogsources := defaults.GetGlobalCatalogSourceDefinitions()
+// add adds a new Controller to mgr with r as the reconcile.Reconciler. The
+// default CatalogSources of the events of reloads, if not nil, are reconciled
+// too. The CatalogSources enqueued for an ImageStream tag push are recorded in
+// pushes and forgotten from versions, the cache of r.
+func add(mgr manager.Manager, r reconcile.Reconciler, versions *ResourceVersionCache, pushes *pushedImages, reloads <-chan event.GenericEvent, opts controller.Options) error {
+	// The copies of the default CatalogSources in other namespaces are not
+	// managed by this controller. The definitions are read on each event as
+	// they are reloaded when the defaults directory changes.
+	isDefault := func(obj client.Object) bool {
+		def, ok := defaults.GetGlobalCatalogSourceDefinitions()[obj.GetName()]
+		return ok && def.Namespace == obj.GetNamespace()
+	}
 	pred := predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
 			return false
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
-			if _, ok := defaultCatalogsources[e.ObjectOld.GetName()]; ok {
-				return true
-			}
-			return false
+			return isDefault(e.ObjectOld)
 		},
 		DeleteFunc: func(e event.DeleteEvent) bool {
-			if _, ok := defaultCatalogsources[e.Object.GetName()]; ok {
+			if isDefault(e.Object) {
 				// If DeleteStateUnknown is true it implies that the Delete event was missed
 				// and we can ignore it.
 				if e.DeleteStateUnknown {
@@ -54,18 +107,29 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 			return false
 		},
 		GenericFunc: func(e event.GenericEvent) bool {
-			if _, ok := defaultCatalogsources[e.Object.GetName()]; ok {
-				return true
-			}
-			return false
+			return isDefault(e.Object)
 		},
 	}
 
-	return builder.ControllerManagedBy(mgr).
-		Named("catalogsource-controller").
-		For(&olmv1alpha1.CatalogSource{}).
-		WithEventFilter(pred).
-		Complete(r)
+	// The ClusterVersion and ImageStreams are only available on OpenShift.
+	predicates := []predicate.Predicate{pred}
+	if mktconfig.IsAPIAvailable() {
+		predicates = append(predicates, NewClusterUpgradePredicate(mgr.GetClient()))
+	}
+
+	b := builder.ControllerManagedBy(mgr).
+		Named(controllerName).
+		For(&olmv1alpha1.CatalogSource{}, builder.WithPredicates(predicates...))
+
+	if mktconfig.IsAPIAvailable() {
+		b = b.Watches(&imagev1.ImageStream{}, imageStreamTagHandler(versions, pushes)).
+			Watches(&configv1.ClusterVersion{}, clusterUpgradeCompletedHandler())
+	}
+	if reloads != nil {
+		b = b.WatchesRawSource(source.Channel(reloads, &handler.EnqueueRequestForObject{}))
+	}
+
+	return b.WithOptions(opts).Complete(metrics.NewInstrumentedReconciler(controllerName, r))
 }
 
 // blank assignment to verify that ReconcileOperatorHub implements reconcile.Reconciler
@@ -76,9 +140,125 @@ type ReconcileCatalogSource struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
+	// reader reads directly from the apiserver so that the catalog pods do
+	// not need to be cached.
+	reader client.Reader
+	// templates formats the messages of the conditions set on the default
+	// CatalogSources.
+	templates *MessageTemplates
+	now       func() time.Time
+	// versions records the resourceVersion of the default CatalogSources
+	// last reconciled successfully, so that the ones not modified since are
+	// not applied again.
+	versions *ResourceVersionCache
+	// pushes, if not nil, records the images pushed to the ImageStream tags
+	// of the default CatalogSources until they are applied.
+	pushes *pushedImages
+	// failures, if not nil, aggregates the default CatalogSources failing
+	// to sync into the Degraded condition of the ClusterOperator.
+	failures status.FailureReporter
+	// observations, if not nil, records when the default CatalogSources
+	// were last observed failing, so that their failure condition decays.
+	observations *failureObservations
+	// conflicts, if not nil, reports the default CatalogSources also
+	// managed by another marketplace operator instance.
+	conflicts *conflictDetector
 }
 
 func (r *ReconcileCatalogSource) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	if defaults.RolloutInProgress() {
+		logging.FromContext(ctx).Debugf("[catalogsource] Waiting for the rollout of the default CatalogSources to reconcile CatalogSource %s", request.Name)
+		return reconcile.Result{RequeueAfter: defaults.RolloutRequeueInterval}, nil
+	}
+	catsrc := &olmv1alpha1.CatalogSource{}
+	err := r.client.Get(ctx, request.NamespacedName, catsrc)
+	if err == nil && r.versions.Unchanged(catsrc) {
+		logging.FromContext(ctx).Debugf("[catalogsource] CatalogSource %s was not modified since it was last reconciled", request.Name)
+		return reconcile.Result{}, nil
+	}
+	if err == nil {
+		detectUnexpectedSpecMutation(catsrc)
+		r.detectConflicts(ctx, catsrc)
+	}
+
+	// The image pushed to the ImageStream tag of the CatalogSource, if any,
+	// is pinned before the definitions are read.
+	digest, restart := r.pinPushedImage(ctx, request)
+
 	defaultCatalogsources := defaults.GetGlobalCatalogSourceDefinitions()
-	return reconcile.Result{}, defaults.New(defaultCatalogsources, operatorhub.GetSingleton().Get()).Ensure(ctx, r.client, request.Name)
+	ensureErr := defaults.New(defaultCatalogsources, operatorhub.GetSingleton().Get()).Ensure(ctx, r.client, request.Name)
+	if err := r.setEnsuredCondition(ctx, request, ensureErr); err != nil && ensureErr == nil {
+		r.versions.Forget(request.NamespacedName)
+		return reconcile.Result{}, err
+	}
+	r.reportFailure(request, ensureErr)
+	r.observeFailure(request, ensureErr)
+	if ensureErr != nil {
+		r.versions.Forget(request.NamespacedName)
+	} else {
+		r.recordVersion(ctx, request)
+	}
+	if restart {
+		if ensureErr == nil {
+			ensureErr = r.restartCatalogPods(ctx, request, digest)
+		}
+		// The push is applied again on the retry.
+		if ensureErr != nil {
+			r.pushes.record(request.NamespacedName, digest)
+			r.versions.Forget(request.NamespacedName)
+		}
+	}
+	return reconcile.Result{}, ensureErr
+}
+
+// reportFailure registers the failure to ensure the default CatalogSource with
+// the FailureReporter, or clears it once the CatalogSource is ensured.
+func (r *ReconcileCatalogSource) reportFailure(request reconcile.Request, ensureErr error) {
+	if r.failures == nil {
+		return
+	}
+	if ensureErr != nil {
+		r.failures.RegisterFailure(request.Name, request.Namespace, ensureErr.Error())
+		return
+	}
+	r.failures.ClearFailure(request.Name, request.Namespace)
+}
+
+// recordVersion records the resourceVersion of the CatalogSource that was
+// just reconciled successfully. The version read may predate the writes of
+// the reconcile if the cache lags behind, in which case the CatalogSource is
+// reconciled again on the event of those writes.
+func (r *ReconcileCatalogSource) recordVersion(ctx context.Context, request reconcile.Request) {
+	catsrc := &olmv1alpha1.CatalogSource{}
+	if err := r.client.Get(ctx, request.NamespacedName, catsrc); err != nil {
+		r.versions.Forget(request.NamespacedName)
+		return
+	}
+	r.versions.Record(catsrc)
+}
+
+// setEnsuredCondition reports the result of ensuring the default CatalogSource
+// in its EnsuredConditionType condition. Nothing is reported for a
+// CatalogSource that is absent, either because it is disabled or not yet in
+// the cache.
+func (r *ReconcileCatalogSource) setEnsuredCondition(ctx context.Context, request reconcile.Request, ensureErr error) error {
+	if _, ok := defaults.GetGlobalCatalogSourceDefinitions()[request.Name]; !ok {
+		return nil
+	}
+	catsrc := &olmv1alpha1.CatalogSource{}
+	if err := r.client.Get(ctx, request.NamespacedName, catsrc); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !catsrc.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	condition := r.templates.ensuredCondition(catsrc.Status.Conditions, catsrc.Name, ensureErr, r.now())
+	if !meta.SetStatusCondition(&catsrc.Status.Conditions, condition) {
+		return nil
+	}
+	if err := r.client.Status().Update(ctx, catsrc); err != nil {
+		return fmt.Errorf("failed to set the %s condition of CatalogSource %s: %v", EnsuredConditionType, catsrc.Name, err)
+	}
+	return nil
 }
@@ -0,0 +1,67 @@
+package catalogsource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/operator-framework/operator-marketplace/pkg/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileReportsFailures(t *testing.T) {
+	defaultstest.Populate(t, map[string]string{"redhat-operators.yaml": catsrcManifest})
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	failing := true
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&olmv1alpha1.CatalogSource{}).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if failing {
+				return errors.New("forbidden")
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	}).Build()
+
+	templates, err := NewMessageTemplates(nil)
+	require.NoError(t, err)
+	failures := &status.NoOpReporter{}
+	r := &ReconcileCatalogSource{client: c, templates: templates, now: time.Now, versions: NewResourceVersionCache(), failures: failures}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: "redhat-operators"}}
+	degraded := func() configv1.ClusterOperatorStatusCondition {
+		for _, condition := range failures.RecordedConditions() {
+			if condition.Type == configv1.OperatorDegraded {
+				return condition
+			}
+		}
+		t.Fatal("the Degraded condition is not recorded")
+		return configv1.ClusterOperatorStatusCondition{}
+	}
+
+	_, err = r.Reconcile(context.TODO(), request)
+	require.Error(t, err)
+	require.Len(t, failures.RecordedFailures(), 1)
+	assert.Contains(t, failures.RecordedFailures()[0], "openshift-marketplace/redhat-operators: ")
+	assert.Contains(t, failures.RecordedFailures()[0], "forbidden")
+	assert.Equal(t, configv1.ConditionTrue, degraded().Status)
+	assert.Contains(t, degraded().Message, "1 CatalogSource failing to sync: openshift-marketplace/redhat-operators: ")
+
+	// The failure clears once the CatalogSource is ensured.
+	failing = false
+	_, err = r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	assert.Empty(t, failures.RecordedFailures())
+	assert.Equal(t, configv1.ConditionFalse, degraded().Status)
+}
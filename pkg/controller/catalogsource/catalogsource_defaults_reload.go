@@ -0,0 +1,179 @@
+package catalogsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	log "github.com/sirupsen/logrus"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// defaultsReloadDelay is the time the defaults directory is given to settle
+// after a change before it is reloaded, as a ConfigMap update is a burst of
+// changes ending with the swap of the ..data symlink.
+const defaultsReloadDelay = time.Second
+
+// defaultsReloader watches the defaults directory and reloads the default
+// CatalogSource definitions when its content changes, such as when the CVO
+// updates the ConfigMap mounted on it during an upgrade. The OperatorHub
+// configuration is then refreshed to cover the new definitions, the default
+// CatalogSources whose definition was removed are pruned and the added or
+// updated ones are reconciled, through events, so that they are applied.
+type defaultsReloader struct {
+	client client.Client
+	dir    string
+	// reload populates the global definitions again, keeping the previous
+	// ones if the content of dir is invalid.
+	reload func(context.Context) error
+	// versions is the cache of the reconciler, the default CatalogSources
+	// are forgotten so that they are applied again.
+	versions *ResourceVersionCache
+	events   chan event.GenericEvent
+	// loaded is the hash of the content of dir when it was last loaded.
+	loaded string
+	delay  time.Duration
+}
+
+// newDefaultsReloader returns a defaultsReloader of dir, whose content was
+// loaded on startup, pruning the removed default CatalogSources with client.
+func newDefaultsReloader(client client.Client, dir string, reload func(context.Context) error, versions *ResourceVersionCache) *defaultsReloader {
+	loaded, err := contentHash(dir)
+	if err != nil {
+		log.Warnf("[catalogsource] Unable to read the defaults directory %s - %v", dir, err)
+	}
+	return &defaultsReloader{
+		client:   client,
+		dir:      dir,
+		reload:   reload,
+		versions: versions,
+		events:   make(chan event.GenericEvent),
+		loaded:   loaded,
+		delay:    defaultsReloadDelay,
+	}
+}
+
+// Start watches the defaults directory until ctx is done. The directory is
+// also checked when the reloader starts, as it may have changed while another
+// replica was leading.
+func (r *defaultsReloader) Start(ctx context.Context) error {
+	changes := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+	if err := defaults.NewFileWatcher(r.dir, 0, notify).Run(ctx); err != nil {
+		log.Errorf("[catalogsource] Unable to watch the defaults directory %s, its changes are picked up on restart - %v", r.dir, err)
+		return nil
+	}
+	notify()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changes:
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.delay):
+		}
+		// The changes made while settling are part of this reload.
+		select {
+		case <-changes:
+		default:
+		}
+		r.reloadIfChanged(ctx)
+	}
+}
+
+// reloadIfChanged reloads the default CatalogSource definitions if the content
+// of the defaults directory changed since it was last loaded, prunes the
+// default CatalogSources removed and sends an event for each default
+// CatalogSource added or updated once they are reloaded.
+func (r *defaultsReloader) reloadIfChanged(ctx context.Context) {
+	hash, err := contentHash(r.dir)
+	if err != nil {
+		log.Errorf("[catalogsource] Unable to read the defaults directory %s - %v", r.dir, err)
+		return
+	}
+	if hash == r.loaded {
+		log.Debugf("[catalogsource] The content of the defaults directory %s did not change", r.dir)
+		return
+	}
+	// The same invalid content is not reloaded again until it changes.
+	r.loaded = hash
+	previous := defaults.GetGlobalCatalogSourceDefinitions()
+	if err := r.reload(ctx); err != nil {
+		log.Errorf("[catalogsource] Keeping the previous default CatalogSources, the defaults directory %s could not be reloaded - %v", r.dir, err)
+		return
+	}
+	log.Infof("[catalogsource] Reloaded the default CatalogSources of %s", r.dir)
+
+	diff := defaults.Diff(previous, defaults.GetGlobalCatalogSourceDefinitions())
+	if diff.IsEmpty() {
+		return
+	}
+	// The OperatorHub configuration only covers the defaults it was set
+	// with.
+	operatorhub.GetSingleton().Refresh()
+	r.prune(ctx, diff.Removed)
+
+	for _, catsrc := range append(diff.Added, diff.Updated...) {
+		key := types.NamespacedName{Namespace: catsrc.Namespace, Name: catsrc.Name}
+		r.versions.Forget(key)
+		select {
+		case <-ctx.Done():
+			return
+		case r.events <- event.GenericEvent{Object: &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}}:
+		}
+	}
+}
+
+// prune deletes the CatalogSources of the removed definitions that are
+// managed by the operator.
+func (r *defaultsReloader) prune(ctx context.Context, removed []*olmv1alpha1.CatalogSource) {
+	var existing []olmv1alpha1.CatalogSource
+	for _, def := range removed {
+		catsrc := &olmv1alpha1.CatalogSource{}
+		err := r.client.Get(ctx, types.NamespacedName{Namespace: def.Namespace, Name: def.Name}, catsrc)
+		if err != nil {
+			if !k8sErrors.IsNotFound(err) {
+				log.Errorf("[catalogsource] Unable to get the removed default CatalogSource %s/%s - %v", def.Namespace, def.Name, err)
+			}
+			continue
+		}
+		existing = append(existing, *catsrc)
+	}
+	if err := defaults.PruneObsolete(ctx, r.client, existing, nil); err != nil {
+		log.Errorf("[catalogsource] Unable to prune the removed default CatalogSources - %v", err)
+	}
+}
+
+// contentHash returns a hash of the manifests of dir, as read by
+// defaults.DirLoader, so that the changes to the other files and the symlink
+// swaps that do not change the manifests are ignored.
+func contentHash(dir string) (string, error) {
+	manifests, err := defaults.DirLoader{Dir: dir}.Load(context.Background())
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	for _, m := range manifests {
+		hash.Write([]byte(m.Path))
+		hash.Write([]byte{0})
+		hash.Write(m.Content)
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
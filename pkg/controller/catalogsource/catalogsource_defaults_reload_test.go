@@ -0,0 +1,131 @@
+package catalogsource
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// updateConfigMapMount writes files into dir like the kubelet updates a
+// ConfigMap volume: the files are written into a new timestamped directory,
+// the ..data symlink is atomically swapped to it, and each file is a symlink
+// through ..data.
+func updateConfigMapMount(t *testing.T, dir, timestamp string, files map[string]string) {
+	t.Helper()
+	payload := filepath.Join(dir, "..2026_10_14_"+timestamp)
+	require.NoError(t, os.Mkdir(payload, 0755))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(payload, name), []byte(content), 0644))
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	require.NoError(t, os.Symlink(filepath.Base(payload), tmp))
+	require.NoError(t, os.Rename(tmp, filepath.Join(dir, "..data")))
+	for name := range files {
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); os.IsNotExist(err) {
+			require.NoError(t, os.Symlink(filepath.Join("..data", name), link))
+		}
+	}
+}
+
+// requireEvents returns the keys of the CatalogSources of the events sent,
+// until none is sent for a while.
+func requireEvents(t *testing.T, events <-chan event.GenericEvent) []types.NamespacedName {
+	t.Helper()
+	var keys []types.NamespacedName
+	timeout := time.Second
+	for {
+		select {
+		case e := <-events:
+			keys = append(keys, client.ObjectKeyFromObject(e.Object))
+			timeout = 200 * time.Millisecond
+		case <-time.After(timeout):
+			return keys
+		}
+	}
+}
+
+func TestDefaultsReloaderSymlinkSwap(t *testing.T) {
+	dir := t.TempDir()
+	updateConfigMapMount(t, dir, "08_00_00", map[string]string{"redhat-operators.yaml": catsrcManifest})
+	defaultstest.PopulateFrom(t, dir)
+
+	versions := NewResourceVersionCache()
+	redhatOperators := &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-marketplace", Name: "redhat-operators", ResourceVersion: "1"}}
+	versions.Record(redhatOperators)
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	managed := map[string]string{defaults.ManagedByLabelKey: defaults.ManagedByLabelValue}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-marketplace", Name: "redhat-operators", Labels: managed}},
+		&olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-marketplace", Name: "community-operators", Labels: managed}},
+	).Build()
+	var reloads atomic.Int32
+	reloader := newDefaultsReloader(c, dir, func(ctx context.Context) error {
+		reloads.Add(1)
+		_, err := defaults.ReloadGlobalsFrom(ctx, defaults.DirLoader{Dir: dir})
+		return err
+	}, versions)
+	reloader.delay = 50 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- reloader.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	// The unchanged directory is not reloaded on startup.
+	assert.Empty(t, requireEvents(t, reloader.events))
+	assert.Zero(t, reloads.Load())
+
+	// The CVO updates the image and adds a default CatalogSource.
+	updated := strings.ReplaceAll(catsrcManifest, ":v4.18", ":v4.19")
+	community := strings.ReplaceAll(catsrcManifest, "redhat-operators", "community-operators")
+	updateConfigMapMount(t, dir, "09_00_00", map[string]string{"redhat-operators.yaml": updated, "community-operators.yaml": community})
+	assert.Equal(t, []types.NamespacedName{
+		{Namespace: "openshift-marketplace", Name: "community-operators"},
+		{Namespace: "openshift-marketplace", Name: "redhat-operators"},
+	}, requireEvents(t, reloader.events), "the added and updated default CatalogSources are reconciled")
+	assert.Equal(t, int32(1), reloads.Load(), "the burst of changes of the swap is reloaded once")
+	assert.False(t, versions.Unchanged(redhatOperators), "the reconciled CatalogSources are applied again")
+	require.Len(t, defaults.GetGlobalCatalogSourceDefinitions(), 2)
+	assert.True(t, strings.HasSuffix(defaults.GetGlobalCatalogSourceDefinitions()["redhat-operators"].Spec.Image, ":v4.19"))
+	assert.Contains(t, operatorhub.GetSingleton().Get(), "community-operators", "the OperatorHub configuration covers the added default")
+
+	// The unchanged default CatalogSources are not reconciled, the removed
+	// ones are pruned.
+	versions.Record(redhatOperators)
+	updateConfigMapMount(t, dir, "09_30_00", map[string]string{"redhat-operators.yaml": updated})
+	require.NoError(t, os.Remove(filepath.Join(dir, "community-operators.yaml")))
+	assert.Empty(t, requireEvents(t, reloader.events))
+	assert.True(t, versions.Unchanged(redhatOperators))
+	err := c.Get(context.TODO(), client.ObjectKey{Namespace: "openshift-marketplace", Name: "community-operators"}, &olmv1alpha1.CatalogSource{})
+	assert.True(t, k8sErrors.IsNotFound(err), "the removed default CatalogSource is pruned")
+	assert.NotContains(t, operatorhub.GetSingleton().Get(), "community-operators")
+
+	// A broken update keeps the previous definitions.
+	updateConfigMapMount(t, dir, "10_00_00", map[string]string{"redhat-operators.yaml": "kind: CatalogSource\nmetadata: [\n"})
+	assert.Empty(t, requireEvents(t, reloader.events))
+	assert.Equal(t, int32(3), reloads.Load())
+	require.Len(t, defaults.GetGlobalCatalogSourceDefinitions(), 1)
+	assert.True(t, strings.HasSuffix(defaults.GetGlobalCatalogSourceDefinitions()["redhat-operators"].Spec.Image, ":v4.19"))
+}
@@ -0,0 +1,234 @@
+package catalogsource
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// ImageStreamNamespace is the namespace holding the ImageStreams whose
+	// tags may be referenced by the default CatalogSources.
+	ImageStreamNamespace = "openshift"
+
+	// catalogPodLabelKey is the label OLM sets on the pods serving a
+	// CatalogSource to the name of that CatalogSource.
+	catalogPodLabelKey = "olm.catalogSource"
+)
+
+// imagePush is a default CatalogSource whose image references an ImageStream
+// tag that was pushed, along with the digest of the image pushed.
+type imagePush struct {
+	Request reconcile.Request
+	Digest  string
+}
+
+// pushedImages records the digest of the image last pushed to the ImageStream
+// tag referenced by each default CatalogSource, until the push is applied.
+type pushedImages struct {
+	lock    sync.Mutex
+	digests map[types.NamespacedName]string
+}
+
+// newPushedImages returns an empty pushedImages.
+func newPushedImages() *pushedImages {
+	return &pushedImages{digests: make(map[types.NamespacedName]string)}
+}
+
+// record records that digest was pushed for the CatalogSource with the given
+// key.
+func (p *pushedImages) record(key types.NamespacedName, digest string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.digests[key] = digest
+}
+
+// take returns the digest pushed for the CatalogSource with the given key and
+// forgets it, or false if none was pushed.
+func (p *pushedImages) take(key types.NamespacedName) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	digest, pushed := p.digests[key]
+	delete(p.digests, key)
+	return digest, pushed
+}
+
+// imageStreamTagHandler enqueues the default CatalogSources whose image is
+// referenced by an ImageStream tag that was just pushed or imported, so that
+// they are reconciled right away rather than on the next poll. The digests
+// pushed are recorded in pushes for the reconciler to roll them out, and the
+// CatalogSources are forgotten from versions, as a push does not change their
+// resourceVersion.
+//
+// ImageStreamTags can not be watched, the tag pushes are detected on their
+// ImageStream instead, where they show up as a new most recent TagEvent.
+func imageStreamTagHandler(versions *ResourceVersionCache, pushes *pushedImages) handler.Funcs {
+	return handler.Funcs{
+		UpdateFunc: func(_ context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			oldStream, ok := e.ObjectOld.(*imagev1.ImageStream)
+			if !ok {
+				return
+			}
+			newStream, ok := e.ObjectNew.(*imagev1.ImageStream)
+			if !ok {
+				return
+			}
+			for _, push := range catalogSourcesForPushedTags(oldStream, newStream) {
+				pushes.record(push.Request.NamespacedName, push.Digest)
+				versions.Forget(push.Request.NamespacedName)
+				q.Add(push.Request)
+			}
+		},
+	}
+}
+
+// catalogSourcesForPushedTags returns the pushes for the default
+// CatalogSources whose image references a tag of the ImageStream that has a
+// new most recent image. The images pinned to the digest their tag was
+// resolved to are matched by their tag.
+func catalogSourcesForPushedTags(oldStream, newStream *imagev1.ImageStream) []imagePush {
+	pushed := make(map[string]string)
+	for _, tag := range newStream.Status.Tags {
+		if len(tag.Items) == 0 {
+			continue
+		}
+		latest := tag.Items[0]
+		if previous := latestTagEvent(oldStream, tag.Tag); previous != nil &&
+			previous.Image == latest.Image && previous.Generation == latest.Generation {
+			continue
+		}
+		pushed[tag.Tag] = latest.Image
+	}
+	if len(pushed) == 0 {
+		return nil
+	}
+
+	// The digest pushed for each reference.
+	references := make(map[string]string)
+	for tag, digest := range pushed {
+		for _, reference := range tagReferences(newStream, tag) {
+			references[reference] = digest
+		}
+	}
+
+	var pushes []imagePush
+	for name, catsrc := range defaults.GetGlobalCatalogSourceDefinitions() {
+		tagged, _, _ := strings.Cut(catsrc.Spec.Image, "@")
+		digest, ok := references[tagged]
+		if !ok {
+			continue
+		}
+		log.Infof("[imagestream] ImageStream %s/%s has a new image %s for CatalogSource %s", newStream.Namespace, newStream.Name, digest, name)
+		pushes = append(pushes, imagePush{
+			Request: reconcile.Request{NamespacedName: types.NamespacedName{Namespace: catsrc.Namespace, Name: name}},
+			Digest:  digest,
+		})
+	}
+	sort.Slice(pushes, func(i, j int) bool { return pushes[i].Request.Name < pushes[j].Request.Name })
+	return pushes
+}
+
+// pinPushedImage pins the definition of the default CatalogSource of request
+// to the digest pushed for it, if any, so that the new image is applied. It
+// returns the digest if the image of the CatalogSource is a tag, whose catalog
+// pods must be restarted to serve it once the CatalogSource is ensured. The
+// pinned images are rolled out by the catalogsourcerestart controller.
+func (r *ReconcileCatalogSource) pinPushedImage(ctx context.Context, request reconcile.Request) (string, bool) {
+	digest, pushed := r.pushes.take(request.NamespacedName)
+	if !pushed {
+		return "", false
+	}
+	def, ok := defaults.GetGlobalCatalogSourceDefinitions()[request.Name]
+	if !ok {
+		return "", false
+	}
+	if strings.Contains(def.Spec.Image, "@") {
+		if defaults.SetImageDigest(request.Name, digest) {
+			logging.FromContext(ctx).Infof("[imagestream] Pinned CatalogSource %s to the pushed image digest %s", request.Name, digest)
+		}
+		return "", false
+	}
+	return digest, true
+}
+
+// restartCatalogPods deletes the catalog pods of the default CatalogSource of
+// request that do not serve digest, for OLM to recreate them pulling the tag
+// again.
+func (r *ReconcileCatalogSource) restartCatalogPods(ctx context.Context, request reconcile.Request, digest string) error {
+	pods := &corev1.PodList{}
+	if err := r.reader.List(ctx, pods, client.InNamespace(request.Namespace), client.MatchingLabels{catalogPodLabelKey: request.Name}); err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !pod.DeletionTimestamp.IsZero() || servesDigest(pod, digest) {
+			continue
+		}
+		if err := r.client.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		logging.FromContext(ctx).Infof("[imagestream] Restarted catalog pod %s/%s of CatalogSource %s to serve the pushed image %s", pod.Namespace, pod.Name, request.Name, digest)
+		metrics.IncCatalogRestarts(request.Name)
+	}
+	return nil
+}
+
+// servesDigest returns true if the registry container of pod runs the image
+// with the given digest.
+func servesDigest(pod *corev1.Pod, digest string) bool {
+	if len(pod.Spec.Containers) == 0 {
+		return false
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == pod.Spec.Containers[0].Name {
+			return strings.HasSuffix(status.ImageID, "@"+digest)
+		}
+	}
+	return false
+}
+
+// latestTagEvent returns the most recent TagEvent of the given tag of the
+// ImageStream, or nil if the tag has no image.
+func latestTagEvent(stream *imagev1.ImageStream, tag string) *imagev1.TagEvent {
+	for i := range stream.Status.Tags {
+		if stream.Status.Tags[i].Tag == tag && len(stream.Status.Tags[i].Items) > 0 {
+			return &stream.Status.Tags[i].Items[0]
+		}
+	}
+	return nil
+}
+
+// tagReferences returns the image references a CatalogSource may use to refer
+// to the given tag of the ImageStream: the internal and public pull specs of
+// the tag, and the external image the tag tracks.
+func tagReferences(stream *imagev1.ImageStream, tag string) []string {
+	var references []string
+	for _, repository := range []string{stream.Status.DockerImageRepository, stream.Status.PublicDockerImageRepository} {
+		if repository != "" {
+			references = append(references, repository+":"+tag)
+		}
+	}
+	for _, reference := range stream.Spec.Tags {
+		if reference.Name == tag && reference.From != nil && reference.From.Kind == "DockerImage" {
+			references = append(references, reference.From.Name)
+		}
+	}
+	return references
+}
@@ -0,0 +1,213 @@
+package catalogsource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const catsrcManifest = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: redhat-operators
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: image-registry.openshift-image-registry.svc:5000/openshift/redhat-operators:v4.18
+`
+
+func imageStream(tags ...imagev1.NamedTagEventList) *imagev1.ImageStream {
+	return &imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Name: "redhat-operators", Namespace: ImageStreamNamespace},
+		Status: imagev1.ImageStreamStatus{
+			DockerImageRepository: "image-registry.openshift-image-registry.svc:5000/openshift/redhat-operators",
+			Tags:                  tags,
+		},
+	}
+}
+
+func tagEvents(tag string, images ...string) imagev1.NamedTagEventList {
+	list := imagev1.NamedTagEventList{Tag: tag}
+	for _, image := range images {
+		list.Items = append(list.Items, imagev1.TagEvent{Image: image})
+	}
+	return list
+}
+
+// digestResolverFunc resolves the digests with a function.
+type digestResolverFunc func(ctx context.Context, image string) (string, error)
+
+func (f digestResolverFunc) ResolveDigest(ctx context.Context, image string) (string, error) {
+	return f(ctx, image)
+}
+
+// populateDefaults populates the default CatalogSources from catsrcManifest
+// with the given options, until the test ends.
+func populateDefaults(t *testing.T, opts ...defaults.Option) {
+	t.Helper()
+	defaultstest.Populate(t, map[string]string{"redhat-operators.yaml": catsrcManifest}, opts...)
+}
+
+func TestImageStreamTagHandler(t *testing.T) {
+	populateDefaults(t)
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: "redhat-operators"}}
+	pushed := func(digest string) []imagePush {
+		return []imagePush{{Request: request, Digest: digest}}
+	}
+	old := imageStream(tagEvents("v4.18", "sha256:1"), tagEvents("v4.17", "sha256:a"))
+	tests := []struct {
+		description string
+		new         *imagev1.ImageStream
+		expected    []imagePush
+	}{
+		{
+			description: "the referenced tag was pushed",
+			new:         imageStream(tagEvents("v4.18", "sha256:2", "sha256:1"), tagEvents("v4.17", "sha256:a")),
+			expected:    pushed("sha256:2"),
+		},
+		{
+			description: "the tags were reordered",
+			new:         imageStream(tagEvents("v4.17", "sha256:a"), tagEvents("v4.18", "sha256:1")),
+			expected:    nil,
+		},
+		{
+			description: "another tag was pushed",
+			new:         imageStream(tagEvents("v4.18", "sha256:1"), tagEvents("v4.17", "sha256:b", "sha256:a")),
+			expected:    nil,
+		},
+		{
+			description: "nothing was pushed",
+			new:         old.DeepCopy(),
+			expected:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			assert.Equal(t, tt.expected, catalogSourcesForPushedTags(old, tt.new))
+		})
+	}
+
+	// A tag created with an image is a push as well.
+	empty := imageStream()
+	assert.Equal(t, pushed("sha256:1"), catalogSourcesForPushedTags(empty, imageStream(tagEvents("v4.18", "sha256:1"))))
+
+	// The external image tracked by a tag is matched too.
+	tracking := imageStream(tagEvents("v4.18", "sha256:2"))
+	tracking.Status.DockerImageRepository = ""
+	tracking.Spec.Tags = []imagev1.TagReference{{
+		Name: "v4.18",
+		From: &corev1.ObjectReference{Kind: "DockerImage", Name: "image-registry.openshift-image-registry.svc:5000/openshift/redhat-operators:v4.18"},
+	}}
+	assert.Equal(t, pushed("sha256:2"), catalogSourcesForPushedTags(old, tracking))
+
+	q := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer q.ShutDown()
+	versions := NewResourceVersionCache()
+	reconciled := &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-marketplace", Name: "redhat-operators", ResourceVersion: "1"}}
+	versions.Record(reconciled)
+	pushes := newPushedImages()
+	imageStreamTagHandler(versions, pushes).Update(context.TODO(), event.UpdateEvent{
+		ObjectOld: old,
+		ObjectNew: imageStream(tagEvents("v4.18", "sha256:2", "sha256:1")),
+	}, q)
+	require.Equal(t, 1, q.Len())
+	queued, _ := q.Get()
+	assert.Equal(t, request, queued)
+	assert.False(t, versions.Unchanged(reconciled), "a push does not change the resourceVersion, the CatalogSource is applied again")
+	digest, ok := pushes.take(request.NamespacedName)
+	assert.True(t, ok)
+	assert.Equal(t, "sha256:2", digest)
+}
+
+// catalogPod returns a catalog pod of the redhat-operators CatalogSource
+// running the image with the given digest.
+func catalogPod(name, digest string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-marketplace", Name: name, Labels: map[string]string{catalogPodLabelKey: "redhat-operators"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "registry-server"}}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:    "registry-server",
+			ImageID: "image-registry.openshift-image-registry.svc:5000/openshift/redhat-operators@" + digest,
+		}}},
+	}
+}
+
+// reconcilePush sends the push of digest to the v4.18 tag through the handler
+// of r, then reconciles the request enqueued.
+func reconcilePush(t *testing.T, r *ReconcileCatalogSource, digest string) {
+	t.Helper()
+	q := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer q.ShutDown()
+	imageStreamTagHandler(r.versions, r.pushes).Update(context.TODO(), event.UpdateEvent{
+		ObjectOld: imageStream(tagEvents("v4.18", "sha256:1")),
+		ObjectNew: imageStream(tagEvents("v4.18", digest, "sha256:1")),
+	}, q)
+	require.Equal(t, 1, q.Len())
+	request, _ := q.Get()
+	_, err := r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+}
+
+// newPushReconciler returns a ReconcileCatalogSource on a cluster holding the
+// given objects, that has reconciled the redhat-operators CatalogSource.
+func newPushReconciler(t *testing.T, objs ...client.Object) (*ReconcileCatalogSource, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(&olmv1alpha1.CatalogSource{}).Build()
+	templates, err := NewMessageTemplates(nil)
+	require.NoError(t, err)
+	r := &ReconcileCatalogSource{client: c, reader: c, templates: templates, now: time.Now, versions: NewResourceVersionCache(), pushes: newPushedImages()}
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: "redhat-operators"}})
+	require.NoError(t, err)
+	return r, c
+}
+
+func TestReconcileRestartsCatalogPodsOnTagPush(t *testing.T) {
+	populateDefaults(t)
+	r, c := newPushReconciler(t, catalogPod("stale", "sha256:1"), catalogPod("current", "sha256:2"))
+
+	reconcilePush(t, r, "sha256:2")
+	err := c.Get(context.TODO(), client.ObjectKey{Namespace: "openshift-marketplace", Name: "stale"}, &corev1.Pod{})
+	assert.True(t, k8sErrors.IsNotFound(err), "the pod serving the previous image is restarted")
+	assert.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: "openshift-marketplace", Name: "current"}, &corev1.Pod{}),
+		"the pod serving the pushed image is kept")
+}
+
+func TestReconcilePinsPushedDigest(t *testing.T) {
+	populateDefaults(t, defaults.WithDigestResolver(digestResolverFunc(func(context.Context, string) (string, error) {
+		return "sha256:1", nil
+	})))
+	r, c := newPushReconciler(t)
+	key := client.ObjectKey{Namespace: "openshift-marketplace", Name: "redhat-operators"}
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), key, catsrc))
+	require.Equal(t, "image-registry.openshift-image-registry.svc:5000/openshift/redhat-operators:v4.18@sha256:1", catsrc.Spec.Image)
+
+	reconcilePush(t, r, "sha256:2")
+	require.NoError(t, c.Get(context.TODO(), key, catsrc))
+	assert.Equal(t, "image-registry.openshift-image-registry.svc:5000/openshift/redhat-operators:v4.18@sha256:2", catsrc.Spec.Image,
+		"the CatalogSource is pinned to the pushed digest")
+	desired, ok := defaults.GetDesiredCatalogSource("redhat-operators")
+	require.True(t, ok)
+	assert.Equal(t, catsrc.Spec.Image, desired.Spec.Image)
+}
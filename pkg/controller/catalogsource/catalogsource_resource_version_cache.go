@@ -0,0 +1,49 @@
+package catalogsource
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResourceVersionCache records the resourceVersion of each default
+// CatalogSource after it was last successfully reconciled. A CatalogSource
+// whose resourceVersion did not change since has not been modified, by the
+// operator or anyone else, and does not need to be applied again.
+type ResourceVersionCache struct {
+	lock     sync.RWMutex
+	versions map[types.NamespacedName]string
+}
+
+// NewResourceVersionCache returns an empty ResourceVersionCache.
+func NewResourceVersionCache() *ResourceVersionCache {
+	return &ResourceVersionCache{versions: make(map[types.NamespacedName]string)}
+}
+
+// Unchanged returns true if the resourceVersion of obj is the one recorded
+// for it.
+func (c *ResourceVersionCache) Unchanged(obj client.Object) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	version, present := c.versions[client.ObjectKeyFromObject(obj)]
+	return present && version != "" && version == obj.GetResourceVersion()
+}
+
+// Record records the resourceVersion of obj.
+func (c *ResourceVersionCache) Record(obj client.Object) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.versions[client.ObjectKeyFromObject(obj)] = obj.GetResourceVersion()
+}
+
+// Forget removes the resourceVersion recorded for the object with the given
+// key, so that it is applied on its next reconcile.
+func (c *ResourceVersionCache) Forget(key types.NamespacedName) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.versions, key)
+}
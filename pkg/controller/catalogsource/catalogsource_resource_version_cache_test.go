@@ -0,0 +1,87 @@
+package catalogsource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestResourceVersionCache(t *testing.T) {
+	cache := NewResourceVersionCache()
+	catsrc := &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Name: "redhat-operators", Namespace: "openshift-marketplace", ResourceVersion: "1"}}
+	assert.False(t, cache.Unchanged(catsrc), "a CatalogSource never recorded has changed")
+
+	cache.Record(catsrc)
+	assert.True(t, cache.Unchanged(catsrc))
+
+	catsrc.ResourceVersion = "2"
+	assert.False(t, cache.Unchanged(catsrc))
+
+	cache.Record(catsrc)
+	cache.Forget(client.ObjectKeyFromObject(catsrc))
+	assert.False(t, cache.Unchanged(catsrc))
+}
+
+func TestReconcileSkipsUnmodifiedCatalogSource(t *testing.T) {
+	defaultstest.Populate(t, map[string]string{"redhat-operators.yaml": catsrcManifest})
+
+	desired, ok := defaults.GetDesiredCatalogSource("redhat-operators")
+	require.True(t, ok)
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	gets, updates := 0, 0
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&desired).WithStatusSubresource(&desired).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				gets++
+				return c.Get(ctx, key, obj, opts...)
+			},
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				updates++
+				return c.Update(ctx, obj, opts...)
+			},
+		}).Build()
+
+	templates, err := NewMessageTemplates(nil)
+	require.NoError(t, err)
+	r := &ReconcileCatalogSource{client: c, templates: templates, now: time.Now, versions: NewResourceVersionCache()}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: "redhat-operators"}}
+	key := client.ObjectKey{Namespace: "openshift-marketplace", Name: "redhat-operators"}
+
+	_, err = r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), key, catsrc))
+	assert.True(t, r.versions.Unchanged(catsrc), "the version is recorded after a successful reconcile")
+
+	// A modification restores the default spec.
+	catsrc.Spec.Image = "quay.io/example/other:latest"
+	require.NoError(t, c.Update(context.TODO(), catsrc))
+	updates = 0
+	_, err = r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.NoError(t, c.Get(context.TODO(), key, catsrc))
+	assert.Equal(t, desired.Spec.Image, catsrc.Spec.Image)
+	assert.Equal(t, 1, updates)
+
+	// The CatalogSource is not applied again while it is not modified, only
+	// its version is read.
+	gets = 0
+	_, err = r.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	assert.Equal(t, 1, gets)
+	assert.Equal(t, 1, updates)
+}
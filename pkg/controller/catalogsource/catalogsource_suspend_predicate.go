@@ -0,0 +1,101 @@
+package catalogsource
+
+import (
+	"context"
+
+	configv1 "github.com/openshift/api/config/v1"
+	cohelpers "github.com/openshift/library-go/pkg/config/clusteroperator/v1helpers"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ClusterVersionName is the name of the ClusterVersion the cluster upgrades
+// are reported on.
+const ClusterVersionName = "version"
+
+var _ predicate.Predicate = &ClusterUpgradePredicate{}
+
+// ClusterUpgradePredicate drops the CatalogSource events while the cluster is
+// upgrading, that is while the ClusterVersion is Progressing, so that the
+// default CatalogSources are not churned while the nodes and OLM roll out.
+// The default CatalogSources are reconciled once the upgrade completes, see
+// clusterUpgradeCompletedHandler.
+type ClusterUpgradePredicate struct {
+	predicate.Funcs
+	reader client.Reader
+}
+
+// NewClusterUpgradePredicate returns a ClusterUpgradePredicate reading the
+// ClusterVersion through reader.
+func NewClusterUpgradePredicate(reader client.Reader) *ClusterUpgradePredicate {
+	return &ClusterUpgradePredicate{reader: reader}
+}
+
+// Create returns false while the cluster is upgrading.
+func (p *ClusterUpgradePredicate) Create(event.CreateEvent) bool {
+	return !p.upgrading()
+}
+
+// Update returns false while the cluster is upgrading.
+func (p *ClusterUpgradePredicate) Update(event.UpdateEvent) bool {
+	return !p.upgrading()
+}
+
+// Delete returns false while the cluster is upgrading.
+func (p *ClusterUpgradePredicate) Delete(event.DeleteEvent) bool {
+	return !p.upgrading()
+}
+
+// upgrading returns true if the ClusterVersion is Progressing. The cluster is
+// not considered upgrading if the ClusterVersion can not be read, so that a
+// missing ClusterVersion does not stop the reconciles.
+func (p *ClusterUpgradePredicate) upgrading() bool {
+	clusterVersion := &configv1.ClusterVersion{}
+	if err := p.reader.Get(context.TODO(), client.ObjectKey{Name: ClusterVersionName}, clusterVersion); err != nil {
+		log.Debugf("[catalogsource] Unable to get ClusterVersion %s, assuming the cluster is not upgrading - %v", ClusterVersionName, err)
+		return false
+	}
+	if isProgressing(clusterVersion) {
+		log.Debugf("[catalogsource] Cluster is upgrading, dropping the CatalogSource event")
+		return true
+	}
+	return false
+}
+
+// isProgressing returns true if the ClusterVersion has a Progressing=True
+// condition.
+func isProgressing(clusterVersion *configv1.ClusterVersion) bool {
+	return cohelpers.IsStatusConditionTrue(clusterVersion.Status.Conditions, configv1.OperatorProgressing)
+}
+
+// clusterUpgradeCompletedHandler enqueues every default CatalogSource when the
+// ClusterVersion stops Progressing, as the events dropped by the
+// ClusterUpgradePredicate during the upgrade are not replayed.
+func clusterUpgradeCompletedHandler() handler.Funcs {
+	return handler.Funcs{
+		UpdateFunc: func(_ context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			oldVersion, ok := e.ObjectOld.(*configv1.ClusterVersion)
+			if !ok {
+				return
+			}
+			newVersion, ok := e.ObjectNew.(*configv1.ClusterVersion)
+			if !ok || newVersion.Name != ClusterVersionName {
+				return
+			}
+			if !isProgressing(oldVersion) || isProgressing(newVersion) {
+				return
+			}
+			log.Info("[catalogsource] Cluster upgrade completed, reconciling the default CatalogSources")
+			for name, catsrc := range defaults.GetGlobalCatalogSourceDefinitions() {
+				q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: catsrc.Namespace, Name: name}})
+			}
+		},
+	}
+}
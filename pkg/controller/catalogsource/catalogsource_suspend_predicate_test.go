@@ -0,0 +1,88 @@
+package catalogsource
+
+import (
+	"context"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func clusterVersion(progressing configv1.ConditionStatus) *configv1.ClusterVersion {
+	return &configv1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: ClusterVersionName},
+		Status: configv1.ClusterVersionStatus{
+			Conditions: []configv1.ClusterOperatorStatusCondition{
+				{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue},
+				{Type: configv1.OperatorProgressing, Status: progressing},
+			},
+		},
+	}
+}
+
+func TestClusterUpgradePredicate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, configv1.AddToScheme(scheme))
+
+	tests := []struct {
+		description string
+		reader      *fake.ClientBuilder
+		allowed     bool
+	}{
+		{
+			description: "the cluster is upgrading",
+			reader:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterVersion(configv1.ConditionTrue)),
+			allowed:     false,
+		},
+		{
+			description: "the cluster is not upgrading",
+			reader:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterVersion(configv1.ConditionFalse)),
+			allowed:     true,
+		},
+		{
+			description: "the ClusterVersion does not exist",
+			reader:      fake.NewClientBuilder().WithScheme(scheme),
+			allowed:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			p := NewClusterUpgradePredicate(tt.reader.Build())
+			assert.Equal(t, tt.allowed, p.Create(event.CreateEvent{}))
+			assert.Equal(t, tt.allowed, p.Update(event.UpdateEvent{}))
+			assert.Equal(t, tt.allowed, p.Delete(event.DeleteEvent{}))
+			assert.True(t, p.Generic(event.GenericEvent{}))
+		})
+	}
+}
+
+func TestClusterUpgradeCompletedHandler(t *testing.T) {
+	defaultstest.Populate(t, map[string]string{"redhat-operators.yaml": catsrcManifest})
+
+	update := func(old, new configv1.ConditionStatus) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+		q := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		t.Cleanup(q.ShutDown)
+		clusterUpgradeCompletedHandler().Update(context.TODO(), event.UpdateEvent{
+			ObjectOld: clusterVersion(old),
+			ObjectNew: clusterVersion(new),
+		}, q)
+		return q
+	}
+
+	q := update(configv1.ConditionTrue, configv1.ConditionFalse)
+	require.Equal(t, 1, q.Len(), "the default CatalogSources are reconciled once the upgrade completes")
+	request, _ := q.Get()
+	assert.Equal(t, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: "redhat-operators"}}, request)
+
+	assert.Equal(t, 0, update(configv1.ConditionFalse, configv1.ConditionTrue).Len(), "nothing is reconciled when an upgrade starts")
+	assert.Equal(t, 0, update(configv1.ConditionFalse, configv1.ConditionFalse).Len())
+}
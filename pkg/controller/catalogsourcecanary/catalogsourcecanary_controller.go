@@ -0,0 +1,116 @@
+package catalogsourcecanary
+
+import (
+	"context"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// controllerName is the name of the controller, it is used to identify its
+// metrics.
+const controllerName = "catalogsourcecanary-controller"
+
+// Add creates a new CatalogSource canary Controller and adds it to the Manager
+// if the images of the default CatalogSources are upgraded through canaries.
+// The Manager will set fields on the Controller and Start it when the Manager
+// is Started.
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	if defaults.CanaryTimeout <= 0 {
+		return nil
+	}
+	return add(mgr, newReconciler(mgr), o.ControllerRuntimeOptions())
+}
+
+// newReconciler returns a new ReconcileCatalogSourceCanary.
+func newReconciler(mgr manager.Manager) *ReconcileCatalogSourceCanary {
+	return &ReconcileCatalogSourceCanary{client: mgr.GetClient(), now: time.Now}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler, opts controller.Options) error {
+	return builder.ControllerManagedBy(mgr).
+		Named(controllerName).
+		Watches(&olmv1alpha1.CatalogSource{}, handler.EnqueueRequestsFromMapFunc(canaryToCatalogSource)).
+		WithOptions(opts).
+		Complete(metrics.NewInstrumentedReconciler(controllerName, r))
+}
+
+// canaryToCatalogSource maps a canary to the default CatalogSource it is
+// upgrading. Its other CatalogSources are not mapped.
+func canaryToCatalogSource(_ context.Context, obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[defaults.CanaryOfLabelKey]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}}}
+}
+
+var _ reconcile.Reconciler = &ReconcileCatalogSourceCanary{}
+
+// ReconcileCatalogSourceCanary follows the canaries of the default
+// CatalogSources, so that a default CatalogSource is upgraded once its canary
+// is ready and the upgrade is aborted once the canary timed out.
+type ReconcileCatalogSourceCanary struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	now    func() time.Time
+}
+
+// Reconcile ensures the default CatalogSource a canary is upgrading, and
+// requeues it for when its canary times out. The canary of a CatalogSource
+// that is no longer an enabled default is deleted.
+func (r *ReconcileCatalogSourceCanary) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	logging.FromContext(ctx).Debugf("Reconciling the canary of CatalogSource %s", request.Name)
+
+	def, ok := defaults.GetGlobalCatalogSourceDefinitions()[request.Name]
+	if !ok || def.Namespace != request.Namespace || operatorhub.GetSingleton().Get()[request.Name] {
+		return reconcile.Result{}, r.deleteCanary(ctx, request)
+	}
+
+	if err := defaults.New(defaults.GetGlobalCatalogSourceDefinitions(), operatorhub.GetSingleton().Get()).Ensure(ctx, r.client, request.Name); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	canary := &olmv1alpha1.CatalogSource{}
+	key := client.ObjectKey{Namespace: request.Namespace, Name: defaults.CanaryName(request.Name)}
+	if err := r.client.Get(ctx, key, canary); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if remaining := defaults.CanaryTimeout - r.now().Sub(canary.CreationTimestamp.Time); remaining > 0 {
+		return reconcile.Result{RequeueAfter: remaining}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// deleteCanary deletes the canary of the CatalogSource of the request, if it
+// exists.
+func (r *ReconcileCatalogSourceCanary) deleteCanary(ctx context.Context, request reconcile.Request) error {
+	canary := &olmv1alpha1.CatalogSource{}
+	key := client.ObjectKey{Namespace: request.Namespace, Name: defaults.CanaryName(request.Name)}
+	if err := r.client.Get(ctx, key, canary); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if canary.Labels[defaults.CanaryOfLabelKey] != request.Name {
+		return nil
+	}
+	if err := r.client.Delete(ctx, canary); err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	logging.FromContext(ctx).Infof("[canary] Deleted canary CatalogSource %s of CatalogSource %s that is no longer an enabled default", canary.Name, request.Name)
+	return nil
+}
@@ -0,0 +1,125 @@
+package catalogsourcecanary
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	catsrcName     = "redhat-operators"
+	catsrcManifest = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: redhat-operators
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: quay.io/example/redhat-operators:v2
+`
+)
+
+// setup populates the default CatalogSources and returns a reconciler backed
+// by an in-memory cluster on which the default CatalogSource serves a previous
+// image and its canary was created a while ago.
+func setup(t *testing.T, age time.Duration) (*ReconcileCatalogSourceCanary, client.Client) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, catsrcName+".yaml"), []byte(catsrcManifest), 0644))
+	previousDir, previousTimeout := defaults.Dir, defaults.CanaryTimeout
+	defaults.Dir, defaults.CanaryTimeout = dir, time.Minute
+	t.Cleanup(func() {
+		defaults.Dir, defaults.CanaryTimeout = previousDir, previousTimeout
+		_, err := defaults.PopulateGlobals()
+		require.NoError(t, err)
+		operatorhub.GetSingleton().Set(configv1.OperatorHubSpec{})
+	})
+	_, err := defaults.PopulateGlobals()
+	require.NoError(t, err)
+	operatorhub.GetSingleton().Set(configv1.OperatorHubSpec{})
+
+	catsrc, ok := defaults.GetDesiredCatalogSource(catsrcName)
+	require.True(t, ok)
+	canary := &olmv1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              defaults.CanaryName(catsrcName),
+			Namespace:         catsrc.Namespace,
+			Labels:            map[string]string{defaults.CanaryOfLabelKey: catsrcName},
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		},
+		Spec: catsrc.Spec,
+	}
+	catsrc.Spec.Image = "quay.io/example/redhat-operators:v1"
+	catsrc.Annotations[defaults.SpecHashAnnotationKey] = defaults.SpecHash(&catsrc.Spec)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&catsrc, canary).Build()
+	return &ReconcileCatalogSourceCanary{client: c, now: time.Now}, c
+}
+
+func request() reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-marketplace", Name: catsrcName}}
+}
+
+func TestCanaryToCatalogSource(t *testing.T) {
+	canary := &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{
+		Name:      defaults.CanaryName(catsrcName),
+		Namespace: "openshift-marketplace",
+		Labels:    map[string]string{defaults.CanaryOfLabelKey: catsrcName},
+	}}
+	assert.Equal(t, []reconcile.Request{request()}, canaryToCatalogSource(context.TODO(), canary))
+
+	other := &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Name: catsrcName, Namespace: "openshift-marketplace"}}
+	assert.Empty(t, canaryToCatalogSource(context.TODO(), other))
+}
+
+func TestReconcileRequeuesPendingCanary(t *testing.T) {
+	r, c := setup(t, 20*time.Second)
+
+	result, err := r.Reconcile(context.TODO(), request())
+	require.NoError(t, err)
+	assert.Greater(t, result.RequeueAfter, 30*time.Second)
+	assert.LessOrEqual(t, result.RequeueAfter, 40*time.Second)
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: "openshift-marketplace", Name: defaults.CanaryName(catsrcName)}, &olmv1alpha1.CatalogSource{}))
+}
+
+func TestReconcileAbortsTimedOutCanary(t *testing.T) {
+	r, c := setup(t, 2*time.Minute)
+
+	result, err := r.Reconcile(context.TODO(), request())
+	require.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, result)
+	err = c.Get(context.TODO(), client.ObjectKey{Namespace: "openshift-marketplace", Name: defaults.CanaryName(catsrcName)}, &olmv1alpha1.CatalogSource{})
+	assert.True(t, k8sErrors.IsNotFound(err), "the canary is deleted once it timed out")
+
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: "openshift-marketplace", Name: catsrcName}, catsrc))
+	assert.Equal(t, "quay.io/example/redhat-operators:v1", catsrc.Spec.Image)
+}
+
+func TestReconcileDeletesCanaryOfDisabledCatalogSource(t *testing.T) {
+	r, c := setup(t, 20*time.Second)
+	operatorhub.GetSingleton().Set(configv1.OperatorHubSpec{Sources: []configv1.HubSource{{Name: catsrcName, Disabled: true}}})
+
+	_, err := r.Reconcile(context.TODO(), request())
+	require.NoError(t, err)
+	err = c.Get(context.TODO(), client.ObjectKey{Namespace: "openshift-marketplace", Name: defaults.CanaryName(catsrcName)}, &olmv1alpha1.CatalogSource{})
+	assert.True(t, k8sErrors.IsNotFound(err), "the canary of a disabled CatalogSource is deleted")
+}
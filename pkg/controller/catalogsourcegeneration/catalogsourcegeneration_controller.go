@@ -0,0 +1,194 @@
+package catalogsourcegeneration
+
+import (
+	"context"
+	"strconv"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// controllerName is the name of the controller, it is used to identify its
+	// metrics and as the source of its events.
+	controllerName = "catalogsourcegeneration-controller"
+
+	// GenerationsConfigMapName is the name of the ConfigMap, in the namespace
+	// of the default CatalogSources, that records the last generation of each
+	// of them that was observed.
+	GenerationsConfigMapName = "marketplace-catalogsource-generations"
+
+	// unauthorizedChangeReason is the reason of the warning event emitted when
+	// the spec of a default CatalogSource is modified outside of the operator.
+	unauthorizedChangeReason = "UnauthorizedSpecChange"
+)
+
+// Add creates a new CatalogSource generation Controller and adds it to the
+// Manager. The Manager will set fields on the Controller and Start it when the
+// Manager is Started.
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	return add(mgr, newReconciler(mgr, o.EnforceImmutableSpec), o.ControllerRuntimeOptions())
+}
+
+// newReconciler returns a new ReconcileCatalogSourceGeneration.
+func newReconciler(mgr manager.Manager, enforce bool) *ReconcileCatalogSourceGeneration {
+	return &ReconcileCatalogSourceGeneration{
+		client:   mgr.GetClient(),
+		reader:   mgr.GetAPIReader(),
+		recorder: mgr.GetEventRecorderFor(controllerName),
+		enforce:  enforce,
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler, opts controller.Options) error {
+	return builder.ControllerManagedBy(mgr).
+		Named(controllerName).
+		For(&olmv1alpha1.CatalogSource{}).
+		WithEventFilter(getPredicateFunctions()).
+		WithOptions(opts).
+		Complete(metrics.NewInstrumentedReconciler(controllerName, r))
+}
+
+// getPredicateFunctions returns the predicate functions used to identify the
+// default CatalogSources whose generation changed. The definitions are read on
+// each event as they are reloaded when the defaults directory changes.
+func getPredicateFunctions() predicate.Funcs {
+	isDefault := func(obj client.Object) bool {
+		def, ok := defaults.GetGlobalCatalogSourceDefinitions()[obj.GetName()]
+		return ok && def.Namespace == obj.GetNamespace()
+	}
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isDefault(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isDefault(e.ObjectNew) && e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration()
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isDefault(e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+var _ reconcile.Reconciler = &ReconcileCatalogSourceGeneration{}
+
+// ReconcileCatalogSourceGeneration tracks the generation of the default
+// CatalogSources to detect modifications of their spec that were not made by
+// the operator.
+type ReconcileCatalogSourceGeneration struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	// reader reads directly from the apiserver as the cache only holds the
+	// trusted CA ConfigMap.
+	reader   client.Reader
+	recorder record.EventRecorder
+	// enforce reverts unauthorized modifications of the spec.
+	enforce bool
+}
+
+// Reconcile compares the generation of a default CatalogSource with the one
+// last recorded. A generation increment that leaves the spec different from
+// the spec hash recorded by the operator instance that last applied it, the
+// same check as the catalogsource controller makes, is reported with a warning
+// event, and reverted if enforcement is enabled.
+func (r *ReconcileCatalogSourceGeneration) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	logging.FromContext(ctx).Debugf("Reconciling CatalogSource generation %s/%s", request.Namespace, request.Name)
+
+	generations, err := r.getGenerations(ctx, request.Namespace)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	catsrc := &olmv1alpha1.CatalogSource{}
+	if err := r.client.Get(ctx, request.NamespacedName, catsrc); err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		// A recreated CatalogSource starts over from the first generation.
+		if _, present := generations.Data[request.Name]; !present {
+			return reconcile.Result{}, nil
+		}
+		delete(generations.Data, request.Name)
+		return reconcile.Result{}, r.saveGenerations(ctx, generations)
+	}
+
+	desired, present := defaults.GetDesiredCatalogSource(request.Name)
+	disabled := operatorhub.GetSingleton().Get()[request.Name]
+	if !present || disabled || !catsrc.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	recorded, err := strconv.ParseInt(generations.Data[request.Name], 10, 64)
+	if err == nil && catsrc.Generation > recorded && defaults.IsSpecModified(catsrc) {
+		logging.FromContext(ctx).Warnf("[generation] CatalogSource %s was modified outside of the operator, generation %d to %d",
+			catsrc.Name, recorded, catsrc.Generation)
+		r.recorder.Eventf(catsrc, corev1.EventTypeWarning, unauthorizedChangeReason,
+			"The spec of the default CatalogSource %s was modified outside of the marketplace operator (generation %d to %d)",
+			catsrc.Name, recorded, catsrc.Generation)
+
+		if r.enforce {
+			catsrc.Spec = desired.Spec
+			if err := r.client.Update(ctx, catsrc); err != nil {
+				return reconcile.Result{}, err
+			}
+			logging.FromContext(ctx).Infof("[generation] Reverted the spec of CatalogSource %s", catsrc.Name)
+		}
+	}
+
+	generation := strconv.FormatInt(catsrc.Generation, 10)
+	if generations.Data[request.Name] == generation {
+		return reconcile.Result{}, nil
+	}
+	generations.Data[request.Name] = generation
+	return reconcile.Result{}, r.saveGenerations(ctx, generations)
+}
+
+// getGenerations returns the ConfigMap recording the generations in the given
+// namespace. A ConfigMap that does not exist yet is returned without a
+// resourceVersion.
+func (r *ReconcileCatalogSourceGeneration) getGenerations(ctx context.Context, namespace string) (*corev1.ConfigMap, error) {
+	generations := &corev1.ConfigMap{}
+	err := r.reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: GenerationsConfigMapName}, generations)
+	if k8sErrors.IsNotFound(err) {
+		generations = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      GenerationsConfigMapName,
+				Namespace: namespace,
+			},
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if generations.Data == nil {
+		generations.Data = make(map[string]string)
+	}
+	return generations, nil
+}
+
+// saveGenerations creates or updates the ConfigMap recording the generations.
+func (r *ReconcileCatalogSourceGeneration) saveGenerations(ctx context.Context, generations *corev1.ConfigMap) error {
+	if generations.ResourceVersion == "" {
+		return r.client.Create(ctx, generations)
+	}
+	return r.client.Update(ctx, generations)
+}
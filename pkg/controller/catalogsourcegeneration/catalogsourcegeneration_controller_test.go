@@ -0,0 +1,175 @@
+package catalogsourcegeneration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	catsrcName      = "redhat-operators"
+	catsrcNamespace = "openshift-marketplace"
+	catsrcManifest  = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: redhat-operators
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: quay.io/example/redhat-operators:latest
+`
+)
+
+// setup populates the default CatalogSources and returns a reconciler backed
+// by an in-memory cluster containing the desired default CatalogSource.
+func setup(t *testing.T, enforce bool) (*ReconcileCatalogSourceGeneration, *record.FakeRecorder, client.Client) {
+	t.Helper()
+	defaultstest.Populate(t, map[string]string{catsrcName + ".yaml": catsrcManifest})
+
+	desired, ok := defaults.GetDesiredCatalogSource(catsrcName)
+	require.True(t, ok)
+	desired.Generation = 1
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&desired).Build()
+
+	recorder := record.NewFakeRecorder(10)
+	return &ReconcileCatalogSourceGeneration{
+		client:   c,
+		reader:   c,
+		recorder: recorder,
+		enforce:  enforce,
+	}, recorder, c
+}
+
+func reconcileCatsrc(t *testing.T, r *ReconcileCatalogSourceGeneration) {
+	t.Helper()
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: catsrcNamespace, Name: catsrcName},
+	})
+	require.NoError(t, err)
+}
+
+// modify changes the CatalogSource on the cluster and bumps its generation
+// the way the apiserver would.
+func modify(t *testing.T, c client.Client, mutate func(*olmv1alpha1.CatalogSource)) *olmv1alpha1.CatalogSource {
+	t.Helper()
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), types.NamespacedName{Namespace: catsrcNamespace, Name: catsrcName}, catsrc))
+	mutate(catsrc)
+	catsrc.Generation++
+	require.NoError(t, c.Update(context.TODO(), catsrc))
+	return catsrc
+}
+
+func recordedGeneration(t *testing.T, c client.Client) string {
+	t.Helper()
+	generations := &corev1.ConfigMap{}
+	require.NoError(t, c.Get(context.TODO(), types.NamespacedName{Namespace: catsrcNamespace, Name: GenerationsConfigMapName}, generations))
+	return generations.Data[catsrcName]
+}
+
+func TestReconcileRecordsOperatorChanges(t *testing.T) {
+	r, recorder, c := setup(t, false)
+
+	reconcileCatsrc(t, r)
+	assert.Equal(t, "1", recordedGeneration(t, c), "the first generation observed is recorded")
+
+	// A generation increment that leaves the desired spec in place is not
+	// reported.
+	modify(t, c, func(*olmv1alpha1.CatalogSource) {})
+	reconcileCatsrc(t, r)
+	assert.Equal(t, "2", recordedGeneration(t, c))
+	assert.Empty(t, recorder.Events)
+}
+
+func TestReconcileReportsUnauthorizedChanges(t *testing.T) {
+	r, recorder, c := setup(t, false)
+	reconcileCatsrc(t, r)
+
+	modify(t, c, func(catsrc *olmv1alpha1.CatalogSource) {
+		catsrc.Spec.Image = "quay.io/attacker/catalog:latest"
+	})
+	reconcileCatsrc(t, r)
+
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Warning "+unauthorizedChangeReason)
+	assert.Equal(t, "2", recordedGeneration(t, c))
+
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), types.NamespacedName{Namespace: catsrcNamespace, Name: catsrcName}, catsrc))
+	assert.Equal(t, "quay.io/attacker/catalog:latest", catsrc.Spec.Image, "the change is not reverted without enforcement")
+}
+
+func TestReconcileRevertsUnauthorizedChanges(t *testing.T) {
+	r, recorder, c := setup(t, true)
+	reconcileCatsrc(t, r)
+
+	modify(t, c, func(catsrc *olmv1alpha1.CatalogSource) {
+		catsrc.Spec.Image = "quay.io/attacker/catalog:latest"
+	})
+	reconcileCatsrc(t, r)
+
+	require.Len(t, recorder.Events, 1)
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), types.NamespacedName{Namespace: catsrcNamespace, Name: catsrcName}, catsrc))
+	assert.Equal(t, "quay.io/example/redhat-operators:latest", catsrc.Spec.Image)
+}
+
+func TestReconcileIgnoresSpecsAppliedWithTheirHash(t *testing.T) {
+	r, recorder, c := setup(t, true)
+	reconcileCatsrc(t, r)
+
+	// The spec applied by another operator instance, or defaulted when it was
+	// applied, is recorded along with its hash.
+	modify(t, c, func(catsrc *olmv1alpha1.CatalogSource) {
+		catsrc.Spec.UpdateStrategy = &olmv1alpha1.UpdateStrategy{RegistryPoll: &olmv1alpha1.RegistryPoll{RawInterval: "1h"}}
+		catsrc.Annotations[defaults.SpecHashAnnotationKey] = defaults.SpecHash(&catsrc.Spec)
+	})
+	reconcileCatsrc(t, r)
+
+	assert.Empty(t, recorder.Events)
+	assert.Equal(t, "2", recordedGeneration(t, c))
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), types.NamespacedName{Namespace: catsrcNamespace, Name: catsrcName}, catsrc))
+	assert.NotNil(t, catsrc.Spec.UpdateStrategy, "the spec is not reverted")
+}
+
+func TestPredicateFollowsReloadedDefinitions(t *testing.T) {
+	setup(t, false)
+	pred := getPredicateFunctions()
+	catsrc := func(name string) *olmv1alpha1.CatalogSource {
+		return &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: catsrcNamespace}}
+	}
+	assert.True(t, pred.Create(event.CreateEvent{Object: catsrc(catsrcName)}))
+	assert.False(t, pred.Create(event.CreateEvent{Object: catsrc("community-operators")}))
+
+	// The definitions are reloaded once the predicate is set up.
+	dir := t.TempDir()
+	manifest := strings.ReplaceAll(catsrcManifest, catsrcName, "community-operators")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "community-operators.yaml"), []byte(manifest), 0644))
+	_, err := defaults.ReloadGlobalsFrom(context.TODO(), defaults.DirLoader{Dir: dir})
+	require.NoError(t, err)
+
+	assert.False(t, pred.Create(event.CreateEvent{Object: catsrc(catsrcName)}), "a removed default is no longer watched")
+	assert.True(t, pred.Create(event.CreateEvent{Object: catsrc("community-operators")}), "an added default is watched")
+}
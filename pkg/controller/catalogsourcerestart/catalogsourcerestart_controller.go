@@ -0,0 +1,158 @@
+package catalogsourcerestart
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// controllerName is the name of the controller, it is used to identify its
+	// metrics.
+	controllerName = "catalogsourcerestart-controller"
+
+	// LastRestartTimeAnnotation is the annotation of the default
+	// CatalogSources recording, in RFC 3339 format, when their catalog pod
+	// was last restarted to serve their pinned image digest.
+	LastRestartTimeAnnotation = "marketplace.operator.openshift.io/last-restart-time"
+
+	// catalogSourceLabelKey is the label OLM sets on the pods serving a
+	// CatalogSource to the name of that CatalogSource.
+	catalogSourceLabelKey = "olm.catalogSource"
+
+	// restartCheckInterval is the interval at which the catalog pods of a
+	// CatalogSource are checked while one of them is restarting.
+	restartCheckInterval = 10 * time.Second
+)
+
+// Add creates a new CatalogSource restart Controller and adds it to the
+// Manager. The Manager will set fields on the Controller and Start it when the
+// Manager is Started.
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	return add(mgr, newReconciler(mgr), o.ControllerRuntimeOptions())
+}
+
+// newReconciler returns a new ReconcileCatalogSourceRestart.
+func newReconciler(mgr manager.Manager) *ReconcileCatalogSourceRestart {
+	return &ReconcileCatalogSourceRestart{
+		client: mgr.GetClient(),
+		reader: mgr.GetAPIReader(),
+		now:    time.Now,
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler, opts controller.Options) error {
+	return builder.ControllerManagedBy(mgr).
+		Named(controllerName).
+		For(&olmv1alpha1.CatalogSource{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			def, ok := defaults.GetGlobalCatalogSourceDefinitions()[obj.GetName()]
+			return ok && def.Namespace == obj.GetNamespace()
+		}))).
+		WithOptions(opts).
+		Complete(metrics.NewInstrumentedReconciler(controllerName, r))
+}
+
+var _ reconcile.Reconciler = &ReconcileCatalogSourceRestart{}
+
+// ReconcileCatalogSourceRestart restarts the catalog pods of the default
+// CatalogSources pinned to an image digest that serve another digest, one pod
+// at a time, by deleting them for OLM to recreate them.
+type ReconcileCatalogSourceRestart struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	// reader reads directly from the apiserver so that the CatalogSource
+	// pods do not need to be cached.
+	reader client.Reader
+	now    func() time.Time
+}
+
+// Reconcile deletes a stale catalog pod of a default CatalogSource pinned to
+// an image digest, and requeues the CatalogSource until none of its pods is
+// stale or restarting.
+func (r *ReconcileCatalogSourceRestart) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	catsrc := &olmv1alpha1.CatalogSource{}
+	if err := r.client.Get(ctx, request.NamespacedName, catsrc); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	digest := imageDigest(catsrc.Spec.Image)
+	if digest == "" || !catsrc.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	logging.FromContext(ctx).Debugf("Reconciling the catalog pods of CatalogSource %s", request.Name)
+
+	pods := &corev1.PodList{}
+	if err := r.reader.List(ctx, pods, client.InNamespace(catsrc.Namespace), client.MatchingLabels{catalogSourceLabelKey: catsrc.Name}); err != nil {
+		return reconcile.Result{}, err
+	}
+	var stale *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !pod.DeletionTimestamp.IsZero() {
+			// A single pod is restarted at a time.
+			return reconcile.Result{RequeueAfter: restartCheckInterval}, nil
+		}
+		if stale == nil && podDigest(pod) != digest {
+			stale = pod
+		}
+	}
+	if stale == nil {
+		return reconcile.Result{}, nil
+	}
+
+	if err := r.client.Delete(ctx, stale); client.IgnoreNotFound(err) != nil {
+		logging.FromContext(ctx).Errorf("[restart] Error deleting stale catalog pod %s/%s of CatalogSource %s - %v", stale.Namespace, stale.Name, catsrc.Name, err)
+		return reconcile.Result{}, err
+	}
+	logging.FromContext(ctx).Infof("[restart] Restarted catalog pod %s/%s of CatalogSource %s to serve image %s", stale.Namespace, stale.Name, catsrc.Name, catsrc.Spec.Image)
+	metrics.IncCatalogRestarts(catsrc.Name)
+
+	patch := client.MergeFrom(catsrc.DeepCopy())
+	if catsrc.Annotations == nil {
+		catsrc.Annotations = make(map[string]string)
+	}
+	catsrc.Annotations[LastRestartTimeAnnotation] = r.now().UTC().Format(time.RFC3339)
+	if err := r.client.Patch(ctx, catsrc, patch); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	return reconcile.Result{RequeueAfter: restartCheckInterval}, nil
+}
+
+// imageDigest returns the digest of the given image reference, or the empty
+// string if it is not pinned to a digest.
+func imageDigest(image string) string {
+	i := strings.LastIndex(image, "@")
+	if i == -1 {
+		return ""
+	}
+	return image[i+1:]
+}
+
+// podDigest returns the image digest the registry container of pod serves: the
+// digest the container runtime resolved once it is running, the digest of its
+// image reference otherwise.
+func podDigest(pod *corev1.Pod) string {
+	if len(pod.Spec.Containers) == 0 {
+		return ""
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == pod.Spec.Containers[0].Name && status.ImageID != "" {
+			return imageDigest(status.ImageID)
+		}
+	}
+	return imageDigest(pod.Spec.Containers[0].Image)
+}
@@ -0,0 +1,123 @@
+package catalogsourcerestart
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	catsrcName = "redhat-operators"
+	namespace  = "openshift-marketplace"
+	digest     = "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	staleImage = "quay.io/example/redhat-operators@sha256:fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9"
+)
+
+// catalogRestarts returns the number of restarts reported for the catalog pods
+// of the given CatalogSource.
+func catalogRestarts(t *testing.T, name string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "marketplace_catalog_restarts_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == name {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// catalogPod returns a catalog pod of the CatalogSource running the image
+// with the given digest.
+func catalogPod(name, imageID string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{catalogSourceLabelKey: catsrcName}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "registry-server", Image: "quay.io/example/redhat-operators:latest"}}},
+		Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "registry-server", ImageID: imageID}}},
+	}
+}
+
+// setup returns a reconciler backed by an in-memory cluster containing the
+// CatalogSource, pinned to image, and the given pods.
+func setup(t *testing.T, image string, pods ...client.Object) (*ReconcileCatalogSourceRestart, client.Client) {
+	t.Helper()
+	catsrc := &olmv1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: catsrcName, Namespace: namespace},
+		Spec:       olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: image},
+	}
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(pods, catsrc)...).Build()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return &ReconcileCatalogSourceRestart{client: c, reader: c, now: func() time.Time { return now }}, c
+}
+
+func reconcileCatalogSource(t *testing.T, r *ReconcileCatalogSourceRestart) reconcile.Result {
+	t.Helper()
+	result, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: catsrcName}})
+	require.NoError(t, err)
+	return result
+}
+
+func TestReconcileRestartsStalePod(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+	restarts := catalogRestarts(t, catsrcName)
+	stale := catalogPod("stale", "quay.io/example/redhat-operators@"+imageDigest(staleImage))
+	current := catalogPod("current", "quay.io/example/redhat-operators@"+digest)
+	r, c := setup(t, "quay.io/example/redhat-operators@"+digest, stale, current)
+
+	result := reconcileCatalogSource(t, r)
+	assert.Equal(t, restartCheckInterval, result.RequeueAfter)
+	err := c.Get(context.TODO(), client.ObjectKeyFromObject(stale), &corev1.Pod{})
+	assert.True(t, k8sErrors.IsNotFound(err), "expected the stale pod to be deleted, got %v", err)
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKeyFromObject(current), &corev1.Pod{}))
+	assert.Equal(t, restarts+1, catalogRestarts(t, catsrcName))
+
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: catsrcName}, catsrc))
+	assert.Equal(t, "2024-05-01T12:00:00Z", catsrc.Annotations[LastRestartTimeAnnotation])
+
+	// Nothing is restarted once every pod serves the pinned digest.
+	result = reconcileCatalogSource(t, r)
+	assert.Zero(t, result.RequeueAfter)
+}
+
+func TestReconcileIgnoresUnpinnedImage(t *testing.T) {
+	stale := catalogPod("stale", "quay.io/example/redhat-operators@"+digest)
+	r, c := setup(t, "quay.io/example/redhat-operators:latest", stale)
+
+	reconcileCatalogSource(t, r)
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKeyFromObject(stale), &corev1.Pod{}))
+}
+
+func TestPodDigest(t *testing.T) {
+	pod := catalogPod("pod", "docker-pullable://quay.io/example/redhat-operators@"+digest)
+	assert.Equal(t, digest, podDigest(pod))
+
+	// The digest of the image reference is used until the container runs.
+	pod.Status.ContainerStatuses = nil
+	pod.Spec.Containers[0].Image = staleImage
+	assert.Equal(t, imageDigest(staleImage), podDigest(pod))
+}
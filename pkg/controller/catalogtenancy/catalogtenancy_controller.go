@@ -0,0 +1,250 @@
+package catalogtenancy
+
+import (
+	"context"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// controllerName is the name of the controller, it is used to identify its
+	// metrics.
+	controllerName = "catalogtenancy-controller"
+
+	// IsolatedCatalogLabel is the label, set to "true", of the namespaces that
+	// get their own copy of each default CatalogSource.
+	IsolatedCatalogLabel = "marketplace.operator.openshift.io/isolated-catalog"
+
+	// CopyOfLabel is the label of the CatalogSources copied into an isolated
+	// namespace, its value is the name of the default CatalogSource copied, or
+	// of the CatalogSource of the defaults override ConfigMap.
+	CopyOfLabel = "marketplace.operator.openshift.io/copy-of"
+)
+
+// Add creates a new catalog tenancy Controller and adds it to the Manager.
+// The Manager will set fields on the Controller and Start it when the Manager
+// is Started.
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	return add(mgr, newReconciler(mgr), o.ControllerRuntimeOptions())
+}
+
+// newReconciler returns a new ReconcileCatalogTenancy.
+func newReconciler(mgr manager.Manager) *ReconcileCatalogTenancy {
+	return &ReconcileCatalogTenancy{client: mgr.GetClient(), reader: mgr.GetAPIReader()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler, opts controller.Options) error {
+	return builder.ControllerManagedBy(mgr).
+		Named(controllerName).
+		For(&corev1.Namespace{}, builder.WithPredicates(getPredicateFunctions())).
+		Watches(&olmv1alpha1.CatalogSource{}, handler.EnqueueRequestsFromMapFunc(catalogSourceToNamespaces(mgr.GetClient()))).
+		WithOptions(opts).
+		Complete(metrics.NewInstrumentedReconciler(controllerName, r))
+}
+
+// getPredicateFunctions returns the predicate functions used to identify the
+// namespaces that are isolated or stopped being isolated.
+func getPredicateFunctions() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isIsolated(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isIsolated(e.ObjectOld) != isIsolated(e.ObjectNew) ||
+				(isIsolated(e.ObjectNew) && defaultsOverride(e.ObjectOld) != defaultsOverride(e.ObjectNew))
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			// The copies are deleted along with the namespace.
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// isIsolated returns true if the namespace is labeled to get its own copy of
+// the default CatalogSources.
+func isIsolated(namespace client.Object) bool {
+	return namespace.GetLabels()[IsolatedCatalogLabel] == "true"
+}
+
+// catalogSourceToNamespaces maps a copied CatalogSource to its namespace, so
+// that a copy modified or deleted by a tenant is restored, and a default
+// CatalogSource to every isolated namespace, so that the copies follow the
+// changes of the default CatalogSources.
+func catalogSourceToNamespaces(c client.Client) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		if _, ok := obj.GetLabels()[CopyOfLabel]; ok {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: obj.GetNamespace()}}}
+		}
+		def, ok := defaults.GetGlobalCatalogSourceDefinitions()[obj.GetName()]
+		if !ok || def.Namespace != obj.GetNamespace() {
+			return nil
+		}
+
+		namespaces := &corev1.NamespaceList{}
+		if err := c.List(ctx, namespaces, client.MatchingLabels{IsolatedCatalogLabel: "true"}); err != nil {
+			log.Errorf("[tenancy] Error listing the isolated namespaces - %v", err)
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(namespaces.Items))
+		for _, namespace := range namespaces.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: namespace.Name}})
+		}
+		return requests
+	}
+}
+
+var _ reconcile.Reconciler = &ReconcileCatalogTenancy{}
+
+// ReconcileCatalogTenancy copies the default CatalogSources into the isolated
+// namespaces, so that the tenants of a namespace are served by their own
+// catalog pods and gRPC services rather than the ones shared by the cluster.
+type ReconcileCatalogTenancy struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+
+	// reader reads the defaults override ConfigMaps from the apiserver, as
+	// the ConfigMaps of the tenants are not cached.
+	reader client.Reader
+}
+
+// Reconcile creates or updates a copy of each enabled default CatalogSource
+// in an isolated namespace, and deletes the copies of the disabled default
+// CatalogSources. The CatalogSources of the defaults override ConfigMap of the
+// namespace, if any, replace or are added to the copies. Every copy is
+// deleted once the namespace is no longer isolated.
+func (r *ReconcileCatalogTenancy) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	logging.FromContext(ctx).Debugf("Reconciling the CatalogSource copies in namespace %s", request.Name)
+
+	namespace := &corev1.Namespace{}
+	if err := r.client.Get(ctx, request.NamespacedName, namespace); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	isolated := isIsolated(namespace) && namespace.DeletionTimestamp.IsZero()
+
+	desired := make(map[string]olmv1alpha1.CatalogSource)
+	if isolated {
+		disabled := operatorhub.GetSingleton().Get()
+		for name, def := range defaults.GetGlobalCatalogSourceDefinitions() {
+			// The default CatalogSources are never copied over themselves.
+			if disabled[name] || def.Namespace == namespace.Name {
+				continue
+			}
+			catsrc, ok := defaults.GetDesiredCatalogSource(name)
+			if !ok {
+				continue
+			}
+			if catsrc.Spec.Image == "" {
+				logging.FromContext(ctx).Warnf("[tenancy] CatalogSource %s has no image to serve a copy in namespace %s from", name, namespace.Name)
+				continue
+			}
+			desired[name] = copyOf(catsrc)
+		}
+
+		overrides, err := overrideDefinitions(ctx, r.reader, namespace)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[tenancy] Error reading the defaults override of namespace %s - %v", namespace.Name, err)
+			return reconcile.Result{}, err
+		}
+		for name, def := range overrides {
+			desired[name] = def
+		}
+	}
+
+	copies := &olmv1alpha1.CatalogSourceList{}
+	if err := r.client.List(ctx, copies, client.InNamespace(namespace.Name), client.HasLabels{CopyOfLabel}); err != nil {
+		return reconcile.Result{}, err
+	}
+	for i := range copies.Items {
+		existing := &copies.Items[i]
+		if _, ok := desired[existing.Labels[CopyOfLabel]]; ok && existing.Name == existing.Labels[CopyOfLabel] {
+			continue
+		}
+		if err := r.client.Delete(ctx, existing); err != nil && !k8sErrors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		logging.FromContext(ctx).Infof("[tenancy] Deleted CatalogSource %s/%s", existing.Namespace, existing.Name)
+	}
+
+	for name, def := range desired {
+		if err := r.ensureCopy(ctx, namespace.Name, def); err != nil {
+			logging.FromContext(ctx).Errorf("[tenancy] Error ensuring the copy of CatalogSource %s in namespace %s - %v", name, namespace.Name, err)
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{}, nil
+}
+
+// copyOf returns the copy of the default CatalogSource def. The copy is a grpc
+// CatalogSource served from the image of def, for which OLM creates a catalog
+// pod and a gRPC service in the isolated namespace.
+func copyOf(def olmv1alpha1.CatalogSource) olmv1alpha1.CatalogSource {
+	def.Spec = *def.Spec.DeepCopy()
+	def.Spec.SourceType = olmv1alpha1.SourceTypeGrpc
+	// The copy is served by its own catalog pod rather than the registry the
+	// default CatalogSource may point at.
+	def.Spec.Address = ""
+	return def
+}
+
+// ensureCopy creates or updates the copy of def in namespace. A CatalogSource
+// of the tenants with the same name is left untouched.
+func (r *ReconcileCatalogTenancy) ensureCopy(ctx context.Context, namespace string, def olmv1alpha1.CatalogSource) error {
+	catsrc := &olmv1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      def.Name,
+			Namespace: namespace,
+		},
+	}
+	err := r.client.Get(ctx, client.ObjectKeyFromObject(catsrc), catsrc)
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && catsrc.Labels[CopyOfLabel] != def.Name {
+		logging.FromContext(ctx).Warnf("[tenancy] CatalogSource %s/%s is not a copy of the default CatalogSource, leaving it untouched", namespace, def.Name)
+		return nil
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.client, catsrc, func() error {
+		if catsrc.Labels == nil {
+			catsrc.Labels = make(map[string]string)
+		}
+		for key, value := range def.Labels {
+			catsrc.Labels[key] = value
+		}
+		catsrc.Labels[CopyOfLabel] = def.Name
+
+		catsrc.Spec = *def.Spec.DeepCopy()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if result != controllerutil.OperationResultNone {
+		logging.FromContext(ctx).Infof("[tenancy] CatalogSource %s/%s %s", namespace, def.Name, result)
+	}
+	return nil
+}
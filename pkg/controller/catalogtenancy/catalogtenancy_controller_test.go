@@ -0,0 +1,230 @@
+package catalogtenancy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	catsrcName      = "redhat-operators"
+	tenantNamespace = "tenant-a"
+	catsrcManifest  = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: redhat-operators
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: quay.io/example/redhat-operators:latest
+  displayName: Red Hat Operators
+`
+)
+
+// setup populates the default CatalogSources and returns a reconciler backed
+// by an in-memory cluster containing the given objects.
+func setup(t *testing.T, objs ...client.Object) (*ReconcileCatalogTenancy, client.Client) {
+	t.Helper()
+	defaultstest.Populate(t, map[string]string{catsrcName + ".yaml": catsrcManifest})
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &ReconcileCatalogTenancy{client: c, reader: c}, c
+}
+
+func isolatedNamespace(isolated bool) *corev1.Namespace {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: tenantNamespace}}
+	if isolated {
+		namespace.Labels = map[string]string{IsolatedCatalogLabel: "true"}
+	}
+	return namespace
+}
+
+func reconcileNamespace(t *testing.T, r *ReconcileCatalogTenancy) {
+	t.Helper()
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: tenantNamespace}})
+	require.NoError(t, err)
+}
+
+func getCopy(t *testing.T, c client.Client) (*olmv1alpha1.CatalogSource, error) {
+	t.Helper()
+	catsrc := &olmv1alpha1.CatalogSource{}
+	err := c.Get(context.TODO(), client.ObjectKey{Namespace: tenantNamespace, Name: catsrcName}, catsrc)
+	return catsrc, err
+}
+
+func TestReconcileCopiesDefaultsIntoIsolatedNamespace(t *testing.T) {
+	r, c := setup(t, isolatedNamespace(true))
+	reconcileNamespace(t, r)
+
+	catsrc, err := getCopy(t, c)
+	require.NoError(t, err)
+	assert.Equal(t, catsrcName, catsrc.Labels[CopyOfLabel])
+	assert.Equal(t, olmv1alpha1.SourceTypeGrpc, catsrc.Spec.SourceType)
+	assert.Equal(t, "quay.io/example/redhat-operators:latest", catsrc.Spec.Image)
+	assert.Equal(t, "Red Hat Operators", catsrc.Spec.DisplayName)
+
+	// A copy modified by a tenant is restored.
+	catsrc.Spec.Image = "quay.io/example/other:latest"
+	require.NoError(t, c.Update(context.TODO(), catsrc))
+	reconcileNamespace(t, r)
+	catsrc, err = getCopy(t, c)
+	require.NoError(t, err)
+	assert.Equal(t, "quay.io/example/redhat-operators:latest", catsrc.Spec.Image)
+}
+
+func TestReconcileDeletesCopies(t *testing.T) {
+	namespace := isolatedNamespace(true)
+	r, c := setup(t, namespace)
+	reconcileNamespace(t, r)
+	_, err := getCopy(t, c)
+	require.NoError(t, err)
+
+	// The copy of a disabled default CatalogSource is deleted.
+	operatorhub.GetSingleton().Set(configv1.OperatorHubSpec{
+		Sources: []configv1.HubSource{{Name: catsrcName, Disabled: true}},
+	})
+	reconcileNamespace(t, r)
+	_, err = getCopy(t, c)
+	assert.True(t, k8sErrors.IsNotFound(err), "expected the copy to be deleted, got %v", err)
+
+	operatorhub.GetSingleton().Set(configv1.OperatorHubSpec{})
+	reconcileNamespace(t, r)
+	_, err = getCopy(t, c)
+	require.NoError(t, err)
+
+	// Every copy is deleted once the namespace is no longer isolated.
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKeyFromObject(namespace), namespace))
+	namespace.Labels = nil
+	require.NoError(t, c.Update(context.TODO(), namespace))
+	reconcileNamespace(t, r)
+	_, err = getCopy(t, c)
+	assert.True(t, k8sErrors.IsNotFound(err), "expected the copy to be deleted, got %v", err)
+}
+
+func TestReconcileLeavesTenantCatalogSource(t *testing.T) {
+	tenant := &olmv1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: catsrcName, Namespace: tenantNamespace},
+		Spec:       olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: "quay.io/tenant/catalog:latest"},
+	}
+	r, c := setup(t, isolatedNamespace(true), tenant)
+	reconcileNamespace(t, r)
+
+	catsrc, err := getCopy(t, c)
+	require.NoError(t, err)
+	assert.Equal(t, "quay.io/tenant/catalog:latest", catsrc.Spec.Image)
+	assert.NotContains(t, catsrc.Labels, CopyOfLabel)
+}
+
+func TestReconcileSkipsNamespaceNotIsolated(t *testing.T) {
+	r, c := setup(t, isolatedNamespace(false))
+	reconcileNamespace(t, r)
+
+	_, err := getCopy(t, c)
+	assert.True(t, k8sErrors.IsNotFound(err), "expected no copy, got %v", err)
+}
+
+func TestReconcileAppliesDefaultsOverride(t *testing.T) {
+	namespace := isolatedNamespace(true)
+	namespace.Annotations = map[string]string{DefaultsOverrideAnnotation: "catalogs"}
+	override := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "catalogs", Namespace: tenantNamespace},
+		Data: map[string]string{
+			"redhat-operators.yaml": strings.Replace(catsrcManifest, "quay.io/example/redhat-operators:latest", "quay.io/tenant/redhat-operators:v1", 1),
+			"tenant-operators.yaml": `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: tenant-operators
+  namespace: tenant-a
+spec:
+  sourceType: grpc
+  address: tenant-registry.tenant-a.svc:50051
+`,
+		},
+	}
+	r, c := setup(t, namespace, override)
+	reconcileNamespace(t, r)
+
+	// The override replaces the default CatalogSource of the same name.
+	catsrc, err := getCopy(t, c)
+	require.NoError(t, err)
+	assert.Equal(t, catsrcName, catsrc.Labels[CopyOfLabel])
+	assert.Equal(t, "quay.io/tenant/redhat-operators:v1", catsrc.Spec.Image)
+
+	// The other CatalogSources of the override are added as they are.
+	added := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: tenantNamespace, Name: "tenant-operators"}, added))
+	assert.Equal(t, "tenant-operators", added.Labels[CopyOfLabel])
+	assert.Equal(t, "tenant-registry.tenant-a.svc:50051", added.Spec.Address)
+
+	// The CatalogSources added are deleted along with the override.
+	require.NoError(t, c.Delete(context.TODO(), override))
+	reconcileNamespace(t, r)
+	err = c.Get(context.TODO(), client.ObjectKeyFromObject(added), added)
+	assert.True(t, k8sErrors.IsNotFound(err), "expected the CatalogSource to be deleted, got %v", err)
+	catsrc, err = getCopy(t, c)
+	require.NoError(t, err)
+	assert.Equal(t, "quay.io/example/redhat-operators:latest", catsrc.Spec.Image)
+}
+
+func TestReconcileRejectsInvalidDefaultsOverride(t *testing.T) {
+	namespace := isolatedNamespace(true)
+	namespace.Annotations = map[string]string{DefaultsOverrideAnnotation: "catalogs"}
+	override := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "catalogs", Namespace: tenantNamespace},
+		Data:       map[string]string{"invalid.yaml": "kind: CatalogSource\n"},
+	}
+	r, c := setup(t, namespace, override)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: tenantNamespace}})
+	assert.Error(t, err)
+	_, err = getCopy(t, c)
+	assert.True(t, k8sErrors.IsNotFound(err), "expected no copy, got %v", err)
+}
+
+func TestPredicate(t *testing.T) {
+	p := getPredicateFunctions()
+	assert.True(t, p.Create(event.CreateEvent{Object: isolatedNamespace(true)}))
+	assert.False(t, p.Create(event.CreateEvent{Object: isolatedNamespace(false)}))
+	assert.True(t, p.Update(event.UpdateEvent{ObjectOld: isolatedNamespace(true), ObjectNew: isolatedNamespace(false)}))
+	assert.False(t, p.Update(event.UpdateEvent{ObjectOld: isolatedNamespace(true), ObjectNew: isolatedNamespace(true)}))
+	assert.False(t, p.Delete(event.DeleteEvent{Object: isolatedNamespace(true)}))
+
+	overridden := isolatedNamespace(true)
+	overridden.Annotations = map[string]string{DefaultsOverrideAnnotation: "catalogs"}
+	assert.True(t, p.Update(event.UpdateEvent{ObjectOld: isolatedNamespace(true), ObjectNew: overridden}))
+}
+
+func TestCatalogSourceToNamespaces(t *testing.T) {
+	_, c := setup(t, isolatedNamespace(true), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}})
+	mapFn := catalogSourceToNamespaces(c)
+
+	def := &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Name: catsrcName, Namespace: "openshift-marketplace"}}
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: tenantNamespace}}},
+		mapFn(context.TODO(), def), "a default CatalogSource maps to every isolated namespace")
+
+	copied := &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{
+		Name: catsrcName, Namespace: "other", Labels: map[string]string{CopyOfLabel: catsrcName},
+	}}
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "other"}}}, mapFn(context.TODO(), copied))
+
+	unrelated := &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Name: catsrcName, Namespace: "other"}}
+	assert.Empty(t, mapFn(context.TODO(), unrelated))
+}
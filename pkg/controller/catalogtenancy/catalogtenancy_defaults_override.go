@@ -0,0 +1,56 @@
+package catalogtenancy
+
+import (
+	"context"
+	"fmt"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultsOverrideAnnotation is the annotation of the isolated namespaces
+// whose value is the name of a ConfigMap, in the namespace, overriding the
+// default CatalogSources copied into it. Each data key of the ConfigMap is a
+// CatalogSource manifest, like the ones of -defaults-configmap, replacing the
+// default CatalogSource of the same name or added to them.
+const DefaultsOverrideAnnotation = "marketplace.operator.openshift.io/defaults-override"
+
+// defaultsOverride returns the name of the ConfigMap overriding the default
+// CatalogSources of namespace, or the empty string if they are not
+// overridden.
+func defaultsOverride(namespace client.Object) string {
+	return namespace.GetAnnotations()[DefaultsOverrideAnnotation]
+}
+
+// overrideDefinitions returns the CatalogSource definitions of the ConfigMap
+// overriding the default CatalogSources of namespace, read through reader, set
+// in namespace. No definition is returned if the ConfigMap does not exist.
+func overrideDefinitions(ctx context.Context, reader client.Reader, namespace *corev1.Namespace) (map[string]olmv1alpha1.CatalogSource, error) {
+	name := defaultsOverride(namespace)
+	if name == "" {
+		return nil, nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err := reader.Get(ctx, client.ObjectKey{Namespace: namespace.Name, Name: name}, configMap)
+	if k8sErrors.IsNotFound(err) {
+		logging.FromContext(ctx).Warnf("[tenancy] Defaults override ConfigMap %s/%s not found, copying the default CatalogSources only", namespace.Name, name)
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get defaults override ConfigMap %s/%s: %v", namespace.Name, name, err)
+	}
+
+	definitions, err := defaults.DefinitionsFromConfigMap(configMap)
+	if err != nil {
+		return nil, fmt.Errorf("invalid defaults override ConfigMap %s/%s: %v", namespace.Name, name, err)
+	}
+	for name, def := range definitions {
+		def.Namespace = namespace.Name
+		definitions[name] = def
+	}
+	return definitions, nil
+}
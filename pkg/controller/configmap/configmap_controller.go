@@ -4,11 +4,14 @@ import (
 	"context"
 	"os"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	mktconfig "github.com/operator-framework/operator-marketplace/pkg/apis/config/v1"
 	"github.com/operator-framework/operator-marketplace/pkg/apis/operators/shared"
 	ca "github.com/operator-framework/operator-marketplace/pkg/certificateauthority"
 	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -18,10 +21,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// controllerName is the name of the controller, it is used to identify its
+// metrics.
+const controllerName = "configmap-controller"
+
 // Add creates a new ConfigMap Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
-func Add(mgr manager.Manager, _ options.ControllerOptions) error {
-	return add(mgr, newReconciler(mgr))
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	return add(mgr, newReconciler(mgr), o.ControllerRuntimeOptions())
 }
 
 // newReconciler returns a new ReconcileConfigMap.
@@ -34,17 +41,18 @@ func newReconciler(mgr manager.Manager) *ReconcileConfigMap {
 }
 
 // add adds a new Controller to mgr with r as the ReconcileConfigMap.
-func add(mgr manager.Manager, r *ReconcileConfigMap) error {
+func add(mgr manager.Manager, r *ReconcileConfigMap, opts controller.Options) error {
 	if !mktconfig.IsAPIAvailable() || !isRunningOnPod() {
 		log.Printf("[ca] Config API is not available or marketplace is not being ran on a pod, the ConfigMap controller will not be started.")
 		return nil
 	}
 
 	return builder.ControllerManagedBy(mgr).
-		Named("configmap-controller").
+		Named(controllerName).
 		For(&corev1.ConfigMap{}).
 		WithEventFilter(getPredicateFunctions()).
-		Complete(r)
+		WithOptions(opts).
+		Complete(metrics.NewInstrumentedReconciler(controllerName, r))
 }
 
 // getPredicateFunctions returns the predicate functions used to identify the configmap
@@ -86,7 +94,7 @@ type ReconcileConfigMap struct {
 // Reconcile will restart the marketplace operator if the Certificate Authority ConfigMap is
 // not in sync with the Certificate Authority bundle on disk..
 func (r *ReconcileConfigMap) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
-	log.Printf("Reconciling ConfigMap %s/%s", request.Namespace, request.Name)
+	logging.FromContext(ctx).Printf("Reconciling ConfigMap %s/%s", request.Namespace, request.Name)
 
 	// Check if the CA ConfigMap is in the same namespace that Marketplace is deployed in.
 	isConfigMapInOtherNamespace, err := shared.IsObjectInOtherNamespace(request.Namespace)
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/apis"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/backoff"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// recordingManager records the runnables added to the Manager rather than
+// running them.
+type recordingManager struct {
+	manager.Manager
+	runnables []manager.Runnable
+}
+
+func (m *recordingManager) Add(r manager.Runnable) error {
+	m.runnables = append(m.runnables, r)
+	return nil
+}
+
+func TestAddToManagerMaxConcurrentReconciles(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, apis.AddToScheme(scheme))
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	// The Manager is never started, so the API server is never contacted.
+	mgr, err := manager.New(&rest.Config{Host: "https://127.0.0.1:6443"}, manager.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	require.NoError(t, err)
+	recorder := &recordingManager{Manager: mgr}
+
+	require.NoError(t, AddToManager(recorder, options.ControllerOptions{MaxConcurrentReconciles: 5}))
+
+	controllers := 0
+	for _, runnable := range recorder.runnables {
+		value := reflect.Indirect(reflect.ValueOf(runnable))
+		if value.Kind() != reflect.Struct {
+			continue
+		}
+		field := value.FieldByName("MaxConcurrentReconciles")
+		if !field.IsValid() {
+			continue
+		}
+		controllers++
+		assert.Equal(t, int64(5), field.Int(), "%T", runnable)
+		_, jittered := value.FieldByName("RateLimiter").Interface().(*backoff.JitteredRateLimiter)
+		assert.True(t, jittered, "%T retries the failed requests with a jittered backoff", runnable)
+	}
+	// Some controllers are only added on OpenShift or when enabled.
+	assert.NotZero(t, controllers)
+}
@@ -0,0 +1,212 @@
+package defaultsaudit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// AuditConfigMapName is the name of the ConfigMap, in the operator
+	// namespace, that records the changes to the default CatalogSources.
+	AuditConfigMapName = "marketplace-defaults-audit"
+
+	// hashesKey is the key of the audit ConfigMap holding the hashes of the
+	// default CatalogSource definitions that were last audited.
+	hashesKey = "hashes"
+
+	// entryKeyPrefix prefixes the keys of the audit entries. It is followed
+	// by the time of the entry so that the keys sort chronologically.
+	entryKeyPrefix = "entry-"
+
+	// entryKeyTimeFormat is the format of the time in the entry keys. It only
+	// uses characters that are valid in ConfigMap keys.
+	entryKeyTimeFormat = "20060102T150405.000000000Z"
+
+	// maxAuditSize is the size of the audit ConfigMap data beyond which the
+	// oldest entries are rotated out, to avoid bloating etcd.
+	maxAuditSize = 1024 * 1024
+)
+
+// Entry is a single change to the default CatalogSources recorded in the
+// audit ConfigMap.
+type Entry struct {
+	// Timestamp is the time the change was detected.
+	Timestamp metav1.Time `json:"timestamp"`
+	// Changed are the names of the default CatalogSources that were added,
+	// removed or modified.
+	Changed []string `json:"changed"`
+	// Version is the version of the operator that detected the change.
+	Version string `json:"version"`
+}
+
+// Add creates a new defaults audit runnable and adds it to the Manager. It
+// audits the default CatalogSources populated when this replica started
+// leading, every time the Manager is Started, and again each time they are
+// populated or reloaded while it leads.
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	return mgr.Add(&auditor{
+		client:    mgr.GetClient(),
+		reader:    mgr.GetAPIReader(),
+		namespace: o.Namespace,
+		version:   o.Version,
+		now:       time.Now,
+	})
+}
+
+// auditor compares the hashes of the default CatalogSource definitions with
+// the ones recorded in the audit ConfigMap, and appends an Entry to it when
+// they differ.
+type auditor struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	// reader reads directly from the apiserver as the cache only holds the
+	// trusted CA ConfigMap.
+	reader    client.Reader
+	namespace string
+	version   string
+	now       func() time.Time
+}
+
+// Start audits the default CatalogSources, then audits them again each time
+// they are populated until ctx is done. A failure is logged rather than
+// returned as it must not stop the manager.
+func (a *auditor) Start(ctx context.Context) error {
+	populated := make(chan struct{}, 1)
+	unregister := defaults.OnPopulated(func() {
+		select {
+		case populated <- struct{}{}:
+		default:
+		}
+	})
+	defer unregister()
+
+	for {
+		if err := a.audit(ctx); err != nil {
+			logging.FromContext(ctx).Errorf("[audit] Error auditing the default CatalogSources - %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-populated:
+		}
+	}
+}
+
+// audit records the changes to the default CatalogSources since the last
+// audit.
+func (a *auditor) audit(ctx context.Context) error {
+	hashes, err := defaults.GetDefinitionHashes()
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		audit, err := a.getAudit(ctx)
+		if err != nil {
+			return err
+		}
+
+		previous := map[string]string{}
+		if recorded, present := audit.Data[hashesKey]; present {
+			if err := json.Unmarshal([]byte(recorded), &previous); err != nil {
+				logging.FromContext(ctx).Warnf("[audit] Ignoring the malformed hashes in ConfigMap %s - %v", AuditConfigMapName, err)
+			}
+		}
+		changed := changedSources(previous, hashes)
+		if len(changed) == 0 {
+			return nil
+		}
+
+		now := a.now().UTC()
+		entry, err := json.Marshal(Entry{Timestamp: metav1.NewTime(now), Changed: changed, Version: a.version})
+		if err != nil {
+			return err
+		}
+		encodedHashes, err := json.Marshal(hashes)
+		if err != nil {
+			return err
+		}
+		audit.Data[hashesKey] = string(encodedHashes)
+		audit.Data[entryKeyPrefix+now.Format(entryKeyTimeFormat)] = string(entry)
+		rotate(audit.Data)
+		logging.FromContext(ctx).Infof("[audit] Recording changes to the default CatalogSources %v", changed)
+
+		if audit.ResourceVersion == "" {
+			return a.client.Create(ctx, audit)
+		}
+		return a.client.Update(ctx, audit)
+	})
+}
+
+// getAudit returns the audit ConfigMap. A ConfigMap that does not exist yet is
+// returned without a resourceVersion.
+func (a *auditor) getAudit(ctx context.Context) (*corev1.ConfigMap, error) {
+	audit := &corev1.ConfigMap{}
+	err := a.reader.Get(ctx, client.ObjectKey{Namespace: a.namespace, Name: AuditConfigMapName}, audit)
+	if k8sErrors.IsNotFound(err) {
+		audit = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      AuditConfigMapName,
+				Namespace: a.namespace,
+			},
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap %s: %v", AuditConfigMapName, err)
+	}
+	if audit.Data == nil {
+		audit.Data = make(map[string]string)
+	}
+	return audit, nil
+}
+
+// changedSources returns the sorted names of the CatalogSources that were
+// added, removed or whose hash changed.
+func changedSources(previous, current map[string]string) []string {
+	var changed []string
+	for name, hash := range current {
+		if previous[name] != hash {
+			changed = append(changed, name)
+		}
+	}
+	for name := range previous {
+		if _, present := current[name]; !present {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// rotate removes the oldest entries from data until it fits in maxAuditSize.
+// The most recent entry is always kept.
+func rotate(data map[string]string) {
+	size := 0
+	var entries []string
+	for key, value := range data {
+		size += len(key) + len(value)
+		if strings.HasPrefix(key, entryKeyPrefix) {
+			entries = append(entries, key)
+		}
+	}
+	sort.Strings(entries)
+
+	for i := 0; size > maxAuditSize && i < len(entries)-1; i++ {
+		size -= len(entries[i]) + len(data[entries[i]])
+		delete(data, entries[i])
+	}
+}
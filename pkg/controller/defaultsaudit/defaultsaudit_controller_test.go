@@ -0,0 +1,172 @@
+package defaultsaudit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const catsrcManifest = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: %s
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: %s
+`
+
+// writeManifest writes a default CatalogSource manifest to dir and populates
+// the default CatalogSources from it.
+func writeManifest(t *testing.T, dir, name, image string) {
+	t.Helper()
+	manifest := fmt.Sprintf(catsrcManifest, name, image)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(manifest), 0644))
+	_, err := defaults.PopulateGlobals()
+	require.NoError(t, err)
+}
+
+// entries returns the audit entries in chronological order.
+func entries(t *testing.T, c client.Client) []Entry {
+	t.Helper()
+	audit := &corev1.ConfigMap{}
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: "openshift-marketplace", Name: AuditConfigMapName}, audit))
+
+	var keys []string
+	for key := range audit.Data {
+		if strings.HasPrefix(key, entryKeyPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var result []Entry
+	for _, key := range keys {
+		entry := Entry{}
+		require.NoError(t, json.Unmarshal([]byte(audit.Data[key]), &entry))
+		result = append(result, entry)
+	}
+	return result
+}
+
+func TestAudit(t *testing.T) {
+	dir := defaultstest.Populate(t, nil)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := &auditor{
+		client:    c,
+		reader:    c,
+		namespace: "openshift-marketplace",
+		version:   "4.18.0",
+		now: func() time.Time {
+			now = now.Add(time.Minute)
+			return now
+		},
+	}
+
+	writeManifest(t, dir, "redhat-operators", "quay.io/example/redhat:v1")
+	writeManifest(t, dir, "certified-operators", "quay.io/example/certified:v1")
+	require.NoError(t, a.audit(context.TODO()))
+	recorded := entries(t, c)
+	require.Len(t, recorded, 1)
+	assert.Equal(t, []string{"certified-operators", "redhat-operators"}, recorded[0].Changed)
+	assert.Equal(t, "4.18.0", recorded[0].Version)
+
+	require.NoError(t, a.audit(context.TODO()))
+	assert.Len(t, entries(t, c), 1, "nothing is recorded when the defaults did not change")
+
+	writeManifest(t, dir, "redhat-operators", "quay.io/example/redhat:v2")
+	require.NoError(t, a.audit(context.TODO()))
+	recorded = entries(t, c)
+	require.Len(t, recorded, 2)
+	assert.Equal(t, []string{"redhat-operators"}, recorded[1].Changed)
+	assert.True(t, recorded[1].Timestamp.After(recorded[0].Timestamp.Time))
+}
+
+func TestRotate(t *testing.T) {
+	large := strings.Repeat("x", maxAuditSize/3)
+	data := map[string]string{
+		hashesKey:                    "{}",
+		entryKeyPrefix + "20260101T": large,
+		entryKeyPrefix + "20260102T": large,
+		entryKeyPrefix + "20260103T": large,
+		entryKeyPrefix + "20260104T": large,
+	}
+
+	rotate(data)
+	assert.NotContains(t, data, entryKeyPrefix+"20260101T")
+	assert.NotContains(t, data, entryKeyPrefix+"20260102T")
+	assert.Contains(t, data, entryKeyPrefix+"20260103T")
+	assert.Contains(t, data, entryKeyPrefix+"20260104T")
+	assert.Contains(t, data, hashesKey)
+}
+
+func TestStartAuditsEachPopulation(t *testing.T) {
+	dir := defaultstest.Populate(t, nil)
+	writeManifest(t, dir, "redhat-operators", "quay.io/example/redhat:v1")
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := &auditor{
+		client:    c,
+		reader:    c,
+		namespace: "openshift-marketplace",
+		version:   "4.18.0",
+		now: func() time.Time {
+			now = now.Add(time.Minute)
+			return now
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, a.Start(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// The defaults populated before the auditor started are audited.
+	count := func() int {
+		audit := &corev1.ConfigMap{}
+		if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "openshift-marketplace", Name: AuditConfigMapName}, audit); err != nil {
+			return 0
+		}
+		return len(audit.Data) - 1
+	}
+	require.Eventually(t, func() bool { return count() == 1 }, time.Second, 10*time.Millisecond)
+
+	// And so are the ones populated, or reloaded, later on.
+	writeManifest(t, dir, "redhat-operators", "quay.io/example/redhat:v2")
+	require.Eventually(t, func() bool { return count() == 2 }, time.Second, 10*time.Millisecond)
+	_, err := defaults.ReloadGlobalsFrom(context.TODO(), defaults.DirLoader{Dir: dir})
+	require.NoError(t, err)
+	assert.Never(t, func() bool { return count() > 2 }, 100*time.Millisecond, 10*time.Millisecond,
+		"a reload that does not change the defaults is not recorded")
+	writeManifest(t, dir, "certified-operators", "quay.io/example/certified:v1")
+	_, err = defaults.ReloadGlobalsFrom(context.TODO(), defaults.DirLoader{Dir: dir})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return count() == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"certified-operators"}, entries(t, c)[2].Changed)
+}
@@ -0,0 +1,82 @@
+package defaultsrollout
+
+import (
+	"context"
+
+	configv1 "github.com/openshift/api/config/v1"
+	mktconfig "github.com/operator-framework/operator-marketplace/pkg/apis/config/v1"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Add creates a new defaults rollout runnable and adds it to the Manager if
+// o.DefaultsRolloutBatchSize is positive. The default CatalogSources are then
+// rolled out in batches every time the Manager is Started, the other
+// controllers waiting for the rollout to complete.
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	if o.DefaultsRolloutBatchSize <= 0 {
+		return nil
+	}
+	// The rollout is marked as in progress before the Manager starts, so
+	// that the controllers started along with it do not apply the changes
+	// first.
+	defaults.BeginRollout()
+	return mgr.Add(&rollout{
+		client: mgr.GetClient(),
+		reader: mgr.GetAPIReader(),
+		opts: defaults.RolloutOptions{
+			BatchSize:   o.DefaultsRolloutBatchSize,
+			WaitTimeout: o.DefaultsRolloutWaitTimeout,
+		},
+	})
+}
+
+// rollout rolls the default CatalogSources out to the definitions populated
+// when this replica started leading.
+type rollout struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	// reader reads the OperatorHub directly from the apiserver, as it is
+	// read before the cache of the OperatorHub controller is used.
+	reader client.Reader
+	opts   defaults.RolloutOptions
+}
+
+// Start rolls the default CatalogSources out once and marks the rollout as
+// completed. A failure is logged rather than returned as it must not stop the
+// manager, the controllers applying whatever the rollout did not.
+func (r *rollout) Start(ctx context.Context) error {
+	defer defaults.EndRollout()
+
+	config, err := r.operatorHubConfig(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("[rollout] Error reading the OperatorHub config, the default CatalogSources are not rolled out in batches - %v", err)
+		return nil
+	}
+	if err := defaults.RolloutGlobals(ctx, r.client, config, r.opts); err != nil && ctx.Err() == nil {
+		logging.FromContext(ctx).Errorf("[rollout] Error rolling out the default CatalogSources - %v", err)
+	}
+	return nil
+}
+
+// operatorHubConfig returns the config of the default CatalogSources, read
+// from the cluster OperatorHub when the config API is available, so that the
+// disabled CatalogSources are not rolled out before the OperatorHub controller
+// runs.
+func (r *rollout) operatorHubConfig(ctx context.Context) (map[string]bool, error) {
+	if mktconfig.IsAPIAvailable() {
+		hub := &configv1.OperatorHub{}
+		err := r.reader.Get(ctx, client.ObjectKey{Name: operatorhub.DefaultName}, hub)
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return nil, err
+		}
+		operatorhub.GetSingleton().Set(hub.Spec)
+	}
+	return operatorhub.GetSingleton().Get(), nil
+}
@@ -0,0 +1,42 @@
+package defaultsrollout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const catsrcManifest = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: redhat-operators
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: quay.io/example/redhat-operators:v1
+`
+
+func TestStartRollsOutTheDefaults(t *testing.T) {
+	defaultstest.Populate(t, map[string]string{"redhat-operators.yaml": catsrcManifest})
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	r := &rollout{client: c, reader: c, opts: defaults.RolloutOptions{BatchSize: 1, WaitTimeout: 10 * time.Millisecond}}
+
+	defaults.BeginRollout()
+	require.NoError(t, r.Start(context.TODO()))
+	assert.False(t, defaults.RolloutInProgress(), "the rollout is completed once started")
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: "openshift-marketplace", Name: "redhat-operators"}, catsrc))
+	assert.Equal(t, "quay.io/example/redhat-operators:v1", catsrc.Spec.Image)
+}
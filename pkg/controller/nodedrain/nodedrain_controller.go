@@ -0,0 +1,156 @@
+package nodedrain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// controllerName is the name of the controller, it is used to identify its
+	// metrics.
+	controllerName = "nodedrain-controller"
+
+	// catalogSourceLabelKey is the label OLM sets on the pods serving a
+	// CatalogSource to the name of that CatalogSource.
+	catalogSourceLabelKey = "olm.catalogSource"
+)
+
+// Add creates a new node drain Controller and adds it to the Manager. The
+// Manager will set fields on the Controller and Start it when the Manager is
+// Started.
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	return add(mgr, newReconciler(mgr), o.ControllerRuntimeOptions())
+}
+
+// newReconciler returns a new ReconcileNodeDrain.
+func newReconciler(mgr manager.Manager) *ReconcileNodeDrain {
+	return &ReconcileNodeDrain{
+		client: mgr.GetClient(),
+		reader: mgr.GetAPIReader(),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler, opts controller.Options) error {
+	return builder.ControllerManagedBy(mgr).
+		Named(controllerName).
+		For(&corev1.Node{}).
+		WithEventFilter(getPredicateFunctions()).
+		WithOptions(opts).
+		Complete(metrics.NewInstrumentedReconciler(controllerName, r))
+}
+
+// getPredicateFunctions returns the predicate functions used to identify the
+// nodes that started draining.
+func getPredicateFunctions() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			// Nodes that were already draining when the operator started.
+			return isDraining(e.Object.(*corev1.Node))
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return !isDraining(e.ObjectOld.(*corev1.Node)) && isDraining(e.ObjectNew.(*corev1.Node))
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// isDraining returns true if the node has been tainted as unschedulable,
+// which happens when it is cordoned before being drained.
+func isDraining(node *corev1.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == corev1.TaintNodeUnschedulable {
+			return true
+		}
+	}
+	return false
+}
+
+var _ reconcile.Reconciler = &ReconcileNodeDrain{}
+
+// ReconcileNodeDrain reconciles the nodes being drained and deletes the pods
+// of the default CatalogSources scheduled on them ahead of the eviction, so
+// that they are recreated on a schedulable node right away.
+type ReconcileNodeDrain struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	// reader reads directly from the apiserver so that the CatalogSource
+	// pods do not need to be cached.
+	reader client.Reader
+}
+
+// Reconcile deletes the pods of the default CatalogSources running on the
+// node if it is draining.
+func (r *ReconcileNodeDrain) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	node := &corev1.Node{}
+	if err := r.client.Get(ctx, request.NamespacedName, node); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if !isDraining(node) {
+		return reconcile.Result{}, nil
+	}
+	logging.FromContext(ctx).Infof("Reconciling draining node %s", node.Name)
+
+	// The default CatalogSources are grouped by namespace to list their pods.
+	sourcesByNamespace := map[string][]string{}
+	for name, def := range defaults.GetGlobalCatalogSourceDefinitions() {
+		sourcesByNamespace[def.Namespace] = append(sourcesByNamespace[def.Namespace], name)
+	}
+
+	failed := 0
+	for namespace, sources := range sourcesByNamespace {
+		requirement, err := labels.NewRequirement(catalogSourceLabelKey, selection.In, sources)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		pods := &corev1.PodList{}
+		if err := r.reader.List(ctx, pods,
+			client.InNamespace(namespace),
+			client.MatchingLabelsSelector{Selector: labels.NewSelector().Add(*requirement)},
+			client.MatchingFieldsSelector{Selector: fields.OneTermEqualSelector("spec.nodeName", node.Name)},
+		); err != nil {
+			return reconcile.Result{}, err
+		}
+
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if !pod.DeletionTimestamp.IsZero() {
+				continue
+			}
+			if err := r.client.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+				logging.FromContext(ctx).Errorf("[drain] Error deleting CatalogSource pod %s/%s on node %s - %v", pod.Namespace, pod.Name, node.Name, err)
+				failed++
+				continue
+			}
+			logging.FromContext(ctx).Infof("[drain] Deleted CatalogSource %s pod %s/%s ahead of the drain of node %s",
+				pod.Labels[catalogSourceLabelKey], pod.Namespace, pod.Name, node.Name)
+		}
+	}
+
+	if failed != 0 {
+		return reconcile.Result{}, fmt.Errorf("failed to delete %d CatalogSource pods on node %s", failed, node.Name)
+	}
+	return reconcile.Result{}, nil
+}
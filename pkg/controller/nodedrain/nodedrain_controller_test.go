@@ -0,0 +1,93 @@
+package nodedrain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const catsrcManifest = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: redhat-operators
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: quay.io/example/redhat-operators:latest
+`
+
+var unschedulableTaint = corev1.Taint{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule}
+
+func catalogPod(name, catsrc, nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "openshift-marketplace",
+			Labels:    map[string]string{catalogSourceLabelKey: catsrc},
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+func TestPredicate(t *testing.T) {
+	pred := getPredicateFunctions()
+	schedulable := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+	draining := schedulable.DeepCopy()
+	draining.Spec.Taints = []corev1.Taint{unschedulableTaint}
+
+	assert.True(t, pred.Update(event.UpdateEvent{ObjectOld: schedulable, ObjectNew: draining}), "the taint was added")
+	assert.False(t, pred.Update(event.UpdateEvent{ObjectOld: draining, ObjectNew: draining}), "the taint was already present")
+	assert.False(t, pred.Update(event.UpdateEvent{ObjectOld: draining, ObjectNew: schedulable}), "the node was uncordoned")
+	assert.True(t, pred.Create(event.CreateEvent{Object: draining}))
+	assert.False(t, pred.Create(event.CreateEvent{Object: schedulable}))
+}
+
+func TestReconcileDeletesCatalogPodsOnDrainingNode(t *testing.T) {
+	defaultstest.Populate(t, map[string]string{"redhat-operators.yaml": catsrcManifest})
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "draining"},
+		Spec:       corev1.NodeSpec{Taints: []corev1.Taint{unschedulableTaint}},
+	}
+	drained := catalogPod("redhat-operators-abc", "redhat-operators", "draining")
+	elsewhere := catalogPod("redhat-operators-def", "redhat-operators", "healthy")
+	custom := catalogPod("custom-abc", "custom", "draining")
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(node, drained, elsewhere, custom).
+		WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).
+		Build()
+	r := &ReconcileNodeDrain{client: c, reader: c}
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "draining"}})
+	require.NoError(t, err)
+
+	exists := func(pod *corev1.Pod) bool {
+		err := c.Get(context.TODO(), client.ObjectKeyFromObject(pod), &corev1.Pod{})
+		if k8sErrors.IsNotFound(err) {
+			return false
+		}
+		require.NoError(t, err)
+		return true
+	}
+	assert.False(t, exists(drained), "the default CatalogSource pod on the draining node is deleted")
+	assert.True(t, exists(elsewhere), "pods on other nodes are left alone")
+	assert.True(t, exists(custom), "pods of other CatalogSources are left alone")
+}
@@ -3,12 +3,15 @@ package operatorhub
 import (
 	"context"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	configv1 "github.com/openshift/api/config/v1"
 	mktconfig "github.com/operator-framework/operator-marketplace/pkg/apis/config/v1"
 	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
 	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
-	log "github.com/sirupsen/logrus"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -16,10 +19,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// controllerName is the name of the controller, it is used to identify its
+// metrics.
+const controllerName = "operatorhub-controller"
+
 // Add creates a new OperatorHub Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
-func Add(mgr manager.Manager, _ options.ControllerOptions) error {
-	return add(mgr, newReconciler(mgr))
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	return add(mgr, newReconciler(mgr), o.ControllerRuntimeOptions())
 }
 
 // newReconciler returns a new reconcile.Reconciler
@@ -32,7 +39,7 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
+func add(mgr manager.Manager, r reconcile.Reconciler, opts controller.Options) error {
 	if !mktconfig.IsAPIAvailable() {
 		return nil
 	}
@@ -71,10 +78,11 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	}
 
 	return builder.ControllerManagedBy(mgr).
-		Named("operatorhub-controller").
+		Named(controllerName).
 		For(&configv1.OperatorHub{}).
 		WithEventFilter(pred).
-		Complete(r)
+		WithOptions(opts).
+		Complete(metrics.NewInstrumentedReconciler(controllerName, r))
 
 }
 
@@ -92,7 +100,11 @@ type ReconcileOperatorHub struct {
 // Reconcile reads that state of the cluster for a OperatorHub object and makes changes based on the state read
 // and what is in the OperatorHub.Spec
 func (r *ReconcileOperatorHub) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
-	log.Infof("Reconciling OperatorHub %s", request.Name)
+	if defaults.RolloutInProgress() {
+		logging.FromContext(ctx).Debugf("Waiting for the rollout of the default CatalogSources to reconcile OperatorHub %s", request.Name)
+		return reconcile.Result{RequeueAfter: defaults.RolloutRequeueInterval}, nil
+	}
+	logging.FromContext(ctx).Infof("Reconciling OperatorHub %s", request.Name)
 
 	// Fetch the OperatorHub instance
 	instance := &configv1.OperatorHub{}
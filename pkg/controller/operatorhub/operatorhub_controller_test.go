@@ -0,0 +1,112 @@
+package operatorhub
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const catsrcManifest = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: %s
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: quay.io/example/%s:latest
+`
+
+// setup populates the default CatalogSources and returns a reconciler backed
+// by an in-memory cluster containing the cluster OperatorHub.
+func setup(t *testing.T) (*ReconcileOperatorHub, client.Client) {
+	t.Helper()
+	manifests := map[string]string{}
+	for _, name := range []string{"redhat-operators", "community-operators"} {
+		manifests[name+".yaml"] = fmt.Sprintf(catsrcManifest, name, name)
+	}
+	defaultstest.Populate(t, manifests)
+	require.NoError(t, metrics.RegisterMetrics())
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	require.NoError(t, configv1.AddToScheme(scheme))
+	hub := &configv1.OperatorHub{ObjectMeta: metav1.ObjectMeta{Name: operatorhub.DefaultName}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hub).WithStatusSubresource(hub).Build()
+	return &ReconcileOperatorHub{client: c, handler: operatorhub.NewHandler(c)}, c
+}
+
+// reconcileSpec sets the spec of the cluster OperatorHub and reconciles it.
+func reconcileSpec(t *testing.T, r *ReconcileOperatorHub, c client.Client, spec configv1.OperatorHubSpec) {
+	t.Helper()
+	hub := &configv1.OperatorHub{}
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Name: operatorhub.DefaultName}, hub))
+	hub.Spec = spec
+	require.NoError(t, c.Update(context.TODO(), hub))
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: operatorhub.DefaultName}})
+	require.NoError(t, err)
+}
+
+// gauges returns the values of the OperatorHub gauges: the disabled state of
+// each source and whether all the default sources are disabled.
+func gauges(t *testing.T) (map[string]float64, float64) {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	sources := make(map[string]float64)
+	var disableAll float64
+	for _, family := range families {
+		switch family.GetName() {
+		case "marketplace_operatorhub_source_disabled":
+			for _, metric := range family.GetMetric() {
+				sources[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+			}
+		case "marketplace_operatorhub_disable_all_default_sources":
+			disableAll = family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	return sources, disableAll
+}
+
+func TestReconcileReportsOperatorHubConfig(t *testing.T) {
+	r, c := setup(t)
+
+	reconcileSpec(t, r, c, configv1.OperatorHubSpec{})
+	sources, disableAll := gauges(t)
+	assert.Equal(t, map[string]float64{"redhat-operators": 0, "community-operators": 0}, sources)
+	assert.Equal(t, 0.0, disableAll)
+
+	reconcileSpec(t, r, c, configv1.OperatorHubSpec{
+		Sources: []configv1.HubSource{{Name: "community-operators", Disabled: true}, {Name: "custom-operators", Disabled: true}},
+	})
+	sources, _ = gauges(t)
+	assert.Equal(t, map[string]float64{"redhat-operators": 0, "community-operators": 1, "custom-operators": 1}, sources)
+
+	reconcileSpec(t, r, c, configv1.OperatorHubSpec{
+		DisableAllDefaultSources: true,
+		Sources:                  []configv1.HubSource{{Name: "redhat-operators", Disabled: false}},
+	})
+	sources, disableAll = gauges(t)
+	assert.Equal(t, map[string]float64{"redhat-operators": 0, "community-operators": 1}, sources,
+		"the sources removed from the spec are reset to their default state")
+	assert.Equal(t, 1.0, disableAll)
+
+	reconcileSpec(t, r, c, configv1.OperatorHubSpec{})
+	sources, disableAll = gauges(t)
+	assert.Equal(t, map[string]float64{"redhat-operators": 0, "community-operators": 0}, sources)
+	assert.Equal(t, 0.0, disableAll)
+}
@@ -1,4 +1,95 @@
 package options
 
+import (
+	"context"
+	"time"
+
+	"github.com/operator-framework/operator-marketplace/pkg/controller/backoff"
+	"github.com/operator-framework/operator-marketplace/pkg/status"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+)
+
 type ControllerOptions struct {
+	// EnforceImmutableSpec reverts changes made to the spec of the default
+	// CatalogSources by anyone other than the operator.
+	EnforceImmutableSpec bool
+
+	// Namespace is the namespace the operator is running in.
+	Namespace string
+
+	// Version is the version of the operator.
+	Version string
+
+	// ExposeCatalogsExternally creates an Ingress for the grpc Service of
+	// each default CatalogSource.
+	ExposeCatalogsExternally bool
+
+	// CatalogIngressClass is the class of the catalog Ingresses. The cluster
+	// default class is used if empty.
+	CatalogIngressClass string
+
+	// CatalogIngressDomain, if not empty, is the domain the hosts of the
+	// catalog Ingresses are allocated in.
+	CatalogIngressDomain string
+
+	// MessageTemplateConfigMap, if not empty, is the name of the ConfigMap,
+	// in the operator namespace, overriding the templates of the condition
+	// messages set on the default CatalogSources.
+	MessageTemplateConfigMap string
+
+	// MaxConcurrentReconciles is the number of reconciles each controller
+	// runs concurrently. The controller-runtime default of one is used if it
+	// is zero.
+	MaxConcurrentReconciles int
+
+	// EnableWebhooks registers the admission webhooks of the CatalogSources
+	// with the webhook server of the manager.
+	EnableWebhooks bool
+
+	// OAuthRegistryEndpoints holds the OAuth token endpoint URL of each
+	// registry the default CatalogSources are pulled from with a bearer
+	// token. The tokens are refreshed if it is not empty.
+	OAuthRegistryEndpoints map[string]string
+
+	// OAuthClientSecret is the name of the Secret, in the operator namespace,
+	// holding the OAuth client credentials the tokens are requested with.
+	OAuthClientSecret string
+
+	// FailureReporter, if not nil, is notified of the default CatalogSources
+	// failing to sync, and of their recovery.
+	FailureReporter status.FailureReporter
+
+	// DefaultsRolloutBatchSize, if positive, is the number of default
+	// CatalogSources created, updated or deleted at a time when the
+	// definitions change, waiting for the default CatalogSources to be ready
+	// between each batch.
+	DefaultsRolloutBatchSize int
+
+	// DefaultsRolloutWaitTimeout is the time given to the default
+	// CatalogSources to become ready between the batches of a rollout.
+	DefaultsRolloutWaitTimeout time.Duration
+
+	// DefaultsDir, if not empty, is the directory the default CatalogSources
+	// are read from. It is watched, and ReloadDefaults is called when its
+	// content changes before every default CatalogSource is reconciled.
+	DefaultsDir string
+
+	// ReloadDefaults populates the default CatalogSource definitions again,
+	// keeping the previous ones if the new ones are invalid.
+	ReloadDefaults func(ctx context.Context) error
+
+	// ConditionDecayTimeout, if positive, is the time after which the
+	// failure condition of a CatalogSource is cleared if no new failure was
+	// observed.
+	ConditionDecayTimeout time.Duration
+}
+
+// ControllerRuntimeOptions returns the options the controllers are built
+// with. The requests that fail are retried with a jittered exponential
+// backoff, each controller tracking the failures of its own requests.
+func (o ControllerOptions) ControllerRuntimeOptions() controller.Options {
+	return controller.Options{
+		MaxConcurrentReconciles: o.MaxConcurrentReconciles,
+		RateLimiter:             backoff.NewJitteredRateLimiter(backoff.DefaultBaseDelay, backoff.DefaultMaxDelay, backoff.DefaultJitter),
+	}
 }
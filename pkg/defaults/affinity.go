@@ -0,0 +1,53 @@
+package defaults
+
+import (
+	"reflect"
+	"sync"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	// preferredNodeAffinity is the node affinity added to the pod config of
+	// the default CatalogSources. It is nil if no preference has been set.
+	preferredNodeAffinity *corev1.NodeAffinity
+
+	// affinityLock guards preferredNodeAffinity.
+	affinityLock sync.RWMutex
+)
+
+// SetPreferredNodeAffinity sets the node affinity that will be added to the
+// pod config of the default CatalogSources that do not define an affinity of
+// their own. It returns true if the preference changed.
+func SetPreferredNodeAffinity(affinity *corev1.NodeAffinity) bool {
+	affinityLock.Lock()
+	defer affinityLock.Unlock()
+
+	if reflect.DeepEqual(preferredNodeAffinity, affinity) {
+		return false
+	}
+	preferredNodeAffinity = affinity.DeepCopy()
+	return true
+}
+
+// applyPreferredNodeAffinity adds the preferred node affinity, if any, to the
+// given CatalogSource definition. Definitions that already specify an
+// affinity are left untouched.
+func applyPreferredNodeAffinity(def *olmv1alpha1.CatalogSource) {
+	affinityLock.RLock()
+	defer affinityLock.RUnlock()
+
+	if preferredNodeAffinity == nil {
+		return
+	}
+	if def.Spec.GrpcPodConfig == nil {
+		def.Spec.GrpcPodConfig = &olmv1alpha1.GrpcPodConfig{}
+	}
+	if def.Spec.GrpcPodConfig.Affinity != nil {
+		return
+	}
+	def.Spec.GrpcPodConfig.Affinity = &corev1.Affinity{
+		NodeAffinity: preferredNodeAffinity.DeepCopy(),
+	}
+}
@@ -0,0 +1,58 @@
+package defaults
+
+import (
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func preferring(hostname string) *corev1.NodeAffinity {
+	return &corev1.NodeAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+			Weight: 100,
+			Preference: corev1.NodeSelectorTerm{
+				MatchExpressions: []corev1.NodeSelectorRequirement{{
+					Key:      "kubernetes.io/hostname",
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{hostname},
+				}},
+			},
+		}},
+	}
+}
+
+func TestSetPreferredNodeAffinity(t *testing.T) {
+	t.Cleanup(func() { SetPreferredNodeAffinity(nil) })
+
+	assert.True(t, SetPreferredNodeAffinity(preferring("node-a")))
+	assert.False(t, SetPreferredNodeAffinity(preferring("node-a")), "the same preference is not a change")
+	assert.True(t, SetPreferredNodeAffinity(preferring("node-b")))
+
+	affinity := preferring("node-c")
+	SetPreferredNodeAffinity(affinity)
+	affinity.PreferredDuringSchedulingIgnoredDuringExecution[0].Weight = 1
+	assert.False(t, SetPreferredNodeAffinity(preferring("node-c")), "the preference is copied")
+
+	assert.True(t, SetPreferredNodeAffinity(nil))
+	assert.False(t, SetPreferredNodeAffinity(nil))
+}
+
+func TestApplyPreferredNodeAffinity(t *testing.T) {
+	t.Cleanup(func() { SetPreferredNodeAffinity(nil) })
+
+	def := &olmv1alpha1.CatalogSource{}
+	applyPreferredNodeAffinity(def)
+	assert.Nil(t, def.Spec.GrpcPodConfig, "nothing is applied without a preference")
+
+	SetPreferredNodeAffinity(preferring("node-a"))
+	applyPreferredNodeAffinity(def)
+	assert.Equal(t, &corev1.Affinity{NodeAffinity: preferring("node-a")}, def.Spec.GrpcPodConfig.Affinity)
+
+	own := &olmv1alpha1.CatalogSource{Spec: olmv1alpha1.CatalogSourceSpec{
+		GrpcPodConfig: &olmv1alpha1.GrpcPodConfig{Affinity: &corev1.Affinity{NodeAffinity: preferring("node-b")}},
+	}}
+	applyPreferredNodeAffinity(own)
+	assert.Equal(t, &corev1.Affinity{NodeAffinity: preferring("node-b")}, own.Spec.GrpcPodConfig.Affinity, "the affinity of the definition is kept")
+}
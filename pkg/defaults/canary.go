@@ -0,0 +1,138 @@
+package defaults
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	apiutils "github.com/operator-framework/operator-marketplace/pkg/apis/operators/shared"
+	wrapper "github.com/operator-framework/operator-marketplace/pkg/client"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// CanarySuffix is appended to the name of a default CatalogSource to name
+	// its canary.
+	CanarySuffix = "-canary"
+
+	// CanaryOfLabelKey is the label of the canary CatalogSources, its value is
+	// the name of the default CatalogSource the canary is upgrading.
+	CanaryOfLabelKey = "marketplace.operator.openshift.io/canary-of"
+
+	// CanaryFailedImageAnnotationKey is the annotation of a default
+	// CatalogSource whose upgrade was aborted, its value is the image whose
+	// canary did not become ready. The upgrade to that image is not attempted
+	// again.
+	CanaryFailedImageAnnotationKey = "marketplace.operator.openshift.io/canary-failed-image"
+)
+
+// CanaryTimeout is the time given to the canary of a default CatalogSource to
+// become ready before the upgrade to its image is aborted. The image of the
+// default CatalogSources is upgraded in place if it is zero.
+var CanaryTimeout time.Duration
+
+// CanaryName returns the name of the canary of the given default
+// CatalogSource.
+func CanaryName(name string) string {
+	return name + CanarySuffix
+}
+
+// isCanaryUpgrade returns true if the image of the CatalogSource on the
+// cluster must be upgraded to the one of def through a canary. Only the image
+// applied by the operator is upgraded through a canary, a spec modified by
+// someone else is restored in place.
+func isCanaryUpgrade(def olmv1alpha1.CatalogSource, cluster *olmv1alpha1.CatalogSource) bool {
+	return CanaryTimeout > 0 &&
+		cluster.Spec.Image != "" && def.Spec.Image != cluster.Spec.Image &&
+		cluster.Annotations[SpecHashAnnotationKey] == SpecHash(&cluster.Spec)
+}
+
+// desiredCanary returns the canary serving the image of def. The canary is not
+// labeled as managed by the operator, so that it is not pruned along with the
+// obsolete default CatalogSources.
+func desiredCanary(def olmv1alpha1.CatalogSource) *olmv1alpha1.CatalogSource {
+	labels := apiutils.ManagedLabels("", os.Getenv(ReleaseVersionEnv))
+	labels[CanaryOfLabelKey] = def.Name
+	canary := &olmv1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            CanaryName(def.Name),
+			Namespace:       def.Namespace,
+			Labels:          labels,
+			OwnerReferences: def.OwnerReferences,
+		},
+		Spec: *def.Spec.DeepCopy(),
+	}
+	if canary.Spec.DisplayName != "" {
+		canary.Spec.DisplayName += " (canary)"
+	}
+	return canary
+}
+
+// ensureCanary ensures that the canary serving the image of def is present
+// and returns it once it is ready, so that the CatalogSource on the cluster
+// can be upgraded to that image. A canary that does not become ready within
+// CanaryTimeout is deleted and its image recorded on the CatalogSource on the
+// cluster, which aborts the upgrade.
+func ensureCanary(ctx context.Context, client wrapper.Client, def olmv1alpha1.CatalogSource, cluster *olmv1alpha1.CatalogSource) (*olmv1alpha1.CatalogSource, error) {
+	if cluster.Annotations[CanaryFailedImageAnnotationKey] == def.Spec.Image {
+		logging.FromContext(ctx).Infof("[defaults] The upgrade of CatalogSource %s to image %s was aborted, its canary did not become ready", def.Name, def.Spec.Image)
+		return nil, nil
+	}
+
+	desired := desiredCanary(def)
+	canary := &olmv1alpha1.CatalogSource{}
+	err := client.Get(ctx, wrapper.ObjectKey{Name: desired.Name, Namespace: desired.Namespace}, canary)
+	if k8sErrors.IsNotFound(err) {
+		if err := client.Create(ctx, desired); err != nil {
+			return nil, err
+		}
+		logging.FromContext(ctx).Infof("[defaults] Creating canary CatalogSource %s to upgrade CatalogSource %s to image %s", desired.Name, def.Name, def.Spec.Image)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if canary.Labels[CanaryOfLabelKey] != def.Name {
+		return nil, fmt.Errorf("CatalogSource %s is not the canary of CatalogSource %s", canary.Name, def.Name)
+	}
+	if !canary.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	if canary.Spec.Image != def.Spec.Image {
+		// The upgrade is restarted with a canary of the new image.
+		logging.FromContext(ctx).Infof("[defaults] Deleting canary CatalogSource %s of outdated image %s", canary.Name, canary.Spec.Image)
+		return nil, deleteCanary(ctx, client, canary)
+	}
+	if isCatsrcReady(canary) {
+		return canary, nil
+	}
+	if time.Since(canary.CreationTimestamp.Time) < CanaryTimeout {
+		logging.FromContext(ctx).Infof("[defaults] Waiting for canary CatalogSource %s to become ready", canary.Name)
+		return nil, nil
+	}
+
+	logging.FromContext(ctx).Warnf("[defaults] Canary CatalogSource %s did not become ready within %s, aborting the upgrade of CatalogSource %s to image %s",
+		canary.Name, CanaryTimeout, def.Name, def.Spec.Image)
+	if cluster.Annotations == nil {
+		cluster.Annotations = make(map[string]string)
+	}
+	cluster.Annotations[CanaryFailedImageAnnotationKey] = def.Spec.Image
+	if err := client.Update(ctx, cluster); err != nil {
+		return nil, err
+	}
+	metrics.IncCatalogSourceCanaries(def.Name, metrics.CanaryFailed)
+	return nil, deleteCanary(ctx, client, canary)
+}
+
+// deleteCanary deletes the given canary, if it still exists.
+func deleteCanary(ctx context.Context, client wrapper.Client, canary *olmv1alpha1.CatalogSource) error {
+	if err := client.Delete(ctx, canary); err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
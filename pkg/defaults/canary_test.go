@@ -0,0 +1,108 @@
+package defaults
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	wrapper "github.com/operator-framework/operator-marketplace/pkg/client"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// appliedCatsrc returns the CatalogSource the operator applied for def, served
+// from the given image.
+func appliedCatsrc(def olmv1alpha1.CatalogSource, image string) *olmv1alpha1.CatalogSource {
+	def.Spec.Image = image
+	catsrc := desiredCatsrc(def)
+	return &catsrc
+}
+
+// objectKey returns the key of the CatalogSource with the given name.
+func objectKey(name string) wrapper.ObjectKey {
+	return wrapper.ObjectKey{Namespace: "openshift-marketplace", Name: name}
+}
+
+// image returns the image of the CatalogSource with the given name.
+func image(t *testing.T, c wrapper.Client, name string) string {
+	t.Helper()
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), objectKey(name), catsrc))
+	return catsrc.Spec.Image
+}
+
+func TestEnsureCanaryUpgrade(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+	previous := CanaryTimeout
+	CanaryTimeout = time.Minute
+	t.Cleanup(func() { CanaryTimeout = previous })
+
+	def := *managedCatsrc("redhat-operators")
+	def.Spec = olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: "quay.io/example/redhat-operators:v2"}
+	c := newFakeClient(t, appliedCatsrc(def, "quay.io/example/redhat-operators:v1"))
+	config := map[string]bool{"redhat-operators": false}
+
+	// A canary is created, the CatalogSource keeps serving the previous image.
+	require.NoError(t, ensureCatsrc(context.TODO(), c, config, def))
+	canary := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), objectKey(CanaryName("redhat-operators")), canary))
+	assert.Equal(t, "redhat-operators", canary.Labels[CanaryOfLabelKey])
+	assert.Equal(t, def.Spec.Image, canary.Spec.Image)
+	assert.Equal(t, "quay.io/example/redhat-operators:v1", image(t, c, "redhat-operators"))
+	// The fake client does not set the creation timestamp the API server sets.
+	canary.CreationTimestamp = metav1.Now()
+	require.NoError(t, c.Update(context.TODO(), canary))
+
+	// The CatalogSource is not upgraded until the canary is ready.
+	require.NoError(t, ensureCatsrc(context.TODO(), c, config, def))
+	assert.Equal(t, "quay.io/example/redhat-operators:v1", image(t, c, "redhat-operators"))
+
+	// The CatalogSource is upgraded once the canary is ready, which is then
+	// deleted.
+	require.NoError(t, c.Get(context.TODO(), objectKey(CanaryName("redhat-operators")), canary))
+	canary.Status.GRPCConnectionState = &olmv1alpha1.GRPCConnectionState{LastObservedState: grpcReadyState}
+	require.NoError(t, c.Update(context.TODO(), canary))
+	require.NoError(t, ensureCatsrc(context.TODO(), c, config, def))
+	assert.Equal(t, def.Spec.Image, image(t, c, "redhat-operators"))
+	assert.False(t, exists(t, c, CanaryName("redhat-operators")))
+}
+
+func TestEnsureCanaryTimeout(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+	previous := CanaryTimeout
+	CanaryTimeout = time.Minute
+	t.Cleanup(func() { CanaryTimeout = previous })
+
+	def := *managedCatsrc("redhat-operators")
+	def.Spec = olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: "quay.io/example/redhat-operators:v2"}
+	canary := desiredCanary(def)
+	canary.CreationTimestamp = metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	c := newFakeClient(t, appliedCatsrc(def, "quay.io/example/redhat-operators:v1"), canary)
+	config := map[string]bool{"redhat-operators": false}
+
+	// The upgrade is aborted once the canary timed out.
+	require.NoError(t, ensureCatsrc(context.TODO(), c, config, def))
+	assert.False(t, exists(t, c, CanaryName("redhat-operators")))
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), objectKey("redhat-operators"), catsrc))
+	assert.Equal(t, "quay.io/example/redhat-operators:v1", catsrc.Spec.Image)
+	assert.Equal(t, def.Spec.Image, catsrc.Annotations[CanaryFailedImageAnnotationKey])
+
+	// The upgrade to the same image is not attempted again.
+	require.NoError(t, ensureCatsrc(context.TODO(), c, config, def))
+	assert.False(t, exists(t, c, CanaryName("redhat-operators")))
+	assert.Equal(t, "quay.io/example/redhat-operators:v1", image(t, c, "redhat-operators"))
+
+	// A CatalogSource whose spec was modified by someone else is restored in
+	// place, which clears the aborted upgrade.
+	require.NoError(t, c.Get(context.TODO(), objectKey("redhat-operators"), catsrc))
+	catsrc.Spec.Image = "quay.io/example/other:latest"
+	require.NoError(t, c.Update(context.TODO(), catsrc))
+	require.NoError(t, ensureCatsrc(context.TODO(), c, config, def))
+	require.NoError(t, c.Get(context.TODO(), objectKey("redhat-operators"), catsrc))
+	assert.Equal(t, def.Spec.Image, catsrc.Spec.Image)
+	assert.NotContains(t, catsrc.Annotations, CanaryFailedImageAnnotationKey)
+}
@@ -1,17 +1,23 @@
 package defaults
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"os"
-	"path/filepath"
 	"reflect"
 	"strings"
 
 	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	apiutils "github.com/operator-framework/operator-marketplace/pkg/apis/operators/shared"
 	wrapper "github.com/operator-framework/operator-marketplace/pkg/client"
-	"github.com/sirupsen/logrus"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
@@ -34,25 +40,44 @@ func ensureCatsrc(
 	return nil
 }
 
-// getCatsrcDefinition returns a CatalogSource definition from the given file
-// in the defaults directory. It only supports decoding CatalogSources. Any
-// other resource type will result in an error.
-func getCatsrcDefinition(fileName string) (*olmv1alpha1.CatalogSource, error) {
-	file, err := os.Open(filepath.Join(Dir, fileName))
+// decodeCatsrcDefinition returns the CatalogSource definition in content, in
+// YAML or JSON, along with the names of the CatalogSources it depends on. The
+// definition is decoded by the parser of its SchemaVersionField, an unknown
+// version resulting in an error.
+func decodeCatsrcDefinition(content []byte) (*olmv1alpha1.CatalogSource, []string, error) {
+	version, err := SchemaVersion(content)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	parse, ok := definitionParsers[version]
+	if !ok {
+		return nil, nil, &UnsupportedSchemaVersionError{Version: version}
+	}
+	return parse(content)
+}
 
+// decodeV1Definition decodes a definition of SchemaVersionV1: a CatalogSource
+// manifest along with the optional dependsOn field. It only supports decoding
+// CatalogSources. Any other resource type will result in an error.
+func decodeV1Definition(content []byte) (*olmv1alpha1.CatalogSource, []string, error) {
 	catsrc := &olmv1alpha1.CatalogSource{}
-	decoder := yaml.NewYAMLOrJSONDecoder(file, 1024)
-	err = decoder.Decode(catsrc)
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 1024)
+	err := decoder.Decode(catsrc)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if strings.Compare(catsrc.Kind, "CatalogSource") != 0 {
-		return nil, errors.New("Not an CatalogSource")
+		return nil, nil, errors.New("Not an CatalogSource")
+	}
+
+	// The dependencies are declared next to the CatalogSource fields, which
+	// the CatalogSource type does not know about.
+	deps := &dependencies{}
+	decoder = yaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 1024)
+	if err := decoder.Decode(deps); err != nil {
+		return nil, nil, err
 	}
-	return catsrc, nil
+	return catsrc, deps.DependsOn, nil
 }
 
 // processCatsrc will ensure that the given CatalogSource is present or not on
@@ -64,8 +89,9 @@ func processCatsrc(ctx context.Context, client wrapper.Client, def olmv1alpha1.C
 		Name:      def.Name,
 		Namespace: def.Namespace,
 	}, cluster); err != nil && !k8sErrors.IsNotFound(err) {
-		logrus.Errorf("[defaults] Error getting CatalogSource %s - %v", def.Name, err)
-		return err
+		logging.FromContext(ctx).Errorf("[defaults] Error getting CatalogSource %s - %v", def.Name, err)
+		recordCatsrcStatus(def.Name, metrics.DefaultCatalogSourceFailed)
+		return &DefaultsError{Kind: APIError, Cause: err}
 	}
 
 	var err error
@@ -77,8 +103,18 @@ func processCatsrc(ctx context.Context, client wrapper.Client, def olmv1alpha1.C
 		err = ensureCatsrcPresent(ctx, client, def, cluster)
 	}
 
-	if err != nil {
-		logrus.Errorf("[defaults] Error processing CatalogSource %s - %v", def.Name, err)
+	switch {
+	case err != nil:
+		logging.FromContext(ctx).Errorf("[defaults] Error processing CatalogSource %s - %v", def.Name, err)
+		recordCatsrcStatus(def.Name, metrics.DefaultCatalogSourceFailed)
+		err = &DefaultsError{Kind: APIError, Cause: err}
+	case disable:
+		recordCatsrcStatus(def.Name, metrics.DefaultCatalogSourceDeleted)
+		metrics.DeleteDefaultCatalogSourceReady(def.Name)
+	default:
+		recordCatsrcStatus(def.Name, metrics.DefaultCatalogSourceApplied)
+		// A CatalogSource that was just created is not ready yet.
+		metrics.SetDefaultCatalogSourceReady(def.Name, isCatsrcReady(cluster))
 	}
 
 	return err
@@ -93,14 +129,14 @@ func ensureCatsrcAbsent(
 ) error {
 	// CatalogSource is not present on the cluster or has been marked for deletion
 	if cluster.Name == "" || !cluster.ObjectMeta.DeletionTimestamp.IsZero() {
-		logrus.Infof("[defaults] CatalogSource %s not present or has been marked for deletion", def.Name)
+		logging.FromContext(ctx).Infof("[defaults] CatalogSource %s not present or has been marked for deletion", def.Name)
 		return nil
 	}
 
 	if err := client.Delete(ctx, cluster); err != nil {
 		return err
 	}
-	logrus.Infof("[defaults] Deleting CatalogSource %s", def.Name)
+	logging.FromContext(ctx).Infof("[defaults] Deleting CatalogSource %s", def.Name)
 
 	return nil
 }
@@ -112,43 +148,154 @@ func ensureCatsrcPresent(
 	def olmv1alpha1.CatalogSource,
 	cluster *olmv1alpha1.CatalogSource,
 ) error {
-	def = *def.DeepCopy()
-	if def.Annotations == nil {
-		def.Annotations = make(map[string]string)
-	}
-	def.Annotations[defaultCatsrcAnnotationKey] = defaultCatsrcAnnotationValue
+	def = desiredCatsrc(def)
 
 	// Create if not present or is deleted
 	if cluster.Name == "" || (!cluster.ObjectMeta.DeletionTimestamp.IsZero() && len(cluster.Finalizers) == 0) {
+		// The CatalogSource is recreated if it was deleted after it was
+		// applied, or is being deleted.
+		recreated := cluster.Name != "" || catsrcStatus(def.Name) == metrics.DefaultCatalogSourceApplied
 		err := client.Create(ctx, &def)
 		if err != nil {
 			return err
 		}
-		logrus.Infof("[defaults] Creating CatalogSource %s", def.Name)
+		logging.FromContext(ctx).Infof("[defaults] Creating CatalogSource %s", def.Name)
+		if recreated {
+			metrics.IncDefaultCatalogSourceRecreations(def.Name)
+		}
 		return nil
 	}
 
-	if cluster.Annotations[defaultCatsrcAnnotationKey] == defaultCatsrcAnnotationValue && AreCatsrcSpecsEqual(&def.Spec, &cluster.Spec) {
-		logrus.Infof("[defaults] CatalogSource %s is annotated and its spec is the same as the default spec", def.Name)
+	if cluster.Annotations[defaultCatsrcAnnotationKey] == defaultCatsrcAnnotationValue &&
+		hasLabels(cluster.Labels, managedLabels()) && AreCatsrcSpecsEqual(&def.Spec, &cluster.Spec) &&
+		cluster.Annotations[SpecHashAnnotationKey] == def.Annotations[SpecHashAnnotationKey] &&
+		len(mergeOwnerReferences(cluster.OwnerReferences, def.OwnerReferences)) == len(cluster.OwnerReferences) {
+		logging.FromContext(ctx).Infof("[defaults] CatalogSource %s is annotated and its spec is the same as the default spec", def.Name)
 		return nil
 	}
 
+	// The image applied by the operator is only upgraded once the canary of
+	// the new image is ready.
+	var canary *olmv1alpha1.CatalogSource
+	if isCanaryUpgrade(def, cluster) {
+		var err error
+		if canary, err = ensureCanary(ctx, client, def, cluster); err != nil || canary == nil {
+			return err
+		}
+	}
+
 	// Update if the spec has changed
 	cluster.Spec = def.Spec
 	if cluster.Annotations == nil {
 		cluster.Annotations = make(map[string]string)
 	}
 	cluster.Annotations[defaultCatsrcAnnotationKey] = defaultCatsrcAnnotationValue
+	cluster.Annotations[SpecHashAnnotationKey] = def.Annotations[SpecHashAnnotationKey]
+	delete(cluster.Annotations, CanaryFailedImageAnnotationKey)
+	if cluster.Labels == nil {
+		cluster.Labels = make(map[string]string)
+	}
+	for key, value := range managedLabels() {
+		cluster.Labels[key] = value
+	}
+	cluster.OwnerReferences = mergeOwnerReferences(cluster.OwnerReferences, def.OwnerReferences)
 	err := client.Update(ctx, cluster)
 	if err != nil {
 		return err
 	}
 
-	logrus.Infof("[defaults] Restoring CatalogSource %s", def.Name)
+	logging.FromContext(ctx).Infof("[defaults] Restoring CatalogSource %s", def.Name)
+	if canary != nil {
+		logging.FromContext(ctx).Infof("[defaults] Upgraded CatalogSource %s to image %s of its ready canary", def.Name, def.Spec.Image)
+		metrics.IncCatalogSourceCanaries(def.Name, metrics.CanarySucceeded)
+		if err := deleteCanary(ctx, client, canary); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+// isCatsrcReady returns true if OLM last observed the connection to the
+// registry of the CatalogSource as ready.
+func isCatsrcReady(catsrc *olmv1alpha1.CatalogSource) bool {
+	state := catsrc.Status.GRPCConnectionState
+	return state != nil && state.LastObservedState == grpcReadyState
+}
+
+// desiredCatsrc returns a copy of the given default CatalogSource definition
+// as the operator applies it on the cluster.
+func desiredCatsrc(def olmv1alpha1.CatalogSource) olmv1alpha1.CatalogSource {
+	def = *def.DeepCopy()
+	applyPreferredNodeAffinity(&def)
+	addRegistrySecret(&def)
+	if def.Annotations == nil {
+		def.Annotations = make(map[string]string)
+	}
+	def.Annotations[defaultCatsrcAnnotationKey] = defaultCatsrcAnnotationValue
+	def.Annotations[SpecHashAnnotationKey] = SpecHash(&def.Spec)
+	if def.Labels == nil {
+		def.Labels = make(map[string]string)
+	}
+	for key, value := range managedLabels() {
+		def.Labels[key] = value
+	}
+	return def
+}
+
+// managedLabels returns the labels the operator sets on the default
+// CatalogSources it applies.
+func managedLabels() map[string]string {
+	return apiutils.ManagedLabels(ManagedByLabelValue, os.Getenv(ReleaseVersionEnv))
+}
+
+// hasLabels returns true if labels holds every label of expected.
+func hasLabels(labels, expected map[string]string) bool {
+	for key, value := range expected {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// SpecHash returns the hash of the given CatalogSource spec, as recorded in
+// the SpecHashAnnotationKey annotation of the default CatalogSources.
+func SpecHash(spec *olmv1alpha1.CatalogSourceSpec) string {
+	// A CatalogSourceSpec always marshals.
+	content, _ := json.Marshal(spec)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// IsSpecModified returns true if the spec of the CatalogSource on the cluster
+// no longer matches the hash recorded by the operator instance that last
+// applied it, which means it was modified by someone else. A CatalogSource the
+// operator never applied a spec hash on is not reported as modified.
+func IsSpecModified(catsrc *olmv1alpha1.CatalogSource) bool {
+	recorded, ok := catsrc.Annotations[SpecHashAnnotationKey]
+	return ok && recorded != SpecHash(&catsrc.Spec)
+}
+
+// mergeOwnerReferences returns existing along with the references of owners
+// whose UID is not already in existing.
+func mergeOwnerReferences(existing, owners []metav1.OwnerReference) []metav1.OwnerReference {
+	merged := append([]metav1.OwnerReference(nil), existing...)
+	for _, owner := range owners {
+		found := false
+		for _, ref := range existing {
+			if ref.UID == owner.UID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, owner)
+		}
+	}
+	return merged
+}
+
 // AreCatsrcSpecsEqual returns true if the Specs it receives are the same.
 // Otherwise, the function returns false.
 //
@@ -0,0 +1,79 @@
+package defaults
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigMap is the namespace/name of the ConfigMap holding the default
+// CatalogSource definitions, each data key being a CatalogSource manifest. It
+// will be empty if the definitions are read from Dir.
+var ConfigMap string
+
+// PopulateGlobalsFromConfigMap populates the global definitions and default
+// config from the ConfigMap, read through reader, with the given
+// namespace/name key. The ConfigMap and Dir are never combined: Dir is only
+// used if the ConfigMap does not exist. The error returned, if any, is a
+// *DefaultsError.
+func PopulateGlobalsFromConfigMap(ctx context.Context, reader client.Reader, key string, opts ...Option) (PopulationResult, error) {
+	namespace, name, ok := strings.Cut(key, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		err := &DefaultsError{Kind: ValidationError, Path: key, Cause: fmt.Errorf("the defaults ConfigMap must be given as namespace/name")}
+		return PopulationResult{Failed: []string{key}}, err
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, configMap)
+	if k8sErrors.IsNotFound(err) {
+		logrus.Warnf("[defaults] ConfigMap %s not found, reading the default CatalogSources from %q", key, Dir)
+		return PopulateGlobals(opts...)
+	} else if err != nil {
+		return PopulationResult{Failed: []string{key}}, &DefaultsError{Kind: APIError, Path: key, Cause: err}
+	}
+
+	if Dir != "" {
+		logrus.Infof("[defaults] Reading the default CatalogSources from ConfigMap %s, %s is ignored", key, Dir)
+	}
+	catsrcDefinitions, config, deps, err := populateDefsConfigFromConfigMap(ctx, configMap, newPopulateOptions(opts))
+	return setGlobals(key, catsrcDefinitions, config, deps, err)
+}
+
+// populateDefsConfigFromConfigMap returns populated CatalogSource definitions
+// from the data of configMap, an enabled config and the dependencies of each
+// CatalogSource, like populateDefsConfig does for a directory.
+func populateDefsConfigFromConfigMap(ctx context.Context, configMap *corev1.ConfigMap, options populateOptions) (map[string]olmv1alpha1.CatalogSource, map[string]bool, map[string][]string, error) {
+	keys := make([]string, 0, len(configMap.Data))
+	for key := range configMap.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	source := configMap.Namespace + "/" + configMap.Name
+	manifests := make([]Manifest, 0, len(keys))
+	for _, key := range keys {
+		manifests = append(manifests, Manifest{
+			Path:    fmt.Sprintf("%s[%s]", source, key),
+			Content: []byte(configMap.Data[key]),
+			Source:  source,
+		})
+	}
+	return defsConfigFromManifests(ctx, source, manifests, options)
+}
+
+// DefinitionsFromConfigMap returns the CatalogSource definitions of the data of
+// configMap, each data key being a CatalogSource manifest validated and
+// patched like the ones of the defaults ConfigMap. The images are not
+// overridden by the environment, which only applies to the default
+// CatalogSources. The error returned, if any, is a *DefaultsError.
+func DefinitionsFromConfigMap(configMap *corev1.ConfigMap) (map[string]olmv1alpha1.CatalogSource, error) {
+	catsrcDefinitions, _, _, err := populateDefsConfigFromConfigMap(context.Background(), configMap, newPopulateOptions([]Option{WithImageOverrides(nil)}))
+	return catsrcDefinitions, err
+}
@@ -0,0 +1,87 @@
+package defaults
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newConfigMapReader returns a reader backed by an in-memory cluster
+// containing a defaults ConfigMap with a manifest for each name.
+func newConfigMapReader(t *testing.T, names ...string) client.Reader {
+	t.Helper()
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "marketplace-defaults", Namespace: "openshift-marketplace"},
+		Data:       make(map[string]string),
+	}
+	for _, name := range names {
+		configMap.Data[name+".yaml"] = fmt.Sprintf(catsrcManifest, name, name)
+	}
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build()
+}
+
+func TestPopulateGlobalsFromConfigMap(t *testing.T) {
+	writeManifests(t, "redhat-operators")
+	t.Cleanup(func() {
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	reader := newConfigMapReader(t, "mirrored-operators", "community-operators")
+
+	_, err := PopulateGlobalsFromConfigMap(context.TODO(), reader, "openshift-marketplace/marketplace-defaults")
+	require.NoError(t, err)
+	definitions := GetGlobalCatalogSourceDefinitions()
+	assert.Len(t, definitions, 2, "the defaults directory is not combined with the ConfigMap")
+	assert.Equal(t, "quay.io/example/mirrored-operators:latest", definitions["mirrored-operators"].Spec.Image)
+	assert.Contains(t, definitions, "community-operators")
+	assert.Equal(t, map[string]bool{"mirrored-operators": false, "community-operators": false}, GetDefaultConfig())
+
+	// The defaults directory is only used if the ConfigMap does not exist.
+	_, err = PopulateGlobalsFromConfigMap(context.TODO(), reader, "openshift-marketplace/missing")
+	require.NoError(t, err)
+	definitions = GetGlobalCatalogSourceDefinitions()
+	assert.Len(t, definitions, 1)
+	assert.Contains(t, definitions, "redhat-operators")
+}
+
+func TestPopulateGlobalsFromConfigMapErrors(t *testing.T) {
+	writeManifests(t)
+	t.Cleanup(func() {
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+
+	for _, key := range []string{"marketplace-defaults", "/marketplace-defaults", "openshift-marketplace/", "a/b/c"} {
+		_, err := PopulateGlobalsFromConfigMap(context.TODO(), newConfigMapReader(t), key)
+		requireDefaultsError(t, err, ValidationError, key)
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	invalid := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "marketplace-defaults", Namespace: "openshift-marketplace"},
+		Data: map[string]string{
+			"redhat-operators.yaml": fmt.Sprintf(catsrcManifest, "redhat-operators", "redhat-operators"),
+			"malformed.yaml":        "kind: [CatalogSource",
+		},
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(invalid).Build()
+	_, err := PopulateGlobalsFromConfigMap(context.TODO(), reader, "openshift-marketplace/marketplace-defaults")
+	requireDefaultsError(t, err, ValidationError, "openshift-marketplace/marketplace-defaults[malformed.yaml]")
+	assert.Empty(t, GetGlobalCatalogSourceDefinitions())
+
+	// A failure to get the ConfigMap does not fall back to the defaults
+	// directory.
+	_, err = PopulateGlobalsFromConfigMap(context.TODO(), fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build(), "openshift-marketplace/marketplace-defaults")
+	requireDefaultsError(t, err, APIError, "openshift-marketplace/marketplace-defaults")
+}
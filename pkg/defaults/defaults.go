@@ -2,11 +2,18 @@ package defaults
 
 import (
 	"context"
-	"io/ioutil"
-	"os"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
 
 	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
 	wrapper "github.com/operator-framework/operator-marketplace/pkg/client"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var (
@@ -26,11 +33,44 @@ var (
 	// The default is for all the CatalogSources in the globalDefinitions to be
 	// enabled.
 	defaultConfig = make(map[string]bool)
+
+	// globalsLock guards globalCatsrcDefinitions, defaultConfig and
+	// catsrcDependencies, which are replaced when the defaults are reloaded
+	// while the controllers read them.
+	globalsLock sync.RWMutex
+
+	// catsrcStatuses is used to keep track of the status of each default
+	// CatalogSource after it was last processed. It is used to report the
+	// number of default CatalogSources per status.
+	catsrcStatuses = make(map[string]string)
+
+	// catsrcStatusesLock guards catsrcStatuses.
+	catsrcStatusesLock sync.Mutex
+
+	// populatedHooks are called each time the global definitions are set,
+	// keyed by the ID they were registered with.
+	populatedHooks = make(map[int]func())
+
+	// populatedHooksID is the ID of the next hook registered.
+	populatedHooksID int
+
+	// populatedHooksLock guards populatedHooks and populatedHooksID.
+	populatedHooksLock sync.Mutex
 )
 
 const (
 	defaultCatsrcAnnotationKey   string = "operatorframework.io/managed-by"
 	defaultCatsrcAnnotationValue string = "marketplace-operator"
+
+	// SpecHashAnnotationKey is the annotation holding the hash of the spec
+	// the operator last applied on a default CatalogSource. The operator only
+	// writes it along with the spec, so a spec that no longer matches its hash
+	// was modified by someone else.
+	SpecHashAnnotationKey = "marketplace.operator.openshift.io/spec-hash"
+
+	// grpcReadyState is the state OLM reports for the connection to the
+	// registry of a CatalogSource that is ready.
+	grpcReadyState = "READY"
 )
 
 // Defaults is the interface that can be used to ensure the default set
@@ -73,7 +113,7 @@ func (d *defaults) Ensure(ctx context.Context, client wrapper.Client, sourceName
 // or absent on the cluster based on the config.
 func (d *defaults) EnsureAll(ctx context.Context, client wrapper.Client) map[string]error {
 	result := make(map[string]error)
-	for name := range d.config {
+	for _, name := range d.ensureOrder() {
 		err := d.Ensure(ctx, client, name)
 		if err != nil {
 			result[name] = err
@@ -82,70 +122,303 @@ func (d *defaults) EnsureAll(ctx context.Context, client wrapper.Client) map[str
 	return result
 }
 
-// GetGlobals returns the global CatalogSource definitions and the
-// default config
+// ensureOrder returns the names of the CatalogSources in the config, ordered
+// so that CatalogSources are processed after the ones they depend on.
+func (d *defaults) ensureOrder() []string {
+	var sources []*olmv1alpha1.CatalogSource
+	var names []string
+	for name := range d.config {
+		names = append(names, name)
+		if catsrc, present := d.catsrcDefinitions[name]; present {
+			sources = append(sources, &catsrc)
+		}
+	}
+
+	sorted, err := TopoSort(sources)
+	if err != nil {
+		// PopulateGlobals rejects cyclic dependencies, so this is only
+		// possible with definitions that did not come from disk.
+		logrus.Warnf("[defaults] Ignoring the dependencies of the default CatalogSources - %v", err)
+		return names
+	}
+
+	// Names in the config without a definition are still ensured, which is
+	// a no-op.
+	ordered := make([]string, 0, len(names))
+	for _, catsrc := range sorted {
+		ordered = append(ordered, catsrc.Name)
+	}
+	for _, name := range names {
+		if _, present := d.catsrcDefinitions[name]; !present {
+			ordered = append(ordered, name)
+		}
+	}
+	return ordered
+}
+
+// GetGlobals returns a copy of the global CatalogSource definitions and of
+// the default config
 func GetGlobals() (map[string]olmv1alpha1.CatalogSource, map[string]bool) {
-	return globalCatsrcDefinitions, defaultConfig
+	globalsLock.RLock()
+	defer globalsLock.RUnlock()
+	return copyDefinitions(globalCatsrcDefinitions), maps.Clone(defaultConfig)
 }
 
-// GetGlobalCatalogSourceDefinitions returns the global CatalogSource definitions
+// GetGlobalCatalogSourceDefinitions returns a copy of the global CatalogSource
+// definitions
 func GetGlobalCatalogSourceDefinitions() map[string]olmv1alpha1.CatalogSource {
-	return globalCatsrcDefinitions
+	globalsLock.RLock()
+	defer globalsLock.RUnlock()
+	return copyDefinitions(globalCatsrcDefinitions)
 }
 
-// GetDefaultConfig returns the global OperatorHub configuration
+// GetDefaultConfig returns a copy of the global OperatorHub configuration
 func GetDefaultConfig() map[string]bool {
-	return defaultConfig
+	globalsLock.RLock()
+	defer globalsLock.RUnlock()
+	return maps.Clone(defaultConfig)
+}
+
+// copyDefinitions returns a deep copy of catsrcDefinitions, so that the
+// callers can neither observe nor make changes to the global definitions.
+func copyDefinitions(catsrcDefinitions map[string]olmv1alpha1.CatalogSource) map[string]olmv1alpha1.CatalogSource {
+	copied := make(map[string]olmv1alpha1.CatalogSource, len(catsrcDefinitions))
+	for name, def := range catsrcDefinitions {
+		copied[name] = *def.DeepCopy()
+	}
+	return copied
+}
+
+// GetDesiredCatalogSource returns the given default CatalogSource as the
+// operator applies it on the cluster, and false if it is not a default.
+func GetDesiredCatalogSource(name string) (olmv1alpha1.CatalogSource, bool) {
+	globalsLock.RLock()
+	def, present := globalCatsrcDefinitions[name]
+	globalsLock.RUnlock()
+	if !present {
+		return olmv1alpha1.CatalogSource{}, false
+	}
+	return desiredCatsrc(def), true
+}
+
+// GetDefinitionHashes returns a hash of each global CatalogSource definition,
+// keyed by CatalogSource name, so that changes to the definitions can be
+// detected across restarts.
+func GetDefinitionHashes() (map[string]string, error) {
+	globalsLock.RLock()
+	defer globalsLock.RUnlock()
+	hashes := make(map[string]string, len(globalCatsrcDefinitions))
+	for name, def := range globalCatsrcDefinitions {
+		content, err := json.Marshal(def)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+		hashes[name] = hex.EncodeToString(sum[:])
+	}
+	return hashes, nil
 }
 
 // IsDefaultSource returns true if the given name is one of the default
 // CatalogSources
 func IsDefaultSource(name string) bool {
+	globalsLock.RLock()
+	defer globalsLock.RUnlock()
 	_, present := defaultConfig[name]
 	return present
-
 }
 
 // PopulateGlobals populates the global definitions and default config. If Dir
 // is blank, the global definitions and config will be initialized but empty.
-func PopulateGlobals() error {
-	var err error
-	globalCatsrcDefinitions, defaultConfig, err = populateDefsConfig(Dir)
-	return err
+// The result reports how the definitions changed since they were last
+// populated. The error returned, if any, is a *DefaultsError.
+func PopulateGlobals(opts ...Option) (PopulationResult, error) {
+	catsrcDefinitions, config, deps, err := populateDefsConfig(Dir, newPopulateOptions(opts))
+	return setGlobals(Dir, catsrcDefinitions, config, deps, err)
 }
 
-// populateDefsConfig returns populated CatalogSource definitions from files present
-// in the @dir directory and an enabled config. It returns error on the first
-// issue it runs into. The function also guarantees to return an empty map on error.
-func populateDefsConfig(dir string) (map[string]olmv1alpha1.CatalogSource, map[string]bool, error) {
-	catsrcDefinitions := make(map[string]olmv1alpha1.CatalogSource)
-	config := make(map[string]bool)
-	// Default directory has not been specified
-	if dir == "" {
-		return catsrcDefinitions, config, nil
+// setGlobals sets the global definitions and default config to the ones
+// populated from source, or empties them if err is not nil or the
+// dependencies of the definitions are cyclic, and reports the result of the
+// population.
+func setGlobals(source string, catsrcDefinitions map[string]olmv1alpha1.CatalogSource, config map[string]bool, deps map[string][]string, err error) (PopulationResult, error) {
+	if err == nil {
+		err = checkDependencies(source, catsrcDefinitions, deps)
 	}
-
-	_, err := os.Stat(Dir)
 	if err != nil {
-		return catsrcDefinitions, config, err
+		catsrcDefinitions = make(map[string]olmv1alpha1.CatalogSource)
+		config = make(map[string]bool)
+		deps = make(map[string][]string)
+	}
+
+	globalsLock.Lock()
+	previous := globalCatsrcDefinitions
+	globalCatsrcDefinitions, defaultConfig, catsrcDependencies = catsrcDefinitions, config, deps
+	globalsLock.Unlock()
+	resetCatsrcStatuses()
+
+	if err == nil {
+		logImages(catsrcDefinitions)
+	}
+	result := newPopulationResult(previous, catsrcDefinitions, err)
+	metrics.SetDefaultCatalogSourcePopulation(len(result.Created), len(result.Updated), len(result.Skipped), len(result.Failed))
+	runPopulatedHooks()
+	return result, err
+}
+
+// OnPopulated registers hook to be called each time the global definitions
+// are set, by PopulateGlobals or when they are reloaded. The hook is called
+// once the new definitions can be read, and must not block. The function
+// returned unregisters the hook.
+func OnPopulated(hook func()) func() {
+	populatedHooksLock.Lock()
+	defer populatedHooksLock.Unlock()
+	id := populatedHooksID
+	populatedHooksID++
+	populatedHooks[id] = hook
+	return func() {
+		populatedHooksLock.Lock()
+		defer populatedHooksLock.Unlock()
+		delete(populatedHooks, id)
+	}
+}
+
+// runPopulatedHooks calls the hooks registered with OnPopulated.
+func runPopulatedHooks() {
+	populatedHooksLock.Lock()
+	hooks := make([]func(), 0, len(populatedHooks))
+	for _, hook := range populatedHooks {
+		hooks = append(hooks, hook)
+	}
+	populatedHooksLock.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// checkDependencies returns a ValidationError against source if the
+// dependencies deps of the definitions are cyclic.
+func checkDependencies(source string, catsrcDefinitions map[string]olmv1alpha1.CatalogSource, deps map[string][]string) error {
+	sources := make([]*olmv1alpha1.CatalogSource, 0, len(catsrcDefinitions))
+	for name := range catsrcDefinitions {
+		catsrc := catsrcDefinitions[name]
+		sources = append(sources, &catsrc)
+	}
+	if _, err := topoSort(sources, deps); err != nil {
+		return &DefaultsError{Kind: ValidationError, Path: source, Cause: err}
+	}
+	return nil
+}
+
+// recordCatsrcStatus records the status of the given default CatalogSource
+// and updates the number of default CatalogSources per status.
+func recordCatsrcStatus(name, status string) {
+	catsrcStatusesLock.Lock()
+	defer catsrcStatusesLock.Unlock()
+
+	catsrcStatuses[name] = status
+	reportCatsrcStatusesLocked()
+}
+
+// catsrcStatus returns the status of the given default CatalogSource after it
+// was last processed, or an empty string if it was not processed since the
+// globals were populated.
+func catsrcStatus(name string) string {
+	catsrcStatusesLock.Lock()
+	defer catsrcStatusesLock.Unlock()
+
+	return catsrcStatuses[name]
+}
+
+// resetCatsrcStatuses forgets the status of every default CatalogSource.
+func resetCatsrcStatuses() {
+	catsrcStatusesLock.Lock()
+	defer catsrcStatusesLock.Unlock()
+
+	catsrcStatuses = make(map[string]string)
+	reportCatsrcStatusesLocked()
+}
+
+// reportCatsrcStatusesLocked reports the number of default CatalogSources
+// per status. catsrcStatusesLock must be held by the caller.
+func reportCatsrcStatusesLocked() {
+	counts := map[string]int{
+		metrics.DefaultCatalogSourceApplied: 0,
+		metrics.DefaultCatalogSourceFailed:  0,
+		metrics.DefaultCatalogSourceDeleted: 0,
+	}
+	for _, status := range catsrcStatuses {
+		counts[status]++
 	}
+	for status, count := range counts {
+		metrics.SetDefaultCatalogSourceCount(status, count)
+	}
+}
+
+// populateDefsConfig returns populated CatalogSource definitions from files present
+// in the @dir directory, an enabled config and the dependencies of each
+// CatalogSource. Every file is validated before any definition is populated,
+// so that the errors of all the invalid files are returned at once. The
+// function also guarantees to return empty maps on error.
+func populateDefsConfig(dir string, options populateOptions) (map[string]olmv1alpha1.CatalogSource, map[string]bool, map[string][]string, error) {
+	return populateDefsConfigFrom(context.Background(), DirLoader{Dir: dir}, options)
+}
 
-	fileInfos, err := ioutil.ReadDir(Dir)
+// defsConfigFromManifests returns the CatalogSource definitions, the enabled
+// config and the dependencies of each CatalogSource from the manifests read
+// from source, once expanded with the template data of options, owned by the
+// owner reference of options, with the image overrides and mirrors of options
+// and pinned to the digests of the digest resolver of options. Each YAML
+// document of a manifest defines a CatalogSource. It returns empty maps if a
+// manifest can not be expanded, is invalid or can not be patched.
+func defsConfigFromManifests(ctx context.Context, source string, manifests []Manifest, options populateOptions) (map[string]olmv1alpha1.CatalogSource, map[string]bool, map[string][]string, error) {
+	manifests, err := expandManifests(manifests, options.templateData)
 	if err != nil {
-		return catsrcDefinitions, config, err
+		return emptyDefsConfig(err)
+	}
+	if manifests, err = splitDocuments(manifests); err != nil {
+		return emptyDefsConfig(err)
+	}
+	if err := validateManifests(source, manifests); err != nil {
+		return emptyDefsConfig(err)
 	}
 
-	for _, fileInfo := range fileInfos {
-		fileName := fileInfo.Name()
-		catsrc, err := getCatsrcDefinition(fileName)
+	catsrcDefinitions := make(map[string]olmv1alpha1.CatalogSource)
+	config := make(map[string]bool)
+	deps := make(map[string][]string)
+	// definedIn holds the manifest each CatalogSource is defined by.
+	definedIn := make(map[string]Manifest)
+	patcher := NewCatalogSourcePatcher(PatchDir)
+	for _, m := range manifests {
+		catsrc, dependsOn, err := decodeCatsrcDefinition(m.Content)
+		if err == nil {
+			err = patcher.Patch(catsrc)
+		}
 		if err != nil {
 			// Reinitialize the definitions as we hard error on even one failure
-			catsrcDefinitions = make(map[string]olmv1alpha1.CatalogSource)
-			config = make(map[string]bool)
-			return catsrcDefinitions, config, err
+			return emptyDefsConfig(newFileError(m.Path, m.documentError(err)))
+		}
+		if previous, ok := definedIn[catsrc.Name]; ok && previous.Source == m.Source {
+			return emptyDefsConfig(&DefaultsError{Kind: ValidationError, Path: m.Path, Cause: m.documentError(fmt.Errorf("CatalogSource %s is already defined in %s", catsrc.Name, previous.location()))})
+		}
+		definedIn[catsrc.Name] = m
+		if options.ownerReference != nil {
+			catsrc.OwnerReferences = mergeOwnerReferences(catsrc.OwnerReferences, []metav1.OwnerReference{*options.ownerReference})
 		}
 		catsrcDefinitions[catsrc.Name] = *catsrc
 		config[catsrc.Name] = false
+		if len(dependsOn) > 0 {
+			deps[catsrc.Name] = dependsOn
+		}
 	}
-	return catsrcDefinitions, config, nil
+	overrideImages(catsrcDefinitions, options.imageOverrides)
+	mirrorImages(catsrcDefinitions, options.imageMirrors)
+	resolveDigests(ctx, catsrcDefinitions, options.digestResolver)
+	return catsrcDefinitions, config, deps, nil
+}
+
+// emptyDefsConfig returns empty definitions, config and dependencies along
+// with err.
+func emptyDefsConfig(err error) (map[string]olmv1alpha1.CatalogSource, map[string]bool, map[string][]string, error) {
+	return make(map[string]olmv1alpha1.CatalogSource), make(map[string]bool), make(map[string][]string), err
 }
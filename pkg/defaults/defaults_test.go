@@ -0,0 +1,227 @@
+package defaults
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	wrapper "github.com/operator-framework/operator-marketplace/pkg/client"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const catsrcManifest = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: %s
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: quay.io/example/%s:latest
+`
+
+// writeManifests writes a default CatalogSource manifest for each name into
+// a temporary directory and points Dir at it.
+func writeManifests(t *testing.T, names ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		manifest := fmt.Sprintf(catsrcManifest, name, name)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(manifest), 0644))
+	}
+
+	previous := Dir
+	Dir = dir
+	t.Cleanup(func() { Dir = previous })
+}
+
+// newFakeClient returns a client backed by an in-memory cluster containing
+// the given objects.
+func newFakeClient(t *testing.T, objs ...*olmv1alpha1.CatalogSource) wrapper.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	for _, obj := range objs {
+		builder = builder.WithObjects(obj)
+	}
+	return wrapper.NewClient(builder.Build())
+}
+
+// gaugeValue returns the value of the default CatalogSource count gauge for
+// the given status.
+func gaugeValue(t *testing.T, status string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "marketplace_default_catalogsources" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "status" && label.GetValue() == status {
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	t.Fatalf("no gauge found for status %s", status)
+	return 0
+}
+
+func TestDefaultCatalogSourceCount(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+
+	names := []string{"redhat-operators", "certified-operators", "community-operators"}
+	writeManifests(t, names...)
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+
+	definitions, config := GetGlobals()
+	errs := New(definitions, config).EnsureAll(context.TODO(), newFakeClient(t))
+	require.Empty(t, errs)
+
+	assert.Equal(t, float64(len(names)), gaugeValue(t, metrics.DefaultCatalogSourceApplied))
+	assert.Equal(t, float64(0), gaugeValue(t, metrics.DefaultCatalogSourceFailed))
+	assert.Equal(t, float64(0), gaugeValue(t, metrics.DefaultCatalogSourceDeleted))
+
+	// Disabling a default removes it from the applied count.
+	config = map[string]bool{"redhat-operators": true, "certified-operators": false, "community-operators": false}
+	errs = New(definitions, config).EnsureAll(context.TODO(), newFakeClient(t))
+	require.Empty(t, errs)
+
+	assert.Equal(t, float64(len(names)-1), gaugeValue(t, metrics.DefaultCatalogSourceApplied))
+	assert.Equal(t, float64(1), gaugeValue(t, metrics.DefaultCatalogSourceDeleted))
+}
+
+// sourceMetricValue returns the value of the per source metric with the given
+// name for the given default CatalogSource, and whether it is reported.
+func sourceMetricValue(t *testing.T, metricName, name string) (float64, bool) {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != metricName {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == name {
+					if metric.GetCounter() != nil {
+						return metric.GetCounter().GetValue(), true
+					}
+					return metric.GetGauge().GetValue(), true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestDefaultCatalogSourceRecreations(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+
+	writeManifests(t, "recreated-operators")
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+	previous, _ := sourceMetricValue(t, "marketplace_default_catalogsource_recreations_total", "recreated-operators")
+
+	definitions, config := GetGlobals()
+	d := New(definitions, config)
+	c := newFakeClient(t)
+	require.NoError(t, d.Ensure(context.TODO(), c, "recreated-operators"))
+	recreations, _ := sourceMetricValue(t, "marketplace_default_catalogsource_recreations_total", "recreated-operators")
+	assert.Equal(t, previous, recreations, "the first creation is not a recreation")
+
+	catsrc := &olmv1alpha1.CatalogSource{}
+	key := wrapper.ObjectKey{Namespace: "openshift-marketplace", Name: "recreated-operators"}
+	require.NoError(t, c.Get(context.TODO(), key, catsrc))
+	require.NoError(t, c.Delete(context.TODO(), catsrc))
+	require.NoError(t, d.Ensure(context.TODO(), c, "recreated-operators"))
+	require.NoError(t, c.Get(context.TODO(), key, catsrc))
+	recreations, _ = sourceMetricValue(t, "marketplace_default_catalogsource_recreations_total", "recreated-operators")
+	assert.Equal(t, previous+1, recreations)
+
+	// Ensuring the CatalogSource while it is present does not recreate it.
+	require.NoError(t, d.Ensure(context.TODO(), c, "recreated-operators"))
+	recreations, _ = sourceMetricValue(t, "marketplace_default_catalogsource_recreations_total", "recreated-operators")
+	assert.Equal(t, previous+1, recreations)
+}
+
+func TestDefaultCatalogSourceReady(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+
+	writeManifests(t, "ready-operators")
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+	definitions, config := GetGlobals()
+	c := newFakeClient(t)
+	require.NoError(t, New(definitions, config).Ensure(context.TODO(), c, "ready-operators"))
+	ready, reported := sourceMetricValue(t, "marketplace_default_catalogsource_ready", "ready-operators")
+	require.True(t, reported)
+	assert.Equal(t, 0.0, ready, "a CatalogSource that was just created is not ready")
+
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), wrapper.ObjectKey{Namespace: "openshift-marketplace", Name: "ready-operators"}, catsrc))
+	catsrc.Status.GRPCConnectionState = &olmv1alpha1.GRPCConnectionState{LastObservedState: "READY"}
+	require.NoError(t, c.Update(context.TODO(), catsrc))
+	require.NoError(t, New(definitions, config).Ensure(context.TODO(), c, "ready-operators"))
+	ready, _ = sourceMetricValue(t, "marketplace_default_catalogsource_ready", "ready-operators")
+	assert.Equal(t, 1.0, ready)
+
+	// A disabled CatalogSource is no longer reported.
+	require.NoError(t, New(definitions, map[string]bool{"ready-operators": true}).Ensure(context.TODO(), c, "ready-operators"))
+	_, reported = sourceMetricValue(t, "marketplace_default_catalogsource_ready", "ready-operators")
+	assert.False(t, reported)
+}
+
+func TestPopulateGlobalsWithOwnerReference(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+	owner := metav1.OwnerReference{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Name:       "marketplace-operator",
+		UID:        types.UID("6a0b4c1e-59f2-4d3e-9c8b-3f1a2e7d5c40"),
+	}
+
+	writeManifests(t, "owned-operators", "adopted-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	_, err := PopulateGlobals(WithOwnerReference(owner))
+	require.NoError(t, err)
+
+	// A CatalogSource created before the operator owned the defaults is
+	// adopted.
+	adopted := &olmv1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "adopted-operators", Namespace: "openshift-marketplace"},
+		Spec:       olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: "quay.io/example/adopted-operators:latest"},
+	}
+	c := newFakeClient(t, adopted)
+	definitions, config := GetGlobals()
+	require.Empty(t, New(definitions, config).EnsureAll(context.TODO(), c))
+
+	for _, name := range []string{"owned-operators", "adopted-operators"} {
+		catsrc := &olmv1alpha1.CatalogSource{}
+		require.NoError(t, c.Get(context.TODO(), wrapper.ObjectKey{Namespace: "openshift-marketplace", Name: name}, catsrc))
+		assert.Equal(t, []metav1.OwnerReference{owner}, catsrc.OwnerReferences, name)
+	}
+
+	// The owner reference is not added twice.
+	require.Empty(t, New(definitions, config).EnsureAll(context.TODO(), c))
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), wrapper.ObjectKey{Namespace: "openshift-marketplace", Name: "adopted-operators"}, catsrc))
+	assert.Len(t, catsrc.OwnerReferences, 1)
+}
@@ -0,0 +1,46 @@
+// Package defaultstest populates the default CatalogSources for the tests of
+// the packages that manage them.
+package defaultstest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/operatorhub"
+	"github.com/stretchr/testify/require"
+)
+
+// Populate writes the given manifests, keyed by file name, into a temporary
+// directory and populates the default CatalogSources from it, as PopulateFrom
+// does. The directory is returned so that the test can change the manifests.
+func Populate(t testing.TB, manifests map[string]string, opts ...defaults.Option) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, manifest := range manifests {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(manifest), 0644))
+	}
+	PopulateFrom(t, dir, opts...)
+	return dir
+}
+
+// PopulateFrom points defaults.Dir at dir, populates the default
+// CatalogSources from it with the given options and resets the OperatorHub
+// config so that every default CatalogSource is enabled. Once the test ends,
+// the previous directory is populated again and the OperatorHub config reset.
+func PopulateFrom(t testing.TB, dir string, opts ...defaults.Option) {
+	t.Helper()
+	previous := defaults.Dir
+	defaults.Dir = dir
+	t.Cleanup(func() {
+		defaults.Dir = previous
+		_, err := defaults.PopulateGlobals()
+		require.NoError(t, err)
+		operatorhub.GetSingleton().Set(configv1.OperatorHubSpec{})
+	})
+	_, err := defaults.PopulateGlobals(opts...)
+	require.NoError(t, err)
+	operatorhub.GetSingleton().Set(configv1.OperatorHubSpec{})
+}
@@ -0,0 +1,111 @@
+package defaults
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+)
+
+// catsrcDependencies is used to keep an in-memory record of the dependencies
+// of the default CatalogSources, as declared by the dependsOn field of their
+// definition in the defaults directory. It is a map of CatalogSource name to
+// the names of the CatalogSources that must be created before it.
+var catsrcDependencies = make(map[string][]string)
+
+// dependencies is the part of a default CatalogSource definition that is not
+// part of the CatalogSource type.
+type dependencies struct {
+	// DependsOn lists the names of the default CatalogSources that must be
+	// created before this one.
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// CyclicDependencyError is returned by TopoSort when the dependencies of the
+// default CatalogSources can not be ordered.
+type CyclicDependencyError struct {
+	// Names are the CatalogSources that are part of, or depend on, a cycle.
+	Names []string
+}
+
+func (e *CyclicDependencyError) Error() string {
+	return fmt.Sprintf("cyclic dependency between default CatalogSources: %s", strings.Join(e.Names, ", "))
+}
+
+// TopoSort returns the given CatalogSources ordered so that every
+// CatalogSource comes after the ones it depends on. Dependencies on
+// CatalogSources that are not part of sources are ignored, as they may have
+// been disabled. CatalogSources that do not depend on each other are ordered
+// by name. A CyclicDependencyError is returned if the dependencies contain a
+// cycle.
+func TopoSort(sources []*olmv1alpha1.CatalogSource) ([]*olmv1alpha1.CatalogSource, error) {
+	globalsLock.RLock()
+	defer globalsLock.RUnlock()
+	return topoSort(sources, catsrcDependencies)
+}
+
+// topoSort orders sources by the dependencies deps, see TopoSort.
+func topoSort(sources []*olmv1alpha1.CatalogSource, deps map[string][]string) ([]*olmv1alpha1.CatalogSource, error) {
+	byName := make(map[string]*olmv1alpha1.CatalogSource, len(sources))
+	for _, source := range sources {
+		byName[source.Name] = source
+	}
+
+	// Kahn's algorithm: count the dependencies of every source and record
+	// the sources that depend on it, then repeatedly emit the sources that
+	// have no dependencies left.
+	inDegree := make(map[string]int, len(byName))
+	dependents := make(map[string][]string, len(byName))
+	for name := range byName {
+		inDegree[name] = 0
+	}
+	for name := range byName {
+		for _, dependency := range deps[name] {
+			if _, present := byName[dependency]; !present {
+				continue
+			}
+			inDegree[name]++
+			dependents[dependency] = append(dependents[dependency], name)
+		}
+	}
+
+	var ready []string
+	for name, degree := range inDegree {
+		if degree == 0 {
+			ready = append(ready, name)
+		}
+	}
+	sort.Strings(ready)
+
+	sorted := make([]*olmv1alpha1.CatalogSource, 0, len(byName))
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, byName[name])
+
+		released := false
+		for _, dependent := range dependents[name] {
+			inDegree[dependent]--
+			if inDegree[dependent] == 0 {
+				ready = append(ready, dependent)
+				released = true
+			}
+		}
+		if released {
+			sort.Strings(ready)
+		}
+	}
+
+	if len(sorted) != len(byName) {
+		var remaining []string
+		for name, degree := range inDegree {
+			if degree > 0 {
+				remaining = append(remaining, name)
+			}
+		}
+		sort.Strings(remaining)
+		return nil, &CyclicDependencyError{Names: remaining}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,89 @@
+package defaults
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setDependencies replaces the recorded dependencies for the duration of the
+// test.
+func setDependencies(t *testing.T, deps map[string][]string) {
+	t.Helper()
+	previous := catsrcDependencies
+	catsrcDependencies = deps
+	t.Cleanup(func() { catsrcDependencies = previous })
+}
+
+func catsrcsNamed(names ...string) []*olmv1alpha1.CatalogSource {
+	var sources []*olmv1alpha1.CatalogSource
+	for _, name := range names {
+		sources = append(sources, &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	return sources
+}
+
+func sortedNames(sources []*olmv1alpha1.CatalogSource) []string {
+	var names []string
+	for _, source := range sources {
+		names = append(names, source.Name)
+	}
+	return names
+}
+
+func TestTopoSort(t *testing.T) {
+	setDependencies(t, map[string][]string{
+		"a": {"c"},
+		"b": {"a", "c"},
+		"d": {"disabled"},
+	})
+
+	sorted, err := TopoSort(catsrcsNamed("d", "b", "a", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "a", "b", "d"}, sortedNames(sorted))
+}
+
+func TestTopoSortCycle(t *testing.T) {
+	setDependencies(t, map[string][]string{
+		"a": {"b"},
+		"b": {"a"},
+		"c": {"b"},
+	})
+
+	_, err := TopoSort(catsrcsNamed("a", "b", "c", "d"))
+	var cyclic *CyclicDependencyError
+	require.True(t, errors.As(err, &cyclic))
+	assert.Equal(t, []string{"a", "b", "c"}, cyclic.Names)
+}
+
+func TestPopulateGlobalsDependsOn(t *testing.T) {
+	setDependencies(t, catsrcDependencies)
+	writeManifests(t, "redhat-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	writeManifest := func(name string, dependsOn string) {
+		manifest := fmt.Sprintf(catsrcManifest, name, name) + "dependsOn:\n- " + dependsOn + "\n"
+		require.NoError(t, os.WriteFile(filepath.Join(Dir, name+".yaml"), []byte(manifest), 0644))
+	}
+
+	writeManifest("certified-operators", "redhat-operators")
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"certified-operators": {"redhat-operators"}}, catsrcDependencies)
+
+	writeManifest("redhat-operators", "certified-operators")
+	var cyclic *CyclicDependencyError
+	_, err = PopulateGlobals()
+	assert.True(t, errors.As(err, &cyclic))
+	assert.Empty(t, GetGlobalCatalogSourceDefinitions(), "no defaults are loaded when their dependencies are cyclic")
+}
@@ -0,0 +1,56 @@
+package defaults
+
+import (
+	"sort"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// CatalogSourceSetDiff is the difference between two sets of CatalogSource
+// definitions. Every list is sorted by name.
+type CatalogSourceSetDiff struct {
+	// Added holds the definitions of the new set that are not in the old one.
+	Added []*olmv1alpha1.CatalogSource
+	// Removed holds the definitions of the old set that are not in the new
+	// one.
+	Removed []*olmv1alpha1.CatalogSource
+	// Updated holds the definitions of the new set that differ from the ones
+	// of the old set with the same name.
+	Updated []*olmv1alpha1.CatalogSource
+}
+
+// IsEmpty returns true if the two sets hold the same definitions.
+func (d CatalogSourceSetDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Updated) == 0
+}
+
+// Diff returns the difference between the oldSet and newSet definitions,
+// keyed by CatalogSource name like the global definitions. The definitions
+// returned are copies.
+func Diff(oldSet, newSet map[string]olmv1alpha1.CatalogSource) CatalogSourceSetDiff {
+	diff := CatalogSourceSetDiff{}
+	for name, def := range newSet {
+		old, present := oldSet[name]
+		switch {
+		case !present:
+			diff.Added = append(diff.Added, def.DeepCopy())
+		case !equality.Semantic.DeepEqual(old, def):
+			diff.Updated = append(diff.Updated, def.DeepCopy())
+		}
+	}
+	for name, def := range oldSet {
+		if _, present := newSet[name]; !present {
+			diff.Removed = append(diff.Removed, def.DeepCopy())
+		}
+	}
+	sortByName(diff.Added)
+	sortByName(diff.Removed)
+	sortByName(diff.Updated)
+	return diff
+}
+
+// sortByName sorts the CatalogSources by name.
+func sortByName(catsrcs []*olmv1alpha1.CatalogSource) {
+	sort.Slice(catsrcs, func(i, j int) bool { return catsrcs[i].Name < catsrcs[j].Name })
+}
@@ -0,0 +1,60 @@
+package defaults
+
+import (
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newDefinition(name, image string) olmv1alpha1.CatalogSource {
+	return olmv1alpha1.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openshift-marketplace"},
+		Spec:       olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: image},
+	}
+}
+
+// names returns the names of the CatalogSources.
+func names(catsrcs []*olmv1alpha1.CatalogSource) []string {
+	var result []string
+	for _, catsrc := range catsrcs {
+		result = append(result, catsrc.Name)
+	}
+	return result
+}
+
+func TestDiff(t *testing.T) {
+	oldSet := map[string]olmv1alpha1.CatalogSource{
+		"redhat-operators":    newDefinition("redhat-operators", "quay.io/example/redhat-operators:v1"),
+		"certified-operators": newDefinition("certified-operators", "quay.io/example/certified-operators:v1"),
+		"community-operators": newDefinition("community-operators", "quay.io/example/community-operators:v1"),
+		"removed-operators":   newDefinition("removed-operators", "quay.io/example/removed-operators:v1"),
+	}
+	labeled := newDefinition("community-operators", "quay.io/example/community-operators:v1")
+	labeled.Labels = map[string]string{"tier": "community"}
+	newSet := map[string]olmv1alpha1.CatalogSource{
+		"redhat-operators":    newDefinition("redhat-operators", "quay.io/example/redhat-operators:v1"),
+		"certified-operators": newDefinition("certified-operators", "quay.io/example/certified-operators:v2"),
+		"community-operators": labeled,
+		"mirrored-operators":  newDefinition("mirrored-operators", "quay.io/example/mirrored-operators:v1"),
+		"zz-operators":        newDefinition("zz-operators", "quay.io/example/zz-operators:v1"),
+	}
+
+	diff := Diff(oldSet, newSet)
+	assert.Equal(t, []string{"mirrored-operators", "zz-operators"}, names(diff.Added))
+	assert.Equal(t, []string{"removed-operators"}, names(diff.Removed))
+	assert.Equal(t, []string{"certified-operators", "community-operators"}, names(diff.Updated), "a change of the metadata is an update")
+	assert.Equal(t, "quay.io/example/certified-operators:v2", diff.Updated[0].Spec.Image, "the updated definition is the new one")
+	assert.False(t, diff.IsEmpty())
+
+	// The definitions returned are copies.
+	diff.Added[0].Spec.Image = "modified"
+	assert.Equal(t, "quay.io/example/mirrored-operators:v1", newSet["mirrored-operators"].Spec.Image)
+
+	assert.True(t, Diff(newSet, newSet).IsEmpty())
+	assert.Equal(t, []string{"redhat-operators"}, names(Diff(nil, map[string]olmv1alpha1.CatalogSource{
+		"redhat-operators": newDefinition("redhat-operators", "quay.io/example/redhat-operators:v1"),
+	}).Added))
+	assert.Len(t, Diff(oldSet, nil).Removed, len(oldSet))
+}
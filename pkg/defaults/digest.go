@@ -0,0 +1,307 @@
+package defaults
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultDigestResolveTimeout is the time given by default to resolve
+	// the digest of each image.
+	DefaultDigestResolveTimeout = 10 * time.Second
+
+	// digestHeader is the header of the registry responses holding the
+	// digest of the manifest.
+	digestHeader = "Docker-Content-Digest"
+)
+
+// manifestMediaTypes are the media types of the manifests accepted from the
+// registries, the image indexes first so that the digest resolved is the one
+// of the multi-arch image rather than of the manifest of one architecture.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// digestPattern matches an image digest, as algorithm:encoded.
+var digestPattern = regexp.MustCompile(`^[a-z0-9]+(?:[+._-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+
+// DigestResolver resolves the tag of an image reference to the digest of the
+// image it points to.
+type DigestResolver interface {
+	// ResolveDigest returns the digest, as algorithm:encoded, the tag of
+	// image points to.
+	ResolveDigest(ctx context.Context, image string) (string, error)
+}
+
+// CatalogSourceImageDigestResolver resolves the tags of the images of the
+// default CatalogSources to digests, from the ImageStreams on OpenShift or
+// from the registry the images are pulled from, so that the CatalogSources are
+// pinned to a digest from the start. The registries are queried anonymously,
+// negotiating a bearer token if they require one.
+type CatalogSourceImageDigestResolver struct {
+	// ImageStreams, if not nil, reads the ImageStreams of
+	// ImageStreamNamespace, whose tags are resolved to their most recent
+	// image without querying the registry.
+	ImageStreams client.Reader
+	// ImageStreamNamespace is the namespace of the ImageStreams.
+	ImageStreamNamespace string
+	// Client queries the registries.
+	Client *http.Client
+}
+
+// NewCatalogSourceImageDigestResolver returns a
+// CatalogSourceImageDigestResolver giving each image timeout to be resolved,
+// looking up the ImageStreams of namespace through imageStreams if it is not
+// nil.
+func NewCatalogSourceImageDigestResolver(imageStreams client.Reader, namespace string, timeout time.Duration) *CatalogSourceImageDigestResolver {
+	return &CatalogSourceImageDigestResolver{
+		ImageStreams:         imageStreams,
+		ImageStreamNamespace: namespace,
+		Client:               &http.Client{Timeout: timeout},
+	}
+}
+
+// ResolveDigest returns the digest the tag of image points to: the most recent
+// image of the ImageStream tag image refers to, if any, or the digest of the
+// manifest the registry serves for the tag otherwise.
+func (r *CatalogSourceImageDigestResolver) ResolveDigest(ctx context.Context, image string) (string, error) {
+	if r.ImageStreams != nil {
+		digest, err := r.imageStreamDigest(ctx, image)
+		if err != nil {
+			logrus.Debugf("[defaults] Unable to look up the ImageStream of image %s, querying its registry - %v", image, err)
+		} else if digest != "" {
+			return digest, nil
+		}
+	}
+	return r.registryDigest(ctx, image)
+}
+
+// imageStreamDigest returns the most recent image of the ImageStream tag whose
+// internal or public pull spec is image, or the empty string if image does not
+// refer to an ImageStream tag.
+func (r *CatalogSourceImageDigestResolver) imageStreamDigest(ctx context.Context, image string) (string, error) {
+	streams := &imagev1.ImageStreamList{}
+	if err := r.ImageStreams.List(ctx, streams, client.InNamespace(r.ImageStreamNamespace)); err != nil {
+		return "", err
+	}
+	for _, stream := range streams.Items {
+		for _, tag := range stream.Status.Tags {
+			if len(tag.Items) == 0 {
+				continue
+			}
+			for _, repository := range []string{stream.Status.DockerImageRepository, stream.Status.PublicDockerImageRepository} {
+				if repository != "" && repository+":"+tag.Tag == image {
+					return tag.Items[0].Image, nil
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
+// registryDigest returns the digest of the manifest the registry of image
+// serves for its tag, through the registry API.
+func (r *CatalogSourceImageDigestResolver) registryDigest(ctx context.Context, image string) (string, error) {
+	registry, repository, tag, err := splitImageReference(image)
+	if err != nil {
+		return "", err
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", registry, repository, tag)
+
+	resp, err := r.headManifest(ctx, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := r.token(ctx, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", fmt.Errorf("failed to authenticate with registry %s: %v", registry, err)
+		}
+		if resp, err = r.headManifest(ctx, manifestURL, token); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry %s responded with %s for %s:%s", registry, resp.Status, repository, tag)
+	}
+	digest := resp.Header.Get(digestHeader)
+	if !digestPattern.MatchString(digest) {
+		return "", fmt.Errorf("registry %s responded with invalid digest %q for %s:%s", registry, digest, repository, tag)
+	}
+	return digest, nil
+}
+
+// headManifest requests the headers of the manifest at manifestURL, with the
+// given bearer token if it is not empty.
+func (r *CatalogSourceImageDigestResolver) headManifest(ctx context.Context, manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// token returns an anonymous bearer token from the token service of the
+// Bearer challenge of a registry.
+func (r *CatalogSourceImageDigestResolver) token(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	values := parseChallengeParams(params)
+	realm := values["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("no realm in authentication challenge %q", challenge)
+	}
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid realm %q: %v", realm, err)
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if values[key] != "" {
+			query.Set(key, values[key])
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("the token service responded with %s", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid token response: %v", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("the token service returned no token")
+}
+
+// parseChallengeParams returns the comma-separated key="value" parameters of
+// an authentication challenge.
+func parseChallengeParams(params string) map[string]string {
+	values := make(map[string]string)
+	for params != "" {
+		var key, value string
+		key, params, _ = strings.Cut(strings.TrimLeft(params, " ,"), "=")
+		if strings.HasPrefix(params, `"`) {
+			value, params, _ = strings.Cut(params[1:], `"`)
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		if key != "" {
+			values[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return values
+}
+
+// splitImageReference returns the registry host, the repository and the tag
+// of a tagged image reference. The images of docker.io are queried on
+// registry-1.docker.io, in the library namespace if they have none. The tag
+// defaults to latest.
+func splitImageReference(image string) (registry, repository, tag string, err error) {
+	if strings.Contains(image, "@") {
+		return "", "", "", fmt.Errorf("image %s is already pinned to a digest", image)
+	}
+	registry = ImageRegistry(image)
+	repository = strings.TrimPrefix(image, registry+"/")
+	tag = "latest"
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:i], repository[i+1:]
+	}
+	if repository == "" || tag == "" {
+		return "", "", "", fmt.Errorf("invalid image reference %q", image)
+	}
+	if registry == "docker.io" {
+		registry = "registry-1.docker.io"
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+	return registry, repository, tag, nil
+}
+
+// resolveDigests pins the image of each definition that is not pinned yet to
+// the digest resolver resolves its tag to, keeping the tag in the reference
+// for readability. An image that can not be resolved is logged and kept as
+// is, so that an unreachable registry does not prevent the default
+// CatalogSources from being applied.
+func resolveDigests(ctx context.Context, catsrcDefinitions map[string]olmv1alpha1.CatalogSource, resolver DigestResolver) {
+	if resolver == nil {
+		return
+	}
+	for name, def := range catsrcDefinitions {
+		if def.Spec.Image == "" || strings.Contains(def.Spec.Image, "@") {
+			continue
+		}
+		digest, err := resolver.ResolveDigest(ctx, def.Spec.Image)
+		if err != nil {
+			logrus.Warnf("[defaults] Unable to resolve the digest of image %s of CatalogSource %s, keeping its tag - %v", def.Spec.Image, name, err)
+			continue
+		}
+		logrus.Infof("[defaults] Resolved image %s of CatalogSource %s to digest %s", def.Spec.Image, name, digest)
+		def.Spec.Image = def.Spec.Image + "@" + digest
+		catsrcDefinitions[name] = def
+	}
+}
+
+// SetImageDigest pins the image of the global definition of the default
+// CatalogSource with the given name to digest, if the image is pinned to
+// another digest, as when the tag its digest was resolved from was pushed
+// since. It returns false if the definition is unknown, not pinned or already
+// pinned to digest.
+func SetImageDigest(name, digest string) bool {
+	globalsLock.Lock()
+	defer globalsLock.Unlock()
+	def, present := globalCatsrcDefinitions[name]
+	if !present {
+		return false
+	}
+	image, pinned, found := strings.Cut(def.Spec.Image, "@")
+	if !found || pinned == digest {
+		return false
+	}
+	def.Spec.Image = image + "@" + digest
+	globalCatsrcDefinitions[name] = def
+	return true
+}
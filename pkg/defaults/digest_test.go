@@ -0,0 +1,156 @@
+package defaults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// digestResolverFunc resolves the digests with a function.
+type digestResolverFunc func(ctx context.Context, image string) (string, error)
+
+func (f digestResolverFunc) ResolveDigest(ctx context.Context, image string) (string, error) {
+	return f(ctx, image)
+}
+
+func TestSplitImageReference(t *testing.T) {
+	for _, tt := range []struct {
+		image                     string
+		registry, repository, tag string
+		wantErr                   bool
+	}{
+		{image: "registry.redhat.io/redhat/redhat-operator-index:v4.17", registry: "registry.redhat.io", repository: "redhat/redhat-operator-index", tag: "v4.17"},
+		{image: "localhost:5000/catalog", registry: "localhost:5000", repository: "catalog", tag: "latest"},
+		{image: "quay.io/example/catalog:latest", registry: "quay.io", repository: "example/catalog", tag: "latest"},
+		{image: "example/catalog:v1", registry: "registry-1.docker.io", repository: "example/catalog", tag: "v1"},
+		{image: "catalog", registry: "registry-1.docker.io", repository: "library/catalog", tag: "latest"},
+		{image: "quay.io/example/catalog@" + testDigest, wantErr: true},
+		{image: "quay.io/example/catalog:", wantErr: true},
+	} {
+		t.Run(tt.image, func(t *testing.T) {
+			registry, repository, tag, err := splitImageReference(tt.image)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{tt.registry, tt.repository, tt.tag}, []string{registry, repository, tag})
+		})
+	}
+}
+
+func TestResolveDigestFromRegistry(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/token":
+			assert.Equal(t, "registry.example.com", req.URL.Query().Get("service"))
+			assert.Equal(t, "repository:redhat/redhat-operator-index:pull", req.URL.Query().Get("scope"))
+			fmt.Fprint(w, `{"token":"anonymous"}`)
+		case "/v2/redhat/redhat-operator-index/manifests/v4.17":
+			assert.Equal(t, http.MethodHead, req.Method)
+			assert.Contains(t, req.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json")
+			if req.Header.Get("Authorization") != "Bearer anonymous" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry.example.com",scope="repository:redhat/redhat-operator-index:pull"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set(digestHeader, testDigest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	resolver := NewCatalogSourceImageDigestResolver(nil, "", time.Second)
+	resolver.Client = server.Client()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	digest, err := resolver.ResolveDigest(context.TODO(), host+"/redhat/redhat-operator-index:v4.17")
+	require.NoError(t, err)
+	assert.Equal(t, testDigest, digest)
+
+	_, err = resolver.ResolveDigest(context.TODO(), host+"/redhat/missing:v4.17")
+	assert.ErrorContains(t, err, "404 Not Found")
+}
+
+func TestResolveDigestFromImageStream(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, imagev1.AddToScheme(scheme))
+	stream := &imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Name: "redhat-operator-index", Namespace: "openshift"},
+		Status: imagev1.ImageStreamStatus{
+			DockerImageRepository: "image-registry.openshift-image-registry.svc:5000/openshift/redhat-operator-index",
+			Tags: []imagev1.NamedTagEventList{{
+				Tag:   "v4.17",
+				Items: []imagev1.TagEvent{{Image: testDigest}},
+			}},
+		},
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(stream).Build()
+	resolver := NewCatalogSourceImageDigestResolver(reader, "openshift", time.Second)
+
+	digest, err := resolver.ResolveDigest(context.TODO(), "image-registry.openshift-image-registry.svc:5000/openshift/redhat-operator-index:v4.17")
+	require.NoError(t, err)
+	assert.Equal(t, testDigest, digest, "the ImageStream tag is resolved without querying the registry")
+}
+
+func TestPopulateGlobalsResolvesDigests(t *testing.T) {
+	writeManifests(t, "redhat-operators", "certified-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	resolver := digestResolverFunc(func(_ context.Context, image string) (string, error) {
+		if strings.Contains(image, "certified-operators") {
+			return "", errors.New("registry unreachable")
+		}
+		return testDigest, nil
+	})
+
+	_, err := PopulateGlobals(WithDigestResolver(resolver))
+	require.NoError(t, err)
+	definitions := GetGlobalCatalogSourceDefinitions()
+	assert.Equal(t, "quay.io/example/redhat-operators:latest@"+testDigest, definitions["redhat-operators"].Spec.Image)
+	assert.Equal(t, "quay.io/example/certified-operators:latest", definitions["certified-operators"].Spec.Image, "an image that can not be resolved keeps its tag")
+}
+
+func TestSetImageDigest(t *testing.T) {
+	writeManifests(t, "redhat-operators", "certified-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	resolver := digestResolverFunc(func(_ context.Context, image string) (string, error) {
+		if strings.Contains(image, "certified-operators") {
+			return "", errors.New("registry unreachable")
+		}
+		return testDigest, nil
+	})
+	_, err := PopulateGlobals(WithDigestResolver(resolver))
+	require.NoError(t, err)
+
+	const pushed = "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	assert.True(t, SetImageDigest("redhat-operators", pushed))
+	assert.Equal(t, "quay.io/example/redhat-operators:latest@"+pushed, GetGlobalCatalogSourceDefinitions()["redhat-operators"].Spec.Image)
+	assert.False(t, SetImageDigest("redhat-operators", pushed), "the image is already pinned to the digest")
+	assert.False(t, SetImageDigest("certified-operators", pushed), "an image that is not pinned is kept")
+	assert.Equal(t, "quay.io/example/certified-operators:latest", GetGlobalCatalogSourceDefinitions()["certified-operators"].Spec.Image)
+	assert.False(t, SetImageDigest("community-operators", pushed))
+}
@@ -0,0 +1,97 @@
+package defaults
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// location returns the path of the manifest, along with the index of its
+// document if its file holds several documents.
+func (m Manifest) location() string {
+	if m.Document == 0 {
+		return m.Path
+	}
+	return fmt.Sprintf("%s (document %d)", m.Path, m.Document)
+}
+
+// documentError returns err prefixed with the index of the document of the
+// manifest if its file holds several documents.
+func (m Manifest) documentError(err error) error {
+	if m.Document == 0 {
+		return err
+	}
+	return fmt.Errorf("document %d: %w", m.Document, err)
+}
+
+// splitDocuments returns a manifest for each document of the given manifests,
+// see splitManifest.
+func splitDocuments(manifests []Manifest) ([]Manifest, error) {
+	split := make([]Manifest, 0, len(manifests))
+	for _, m := range manifests {
+		documents, err := splitManifest(m)
+		if err != nil {
+			return nil, &DefaultsError{Kind: ValidationError, Path: m.Path, Cause: err}
+		}
+		split = append(split, documents...)
+	}
+	return split, nil
+}
+
+// splitManifest returns a manifest for each document of m, separated by "---"
+// in YAML. The documents holding nothing but whitespace and comments are
+// skipped. The documents of a file holding several are indexed from 1 in
+// Manifest.Document, in the order of the file.
+func splitManifest(m Manifest) ([]Manifest, error) {
+	documents, err := readDocuments(m.Content)
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) == 1 {
+		m.Content = documents[0]
+		return []Manifest{m}, nil
+	}
+	split := make([]Manifest, 0, len(documents))
+	for i, document := range documents {
+		if isEmptyDocument(document) {
+			continue
+		}
+		split = append(split, Manifest{Path: m.Path, Content: document, Source: m.Source, Document: i + 1})
+	}
+	return split, nil
+}
+
+// readDocuments returns the YAML documents of content.
+func readDocuments(content []byte) ([][]byte, error) {
+	reader := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(content)))
+	var documents [][]byte
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	if len(documents) == 0 {
+		// An empty file is decoded, and rejected, as a single document.
+		documents = append(documents, content)
+	}
+	return documents, nil
+}
+
+// isEmptyDocument returns true if the YAML document holds nothing but
+// whitespace and comments.
+func isEmptyDocument(document []byte) bool {
+	for _, line := range bytes.Split(document, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package defaults
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// multiDocumentManifest returns a YAML file holding the given documents.
+func multiDocumentManifest(documents ...string) string {
+	return strings.Join(documents, "---\n")
+}
+
+func TestPopulateGlobalsMultiDocument(t *testing.T) {
+	yamlManifest := func(name string) string { return fmt.Sprintf(catsrcManifest, name, name) }
+	for _, tt := range []struct {
+		name    string
+		content string
+		// names are the CatalogSources defined, if the file is valid.
+		names []string
+		// errContains is the error message, if the file is invalid.
+		errContains string
+	}{
+		{
+			name: "every default",
+			content: "# The default CatalogSources.\n---\n" + multiDocumentManifest(
+				yamlManifest("redhat-operators"),
+				yamlManifest("certified-operators"),
+				"\n# No CatalogSource.\n",
+				yamlManifest("community-operators"),
+				yamlManifest("redhat-marketplace"),
+			) + "---\n",
+			names: []string{"certified-operators", "community-operators", "redhat-marketplace", "redhat-operators"},
+		},
+		{
+			name:        "duplicate",
+			content:     multiDocumentManifest(yamlManifest("redhat-operators"), yamlManifest("certified-operators"), yamlManifest("redhat-operators")),
+			errContains: "document 3: CatalogSource redhat-operators is already defined in %s (document 1)",
+		},
+		{
+			name:        "invalid document",
+			content:     multiDocumentManifest(yamlManifest("redhat-operators"), "apiVersion: operators.coreos.com/v1alpha1\nkind: CatalogSource\nmetadata:\n  name: missing-source-type\n  namespace: openshift-marketplace\n"),
+			errContains: "document 2: ",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			writeManifests(t)
+			t.Cleanup(func() {
+				Dir = ""
+				_, err := PopulateGlobals()
+				require.NoError(t, err)
+			})
+			path := filepath.Join(Dir, "defaults.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			_, err := PopulateGlobals()
+			if tt.errContains != "" {
+				requireDefaultsError(t, err, ValidationError, path)
+				assert.Contains(t, err.Error(), strings.ReplaceAll(tt.errContains, "%s", path))
+				assert.Empty(t, GetGlobalCatalogSourceDefinitions())
+				return
+			}
+			require.NoError(t, err)
+			var names []string
+			for name, def := range GetGlobalCatalogSourceDefinitions() {
+				names = append(names, name)
+				assert.Equal(t, "quay.io/example/"+name+":latest", def.Spec.Image)
+			}
+			assert.ElementsMatch(t, tt.names, names)
+		})
+	}
+}
+
+func TestPopulateGlobalsMultiDocumentUniqueAcrossFiles(t *testing.T) {
+	writeManifests(t, "redhat-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	path := filepath.Join(Dir, "z-defaults.yaml")
+	content := multiDocumentManifest(fmt.Sprintf(catsrcManifest, "certified-operators", "certified-operators"), fmt.Sprintf(catsrcManifest, "redhat-operators", "redhat-operators"))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	_, err := PopulateGlobals()
+	requireDefaultsError(t, err, ValidationError, path)
+	assert.EqualError(t, err, fmt.Sprintf("ValidationError in %s: document 2: CatalogSource redhat-operators is already defined in %s", path, filepath.Join(Dir, "redhat-operators.yaml")))
+}
+
+func TestValidateDirMultiDocument(t *testing.T) {
+	writeManifests(t)
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	path := filepath.Join(Dir, "defaults.yaml")
+	content := multiDocumentManifest(fmt.Sprintf(catsrcManifest, "redhat-operators", "redhat-operators"), "# Empty.\n", fmt.Sprintf(catsrcManifest, "redhat-operators", "redhat-operators-copy"))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	results, err := ValidateDir(Dir)
+	require.NoError(t, err)
+	require.Len(t, results, 2, "the empty document is skipped")
+	assert.Equal(t, path+" (document 1)", results[0].Path)
+	assert.Equal(t, "redhat-operators", results[0].Name)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, path+" (document 3)", results[1].Path)
+	assert.EqualError(t, results[1].Err, "CatalogSource redhat-operators is already defined in "+path+" (document 1)")
+}
@@ -0,0 +1,64 @@
+package defaults
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+const (
+	// FilesystemError is the kind of the errors reading the defaults or the
+	// patch directory.
+	FilesystemError = "FilesystemError"
+
+	// ValidationError is the kind of the errors caused by an invalid default
+	// CatalogSource definition or patch.
+	ValidationError = "ValidationError"
+
+	// APIError is the kind of the errors writing a default CatalogSource to
+	// the API server.
+	APIError = "APIError"
+
+	// HTTPError is the kind of the errors fetching the default CatalogSource
+	// manifests from a URL.
+	HTTPError = "HTTPError"
+)
+
+// DefaultsError is the error returned when the default CatalogSources can not
+// be populated or ensured. Kind tells the callers what failed, while the
+// underlying error is available through errors.Unwrap.
+type DefaultsError struct {
+	// Kind is one of FilesystemError, ValidationError, APIError or
+	// HTTPError.
+	Kind string
+	// Path is the file, directory or URL that caused the error, it is
+	// empty for an APIError.
+	Path string
+	// Cause is the underlying error.
+	Cause error
+}
+
+// Error implements the error interface.
+func (e *DefaultsError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s: %v", e.Kind, e.Cause)
+	}
+	return fmt.Sprintf("%s in %s: %v", e.Kind, e.Path, e.Cause)
+}
+
+// Unwrap returns the underlying error.
+func (e *DefaultsError) Unwrap() error {
+	return e.Cause
+}
+
+// newFileError returns the DefaultsError of the failure to load the file or
+// directory at path. A failure to access the filesystem is a FilesystemError,
+// any other failure is a ValidationError.
+func newFileError(path string, err error) *DefaultsError {
+	kind := ValidationError
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		kind = FilesystemError
+	}
+	return &DefaultsError{Kind: kind, Path: path, Cause: err}
+}
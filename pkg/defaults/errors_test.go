@@ -0,0 +1,87 @@
+package defaults
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	wrapper "github.com/operator-framework/operator-marketplace/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// requireDefaultsError asserts that err is a DefaultsError of the given kind
+// and path and returns it.
+func requireDefaultsError(t *testing.T, err error, kind, path string) *DefaultsError {
+	t.Helper()
+	var defaultsErr *DefaultsError
+	require.True(t, errors.As(err, &defaultsErr), "%v is a DefaultsError", err)
+	assert.Equal(t, kind, defaultsErr.Kind)
+	assert.Equal(t, path, defaultsErr.Path)
+	require.Error(t, defaultsErr.Cause)
+	return defaultsErr
+}
+
+func TestPopulateGlobalsFilesystemError(t *testing.T) {
+	writeManifests(t)
+	dir := Dir
+	missing := filepath.Join(dir, "missing")
+	Dir = missing
+	t.Cleanup(func() {
+		Dir = dir
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+
+	_, err := PopulateGlobals()
+	requireDefaultsError(t, err, FilesystemError, missing)
+	assert.True(t, errors.Is(err, fs.ErrNotExist), "the cause is unwrapped")
+}
+
+func TestPopulateGlobalsValidationError(t *testing.T) {
+	writeManifests(t, "redhat-operators")
+	path := filepath.Join(Dir, "malformed.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("kind: [CatalogSource"), 0644))
+	_, err := PopulateGlobals()
+	requireDefaultsError(t, err, ValidationError, path)
+
+	require.NoError(t, os.WriteFile(path, []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: malformed\n"), 0644))
+	_, err = PopulateGlobals()
+	requireDefaultsError(t, err, ValidationError, path)
+	assert.Empty(t, GetGlobalCatalogSourceDefinitions())
+
+	require.NoError(t, os.Remove(path))
+	_, err = PopulateGlobals()
+	require.NoError(t, err)
+}
+
+func TestEnsureAPIError(t *testing.T) {
+	writeManifests(t, "redhat-operators")
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	forbidden := k8sErrors.NewForbidden(schema.GroupResource{Group: "operators.coreos.com", Resource: "catalogsources"}, "redhat-operators", errors.New("denied"))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			return forbidden
+		},
+	}).Build()
+
+	definitions, config := GetGlobals()
+	err = New(definitions, config).Ensure(context.TODO(), wrapper.NewClient(c), "redhat-operators")
+	defaultsErr := requireDefaultsError(t, err, APIError, "")
+	assert.Equal(t, forbidden, defaultsErr.Cause)
+	assert.True(t, k8sErrors.IsForbidden(err), "the API error is still recognized")
+}
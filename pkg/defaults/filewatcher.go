@@ -0,0 +1,147 @@
+package defaults
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultPollInterval is the interval at which the FileWatcher checks the
+// watched path for changes on platforms where inotify is not available.
+const defaultPollInterval = 5 * time.Second
+
+// errInotifyUnsupported is returned when inotify based watching is attempted
+// on a platform other than Linux.
+var errInotifyUnsupported = errors.New("inotify is only supported on linux")
+
+// FileWatcher watches a file or a directory on disk and invokes a callback
+// whenever its contents change. On Linux it relies on inotify, which is what
+// the operator uses when running in a container. On other platforms, such as
+// macOS and Windows, it falls back to polling the path on an interval.
+type FileWatcher struct {
+	path         string
+	pollInterval time.Duration
+	onChange     func()
+}
+
+// NewFileWatcher returns a FileWatcher for the given path. The onChange
+// function is called every time a change is detected. If pollInterval is zero
+// the default poll interval is used.
+func NewFileWatcher(path string, pollInterval time.Duration, onChange func()) *FileWatcher {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	return &FileWatcher{
+		path:         path,
+		pollInterval: pollInterval,
+		onChange:     onChange,
+	}
+}
+
+// Run starts watching the path in a separate goroutine until the context is
+// cancelled. An error is returned if the watch could not be established.
+func (w *FileWatcher) Run(ctx context.Context) error {
+	if _, err := os.Stat(w.path); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "linux" {
+		return w.watchInotify(ctx)
+	}
+	return w.watchPoll(ctx)
+}
+
+// notify invokes the onChange callback if one was provided.
+func (w *FileWatcher) notify() {
+	if w.onChange != nil {
+		w.onChange()
+	}
+}
+
+// watchPoll periodically takes a snapshot of the watched path and calls
+// notify whenever the snapshot differs from the previous one.
+func (w *FileWatcher) watchPoll(ctx context.Context) error {
+	previous, err := snapshot(w.path)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(w.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				logrus.Debugf("[defaults] Terminating poll watcher for %s", w.path)
+				return
+			case <-ticker.C:
+				current, err := snapshot(w.path)
+				if err != nil {
+					logrus.Warnf("[defaults] Error polling %s - %v", w.path, err)
+					continue
+				}
+				if !snapshotsEqual(previous, current) {
+					previous = current
+					w.notify()
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// fileState is the state of a single file used to detect changes while
+// polling.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshot returns the state of the given path. If the path is a directory,
+// the state of each of its entries is recorded. Symlinks are followed so that
+// the atomic symlink swaps performed by ConfigMap mounts are detected.
+func snapshot(path string) (map[string]fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	states := map[string]fileState{path: {size: info.Size(), modTime: info.ModTime()}}
+	if !info.IsDir() {
+		return states, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := filepath.Join(path, entry.Name())
+		entryInfo, err := os.Stat(name)
+		if err != nil {
+			// The entry may have been removed between ReadDir and Stat.
+			continue
+		}
+		states[name] = fileState{size: entryInfo.Size(), modTime: entryInfo.ModTime()}
+	}
+	return states, nil
+}
+
+// snapshotsEqual returns true if both snapshots describe the same state.
+func snapshotsEqual(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, state := range a {
+		other, present := b[name]
+		if !present || other.size != state.size || !other.modTime.Equal(state.modTime) {
+			return false
+		}
+	}
+	return true
+}
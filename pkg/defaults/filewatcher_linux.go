@@ -0,0 +1,113 @@
+//go:build linux
+
+package defaults
+
+import (
+	"context"
+	"fmt"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// inotifyEvents is the set of inotify events that are considered a change
+	// to the watched path.
+	inotifyEvents = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MODIFY | unix.IN_CLOSE_WRITE |
+		unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_ATTRIB | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+	// inotifyPollTimeout is the time in milliseconds to wait for events before
+	// checking if the context has been cancelled.
+	inotifyPollTimeout = 500
+)
+
+// watchInotify registers an inotify watch on the watched path and calls
+// notify whenever events are read from it. When the watched file is removed or
+// replaced, for instance by an atomic rename or a symlink swap, its watch is
+// dropped by the kernel, so the watch is added again on the path once it
+// exists.
+func (w *FileWatcher) watchInotify(ctx context.Context) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return fmt.Errorf("failed to initialize inotify: %v", err)
+	}
+
+	wd, err := unix.InotifyAddWatch(fd, w.path, inotifyEvents)
+	if err != nil {
+		unix.Close(fd)
+		return fmt.Errorf("failed to add inotify watch on %s: %v", w.path, err)
+	}
+	logrus.Debugf("[defaults] Monitoring path %s", w.path)
+
+	go func() {
+		defer unix.Close(fd)
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		for {
+			select {
+			case <-ctx.Done():
+				logrus.Debugf("[defaults] Terminating inotify watcher for %s", w.path)
+				return
+			default:
+			}
+
+			if wd < 0 {
+				// The path is watched again once it has been replaced, and
+				// its replacement is a change of its own.
+				if wd, err = unix.InotifyAddWatch(fd, w.path, inotifyEvents); err == nil {
+					logrus.Debugf("[defaults] Monitoring path %s again", w.path)
+					w.notify()
+				}
+			}
+
+			n, err := unix.Poll(fds, inotifyPollTimeout)
+			if err != nil {
+				if err == unix.EINTR {
+					continue
+				}
+				logrus.Errorf("[defaults] Error waiting for inotify events on %s - %v", w.path, err)
+				return
+			}
+			if n == 0 {
+				continue
+			}
+
+			// Drain every pending event so that a burst of changes results
+			// in a single notification.
+			changed := false
+			for {
+				read, err := unix.Read(fd, buf)
+				if err != nil || read <= 0 {
+					break
+				}
+				changed = true
+				if wd >= 0 && watchRemoved(buf[:read], wd) {
+					// A file moved away keeps its watch, which is of no use
+					// anymore.
+					unix.InotifyRmWatch(fd, uint32(wd))
+					wd = -1
+				}
+			}
+			if changed {
+				w.notify()
+			}
+		}
+	}()
+	return nil
+}
+
+// watchRemoved reports whether the given inotify events show that the watch
+// wd no longer follows the watched path, because the kernel removed it or
+// because the watched file was moved away.
+func watchRemoved(events []byte, wd int) bool {
+	removed := false
+	for offset := 0; offset+unix.SizeofInotifyEvent <= len(events); {
+		event := (*unix.InotifyEvent)(unsafe.Pointer(&events[offset]))
+		if int(event.Wd) == wd && event.Mask&(unix.IN_IGNORED|unix.IN_DELETE_SELF|unix.IN_MOVE_SELF) != 0 {
+			removed = true
+		}
+		offset += unix.SizeofInotifyEvent + int(event.Len)
+	}
+	return removed
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package defaults
+
+import "context"
+
+// watchInotify is not supported outside of Linux, the FileWatcher polls the
+// watched path instead.
+func (w *FileWatcher) watchInotify(_ context.Context) error {
+	return errInotifyUnsupported
+}
@@ -0,0 +1,154 @@
+package defaults
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// watch starts watching the path, by polling it if poll is set, and returns
+// the channel the changes are signalled on.
+func watch(t *testing.T, path string, poll bool) <-chan struct{} {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	changes := make(chan struct{}, 1)
+	w := NewFileWatcher(path, 10*time.Millisecond, func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+	if poll {
+		require.NoError(t, w.watchPoll(ctx))
+	} else {
+		require.NoError(t, w.Run(ctx))
+	}
+	return changes
+}
+
+// expectChange waits for the pending notifications to settle, then applies
+// the change and expects it to be signalled.
+func expectChange(t *testing.T, changes <-chan struct{}, change func()) {
+	t.Helper()
+	for settled := false; !settled; {
+		select {
+		case <-changes:
+		case <-time.After(200 * time.Millisecond):
+			settled = true
+		}
+	}
+	change()
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the change was not detected")
+	}
+}
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+}
+
+// replaceFile atomically replaces the file with new contents, as editors and
+// configuration management tools do.
+func replaceFile(t *testing.T, path, contents string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	writeFile(t, tmp, contents)
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+// swapData points the ..data symlink of the directory at a new timestamped
+// directory holding the file, and removes the previous one, the way the
+// kubelet updates a mounted ConfigMap.
+func swapData(t *testing.T, dir, version, name, contents string) {
+	t.Helper()
+	previous, _ := os.Readlink(filepath.Join(dir, "..data"))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, version), 0755))
+	writeFile(t, filepath.Join(dir, version, name), contents)
+	require.NoError(t, os.Symlink(version, filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	if previous != "" {
+		require.NoError(t, os.RemoveAll(filepath.Join(dir, previous)))
+	}
+}
+
+// configMapDir returns a directory laid out like a mounted ConfigMap holding
+// a single file, and the path of that file.
+func configMapDir(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	swapData(t, dir, "..v1", "operatorhub.yaml", "v1")
+	require.NoError(t, os.Symlink(filepath.Join("..data", "operatorhub.yaml"), filepath.Join(dir, "operatorhub.yaml")))
+	return dir, filepath.Join(dir, "operatorhub.yaml")
+}
+
+func TestFileWatcherRunMissingPath(t *testing.T) {
+	w := NewFileWatcher(filepath.Join(t.TempDir(), "missing"), 0, nil)
+	require.True(t, os.IsNotExist(w.Run(context.TODO())))
+}
+
+func TestFileWatcher(t *testing.T) {
+	for _, mode := range []struct {
+		name string
+		poll bool
+	}{
+		{name: "inotify"},
+		{name: "poll", poll: true},
+	} {
+		t.Run(mode.name, func(t *testing.T) {
+			t.Run("directory", func(t *testing.T) {
+				dir := t.TempDir()
+				changes := watch(t, dir, mode.poll)
+
+				expectChange(t, changes, func() { writeFile(t, filepath.Join(dir, "a.yaml"), "a") })
+				expectChange(t, changes, func() { writeFile(t, filepath.Join(dir, "a.yaml"), "ab") })
+				expectChange(t, changes, func() { require.NoError(t, os.Remove(filepath.Join(dir, "a.yaml"))) })
+			})
+
+			t.Run("file", func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "operatorhub.yaml")
+				writeFile(t, path, "v1")
+				changes := watch(t, path, mode.poll)
+
+				expectChange(t, changes, func() { writeFile(t, path, "v22") })
+			})
+
+			t.Run("file replaced", func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "operatorhub.yaml")
+				writeFile(t, path, "v1")
+				changes := watch(t, path, mode.poll)
+
+				expectChange(t, changes, func() { replaceFile(t, path, "v22") })
+				// The replacement is watched in turn.
+				expectChange(t, changes, func() { writeFile(t, path, "v333") })
+				expectChange(t, changes, func() { replaceFile(t, path, "v4444") })
+			})
+
+			t.Run("directory symlink swap", func(t *testing.T) {
+				dir, _ := configMapDir(t)
+				changes := watch(t, dir, mode.poll)
+
+				expectChange(t, changes, func() { swapData(t, dir, "..v2", "operatorhub.yaml", "v22") })
+				expectChange(t, changes, func() { swapData(t, dir, "..v3", "operatorhub.yaml", "v333") })
+			})
+
+			t.Run("file symlink swap", func(t *testing.T) {
+				dir, path := configMapDir(t)
+				changes := watch(t, path, mode.poll)
+
+				expectChange(t, changes, func() { swapData(t, dir, "..v2", "operatorhub.yaml", "v22") })
+				// The file the symlink now resolves to is watched.
+				expectChange(t, changes, func() { writeFile(t, filepath.Join(dir, "..v2", "operatorhub.yaml"), "v333") })
+				expectChange(t, changes, func() { swapData(t, dir, "..v3", "operatorhub.yaml", "v4444") })
+			})
+		})
+	}
+}
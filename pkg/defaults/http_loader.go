@@ -0,0 +1,85 @@
+package defaults
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHTTPLoaderTimeout is the default time given to a request for a
+	// manifest to complete.
+	DefaultHTTPLoaderTimeout = 30 * time.Second
+
+	// maxManifestSize bounds the size of a manifest fetched from a URL.
+	maxManifestSize = 1 << 20
+)
+
+// HTTPLoader fetches a manifest from an HTTP or HTTPS URL. The manifest
+// fetched is cached along with its ETag, so that it is only downloaded again
+// once modified. It is safe for concurrent use.
+type HTTPLoader struct {
+	url    string
+	client *http.Client
+
+	mutex   sync.Mutex
+	etag    string
+	content []byte
+}
+
+var _ Loader = &HTTPLoader{}
+
+// NewHTTPLoader returns an HTTPLoader fetching the manifest at url, giving up
+// on a request after timeout.
+func NewHTTPLoader(url string, timeout time.Duration) *HTTPLoader {
+	return &HTTPLoader{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Source returns the URL the manifest is fetched from.
+func (l *HTTPLoader) Source() string {
+	return l.url
+}
+
+// Load fetches the manifest, or returns the cached manifest if the server
+// reports it was not modified since it was fetched.
+func (l *HTTPLoader) Load(ctx context.Context) ([]Manifest, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url, nil)
+	if err != nil {
+		return nil, &DefaultsError{Kind: ValidationError, Path: l.url, Cause: err}
+	}
+	if l.etag != "" {
+		request.Header.Set("If-None-Match", l.etag)
+	}
+	response, err := l.client.Do(request)
+	if err != nil {
+		return nil, &DefaultsError{Kind: HTTPError, Path: l.url, Cause: err}
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if l.content != nil {
+			return []Manifest{{Path: l.url, Content: l.content, Source: l.url}}, nil
+		}
+		fallthrough
+	default:
+		return nil, &DefaultsError{Kind: HTTPError, Path: l.url, Cause: fmt.Errorf("unexpected status %s", response.Status)}
+	}
+
+	content, err := io.ReadAll(io.LimitReader(response.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, &DefaultsError{Kind: HTTPError, Path: l.url, Cause: err}
+	}
+	if len(content) > maxManifestSize {
+		return nil, &DefaultsError{Kind: ValidationError, Path: l.url, Cause: fmt.Errorf("the manifest exceeds %d bytes", maxManifestSize)}
+	}
+	l.etag, l.content = response.Header.Get("ETag"), content
+	return []Manifest{{Path: l.url, Content: content, Source: l.url}}, nil
+}
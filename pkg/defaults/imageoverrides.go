@@ -0,0 +1,93 @@
+package defaults
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/sirupsen/logrus"
+)
+
+// ImageOverrideEnvPrefix is the prefix of the environment variables overriding
+// the image of a default CatalogSource, as in
+// DEFAULT_CATALOGSOURCE_IMAGE_REDHAT_OPERATORS for redhat-operators.
+const ImageOverrideEnvPrefix = "DEFAULT_CATALOGSOURCE_IMAGE_"
+
+// ImageOverrideEnv returns the environment variable overriding the image of
+// the default CatalogSource name: ImageOverrideEnvPrefix followed by the name
+// in upper case, each character other than a letter or a digit being replaced
+// by an underscore.
+func ImageOverrideEnv(name string) string {
+	normalized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	return ImageOverrideEnvPrefix + normalized
+}
+
+// ImageOverridesFromEnv returns the images set by the environment variables
+// prefixed with ImageOverrideEnvPrefix, keyed by variable name. The variables
+// that are empty are ignored.
+func ImageOverridesFromEnv() map[string]string {
+	overrides := make(map[string]string)
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(name, ImageOverrideEnvPrefix) && value != "" {
+			overrides[name] = value
+		}
+	}
+	return overrides
+}
+
+// overrideImages replaces the image of each definition with the one of its
+// variable in overrides, keyed by variable name, so that the images take
+// precedence over the manifests and their patches. The variables matching no
+// definition are logged, rather than failing the population, as they may
+// target a CatalogSource that is no longer a default.
+func overrideImages(catsrcDefinitions map[string]olmv1alpha1.CatalogSource, overrides map[string]string) {
+	used := make(map[string]bool, len(overrides))
+	for name, def := range catsrcDefinitions {
+		env := ImageOverrideEnv(name)
+		image, ok := overrides[env]
+		if !ok {
+			continue
+		}
+		used[env] = true
+		if def.Spec.Image == image {
+			continue
+		}
+		logrus.Infof("[defaults] Overriding the image %s of CatalogSource %s with %s from %s", def.Spec.Image, name, image, env)
+		def.Spec.Image = image
+		catsrcDefinitions[name] = def
+	}
+
+	unused := make([]string, 0, len(overrides)-len(used))
+	for env := range overrides {
+		if !used[env] {
+			unused = append(unused, env)
+		}
+	}
+	sort.Strings(unused)
+	for _, env := range unused {
+		logrus.Warnf("[defaults] Ignoring %s, it does not match any default CatalogSource", env)
+	}
+}
+
+// logImages logs the image each default CatalogSource is applied with.
+func logImages(catsrcDefinitions map[string]olmv1alpha1.CatalogSource) {
+	names := make([]string, 0, len(catsrcDefinitions))
+	for name := range catsrcDefinitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		logrus.Infof("[defaults] CatalogSource %s uses image %s", name, catsrcDefinitions[name].Spec.Image)
+	}
+}
@@ -0,0 +1,91 @@
+package defaults
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	wrapper "github.com/operator-framework/operator-marketplace/pkg/client"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestImageOverrideEnv(t *testing.T) {
+	assert.Equal(t, "DEFAULT_CATALOGSOURCE_IMAGE_REDHAT_OPERATORS", ImageOverrideEnv("redhat-operators"))
+	assert.Equal(t, "DEFAULT_CATALOGSOURCE_IMAGE_REDHAT_MARKETPLACE_V2", ImageOverrideEnv("redhat.marketplace-v2"))
+}
+
+// populateWithOverrides populates the globals from the manifests of names
+// with the environment setting the given image overrides, and resets them
+// once the test completes.
+func populateWithOverrides(t *testing.T, overrides map[string]string, names ...string) {
+	t.Helper()
+	writeManifests(t, names...)
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	for env, image := range overrides {
+		t.Setenv(env, image)
+	}
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+}
+
+func TestPopulateGlobalsImageOverridesFromEnv(t *testing.T) {
+	populateWithOverrides(t, map[string]string{
+		"DEFAULT_CATALOGSOURCE_IMAGE_REDHAT_OPERATORS": "mirror.example.com:5000/redhat/redhat-operator-index:v4.17",
+	}, "redhat-operators", "certified-operators")
+
+	definitions := GetGlobalCatalogSourceDefinitions()
+	assert.Equal(t, "mirror.example.com:5000/redhat/redhat-operator-index:v4.17", definitions["redhat-operators"].Spec.Image, "the environment takes precedence over the manifest")
+	assert.Equal(t, "quay.io/example/certified-operators:latest", definitions["certified-operators"].Spec.Image)
+}
+
+func TestPopulateGlobalsUnknownImageOverride(t *testing.T) {
+	hook := logtest.NewGlobal()
+	t.Cleanup(hook.Reset)
+	populateWithOverrides(t, map[string]string{
+		"DEFAULT_CATALOGSOURCE_IMAGE_COMMUNITY_OPERATORS": "mirror.example.com:5000/community-operator-index:v4.17",
+	}, "redhat-operators")
+
+	assert.Equal(t, "quay.io/example/redhat-operators:latest", GetGlobalCatalogSourceDefinitions()["redhat-operators"].Spec.Image)
+	warned := false
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel && entry.Message == "[defaults] Ignoring DEFAULT_CATALOGSOURCE_IMAGE_COMMUNITY_OPERATORS, it does not match any default CatalogSource" {
+			warned = true
+		}
+	}
+	assert.True(t, warned, "an override matching no default CatalogSource is logged")
+}
+
+func TestEnsureRestoresOverriddenImage(t *testing.T) {
+	populateWithOverrides(t, map[string]string{
+		"DEFAULT_CATALOGSOURCE_IMAGE_REDHAT_OPERATORS": "mirror.example.com:5000/redhat/redhat-operator-index:v4.17",
+	}, "redhat-operators")
+
+	client := newFakeClient(t)
+	definitions, config := GetGlobals()
+	require.NoError(t, New(definitions, config).Ensure(context.TODO(), client, "redhat-operators"))
+	restored := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, client.Get(context.TODO(), wrapper.ObjectKey{Namespace: "openshift-marketplace", Name: "redhat-operators"}, restored))
+	assert.Equal(t, "mirror.example.com:5000/redhat/redhat-operator-index:v4.17", restored.Spec.Image)
+}
+
+func TestDefinitionsFromConfigMapIgnoresImageOverrides(t *testing.T) {
+	t.Setenv("DEFAULT_CATALOGSOURCE_IMAGE_REDHAT_OPERATORS", "mirror.example.com:5000/redhat/redhat-operator-index:v4.17")
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenant-defaults", Namespace: "tenant"},
+		Data:       map[string]string{"redhat-operators.yaml": fmt.Sprintf(catsrcManifest, "redhat-operators", "redhat-operators")},
+	}
+
+	definitions, err := DefinitionsFromConfigMap(configMap)
+	require.NoError(t, err)
+	assert.Equal(t, "quay.io/example/redhat-operators:latest", definitions["redhat-operators"].Spec.Image)
+}
@@ -0,0 +1,134 @@
+package defaults
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/sirupsen/logrus"
+)
+
+// Loader reads the default CatalogSource manifests from a source.
+type Loader interface {
+	// Source returns the name of the source the manifests are read from, the
+	// errors common to every manifest are reported against it.
+	Source() string
+	// Load returns the manifests read from the source. The error returned,
+	// if any, is a *DefaultsError.
+	Load(ctx context.Context) ([]Manifest, error)
+}
+
+// manifestExtensions are the extensions of the files of a defaults directory
+// that are read as manifests, in YAML or JSON.
+var manifestExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// DirLoader reads a manifest from each YAML or JSON file of the directory Dir,
+// in the order of their names. The hidden files, such as the ..data symlink of
+// a ConfigMap mount, the directories and the files of other extensions are
+// ignored. No manifest is read if Dir is empty.
+type DirLoader struct {
+	Dir string
+}
+
+var _ Loader = DirLoader{}
+
+// Source returns the directory the manifests are read from.
+func (l DirLoader) Source() string {
+	return l.Dir
+}
+
+// Load returns the manifest of each file of the directory.
+func (l DirLoader) Load(_ context.Context) ([]Manifest, error) {
+	if l.Dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(l.Dir)
+	if err != nil {
+		return nil, &DefaultsError{Kind: FilesystemError, Path: l.Dir, Cause: err}
+	}
+
+	manifests := make([]Manifest, 0, len(entries))
+	for _, entry := range entries {
+		path := filepath.Join(l.Dir, entry.Name())
+		if strings.HasPrefix(entry.Name(), ".") || !manifestExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+			logrus.Debugf("[defaults] Ignoring %s, it is not a YAML or JSON file", path)
+			continue
+		}
+		// The files of a ConfigMap mount are symlinks, which are followed.
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, newFileError(path, err)
+		}
+		if info.IsDir() {
+			logrus.Debugf("[defaults] Ignoring directory %s", path)
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, newFileError(path, err)
+		}
+		manifests = append(manifests, Manifest{Path: path, Content: content, Source: l.Dir})
+	}
+	return manifests, nil
+}
+
+// MultiSourceLoader merges the manifests read by several loaders. A
+// CatalogSource defined by several loaders is defined by the last one, see
+// Manifest.Source.
+type MultiSourceLoader struct {
+	loaders []Loader
+}
+
+var _ Loader = &MultiSourceLoader{}
+
+// NewMultiSourceLoader returns a MultiSourceLoader merging the manifests read
+// by loaders, in order.
+func NewMultiSourceLoader(loaders ...Loader) *MultiSourceLoader {
+	return &MultiSourceLoader{loaders: loaders}
+}
+
+// Source returns the comma-separated sources of the loaders.
+func (l *MultiSourceLoader) Source() string {
+	sources := make([]string, 0, len(l.loaders))
+	for _, loader := range l.loaders {
+		if source := loader.Source(); source != "" {
+			sources = append(sources, source)
+		}
+	}
+	return strings.Join(sources, ",")
+}
+
+// Load returns the manifests read by every loader, in order. No manifest is
+// returned if one of the loaders fails.
+func (l *MultiSourceLoader) Load(ctx context.Context) ([]Manifest, error) {
+	var manifests []Manifest
+	for _, loader := range l.loaders {
+		loaded, err := loader.Load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, loaded...)
+	}
+	return manifests, nil
+}
+
+// PopulateGlobalsFrom populates the global definitions and default config
+// from the manifests read by loader. The error returned, if any, is a
+// *DefaultsError.
+func PopulateGlobalsFrom(ctx context.Context, loader Loader, opts ...Option) (PopulationResult, error) {
+	catsrcDefinitions, config, deps, err := populateDefsConfigFrom(ctx, loader, newPopulateOptions(opts))
+	return setGlobals(loader.Source(), catsrcDefinitions, config, deps, err)
+}
+
+// populateDefsConfigFrom returns the CatalogSource definitions, the enabled
+// config and the dependencies of each CatalogSource from the manifests read
+// by loader, like populateDefsConfig does for a directory.
+func populateDefsConfigFrom(ctx context.Context, loader Loader, options populateOptions) (map[string]olmv1alpha1.CatalogSource, map[string]bool, map[string][]string, error) {
+	manifests, err := loader.Load(ctx)
+	if err != nil {
+		return emptyDefsConfig(err)
+	}
+	return defsConfigFromManifests(ctx, loader.Source(), manifests, options)
+}
@@ -0,0 +1,243 @@
+package defaults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// manifestServer serves the manifest of a CatalogSource with the given ETag,
+// answering 304 to the requests presenting it, and counts the manifests
+// served in full.
+func manifestServer(t *testing.T, etag string, content *atomic.Value, served *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		served.Add(1)
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(content.Load().(string)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPLoaderETag(t *testing.T) {
+	content := &atomic.Value{}
+	content.Store(fmt.Sprintf(catsrcManifest, "remote-operators", "remote-operators"))
+	served := &atomic.Int32{}
+	server := manifestServer(t, `"v1"`, content, served)
+	loader := NewHTTPLoader(server.URL, time.Second)
+
+	manifests, err := loader.Load(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	assert.Equal(t, server.URL, manifests[0].Path)
+	assert.Contains(t, string(manifests[0].Content), "remote-operators")
+
+	content.Store("modified but with the same ETag")
+	manifests, err = loader.Load(context.TODO())
+	require.NoError(t, err)
+	assert.Contains(t, string(manifests[0].Content), "remote-operators", "a manifest not modified is served from the cache")
+	assert.Equal(t, int32(1), served.Load())
+}
+
+func TestHTTPLoaderErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/not-modified":
+			// A server answering 304 to a request without an ETag.
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	for path, kind := range map[string]string{
+		"/missing":      HTTPError,
+		"/not-modified": HTTPError,
+		"/slow":         HTTPError,
+	} {
+		_, err := NewHTTPLoader(server.URL+path, 50*time.Millisecond).Load(context.TODO())
+		var defaultsErr *DefaultsError
+		require.True(t, errors.As(err, &defaultsErr), "%s: %v", path, err)
+		assert.Equal(t, kind, defaultsErr.Kind, path)
+		assert.Equal(t, server.URL+path, defaultsErr.Path, path)
+	}
+
+	_, err := NewHTTPLoader("://invalid", time.Second).Load(context.TODO())
+	assert.Error(t, err)
+}
+
+func TestPopulateGlobalsFromMultiSourceLoader(t *testing.T) {
+	writeManifests(t, "redhat-operators", "certified-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	content := &atomic.Value{}
+	// The remote manifest takes the place of the manifest in the directory.
+	content.Store(fmt.Sprintf(catsrcManifest, "certified-operators", "remote-certified-operators"))
+	served := &atomic.Int32{}
+	certified := manifestServer(t, `"certified"`, content, served)
+	communityContent := &atomic.Value{}
+	communityContent.Store(fmt.Sprintf(catsrcManifest, "community-operators", "community-operators"))
+	community := manifestServer(t, `"community"`, communityContent, served)
+
+	loader := NewMultiSourceLoader(DirLoader{Dir: Dir}, NewHTTPLoader(certified.URL, time.Second), NewHTTPLoader(community.URL, time.Second))
+	assert.Equal(t, Dir+","+certified.URL+","+community.URL, loader.Source())
+	_, err := PopulateGlobalsFrom(context.TODO(), loader)
+	require.NoError(t, err)
+	definitions := GetGlobalCatalogSourceDefinitions()
+	assert.Len(t, definitions, 3)
+	assert.Equal(t, "quay.io/example/remote-certified-operators:latest", definitions["certified-operators"].Spec.Image)
+	assert.Equal(t, "quay.io/example/redhat-operators:latest", definitions["redhat-operators"].Spec.Image)
+
+	// No definition is populated if one of the sources fails.
+	community.Close()
+	result, err := PopulateGlobalsFrom(context.TODO(), loader)
+	var defaultsErr *DefaultsError
+	require.True(t, errors.As(err, &defaultsErr))
+	assert.Equal(t, HTTPError, defaultsErr.Kind)
+	assert.Equal(t, []string{community.URL}, result.Failed)
+	assert.Empty(t, GetGlobalCatalogSourceDefinitions())
+}
+
+func TestDirLoader(t *testing.T) {
+	manifests, err := DirLoader{}.Load(context.TODO())
+	require.NoError(t, err)
+	assert.Empty(t, manifests, "no manifest is read without a directory")
+
+	_, err = DirLoader{Dir: "/does/not/exist"}.Load(context.TODO())
+	var defaultsErr *DefaultsError
+	require.True(t, errors.As(err, &defaultsErr))
+	assert.Equal(t, FilesystemError, defaultsErr.Kind)
+}
+
+// catsrcJSONManifest is the JSON equivalent of catsrcManifest.
+const catsrcJSONManifest = `{
+  "apiVersion": "operators.coreos.com/v1alpha1",
+  "kind": "CatalogSource",
+  "metadata": {"name": "%s", "namespace": "openshift-marketplace"},
+  "spec": {"sourceType": "grpc", "image": "quay.io/example/%s:latest"}
+}`
+
+func TestDirLoaderIgnoresUnrelatedFiles(t *testing.T) {
+	// The layout of a ConfigMap mount: the keys are symlinks into the ..data
+	// directory.
+	dir := t.TempDir()
+	data := filepath.Join(dir, "..2026_10_14_08_00_00.000000000")
+	require.NoError(t, os.Mkdir(data, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(data, "redhat-operators.yaml"), []byte("yaml"), 0644))
+	require.NoError(t, os.Symlink(filepath.Base(data), filepath.Join(dir, "..data")))
+	require.NoError(t, os.Symlink(filepath.Join("..data", "redhat-operators.yaml"), filepath.Join(dir, "redhat-operators.yaml")))
+	for name, content := range map[string]string{
+		"certified-operators.json": "json",
+		"community-operators.YML":  "yml",
+		".hidden.yaml":             "hidden",
+		"README.md":                "readme",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "patches.yaml"), 0755))
+
+	manifests, err := DirLoader{Dir: dir}.Load(context.TODO())
+	require.NoError(t, err)
+	var loaded []string
+	for _, m := range manifests {
+		assert.Equal(t, dir, m.Source)
+		loaded = append(loaded, filepath.Base(m.Path)+"="+string(m.Content))
+	}
+	assert.Equal(t, []string{"certified-operators.json=json", "community-operators.YML=yml", "redhat-operators.yaml=yaml"}, loaded)
+}
+
+func TestPopulateGlobalsFormats(t *testing.T) {
+	yamlManifest := func(name string) string { return fmt.Sprintf(catsrcManifest, name, name) }
+	jsonManifest := func(name string) string { return fmt.Sprintf(catsrcJSONManifest, name, name) }
+	for _, tt := range []struct {
+		name  string
+		files map[string]string
+		// names are the CatalogSources defined, if the files are valid.
+		names []string
+		// duplicate is the file rejected for defining a CatalogSource
+		// already defined by defined.
+		duplicate, defined string
+	}{
+		{
+			name:  "json",
+			files: map[string]string{"redhat-operators.json": jsonManifest("redhat-operators")},
+			names: []string{"redhat-operators"},
+		},
+		{
+			name: "mixed",
+			files: map[string]string{
+				"redhat-operators.yaml":    yamlManifest("redhat-operators"),
+				"certified-operators.json": jsonManifest("certified-operators"),
+				"community-operators.yml":  yamlManifest("community-operators"),
+			},
+			names: []string{"certified-operators", "community-operators", "redhat-operators"},
+		},
+		{
+			name: "json with dependsOn",
+			files: map[string]string{
+				"redhat-operators.yaml": yamlManifest("redhat-operators"),
+				"community-operators.json": `{"apiVersion": "operators.coreos.com/v1alpha1", "kind": "CatalogSource", "dependsOn": ["redhat-operators"],
+  "metadata": {"name": "community-operators", "namespace": "openshift-marketplace"},
+  "spec": {"sourceType": "grpc", "image": "quay.io/example/community-operators:latest"}}`,
+			},
+			names: []string{"community-operators", "redhat-operators"},
+		},
+		{
+			name: "duplicate across formats",
+			files: map[string]string{
+				"01-redhat-operators.yaml": yamlManifest("redhat-operators"),
+				"02-redhat-operators.json": jsonManifest("redhat-operators"),
+			},
+			duplicate: "02-redhat-operators.json",
+			defined:   "01-redhat-operators.yaml",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			writeManifests(t)
+			t.Cleanup(func() {
+				Dir = ""
+				_, err := PopulateGlobals()
+				require.NoError(t, err)
+			})
+			for name, content := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(Dir, name), []byte(content), 0644))
+			}
+
+			_, err := PopulateGlobals()
+			if tt.duplicate != "" {
+				requireDefaultsError(t, err, ValidationError, filepath.Join(Dir, tt.duplicate))
+				assert.Contains(t, err.Error(), "CatalogSource redhat-operators is already defined in "+filepath.Join(Dir, tt.defined))
+				assert.Empty(t, GetGlobalCatalogSourceDefinitions())
+				return
+			}
+			require.NoError(t, err)
+			var names []string
+			for name, def := range GetGlobalCatalogSourceDefinitions() {
+				names = append(names, name)
+				assert.Equal(t, "quay.io/example/"+name+":latest", def.Spec.Image)
+			}
+			assert.ElementsMatch(t, tt.names, names)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package defaults
+
+import (
+	"fmt"
+	"strings"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/sirupsen/logrus"
+)
+
+// ImageMirror rewrites the images of the default CatalogSources starting with
+// the Source repository prefix to start with the Mirror prefix instead, so
+// that their spec shows the registry they are actually pulled from.
+type ImageMirror struct {
+	Source string
+	Mirror string
+}
+
+// ParseImageMirrors parses a comma-separated list of <source>=<mirror>
+// repository prefixes, as in
+// registry.redhat.io=mirror.corp.example.com. An error is returned if a
+// source is given several mirrors, or if a mirror and a source are prefixes of
+// one another, as an image rewritten again would then change, so that the
+// rewrite is idempotent.
+func ParseImageMirrors(value string) ([]ImageMirror, error) {
+	var mirrors []ImageMirror
+	sources := make(map[string]bool)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		source, mirror, ok := strings.Cut(pair, "=")
+		source, mirror = strings.TrimSuffix(source, "/"), strings.TrimSuffix(mirror, "/")
+		if !ok || source == "" || mirror == "" {
+			return nil, fmt.Errorf("invalid image mirror %q, must be <source>=<mirror>", pair)
+		}
+		if sources[source] {
+			return nil, fmt.Errorf("repository prefix %s has several mirrors", source)
+		}
+		sources[source] = true
+		mirrors = append(mirrors, ImageMirror{Source: source, Mirror: mirror})
+	}
+	for _, m := range mirrors {
+		for _, other := range mirrors {
+			if isRepositoryPrefix(other.Source, m.Mirror) || isRepositoryPrefix(m.Mirror, other.Source) {
+				return nil, fmt.Errorf("mirror %s of %s overlaps with the source %s of another mirror", m.Mirror, m.Source, other.Source)
+			}
+		}
+	}
+	return mirrors, nil
+}
+
+// RewriteImage returns image with the repository prefix of the mirror with the
+// longest matching source replaced by its mirror, keeping the rest of the
+// repository and its tag or digest. image is returned as is if no source
+// matches it.
+func RewriteImage(image string, mirrors []ImageMirror) string {
+	m, ok := matchMirror(image, mirrors)
+	if !ok {
+		return image
+	}
+	return m.Mirror + strings.TrimPrefix(image, m.Source)
+}
+
+// matchMirror returns the mirror with the longest source that is a repository
+// prefix of image.
+func matchMirror(image string, mirrors []ImageMirror) (ImageMirror, bool) {
+	var match ImageMirror
+	found := false
+	for _, m := range mirrors {
+		if !isRepositoryPrefix(m.Source, image) {
+			continue
+		}
+		if !found || len(m.Source) > len(match.Source) {
+			match, found = m, true
+		}
+	}
+	return match, found
+}
+
+// isRepositoryPrefix returns true if image is prefix, or continues it with a
+// path component, or with a tag or a digest if prefix is a repository rather
+// than a registry host, whose port would follow a colon.
+func isRepositoryPrefix(prefix, image string) bool {
+	rest, ok := strings.CutPrefix(image, prefix)
+	if !ok {
+		return false
+	}
+	separators := "/"
+	if strings.Contains(prefix, "/") {
+		separators = "/:@"
+	}
+	return rest == "" || strings.ContainsAny(rest[:1], separators)
+}
+
+// mirrorImages rewrites the image of each definition with mirrors.
+func mirrorImages(catsrcDefinitions map[string]olmv1alpha1.CatalogSource, mirrors []ImageMirror) {
+	if len(mirrors) == 0 {
+		return
+	}
+	for name, def := range catsrcDefinitions {
+		image := RewriteImage(def.Spec.Image, mirrors)
+		if image == def.Spec.Image {
+			continue
+		}
+		logrus.Debugf("[defaults] Rewriting the image %s of CatalogSource %s to its mirror %s", def.Spec.Image, name, image)
+		def.Spec.Image = image
+		catsrcDefinitions[name] = def
+	}
+}
@@ -0,0 +1,96 @@
+package defaults
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImageMirrors(t *testing.T) {
+	mirrors, err := ParseImageMirrors("registry.redhat.io=mirror.corp.example.com, quay.io/openshift-community-operators=mirror.corp.example.com/community/,")
+	require.NoError(t, err)
+	assert.Equal(t, []ImageMirror{
+		{Source: "registry.redhat.io", Mirror: "mirror.corp.example.com"},
+		{Source: "quay.io/openshift-community-operators", Mirror: "mirror.corp.example.com/community"},
+	}, mirrors)
+
+	mirrors, err = ParseImageMirrors("")
+	require.NoError(t, err)
+	assert.Empty(t, mirrors)
+
+	for _, value := range []string{
+		"registry.redhat.io",
+		"=mirror.corp.example.com",
+		"registry.redhat.io=",
+		"registry.redhat.io=a.example.com,registry.redhat.io=b.example.com",
+		"registry.redhat.io=registry.redhat.io",
+		"registry.redhat.io=mirror.corp.example.com,mirror.corp.example.com/redhat=other.example.com",
+		"registry.redhat.io=mirror.corp.example.com/redhat,mirror.corp.example.com=other.example.com",
+	} {
+		_, err := ParseImageMirrors(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRewriteImage(t *testing.T) {
+	mirrors := []ImageMirror{
+		{Source: "registry.redhat.io", Mirror: "mirror.corp.example.com"},
+		{Source: "registry.redhat.io/redhat/community-operator-index", Mirror: "mirror.corp.example.com/community"},
+		{Source: "quay.io/example/catalog", Mirror: "mirror.corp.example.com/example"},
+	}
+	for _, tt := range []struct {
+		image, want string
+	}{
+		{"registry.redhat.io/redhat/redhat-operator-index:v4.17", "mirror.corp.example.com/redhat/redhat-operator-index:v4.17"},
+		{"registry.redhat.io/redhat/redhat-operator-index@" + testDigest, "mirror.corp.example.com/redhat/redhat-operator-index@" + testDigest},
+		{"registry.redhat.io/redhat/redhat-operator-index:v4.17@" + testDigest, "mirror.corp.example.com/redhat/redhat-operator-index:v4.17@" + testDigest},
+		// The longest source matching wins.
+		{"registry.redhat.io/redhat/community-operator-index:v4.17", "mirror.corp.example.com/community:v4.17"},
+		{"quay.io/example/catalog@" + testDigest, "mirror.corp.example.com/example@" + testDigest},
+		// The sources only match whole path components.
+		{"quay.io/example/catalog-v2:latest", "quay.io/example/catalog-v2:latest"},
+		{"registry.redhat.io.example.com/redhat/redhat-operator-index:v4.17", "registry.redhat.io.example.com/redhat/redhat-operator-index:v4.17"},
+		{"registry.redhat.io:443/redhat/redhat-operator-index:v4.17", "registry.redhat.io:443/redhat/redhat-operator-index:v4.17"},
+		{"quay.io/other/catalog:latest", "quay.io/other/catalog:latest"},
+	} {
+		t.Run(tt.image, func(t *testing.T) {
+			rewritten := RewriteImage(tt.image, mirrors)
+			assert.Equal(t, tt.want, rewritten)
+			assert.Equal(t, rewritten, RewriteImage(rewritten, mirrors), "the rewrite is idempotent")
+		})
+	}
+}
+
+func TestPopulateGlobalsMirrorsImages(t *testing.T) {
+	writeManifests(t, "redhat-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	mirrors, err := ParseImageMirrors("quay.io/example=mirror.corp.example.com")
+	require.NoError(t, err)
+	var resolved []string
+	resolver := digestResolverFunc(func(_ context.Context, image string) (string, error) {
+		resolved = append(resolved, image)
+		return testDigest, nil
+	})
+
+	_, err = PopulateGlobals(WithImageMirrors(mirrors), WithDigestResolver(resolver))
+	require.NoError(t, err)
+	desired, ok := GetDesiredCatalogSource("redhat-operators")
+	require.True(t, ok)
+	assert.Equal(t, "mirror.corp.example.com/redhat-operators:latest@"+testDigest, desired.Spec.Image)
+	assert.Equal(t, []string{"mirror.corp.example.com/redhat-operators:latest"}, resolved, "the digest is resolved from the mirror")
+
+	// Populating the defaults again yields the same spec, so that the
+	// default CatalogSources are not updated on each population.
+	hash := desired.Annotations[SpecHashAnnotationKey]
+	require.NotEmpty(t, hash)
+	_, err = PopulateGlobals(WithImageMirrors(mirrors), WithDigestResolver(resolver))
+	require.NoError(t, err)
+	desired, _ = GetDesiredCatalogSource("redhat-operators")
+	assert.Equal(t, hash, desired.Annotations[SpecHashAnnotationKey])
+}
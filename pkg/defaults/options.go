@@ -0,0 +1,84 @@
+package defaults
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Option configures how the global definitions are populated.
+type Option func(*populateOptions)
+
+// populateOptions holds the configuration the global definitions are
+// populated with.
+type populateOptions struct {
+	// templateData is the data the manifests are expanded with.
+	templateData TemplateData
+	// ownerReference, if not nil, is added to the owner references of every
+	// definition.
+	ownerReference *metav1.OwnerReference
+	// imageOverrides holds the images overriding the ones of the
+	// definitions, keyed by environment variable name.
+	imageOverrides map[string]string
+	// imageMirrors rewrite the repository prefixes of the images of the
+	// definitions.
+	imageMirrors []ImageMirror
+	// digestResolver, if not nil, pins the images of the definitions to
+	// the digest of their tag.
+	digestResolver DigestResolver
+}
+
+// newPopulateOptions returns the configuration set by opts. The manifests are
+// expanded with the data from the environment unless WithTemplateData is
+// given, and their images are overridden by the environment unless
+// WithImageOverrides is given.
+func newPopulateOptions(opts []Option) populateOptions {
+	options := populateOptions{templateData: TemplateDataFromEnv(), imageOverrides: ImageOverridesFromEnv()}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithTemplateData expands the manifests of the default CatalogSources with
+// data rather than with the data from the environment.
+func WithTemplateData(data TemplateData) Option {
+	return func(options *populateOptions) {
+		options.templateData = data
+	}
+}
+
+// WithImageOverrides overrides the images of the default CatalogSources with
+// overrides, keyed by the ImageOverrideEnv of the CatalogSource name, rather
+// than with the images set in the environment.
+func WithImageOverrides(overrides map[string]string) Option {
+	return func(options *populateOptions) {
+		options.imageOverrides = overrides
+	}
+}
+
+// WithImageMirrors rewrites the images of the default CatalogSources with
+// mirrors, once the images are overridden and before their digest is
+// resolved, so that the digests are resolved from the mirrors.
+func WithImageMirrors(mirrors []ImageMirror) Option {
+	return func(options *populateOptions) {
+		options.imageMirrors = mirrors
+	}
+}
+
+// WithDigestResolver pins the image of each default CatalogSource that is not
+// pinned to a digest yet to the digest resolver resolves its tag to, once the
+// images are overridden and mirrored.
+func WithDigestResolver(resolver DigestResolver) Option {
+	return func(options *populateOptions) {
+		options.digestResolver = resolver
+	}
+}
+
+// WithOwnerReference adds owner to the owner references of every default
+// CatalogSource, so that the CatalogSources are associated with the object,
+// usually the operator Deployment, that manages them. The owner must be in
+// the namespace of the CatalogSources.
+func WithOwnerReference(owner metav1.OwnerReference) Option {
+	return func(options *populateOptions) {
+		options.ownerReference = &owner
+	}
+}
@@ -0,0 +1,89 @@
+package defaults
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// PatchDir is the directory where the strategic merge patches of the default
+// CatalogSources are placed on disk. It will be empty if the defaults are
+// used as is.
+var PatchDir string
+
+// patchExtensions are the extensions, in order of precedence, of the patch
+// files. A patch file is named after the CatalogSource it applies to.
+var patchExtensions = []string{".yaml", ".yml", ".json"}
+
+// CatalogSourcePatcher applies the strategic merge patches found in a
+// directory to the default CatalogSources, allowing cluster specific
+// overrides without modifying the defaults directory.
+type CatalogSourcePatcher struct {
+	dir string
+}
+
+// NewCatalogSourcePatcher returns a CatalogSourcePatcher reading the patches
+// from dir. If dir is empty the CatalogSources are left untouched.
+func NewCatalogSourcePatcher(dir string) *CatalogSourcePatcher {
+	return &CatalogSourcePatcher{dir: dir}
+}
+
+// Patch applies the patch matching the name of catsrc, if any, to catsrc. The
+// patch can be written in YAML or JSON but must not rename the CatalogSource.
+func (p *CatalogSourcePatcher) Patch(catsrc *olmv1alpha1.CatalogSource) error {
+	path, err := p.patchFile(catsrc.Name)
+	if err != nil || path == "" {
+		return err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	patch, err := yaml.ToJSON(content)
+	if err != nil {
+		return fmt.Errorf("invalid patch %s: %v", path, err)
+	}
+	original, err := json.Marshal(catsrc)
+	if err != nil {
+		return err
+	}
+	patched, err := strategicpatch.StrategicMergePatch(original, patch, olmv1alpha1.CatalogSource{})
+	if err != nil {
+		return fmt.Errorf("failed to apply patch %s to CatalogSource %s: %v", path, catsrc.Name, err)
+	}
+
+	result := &olmv1alpha1.CatalogSource{}
+	if err := json.Unmarshal(patched, result); err != nil {
+		return fmt.Errorf("failed to apply patch %s to CatalogSource %s: %v", path, catsrc.Name, err)
+	}
+	if result.Name != catsrc.Name {
+		return fmt.Errorf("patch %s must not rename CatalogSource %s", path, catsrc.Name)
+	}
+	*catsrc = *result
+	logrus.Infof("[defaults] Applied patch %s to CatalogSource %s", path, catsrc.Name)
+	return nil
+}
+
+// patchFile returns the path of the patch file for the CatalogSource with the
+// given name, or an empty string if there is none.
+func (p *CatalogSourcePatcher) patchFile(name string) (string, error) {
+	if p.dir == "" {
+		return "", nil
+	}
+	for _, extension := range patchExtensions {
+		path := filepath.Join(p.dir, name+extension)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,94 @@
+package defaults
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newCatsrc(name string) *olmv1alpha1.CatalogSource {
+	return &olmv1alpha1.CatalogSource{
+		TypeMeta: metav1.TypeMeta{Kind: "CatalogSource", APIVersion: "operators.coreos.com/v1alpha1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "openshift-marketplace",
+			Annotations: map[string]string{"existing": "annotation"},
+		},
+		Spec: olmv1alpha1.CatalogSourceSpec{
+			SourceType:  olmv1alpha1.SourceTypeGrpc,
+			Image:       "quay.io/example/" + name + ":latest",
+			DisplayName: name,
+		},
+	}
+}
+
+func writePatch(t *testing.T, dir, fileName, patch string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fileName), []byte(patch), 0644))
+}
+
+func TestCatalogSourcePatcher(t *testing.T) {
+	dir := t.TempDir()
+	writePatch(t, dir, "redhat-operators.yaml", `
+metadata:
+  annotations:
+    patched: "true"
+spec:
+  image: mirror.example.com/redhat-operators:latest
+`)
+	writePatch(t, dir, "certified-operators.json", `{"spec": {"priority": -100}}`)
+	writePatch(t, dir, "renamed.yaml", `{"metadata": {"name": "other"}}`)
+	writePatch(t, dir, "invalid.yaml", `spec: [image`)
+
+	patcher := NewCatalogSourcePatcher(dir)
+
+	catsrc := newCatsrc("redhat-operators")
+	require.NoError(t, patcher.Patch(catsrc))
+	assert.Equal(t, "mirror.example.com/redhat-operators:latest", catsrc.Spec.Image)
+	assert.Equal(t, map[string]string{"existing": "annotation", "patched": "true"}, catsrc.Annotations)
+	assert.Equal(t, "redhat-operators", catsrc.Spec.DisplayName, "the fields missing from the patch are kept")
+
+	catsrc = newCatsrc("certified-operators")
+	require.NoError(t, patcher.Patch(catsrc))
+	assert.Equal(t, -100, catsrc.Spec.Priority)
+
+	catsrc = newCatsrc("community-operators")
+	require.NoError(t, patcher.Patch(catsrc))
+	assert.Equal(t, newCatsrc("community-operators"), catsrc, "a CatalogSource without a patch is left untouched")
+
+	assert.Error(t, patcher.Patch(newCatsrc("renamed")))
+	assert.Error(t, patcher.Patch(newCatsrc("invalid")))
+
+	catsrc = newCatsrc("redhat-operators")
+	require.NoError(t, NewCatalogSourcePatcher("").Patch(catsrc))
+	assert.Equal(t, newCatsrc("redhat-operators"), catsrc)
+}
+
+func TestPopulateGlobalsAppliesPatches(t *testing.T) {
+	writeManifests(t, "redhat-operators", "certified-operators")
+	patchDir := t.TempDir()
+	writePatch(t, patchDir, "redhat-operators.yaml", "spec:\n  image: mirror.example.com/redhat-operators:latest\n")
+	previous := PatchDir
+	PatchDir = patchDir
+	t.Cleanup(func() {
+		PatchDir = previous
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+	definitions := GetGlobalCatalogSourceDefinitions()
+	assert.Equal(t, "mirror.example.com/redhat-operators:latest", definitions["redhat-operators"].Spec.Image)
+	assert.Equal(t, "quay.io/example/certified-operators:latest", definitions["certified-operators"].Spec.Image)
+
+	writePatch(t, patchDir, "certified-operators.yaml", "spec: [image")
+	_, err = PopulateGlobals()
+	assert.Error(t, err, "an invalid patch fails like an invalid definition")
+	assert.Empty(t, GetGlobalCatalogSourceDefinitions())
+}
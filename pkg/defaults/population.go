@@ -0,0 +1,82 @@
+package defaults
+
+import (
+	"errors"
+	"sort"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// PopulationResult reports how the default CatalogSource definitions changed
+// when the globals were populated, compared to the definitions previously
+// populated. Every list is sorted.
+type PopulationResult struct {
+	// Created holds the names of the definitions that were not previously
+	// populated.
+	Created []string
+	// Updated holds the names of the definitions that differ from the ones
+	// previously populated.
+	Updated []string
+	// Skipped holds the names of the definitions identical to the ones
+	// previously populated.
+	Skipped []string
+	// Failed holds the paths of the manifests, or the source, that could not
+	// be populated. No definition is populated if Failed is not empty.
+	Failed []string
+}
+
+// newPopulationResult compares the definitions populated to the previous ones.
+// If err is not nil, the result only holds the paths err was reported
+// against.
+func newPopulationResult(previous, populated map[string]olmv1alpha1.CatalogSource, err error) PopulationResult {
+	result := PopulationResult{}
+	if err != nil {
+		result.Failed = failedPaths(err)
+		return result
+	}
+
+	diff := Diff(previous, populated)
+	changed := make(map[string]bool)
+	for _, catsrc := range diff.Added {
+		result.Created = append(result.Created, catsrc.Name)
+		changed[catsrc.Name] = true
+	}
+	for _, catsrc := range diff.Updated {
+		result.Updated = append(result.Updated, catsrc.Name)
+		changed[catsrc.Name] = true
+	}
+	for name := range populated {
+		if !changed[name] {
+			result.Skipped = append(result.Skipped, name)
+		}
+	}
+	sort.Strings(result.Skipped)
+	return result
+}
+
+// failedPaths returns the paths err was reported against: the path of each
+// invalid manifest if several were aggregated, the path of the *DefaultsError
+// otherwise.
+func failedPaths(err error) []string {
+	var defaultsErr *DefaultsError
+	if !errors.As(err, &defaultsErr) {
+		return nil
+	}
+	var aggregate utilerrors.Aggregate
+	if !errors.As(defaultsErr.Cause, &aggregate) {
+		return []string{defaultsErr.Path}
+	}
+
+	var paths []string
+	for _, err := range aggregate.Errors() {
+		var manifestErr *manifestError
+		if errors.As(err, &manifestErr) {
+			paths = append(paths, manifestErr.path)
+		}
+	}
+	if len(paths) == 0 {
+		return []string{defaultsErr.Path}
+	}
+	return paths
+}
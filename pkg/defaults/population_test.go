@@ -0,0 +1,89 @@
+package defaults
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// populationMetricValue returns the number of default CatalogSources reported
+// for the given population result.
+func populationMetricValue(t *testing.T, result string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "marketplace_default_catalogsources_populated" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "result" && label.GetValue() == result {
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	t.Fatalf("no population metric for result %s", result)
+	return 0
+}
+
+func TestPopulateGlobalsResult(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+	writeManifests(t)
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	writeManifest := func(name, image string) {
+		require.NoError(t, os.WriteFile(filepath.Join(Dir, name+".yaml"), []byte(fmt.Sprintf(catsrcManifest, name, image)), 0644))
+	}
+	result, err := PopulateGlobals()
+	require.NoError(t, err)
+	assert.Equal(t, PopulationResult{}, result)
+
+	// Every definition is created on the first population.
+	writeManifest("redhat-operators", "redhat-operators")
+	writeManifest("certified-operators", "certified-operators")
+	result, err = PopulateGlobals()
+	require.NoError(t, err)
+	assert.Equal(t, PopulationResult{Created: []string{"certified-operators", "redhat-operators"}}, result)
+
+	// A mix of new, modified and pre-existing definitions.
+	writeManifest("community-operators", "community-operators")
+	writeManifest("certified-operators", "certified-operators-v2")
+	result, err = PopulateGlobals()
+	require.NoError(t, err)
+	assert.Equal(t, PopulationResult{
+		Created: []string{"community-operators"},
+		Updated: []string{"certified-operators"},
+		Skipped: []string{"redhat-operators"},
+	}, result)
+	assert.Equal(t, 1.0, populationMetricValue(t, "created"))
+	assert.Equal(t, 1.0, populationMetricValue(t, "updated"))
+	assert.Equal(t, 1.0, populationMetricValue(t, "skipped"))
+	assert.Equal(t, 0.0, populationMetricValue(t, "failed"))
+
+	// Every invalid manifest is reported as failed.
+	missingSourceType := filepath.Join(Dir, "missing-source-type.yaml")
+	require.NoError(t, os.WriteFile(missingSourceType, []byte("apiVersion: operators.coreos.com/v1alpha1\nkind: CatalogSource\nmetadata:\n  name: missing-source-type\n  namespace: openshift-marketplace\n"), 0644))
+	unparseable := filepath.Join(Dir, "unparseable.yaml")
+	require.NoError(t, os.WriteFile(unparseable, []byte("kind: [CatalogSource"), 0644))
+	result, err = PopulateGlobals()
+	require.Error(t, err)
+	assert.Equal(t, PopulationResult{Failed: []string{missingSourceType, unparseable}}, result)
+	assert.Equal(t, 0.0, populationMetricValue(t, "created"))
+	assert.Equal(t, 2.0, populationMetricValue(t, "failed"))
+
+	require.NoError(t, os.Remove(unparseable))
+	result, err = PopulateGlobals()
+	require.Error(t, err)
+	assert.Equal(t, PopulationResult{Failed: []string{missingSourceType}}, result)
+}
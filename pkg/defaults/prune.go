@@ -0,0 +1,82 @@
+package defaults
+
+import (
+	"context"
+	"fmt"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	apiutils "github.com/operator-framework/operator-marketplace/pkg/apis/operators/shared"
+	wrapper "github.com/operator-framework/operator-marketplace/pkg/client"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ManagedByLabelKey is the label set on the default CatalogSources the
+	// operator applies, so that the ones it no longer defines can be pruned.
+	ManagedByLabelKey = apiutils.ManagedByLabelKey
+
+	// ManagedByLabelValue is the value of ManagedByLabelKey on the default
+	// CatalogSources.
+	ManagedByLabelValue = "marketplace-operator"
+)
+
+// PruneObsolete deletes the CatalogSources of existing that are labeled as
+// managed by the operator and whose namespace and name are not the ones of
+// one of the desired CatalogSources, such as the default CatalogSources whose
+// definition was removed from Dir by an upgrade of the operator. The error
+// returned, if any, is an APIError *DefaultsError aggregating the failed
+// deletions.
+func PruneObsolete(ctx context.Context, client wrapper.Client, existing []olmv1alpha1.CatalogSource, desired []olmv1alpha1.CatalogSource) error {
+	keys := make(map[types.NamespacedName]bool, len(desired))
+	for _, catsrc := range desired {
+		keys[types.NamespacedName{Namespace: catsrc.Namespace, Name: catsrc.Name}] = true
+	}
+
+	var errs []error
+	for i := range existing {
+		catsrc := &existing[i]
+		key := types.NamespacedName{Namespace: catsrc.Namespace, Name: catsrc.Name}
+		if catsrc.Labels[ManagedByLabelKey] != ManagedByLabelValue || keys[key] || !catsrc.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if err := client.Delete(ctx, catsrc); err != nil && !k8sErrors.IsNotFound(err) {
+			logging.FromContext(ctx).Errorf("[defaults] Error deleting obsolete CatalogSource %s/%s - %v", catsrc.Namespace, catsrc.Name, err)
+			errs = append(errs, fmt.Errorf("%s/%s: %v", catsrc.Namespace, catsrc.Name, err))
+			continue
+		}
+		logging.FromContext(ctx).Infof("[defaults] Deleted obsolete CatalogSource %s/%s", catsrc.Namespace, catsrc.Name)
+	}
+	if len(errs) > 0 {
+		return &DefaultsError{Kind: APIError, Cause: utilerrors.NewAggregate(errs)}
+	}
+	return nil
+}
+
+// PruneObsoleteGlobals deletes the CatalogSources in the namespaces of the
+// global definitions that are labeled as managed by the operator but are not
+// one of the global definitions, see PruneObsolete. The copies of the default
+// CatalogSources in the other namespaces are left to their own controller,
+// and nothing is pruned if there are no global definitions.
+func PruneObsoleteGlobals(ctx context.Context, client wrapper.Client) error {
+	definitions := GetGlobalCatalogSourceDefinitions()
+	desired := make([]olmv1alpha1.CatalogSource, 0, len(definitions))
+	namespaces := make(map[string]bool)
+	for _, def := range definitions {
+		desired = append(desired, def)
+		namespaces[def.Namespace] = true
+	}
+
+	var existing []olmv1alpha1.CatalogSource
+	for namespace := range namespaces {
+		list := &olmv1alpha1.CatalogSourceList{}
+		if err := client.List(ctx, list, ctrlclient.InNamespace(namespace), ctrlclient.MatchingLabels{ManagedByLabelKey: ManagedByLabelValue}); err != nil {
+			return &DefaultsError{Kind: APIError, Cause: err}
+		}
+		existing = append(existing, list.Items...)
+	}
+	return PruneObsolete(ctx, client, existing, desired)
+}
@@ -0,0 +1,123 @@
+package defaults
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	wrapper "github.com/operator-framework/operator-marketplace/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func managedCatsrc(name string) *olmv1alpha1.CatalogSource {
+	return &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: "openshift-marketplace",
+		Labels:    map[string]string{ManagedByLabelKey: ManagedByLabelValue},
+	}}
+}
+
+// exists returns true if the CatalogSource with the given name is on the
+// cluster.
+func exists(t *testing.T, c wrapper.Client, name string) bool {
+	t.Helper()
+	err := c.Get(context.TODO(), wrapper.ObjectKey{Namespace: "openshift-marketplace", Name: name}, &olmv1alpha1.CatalogSource{})
+	if k8sErrors.IsNotFound(err) {
+		return false
+	}
+	require.NoError(t, err)
+	return true
+}
+
+func TestPruneObsolete(t *testing.T) {
+	unmanaged := &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Name: "custom-operators", Namespace: "openshift-marketplace"}}
+	existing := []*olmv1alpha1.CatalogSource{managedCatsrc("redhat-operators"), managedCatsrc("obsolete-operators"), unmanaged}
+	c := newFakeClient(t, existing...)
+
+	items := make([]olmv1alpha1.CatalogSource, 0, len(existing))
+	for _, catsrc := range existing {
+		items = append(items, *catsrc)
+	}
+	require.NoError(t, PruneObsolete(context.TODO(), c, items, []olmv1alpha1.CatalogSource{*managedCatsrc("redhat-operators")}))
+
+	assert.True(t, exists(t, c, "redhat-operators"), "a desired CatalogSource is kept")
+	assert.False(t, exists(t, c, "obsolete-operators"), "an obsolete CatalogSource is deleted")
+	assert.True(t, exists(t, c, "custom-operators"), "a CatalogSource not managed by the operator is kept")
+
+	// The desired CatalogSources are matched by namespace too.
+	tenant := managedCatsrc("redhat-operators")
+	tenant.Namespace = "tenant"
+	require.NoError(t, c.Create(context.TODO(), tenant))
+	require.NoError(t, PruneObsolete(context.TODO(), c, []olmv1alpha1.CatalogSource{*tenant}, []olmv1alpha1.CatalogSource{*managedCatsrc("redhat-operators")}))
+	err := c.Get(context.TODO(), wrapper.ObjectKey{Namespace: "tenant", Name: "redhat-operators"}, &olmv1alpha1.CatalogSource{})
+	assert.True(t, k8sErrors.IsNotFound(err), "a CatalogSource of another namespace is not desired")
+
+	// A CatalogSource already deleted is not an error.
+	require.NoError(t, PruneObsolete(context.TODO(), c, items, nil))
+	assert.False(t, exists(t, c, "redhat-operators"))
+}
+
+func TestPruneObsoleteAPIError(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+			return errors.New("denied")
+		},
+	}).Build()
+
+	err := PruneObsolete(context.TODO(), wrapper.NewClient(c), []olmv1alpha1.CatalogSource{*managedCatsrc("obsolete-operators")}, nil)
+	requireDefaultsError(t, err, APIError, "")
+	assert.Contains(t, err.Error(), "openshift-marketplace/obsolete-operators: denied")
+}
+
+func TestPruneObsoleteGlobals(t *testing.T) {
+	writeManifests(t, "redhat-operators", "certified-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+
+	// The default CatalogSources are labeled as managed by the operator.
+	c := newFakeClient(t)
+	definitions, config := GetGlobals()
+	require.Empty(t, New(definitions, config).EnsureAll(context.TODO(), c))
+	catsrc := &olmv1alpha1.CatalogSource{}
+	require.NoError(t, c.Get(context.TODO(), wrapper.ObjectKey{Namespace: "openshift-marketplace", Name: "certified-operators"}, catsrc))
+	assert.Equal(t, ManagedByLabelValue, catsrc.Labels[ManagedByLabelKey])
+
+	// The copies of the defaults in other namespaces are not pruned.
+	tenant := managedCatsrc("redhat-operators")
+	tenant.Namespace = "tenant"
+	require.NoError(t, c.Create(context.TODO(), tenant))
+
+	// Removing a file from the defaults directory prunes its CatalogSource.
+	require.NoError(t, os.Remove(filepath.Join(Dir, "certified-operators.yaml")))
+	_, err = PopulateGlobals()
+	require.NoError(t, err)
+	require.NoError(t, PruneObsoleteGlobals(context.TODO(), c))
+	assert.True(t, exists(t, c, "redhat-operators"))
+	assert.False(t, exists(t, c, "certified-operators"))
+	assert.NoError(t, c.Get(context.TODO(), wrapper.ObjectKey{Namespace: "tenant", Name: "redhat-operators"}, &olmv1alpha1.CatalogSource{}))
+
+	// Nothing is pruned without definitions, as when they failed to
+	// populate.
+	Dir = ""
+	_, err = PopulateGlobals()
+	require.NoError(t, err)
+	require.NoError(t, PruneObsoleteGlobals(context.TODO(), c))
+	assert.True(t, exists(t, c, "redhat-operators"))
+}
@@ -0,0 +1,41 @@
+package defaults
+
+import (
+	"strings"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+)
+
+// RegistrySecrets holds the name of the pull Secret, in the namespace of the
+// default CatalogSources, of each registry host. The Secret of the registry
+// of its image is referenced by each default CatalogSource.
+var RegistrySecrets = map[string]string{}
+
+// addRegistrySecret references the pull Secret of the registry of the image of
+// catsrc, if there is one.
+func addRegistrySecret(catsrc *olmv1alpha1.CatalogSource) {
+	secret, ok := RegistrySecrets[ImageRegistry(catsrc.Spec.Image)]
+	if !ok {
+		return
+	}
+	for _, name := range catsrc.Spec.Secrets {
+		if name == secret {
+			return
+		}
+	}
+	catsrc.Spec.Secrets = append(catsrc.Spec.Secrets, secret)
+}
+
+// ImageRegistry returns the registry host of the given image reference, which
+// is docker.io if the reference does not start with a host.
+func ImageRegistry(image string) string {
+	i := strings.IndexRune(image, '/')
+	if i == -1 {
+		return "docker.io"
+	}
+	host := image[:i]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return "docker.io"
+	}
+	return host
+}
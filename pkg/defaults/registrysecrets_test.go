@@ -0,0 +1,35 @@
+package defaults
+
+import (
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageRegistry(t *testing.T) {
+	for image, registry := range map[string]string{
+		"registry.redhat.io/redhat/redhat-operator-index:v4.16": "registry.redhat.io",
+		"localhost:5000/catalog:latest":                         "localhost:5000",
+		"localhost/catalog:latest":                              "localhost",
+		"library/catalog:latest":                                "docker.io",
+		"catalog":                                               "docker.io",
+	} {
+		assert.Equal(t, registry, ImageRegistry(image), image)
+	}
+}
+
+func TestAddRegistrySecret(t *testing.T) {
+	previous := RegistrySecrets
+	RegistrySecrets = map[string]string{"registry.example.com": "marketplace-oauth-registry-example-com"}
+	t.Cleanup(func() { RegistrySecrets = previous })
+
+	catsrc := &olmv1alpha1.CatalogSource{Spec: olmv1alpha1.CatalogSourceSpec{Image: "registry.example.com/catalog:latest", Secrets: []string{"other"}}}
+	addRegistrySecret(catsrc)
+	addRegistrySecret(catsrc)
+	assert.Equal(t, []string{"other", "marketplace-oauth-registry-example-com"}, catsrc.Spec.Secrets)
+
+	catsrc = &olmv1alpha1.CatalogSource{Spec: olmv1alpha1.CatalogSourceSpec{Image: "quay.io/example/catalog:latest"}}
+	addRegistrySecret(catsrc)
+	assert.Empty(t, catsrc.Spec.Secrets)
+}
@@ -0,0 +1,26 @@
+package defaults
+
+import (
+	"context"
+
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+)
+
+// ReloadGlobalsFrom populates the global definitions and default config again
+// from the manifests read by loader, like PopulateGlobalsFrom, when the
+// manifests change at runtime. Unlike PopulateGlobalsFrom, the previous
+// definitions and config are kept if the new manifests are invalid, so that a
+// broken update does not remove the default CatalogSources, and the failure
+// is counted in the reload failures metric. The error returned, if any, is a
+// *DefaultsError.
+func ReloadGlobalsFrom(ctx context.Context, loader Loader, opts ...Option) (PopulationResult, error) {
+	catsrcDefinitions, config, deps, err := populateDefsConfigFrom(ctx, loader, newPopulateOptions(opts))
+	if err == nil {
+		err = checkDependencies(loader.Source(), catsrcDefinitions, deps)
+	}
+	if err != nil {
+		metrics.IncDefaultCatalogSourceReloadFailures()
+		return PopulationResult{}, err
+	}
+	return setGlobals(loader.Source(), catsrcDefinitions, config, deps, nil)
+}
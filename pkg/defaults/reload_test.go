@@ -0,0 +1,130 @@
+package defaults
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counterValue returns the value of the unlabeled counter with the given name.
+func counterValue(t *testing.T, name string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	t.Fatalf("no metric %s found", name)
+	return 0
+}
+
+func TestReloadGlobalsFrom(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+	writeManifests(t, "redhat-operators", "certified-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+	loader := DirLoader{Dir: Dir}
+
+	// A valid update replaces the definitions.
+	require.NoError(t, os.Remove(filepath.Join(Dir, "certified-operators.yaml")))
+	require.NoError(t, os.WriteFile(filepath.Join(Dir, "redhat-operators.yaml"), []byte(fmt.Sprintf(catsrcManifest, "redhat-operators", "redhat-operators-v2")), 0644))
+	result, err := ReloadGlobalsFrom(context.Background(), loader)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"redhat-operators"}, result.Updated)
+	require.Len(t, GetGlobalCatalogSourceDefinitions(), 1)
+	assert.Equal(t, "quay.io/example/redhat-operators-v2:latest", GetGlobalCatalogSourceDefinitions()["redhat-operators"].Spec.Image)
+
+	// An invalid update keeps the previous definitions.
+	failures := counterValue(t, "marketplace_default_catalogsource_reload_failures_total")
+	require.NoError(t, os.WriteFile(filepath.Join(Dir, "redhat-operators.yaml"), []byte("kind: CatalogSource\nmetadata: [\n"), 0644))
+	_, err = ReloadGlobalsFrom(context.Background(), loader)
+	requireDefaultsError(t, err, ValidationError, filepath.Join(Dir, "redhat-operators.yaml"))
+	require.Len(t, GetGlobalCatalogSourceDefinitions(), 1)
+	assert.Equal(t, "quay.io/example/redhat-operators-v2:latest", GetGlobalCatalogSourceDefinitions()["redhat-operators"].Spec.Image)
+	assert.Contains(t, GetDefaultConfig(), "redhat-operators")
+	assert.Equal(t, failures+1, counterValue(t, "marketplace_default_catalogsource_reload_failures_total"))
+}
+
+func TestReloadGlobalsFromConcurrentReads(t *testing.T) {
+	writeManifests(t, "redhat-operators", "certified-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+	loader := DirLoader{Dir: Dir}
+
+	// The definitions returned are copies, that the callers may modify.
+	definitions := GetGlobalCatalogSourceDefinitions()
+	delete(definitions, "redhat-operators")
+	assert.Contains(t, GetGlobalCatalogSourceDefinitions(), "redhat-operators")
+
+	// The globals are read while they are reloaded, which the race detector
+	// reports if they are not guarded.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_, err := ReloadGlobalsFrom(context.Background(), loader)
+			assert.NoError(t, err)
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		for name := range GetGlobalCatalogSourceDefinitions() {
+			GetDesiredCatalogSource(name)
+			IsDefaultSource(name)
+		}
+		_, _ = GetDefinitionHashes()
+		GetDefaultConfig()
+	}
+}
+
+func TestOnPopulated(t *testing.T) {
+	writeManifests(t, "redhat-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	var populated []int
+	unregister := OnPopulated(func() { populated = append(populated, len(GetGlobalCatalogSourceDefinitions())) })
+
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+	writeManifests(t, "redhat-operators", "certified-operators")
+	_, err = ReloadGlobalsFrom(context.Background(), DirLoader{Dir: Dir})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, populated, "the hook is called once the definitions are set")
+
+	// The definitions kept on an invalid reload are not populated again.
+	require.NoError(t, os.WriteFile(filepath.Join(Dir, "redhat-operators.yaml"), []byte("kind: CatalogSource\nmetadata: [\n"), 0644))
+	_, err = ReloadGlobalsFrom(context.Background(), DirLoader{Dir: Dir})
+	require.Error(t, err)
+	assert.Len(t, populated, 2)
+
+	unregister()
+	_, err = PopulateGlobals()
+	require.Error(t, err)
+	assert.Len(t, populated, 2, "an unregistered hook is no longer called")
+}
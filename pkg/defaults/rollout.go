@@ -0,0 +1,201 @@
+package defaults
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	wrapper "github.com/operator-framework/operator-marketplace/pkg/client"
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// DefaultRolloutWaitTimeout is the time given by default to the default
+	// CatalogSources to become ready after each batch of a rollout.
+	DefaultRolloutWaitTimeout = 10 * time.Minute
+
+	// RolloutRequeueInterval is the interval at which the controllers
+	// applying the default CatalogSources check whether a rollout completed.
+	RolloutRequeueInterval = 10 * time.Second
+
+	// rolloutPollInterval is the interval at which the readiness of the
+	// default CatalogSources is checked between the batches of a rollout.
+	rolloutPollInterval = 5 * time.Second
+)
+
+// rolloutInProgress is set while the default CatalogSources are rolled out in
+// batches, so that the controllers applying them do not apply every change at
+// once.
+var rolloutInProgress atomic.Bool
+
+// BeginRollout marks a rollout of the default CatalogSources as in progress.
+func BeginRollout() {
+	rolloutInProgress.Store(true)
+}
+
+// EndRollout marks the rollout of the default CatalogSources as completed.
+func EndRollout() {
+	rolloutInProgress.Store(false)
+}
+
+// RolloutInProgress returns true while the default CatalogSources are rolled
+// out in batches. The controllers applying the default CatalogSources requeue
+// their requests until the rollout completes.
+func RolloutInProgress() bool {
+	return rolloutInProgress.Load()
+}
+
+// RolloutOptions configure a rollout of the default CatalogSources.
+type RolloutOptions struct {
+	// BatchSize is the number of CatalogSources created, updated or deleted
+	// at a time. It must be positive.
+	BatchSize int
+	// WaitTimeout is the time given to the default CatalogSources to become
+	// ready after each batch. The rollout carries on with the next batch
+	// once it elapses.
+	WaitTimeout time.Duration
+	// PollInterval is the interval at which the readiness of the default
+	// CatalogSources is checked. rolloutPollInterval is used if it is zero.
+	PollInterval time.Duration
+}
+
+// PlanRollout returns the changes bringing the existing CatalogSources to the
+// given definitions: the enabled definitions that do not exist are Added, the
+// existing ones whose spec hash differs from the definition are Updated, and
+// the CatalogSources managed by the operator that are no longer defined are
+// Removed. The disabled definitions are left to the OperatorHub config.
+func PlanRollout(existing []olmv1alpha1.CatalogSource, definitions map[string]olmv1alpha1.CatalogSource, config map[string]bool) CatalogSourceSetDiff {
+	byKey := make(map[wrapper.ObjectKey]*olmv1alpha1.CatalogSource, len(existing))
+	for i := range existing {
+		byKey[wrapper.ObjectKey{Namespace: existing[i].Namespace, Name: existing[i].Name}] = &existing[i]
+	}
+
+	plan := CatalogSourceSetDiff{}
+	for name, def := range definitions {
+		if config[name] {
+			continue
+		}
+		desired := desiredCatsrc(def)
+		cluster, present := byKey[wrapper.ObjectKey{Namespace: def.Namespace, Name: name}]
+		switch {
+		case !present:
+			plan.Added = append(plan.Added, def.DeepCopy())
+		case cluster.Annotations[SpecHashAnnotationKey] != desired.Annotations[SpecHashAnnotationKey]:
+			plan.Updated = append(plan.Updated, def.DeepCopy())
+		}
+	}
+	for i := range existing {
+		catsrc := &existing[i]
+		if _, defined := definitions[catsrc.Name]; defined || catsrc.Labels[ManagedByLabelKey] != ManagedByLabelValue || !catsrc.DeletionTimestamp.IsZero() {
+			continue
+		}
+		plan.Removed = append(plan.Removed, catsrc.DeepCopy())
+	}
+	sortByName(plan.Added)
+	sortByName(plan.Updated)
+	sortByName(plan.Removed)
+	return plan
+}
+
+// RolloutGlobals rolls the CatalogSources on the cluster out to the global
+// definitions with the given OperatorHub config, in batches of
+// opts.BatchSize: the new CatalogSources are created first, then the modified
+// ones are updated and the removed ones are deleted last. Between each batch,
+// the rollout waits for every enabled default CatalogSource to be ready. The
+// error returned, if any, is an APIError *DefaultsError aggregating the
+// failed changes, or the error of ctx.
+func RolloutGlobals(ctx context.Context, client wrapper.Client, config map[string]bool, opts RolloutOptions) error {
+	existing := &olmv1alpha1.CatalogSourceList{}
+	if err := client.List(ctx, existing); err != nil {
+		return &DefaultsError{Kind: APIError, Cause: err}
+	}
+	plan := PlanRollout(existing.Items, GetGlobalCatalogSourceDefinitions(), config)
+	if plan.IsEmpty() {
+		return nil
+	}
+	logging.FromContext(ctx).Infof("[defaults] Rolling out the default CatalogSources: %d to create, %d to update and %d to delete, %d at a time",
+		len(plan.Added), len(plan.Updated), len(plan.Removed), opts.BatchSize)
+
+	// The CatalogSources still to create are not waited for.
+	pending := make(map[string]bool, len(plan.Added))
+	for _, catsrc := range plan.Added {
+		pending[catsrc.Name] = true
+	}
+	var errs []error
+	apply := func(catsrc *olmv1alpha1.CatalogSource) error {
+		return ensureCatsrc(ctx, client, config, *catsrc)
+	}
+	remove := func(catsrc *olmv1alpha1.CatalogSource) error {
+		if err := client.Delete(ctx, catsrc); err != nil && !k8sErrors.IsNotFound(err) {
+			return err
+		}
+		logging.FromContext(ctx).Infof("[defaults] Deleted obsolete CatalogSource %s/%s", catsrc.Namespace, catsrc.Name)
+		return nil
+	}
+	for _, step := range []struct {
+		catsrcs []*olmv1alpha1.CatalogSource
+		change  func(*olmv1alpha1.CatalogSource) error
+	}{
+		{plan.Added, apply},
+		{plan.Updated, apply},
+		{plan.Removed, remove},
+	} {
+		for start := 0; start < len(step.catsrcs); start += opts.BatchSize {
+			end := min(start+opts.BatchSize, len(step.catsrcs))
+			for _, catsrc := range step.catsrcs[start:end] {
+				delete(pending, catsrc.Name)
+				if err := step.change(catsrc); err != nil {
+					logging.FromContext(ctx).Errorf("[defaults] Error rolling out CatalogSource %s/%s - %v", catsrc.Namespace, catsrc.Name, err)
+					errs = append(errs, fmt.Errorf("%s/%s: %v", catsrc.Namespace, catsrc.Name, err))
+				}
+			}
+			if err := waitForReady(ctx, client, config, pending, opts); err != nil {
+				return err
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &DefaultsError{Kind: APIError, Cause: utilerrors.NewAggregate(errs)}
+	}
+	logging.FromContext(ctx).Info("[defaults] Rolled out the default CatalogSources")
+	return nil
+}
+
+// waitForReady waits up to opts.WaitTimeout for every enabled global
+// definition that is not pending creation to be ready on the cluster. The
+// CatalogSources still not ready once it elapses are logged, only the error
+// of ctx is returned.
+func waitForReady(ctx context.Context, client wrapper.Client, config map[string]bool, pending map[string]bool, opts RolloutOptions) error {
+	interval := opts.PollInterval
+	if interval == 0 {
+		interval = rolloutPollInterval
+	}
+	var notReady []string
+	err := wait.PollUntilContextTimeout(ctx, interval, opts.WaitTimeout, true, func(ctx context.Context) (bool, error) {
+		notReady = notReady[:0]
+		for name, def := range GetGlobalCatalogSourceDefinitions() {
+			if config[name] || pending[name] {
+				continue
+			}
+			cluster := &olmv1alpha1.CatalogSource{}
+			if err := client.Get(ctx, wrapper.ObjectKey{Namespace: def.Namespace, Name: name}, cluster); err != nil || !isCatsrcReady(cluster) {
+				notReady = append(notReady, name)
+			}
+		}
+		return len(notReady) == 0, nil
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		sort.Strings(notReady)
+		logging.FromContext(ctx).Warnf("[defaults] CatalogSources %v are not ready after %s, carrying on with the rollout", notReady, opts.WaitTimeout)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package defaults
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	wrapper "github.com/operator-framework/operator-marketplace/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestPlanRollout(t *testing.T) {
+	writeManifests(t, "certified-operators", "community-operators", "redhat-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+
+	upToDate := desiredCatsrc(globalCatsrcDefinitions["redhat-operators"])
+	existing := []olmv1alpha1.CatalogSource{upToDate, *managedCatsrc("certified-operators"), *managedCatsrc("obsolete-operators")}
+	plan := PlanRollout(existing, globalCatsrcDefinitions, map[string]bool{"community-operators": false})
+	assert.Equal(t, []string{"community-operators"}, names(plan.Added))
+	assert.Equal(t, []string{"certified-operators"}, names(plan.Updated), "a CatalogSource whose spec hash differs is updated")
+	assert.Equal(t, []string{"obsolete-operators"}, names(plan.Removed))
+
+	plan = PlanRollout(existing, globalCatsrcDefinitions, map[string]bool{"community-operators": true, "certified-operators": true})
+	assert.Empty(t, plan.Added, "a disabled CatalogSource is not rolled out")
+	assert.Empty(t, plan.Updated)
+	assert.Equal(t, []string{"obsolete-operators"}, names(plan.Removed))
+}
+
+func TestRolloutGlobals(t *testing.T) {
+	writeManifests(t, "certified-operators", "community-operators", "redhat-marketplace", "redhat-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+
+	ready := func(catsrc *olmv1alpha1.CatalogSource) *olmv1alpha1.CatalogSource {
+		catsrc.Status.GRPCConnectionState = &olmv1alpha1.GRPCConnectionState{LastObservedState: grpcReadyState}
+		return catsrc
+	}
+	upToDate := desiredCatsrc(globalCatsrcDefinitions["redhat-operators"])
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	// The changes are recorded in order, along with the checks of the
+	// readiness of redhat-marketplace that separate the batches.
+	var changes []string
+	neverReady := ""
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(ready(&upToDate), ready(managedCatsrc("redhat-marketplace")), ready(managedCatsrc("obsolete-operators"))).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				changes = append(changes, "create "+obj.GetName())
+				if obj.GetName() != neverReady {
+					ready(obj.(*olmv1alpha1.CatalogSource))
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				changes = append(changes, "update "+obj.GetName())
+				return c.Update(ctx, obj, opts...)
+			},
+			Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+				changes = append(changes, "delete "+obj.GetName())
+				return c.Delete(ctx, obj, opts...)
+			},
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if key.Name == "redhat-marketplace" {
+					if n := len(changes); n == 0 || changes[n-1] != "wait" {
+						changes = append(changes, "wait")
+					}
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}).Build()
+
+	opts := RolloutOptions{BatchSize: 1, WaitTimeout: time.Second, PollInterval: time.Millisecond}
+	require.NoError(t, RolloutGlobals(context.TODO(), wrapper.NewClient(c), map[string]bool{}, opts))
+	assert.Equal(t, []string{
+		"create certified-operators", "wait",
+		"create community-operators", "wait",
+		"update redhat-marketplace", "wait",
+		"delete obsolete-operators", "wait",
+	}, changes)
+	assert.False(t, exists(t, wrapper.NewClient(c), "obsolete-operators"))
+
+	// Nothing is changed once the CatalogSources are rolled out.
+	changes = nil
+	require.NoError(t, RolloutGlobals(context.TODO(), wrapper.NewClient(c), map[string]bool{}, opts))
+	assert.Empty(t, changes)
+
+	// The rollout carries on once the wait times out.
+	require.NoError(t, c.Delete(context.TODO(), &olmv1alpha1.CatalogSource{ObjectMeta: upToDate.ObjectMeta}))
+	require.NoError(t, c.Delete(context.TODO(), managedCatsrc("certified-operators")))
+	changes = nil
+	neverReady = "certified-operators"
+	opts = RolloutOptions{BatchSize: 2, WaitTimeout: 20 * time.Millisecond, PollInterval: time.Millisecond}
+	require.NoError(t, RolloutGlobals(context.TODO(), wrapper.NewClient(c), map[string]bool{}, opts))
+	assert.Equal(t, []string{"create certified-operators", "create redhat-operators", "wait"}, changes)
+}
+
+func TestRolloutInProgress(t *testing.T) {
+	assert.False(t, RolloutInProgress())
+	BeginRollout()
+	assert.True(t, RolloutInProgress())
+	EndRollout()
+	assert.False(t, RolloutInProgress())
+}
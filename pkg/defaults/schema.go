@@ -0,0 +1,71 @@
+package defaults
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	// SchemaVersionField is the field of a default CatalogSource definition,
+	// next to the CatalogSource fields, declaring the version of the format
+	// of the definition. A definition without it is of SchemaVersionV1.
+	SchemaVersionField = "schemaVersion"
+
+	// SchemaVersionV1 is the current format of the default CatalogSource
+	// definitions: a CatalogSource manifest, optionally declaring the
+	// CatalogSources it depends on in its dependsOn field.
+	SchemaVersionV1 = "v1"
+)
+
+// definitionParser decodes a default CatalogSource definition of a schema
+// version, returning the CatalogSource and the names of the CatalogSources it
+// depends on.
+type definitionParser func(content []byte) (*olmv1alpha1.CatalogSource, []string, error)
+
+// definitionParsers holds the parser of each supported schema version. A new
+// format of the definitions is supported by registering its parser here.
+var definitionParsers = map[string]definitionParser{
+	SchemaVersionV1: decodeV1Definition,
+}
+
+// UnsupportedSchemaVersionError is returned when a default CatalogSource
+// definition declares a schema version that has no parser.
+type UnsupportedSchemaVersionError struct {
+	Version string
+}
+
+func (e *UnsupportedSchemaVersionError) Error() string {
+	return fmt.Sprintf("unsupported %s %q, supported versions are %s", SchemaVersionField, e.Version, strings.Join(SupportedSchemaVersions(), ", "))
+}
+
+// SupportedSchemaVersions returns the sorted schema versions of the default
+// CatalogSource definitions that can be decoded.
+func SupportedSchemaVersions() []string {
+	versions := make([]string, 0, len(definitionParsers))
+	for version := range definitionParsers {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// SchemaVersion returns the schema version declared by the default
+// CatalogSource definition in content, in YAML or JSON, or SchemaVersionV1 if
+// it does not declare one.
+func SchemaVersion(content []byte) (string, error) {
+	header := struct {
+		SchemaVersion string `json:"schemaVersion,omitempty"`
+	}{}
+	if err := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 1024).Decode(&header); err != nil {
+		return "", err
+	}
+	if header.SchemaVersion == "" {
+		return SchemaVersionV1, nil
+	}
+	return header.SchemaVersion, nil
+}
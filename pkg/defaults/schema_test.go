@@ -0,0 +1,67 @@
+package defaults
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		version string
+	}{
+		{"absent", fmt.Sprintf(catsrcManifest, "redhat-operators", "redhat-operators"), SchemaVersionV1},
+		{"yaml", "schemaVersion: v1\n" + fmt.Sprintf(catsrcManifest, "redhat-operators", "redhat-operators"), SchemaVersionV1},
+		{"json", `{"schemaVersion": "v2", "kind": "CatalogSource"}`, "v2"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := SchemaVersion([]byte(tt.content))
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, version)
+		})
+	}
+
+	_, err := SchemaVersion([]byte("schemaVersion: [v1"))
+	assert.Error(t, err, "a malformed definition is rejected")
+}
+
+func TestDecodeCatsrcDefinitionV1(t *testing.T) {
+	for _, header := range []string{"", "schemaVersion: v1\n"} {
+		content := header + fmt.Sprintf(catsrcManifest, "community-operators", "community-operators") + "dependsOn:\n- redhat-operators\n"
+		catsrc, dependsOn, err := decodeCatsrcDefinition([]byte(content))
+		require.NoError(t, err)
+		assert.Equal(t, "community-operators", catsrc.Name)
+		assert.Equal(t, "quay.io/example/community-operators:latest", catsrc.Spec.Image)
+		assert.Equal(t, []string{"redhat-operators"}, dependsOn)
+	}
+}
+
+func TestDecodeCatsrcDefinitionUnsupportedVersion(t *testing.T) {
+	content := "schemaVersion: v99\n" + fmt.Sprintf(catsrcManifest, "redhat-operators", "redhat-operators")
+	_, _, err := decodeCatsrcDefinition([]byte(content))
+	var unsupported *UnsupportedSchemaVersionError
+	require.True(t, errors.As(err, &unsupported), "%v is an UnsupportedSchemaVersionError", err)
+	assert.Equal(t, "v99", unsupported.Version)
+	assert.Equal(t, `unsupported schemaVersion "v99", supported versions are v1`, err.Error())
+
+	// A definition of an unsupported version fails the population.
+	writeManifests(t)
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	path := filepath.Join(Dir, "redhat-operators.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	_, err = PopulateGlobals()
+	requireDefaultsError(t, err, ValidationError, path)
+	assert.True(t, errors.As(err, &unsupported))
+	assert.Empty(t, GetGlobalCatalogSourceDefinitions())
+}
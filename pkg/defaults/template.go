@@ -0,0 +1,91 @@
+package defaults
+
+import (
+	"bytes"
+	"os"
+	"text/template"
+)
+
+const (
+	// ImageRegistryEnv is the environment variable setting the ImageRegistry
+	// of the TemplateData.
+	ImageRegistryEnv = "MARKETPLACE_IMAGE_REGISTRY"
+
+	// ImageTagEnv is the environment variable setting the ImageTag of the
+	// TemplateData.
+	ImageTagEnv = "MARKETPLACE_IMAGE_TAG"
+
+	// ReleaseVersionEnv is the environment variable setting the
+	// ReleaseVersion of the TemplateData.
+	ReleaseVersionEnv = "RELEASE_VERSION"
+)
+
+// TemplateData is the data the manifests of the default CatalogSources are
+// expanded with, as text/template templates, before they are decoded. A
+// manifest referring to a field that is empty is invalid, so that a
+// CatalogSource is never applied with a partial image reference.
+type TemplateData struct {
+	// ImageRegistry is the registry the catalog images are pulled from, as
+	// in {{ .ImageRegistry }}/redhat/redhat-operator-index.
+	ImageRegistry string
+	// ImageTag is the tag of the catalog images.
+	ImageTag string
+	// ReleaseVersion is the version of the cluster the operator is released
+	// with.
+	ReleaseVersion string
+}
+
+// TemplateDataFromEnv returns the TemplateData set by the
+// MARKETPLACE_IMAGE_REGISTRY, MARKETPLACE_IMAGE_TAG and RELEASE_VERSION
+// environment variables.
+func TemplateDataFromEnv() TemplateData {
+	return TemplateData{
+		ImageRegistry:  os.Getenv(ImageRegistryEnv),
+		ImageTag:       os.Getenv(ImageTagEnv),
+		ReleaseVersion: os.Getenv(ReleaseVersionEnv),
+	}
+}
+
+// values returns the fields of the data that are set, keyed by field name.
+func (d TemplateData) values() map[string]string {
+	values := make(map[string]string)
+	for name, value := range map[string]string{
+		"ImageRegistry":  d.ImageRegistry,
+		"ImageTag":       d.ImageTag,
+		"ReleaseVersion": d.ReleaseVersion,
+	} {
+		if value != "" {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// expandManifest returns the content of m expanded with data. It returns an
+// error if the content is not a valid template or refers to a field of data
+// that is empty or does not exist.
+func expandManifest(m Manifest, data TemplateData) ([]byte, error) {
+	tmpl, err := template.New(m.Path).Option("missingkey=error").Parse(string(m.Content))
+	if err != nil {
+		return nil, err
+	}
+	var expanded bytes.Buffer
+	if err := tmpl.Execute(&expanded, data.values()); err != nil {
+		return nil, err
+	}
+	return expanded.Bytes(), nil
+}
+
+// expandManifests expands the content of each manifest with data. The error
+// of the first manifest that can not be expanded is returned.
+func expandManifests(manifests []Manifest, data TemplateData) ([]Manifest, error) {
+	expanded := make([]Manifest, 0, len(manifests))
+	for _, m := range manifests {
+		content, err := expandManifest(m, data)
+		if err != nil {
+			return nil, &DefaultsError{Kind: ValidationError, Path: m.Path, Cause: err}
+		}
+		expanded = append(expanded, Manifest{Path: m.Path, Content: content, Source: m.Source})
+	}
+	return expanded, nil
+}
@@ -0,0 +1,89 @@
+package defaults
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const templatedManifest = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: redhat-operators
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: {{ .ImageRegistry }}/redhat/redhat-operator-index:{{ .ImageTag }}
+`
+
+// writeTemplatedManifest writes content as the only manifest of a temporary
+// directory and points Dir at it.
+func writeTemplatedManifest(t *testing.T, content string) string {
+	t.Helper()
+	writeManifests(t)
+	path := filepath.Join(Dir, "redhat-operators.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	return path
+}
+
+func TestPopulateGlobalsWithTemplateData(t *testing.T) {
+	writeTemplatedManifest(t, templatedManifest)
+
+	_, err := PopulateGlobals(WithTemplateData(TemplateData{ImageRegistry: "mirror.example.com:5000", ImageTag: "v4.17"}))
+	require.NoError(t, err)
+	assert.Equal(t, "mirror.example.com:5000/redhat/redhat-operator-index:v4.17", GetGlobalCatalogSourceDefinitions()["redhat-operators"].Spec.Image)
+}
+
+func TestPopulateGlobalsTemplateDataFromEnv(t *testing.T) {
+	writeTemplatedManifest(t, templatedManifest)
+	t.Setenv(ImageRegistryEnv, "registry.example.com")
+	t.Setenv(ImageTagEnv, "latest")
+
+	_, err := PopulateGlobals()
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com/redhat/redhat-operator-index:latest", GetGlobalCatalogSourceDefinitions()["redhat-operators"].Spec.Image)
+}
+
+func TestPopulateGlobalsTemplateErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		manifest string
+		data     TemplateData
+	}{
+		{
+			name:     "missing variable",
+			manifest: templatedManifest,
+			data:     TemplateData{ImageRegistry: "mirror.example.com:5000"},
+		},
+		{
+			name:     "unknown variable",
+			manifest: "image: {{ .ImageDigest }}\n",
+			data:     TemplateData{ImageRegistry: "mirror.example.com:5000", ImageTag: "v4.17"},
+		},
+		{
+			name:     "malformed template",
+			manifest: "image: {{ .ImageRegistry\n",
+			data:     TemplateData{ImageRegistry: "mirror.example.com:5000"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTemplatedManifest(t, tt.manifest)
+
+			result, err := PopulateGlobals(WithTemplateData(tt.data))
+			var defaultsErr *DefaultsError
+			require.ErrorAs(t, err, &defaultsErr)
+			assert.Equal(t, ValidationError, defaultsErr.Kind)
+			assert.Equal(t, path, defaultsErr.Path)
+			assert.Equal(t, PopulationResult{Failed: []string{path}}, result)
+			assert.Empty(t, GetGlobalCatalogSourceDefinitions())
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package defaults
+
+import (
+	"context"
+	"fmt"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// manifestScheme is the scheme the default CatalogSource manifests are
+// decoded with.
+var manifestScheme = newManifestScheme()
+
+func newManifestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(olmv1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+// Manifest is a default CatalogSource manifest along with the path it was
+// read from.
+type Manifest struct {
+	// Path is the path, or the URL, the manifest was read from.
+	Path string
+	// Content is the manifest, in YAML or JSON.
+	Content []byte
+	// Source is the source the manifest was read from. A CatalogSource can
+	// only be defined once by the manifests of a source, while it can be
+	// defined again by the manifests of a later source.
+	Source string
+	// Document is the index, from 1, of the manifest among the YAML
+	// documents of its file, or 0 if its file holds a single document.
+	Document int
+}
+
+// ValidateManifest returns an error if data, in YAML or JSON, is not a
+// CatalogSource of a kind known to scheme or the CatalogSource is not valid.
+// The error lists the field-level errors of an invalid CatalogSource.
+func ValidateManifest(data []byte, scheme *runtime.Scheme) error {
+	obj, _, err := serializer.NewCodecFactory(scheme).UniversalDeserializer().Decode(data, nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to decode the manifest: %v", err)
+	}
+	catsrc, ok := obj.(*olmv1alpha1.CatalogSource)
+	if !ok {
+		return fmt.Errorf("the manifest is a %T, not a CatalogSource", obj)
+	}
+	return validateCatsrc(catsrc).ToAggregate()
+}
+
+// validateCatsrc returns the field-level errors of catsrc.
+func validateCatsrc(catsrc *olmv1alpha1.CatalogSource) field.ErrorList {
+	errs := validation.ValidateObjectMeta(&catsrc.ObjectMeta, true, validation.NameIsDNSSubdomain, field.NewPath("metadata"))
+
+	specPath := field.NewPath("spec")
+	switch catsrc.Spec.SourceType {
+	case "":
+		errs = append(errs, field.Required(specPath.Child("sourceType"), ""))
+	case olmv1alpha1.SourceTypeGrpc:
+		// A grpc CatalogSource is served either from its image or from an
+		// existing registry at its address.
+		if catsrc.Spec.Image == "" && catsrc.Spec.Address == "" {
+			errs = append(errs, field.Required(specPath.Child("image"), "required for a grpc CatalogSource without an address"))
+		}
+	case olmv1alpha1.SourceTypeConfigmap, olmv1alpha1.SourceTypeInternal:
+		if catsrc.Spec.ConfigMap == "" {
+			errs = append(errs, field.Required(specPath.Child("configMap"), fmt.Sprintf("required for a %s CatalogSource", catsrc.Spec.SourceType)))
+		}
+	default:
+		errs = append(errs, field.NotSupported(specPath.Child("sourceType"), catsrc.Spec.SourceType,
+			[]string{string(olmv1alpha1.SourceTypeGrpc), string(olmv1alpha1.SourceTypeConfigmap), string(olmv1alpha1.SourceTypeInternal)}))
+	}
+	return errs
+}
+
+// manifestError is the error of an invalid manifest aggregated with the errors
+// of other manifests.
+type manifestError struct {
+	path string
+	err  error
+}
+
+// Error implements the error interface.
+func (e *manifestError) Error() string {
+	return fmt.Sprintf("%s: %v", e.path, e.err)
+}
+
+// Unwrap returns the validation error of the manifest.
+func (e *manifestError) Unwrap() error {
+	return e.err
+}
+
+// validateManifests validates each manifest read from source. The error of a
+// single invalid manifest is reported against its path, while the errors of
+// several invalid manifests are aggregated and reported against source.
+func validateManifests(source string, manifests []Manifest) error {
+	var errs []error
+	var invalid *DefaultsError
+	for _, m := range manifests {
+		if err := ValidateManifest(m.Content, manifestScheme); err != nil {
+			invalid = &DefaultsError{Kind: ValidationError, Path: m.Path, Cause: m.documentError(err)}
+			errs = append(errs, &manifestError{path: m.location(), err: err})
+		}
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return invalid
+	default:
+		return &DefaultsError{Kind: ValidationError, Path: source, Cause: utilerrors.NewAggregate(errs)}
+	}
+}
+
+// FileValidation is the result of the validation of a default CatalogSource
+// manifest file.
+type FileValidation struct {
+	// Path is the path of the file, along with the index of the document
+	// for a file holding several YAML documents.
+	Path string
+	// Name is the name of the CatalogSource the file, or document, defines,
+	// if it could be decoded.
+	Name string
+	// Err is the reason the file is invalid, nil if it is valid.
+	Err error
+}
+
+// ValidateDir validates each default CatalogSource manifest file of dir as
+// PopulateGlobals does: the manifest is expanded with the template data from
+// the environment, validated, decoded and patched from PatchDir. A file is
+// also invalid if it defines a CatalogSource already defined by a previous
+// file. A file holding several YAML documents gets a result for each of its
+// documents. The result of each file is returned in the order of their names. The
+// error returned, if dir can not be read, is a *DefaultsError.
+func ValidateDir(dir string) ([]FileValidation, error) {
+	manifests, err := DirLoader{Dir: dir}.Load(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	data := TemplateDataFromEnv()
+	patcher := NewCatalogSourcePatcher(PatchDir)
+	definedIn := make(map[string]string)
+	results := make([]FileValidation, 0, len(manifests))
+	for _, m := range manifests {
+		documents, err := expandDocuments(m, data)
+		if err != nil {
+			results = append(results, FileValidation{Path: m.Path, Err: err})
+			continue
+		}
+		for _, document := range documents {
+			result := FileValidation{Path: document.location()}
+			result.Name, result.Err = validateDocument(document.Content, patcher)
+			if result.Err == nil {
+				if previous, ok := definedIn[result.Name]; ok {
+					result.Err = fmt.Errorf("CatalogSource %s is already defined in %s", result.Name, previous)
+				} else {
+					definedIn[result.Name] = document.location()
+				}
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// expandDocuments returns the YAML documents of the manifest m once expanded
+// with data.
+func expandDocuments(m Manifest, data TemplateData) ([]Manifest, error) {
+	content, err := expandManifest(m, data)
+	if err != nil {
+		return nil, err
+	}
+	m.Content = content
+	return splitManifest(m)
+}
+
+// validateDocument returns the name of the CatalogSource the document content
+// defines, and an error if it is not a valid default CatalogSource once
+// patched by patcher.
+func validateDocument(content []byte, patcher *CatalogSourcePatcher) (string, error) {
+	if err := ValidateManifest(content, manifestScheme); err != nil {
+		return "", err
+	}
+	catsrc, _, err := decodeCatsrcDefinition(content)
+	if err != nil {
+		return "", err
+	}
+	return catsrc.Name, patcher.Patch(catsrc)
+}
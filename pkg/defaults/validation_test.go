@@ -0,0 +1,130 @@
+package defaults
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+func TestValidateManifest(t *testing.T) {
+	tests := []struct {
+		description string
+		manifest    string
+		errors      []string
+	}{
+		{
+			description: "a valid CatalogSource",
+			manifest:    "apiVersion: operators.coreos.com/v1alpha1\nkind: CatalogSource\nmetadata:\n  name: redhat-operators\n  namespace: openshift-marketplace\nspec:\n  sourceType: grpc\n  image: quay.io/example/redhat-operators:latest\n",
+		},
+		{
+			description: "a grpc CatalogSource served from an address",
+			manifest:    "apiVersion: operators.coreos.com/v1alpha1\nkind: CatalogSource\nmetadata:\n  name: redhat-operators\n  namespace: openshift-marketplace\nspec:\n  sourceType: grpc\n  address: registry.example.com:50051\n",
+		},
+		{
+			description: "missing spec.sourceType",
+			manifest:    "apiVersion: operators.coreos.com/v1alpha1\nkind: CatalogSource\nmetadata:\n  name: redhat-operators\n  namespace: openshift-marketplace\nspec:\n  image: quay.io/example/redhat-operators:latest\n",
+			errors:      []string{"spec.sourceType: Required value"},
+		},
+		{
+			description: "empty spec.image",
+			manifest:    "apiVersion: operators.coreos.com/v1alpha1\nkind: CatalogSource\nmetadata:\n  name: Redhat_Operators\nspec:\n  sourceType: grpc\n  image: \"\"\n",
+			errors:      []string{"spec.image: Required value", "metadata.name: Invalid value", "metadata.namespace: Required value"},
+		},
+		{
+			description: "unparseable YAML",
+			manifest:    "kind: [CatalogSource",
+			errors:      []string{"unable to decode the manifest"},
+		},
+		{
+			description: "not a CatalogSource",
+			manifest:    "apiVersion: operators.coreos.com/v1alpha1\nkind: Subscription\nmetadata:\n  name: example\n",
+			errors:      []string{"not a CatalogSource"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			err := ValidateManifest([]byte(tt.manifest), manifestScheme)
+			if len(tt.errors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, expected := range tt.errors {
+				assert.Contains(t, err.Error(), expected)
+			}
+		})
+	}
+}
+
+func TestPopulateGlobalsReportsAllInvalidManifests(t *testing.T) {
+	writeManifests(t, "redhat-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	missingSourceType := filepath.Join(Dir, "missing-source-type.yaml")
+	require.NoError(t, os.WriteFile(missingSourceType, []byte("apiVersion: operators.coreos.com/v1alpha1\nkind: CatalogSource\nmetadata:\n  name: missing-source-type\n  namespace: openshift-marketplace\n"), 0644))
+	unparseable := filepath.Join(Dir, "unparseable.yaml")
+	require.NoError(t, os.WriteFile(unparseable, []byte("kind: [CatalogSource"), 0644))
+
+	_, err := PopulateGlobals()
+	defaultsErr := requireDefaultsError(t, err, ValidationError, Dir)
+	var aggregate utilerrors.Aggregate
+	require.ErrorAs(t, defaultsErr.Cause, &aggregate)
+	require.Len(t, aggregate.Errors(), 2, "every invalid file is reported")
+	assert.Contains(t, aggregate.Errors()[0].Error(), missingSourceType+": spec.sourceType: Required value")
+	assert.Contains(t, aggregate.Errors()[1].Error(), unparseable)
+	assert.Empty(t, GetGlobalCatalogSourceDefinitions())
+}
+
+func TestShippedDefaultsAreValid(t *testing.T) {
+	dir := filepath.Join("..", "..", "defaults")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+		assert.NoError(t, ValidateManifest(content, manifestScheme), entry.Name())
+	}
+}
+
+func TestValidateDir(t *testing.T) {
+	writeManifests(t, "certified-operators", "redhat-operators")
+	t.Cleanup(func() {
+		Dir = ""
+		_, err := PopulateGlobals()
+		require.NoError(t, err)
+	})
+	duplicate := filepath.Join(Dir, "z-redhat-operators.yaml")
+	require.NoError(t, os.WriteFile(duplicate, []byte(fmt.Sprintf(catsrcManifest, "redhat-operators", "redhat-operators-copy")), 0644))
+	missingSourceType := filepath.Join(Dir, "missing-source-type.yaml")
+	require.NoError(t, os.WriteFile(missingSourceType, []byte("apiVersion: operators.coreos.com/v1alpha1\nkind: CatalogSource\nmetadata:\n  name: missing-source-type\n  namespace: openshift-marketplace\n"), 0644))
+	badTemplate := filepath.Join(Dir, "bad-template.yaml")
+	require.NoError(t, os.WriteFile(badTemplate, []byte(fmt.Sprintf(catsrcManifest, "bad-template", "{{.Unknown}}")), 0644))
+
+	results, err := ValidateDir(Dir)
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+	byPath := map[string]FileValidation{}
+	for _, result := range results {
+		byPath[filepath.Base(result.Path)] = result
+	}
+	assert.Equal(t, "bad-template.yaml", filepath.Base(results[0].Path), "the files are validated in the order of their names")
+	assert.Error(t, byPath["bad-template.yaml"].Err)
+	assert.NoError(t, byPath["certified-operators.yaml"].Err)
+	assert.Equal(t, "certified-operators", byPath["certified-operators.yaml"].Name)
+	assert.ErrorContains(t, byPath["missing-source-type.yaml"].Err, "spec.sourceType: Required value")
+	assert.NoError(t, byPath["redhat-operators.yaml"].Err)
+	assert.EqualError(t, byPath["z-redhat-operators.yaml"].Err,
+		"CatalogSource redhat-operators is already defined in "+filepath.Join(Dir, "redhat-operators.yaml"), "the names are unique across files")
+	assert.Empty(t, GetGlobalCatalogSourceDefinitions(), "the global definitions are not populated")
+
+	_, err = ValidateDir(filepath.Join(Dir, "missing"))
+	requireDefaultsError(t, err, FilesystemError, filepath.Join(Dir, "missing"))
+}
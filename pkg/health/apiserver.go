@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// DefaultAPIServerCheckInterval is the interval at which the connectivity
+	// to the API server is checked.
+	DefaultAPIServerCheckInterval = 30 * time.Second
+
+	// DefaultAPIServerFailureThreshold is the number of consecutive failed
+	// checks of the connectivity to the API server after which the liveness
+	// check fails.
+	DefaultAPIServerFailureThreshold = 3
+
+	// apiServerCheckTimeout bounds each check of the connectivity to the API
+	// server.
+	apiServerCheckTimeout = 10 * time.Second
+)
+
+// VersionCheck returns a check that gets the version of the API server with
+// the given client, which fails if the credentials of the client are no
+// longer accepted or the API server can not be reached.
+func VersionCheck(client rest.Interface) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return client.Get().AbsPath("/version").Do(ctx).Error()
+	}
+}
+
+// APIServerChecker is a liveness Checker reporting whether the operator can
+// reach the API server. The connectivity is checked in the background at a
+// fixed interval rather than on every probe, and the liveness check only
+// fails once threshold consecutive checks failed.
+type APIServerChecker struct {
+	check     func(ctx context.Context) error
+	interval  time.Duration
+	threshold int
+
+	mutex    sync.Mutex
+	failures int
+	lastErr  error
+}
+
+// NewAPIServerChecker returns an APIServerChecker running check every
+// interval. The liveness check never fails if threshold is zero.
+func NewAPIServerChecker(check func(ctx context.Context) error, interval time.Duration, threshold int) *APIServerChecker {
+	return &APIServerChecker{check: check, interval: interval, threshold: threshold}
+}
+
+// Name returns the name of the check.
+func (c *APIServerChecker) Name() string {
+	return "apiserver"
+}
+
+// Check returns the error of the last check of the connectivity to the API
+// server if the threshold of consecutive failures is reached.
+func (c *APIServerChecker) Check(_ *http.Request) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.threshold == 0 || c.failures < c.threshold {
+		return nil
+	}
+	return fmt.Errorf("%d consecutive checks failed, last error: %v", c.failures, c.lastErr)
+}
+
+// Run checks the connectivity to the API server every interval until ctx is
+// done.
+func (c *APIServerChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		c.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh checks the connectivity to the API server once and records the
+// result.
+func (c *APIServerChecker) refresh(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, apiServerCheckTimeout)
+	defer cancel()
+	err := c.check(checkCtx)
+	if ctx.Err() != nil {
+		// The check was interrupted by the shutdown.
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err == nil {
+		if c.failures > 0 {
+			logrus.Infof("[health] The API server is reachable again after %d failed checks", c.failures)
+		}
+		c.failures, c.lastErr = 0, nil
+		return
+	}
+	c.failures++
+	c.lastErr = err
+	logrus.Warnf("[health] Failed to reach the API server (%d consecutive failures) - %v", c.failures, err)
+}
@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+// fakeCheck counts its calls and returns the error it is set to.
+type fakeCheck struct {
+	mutex sync.Mutex
+	calls int
+	err   error
+}
+
+func (f *fakeCheck) check(context.Context) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls++
+	return f.err
+}
+
+func (f *fakeCheck) setErr(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.err = err
+}
+
+func (f *fakeCheck) callCount() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.calls
+}
+
+func livenessStatus(liveness *Liveness) int {
+	rec := httptest.NewRecorder()
+	liveness.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	return rec.Code
+}
+
+func TestAPIServerCheckerThreshold(t *testing.T) {
+	check := &fakeCheck{err: errors.New("Unauthorized")}
+	checker := NewAPIServerChecker(check.check, time.Hour, 2)
+	liveness := NewLiveness(checker)
+
+	checker.refresh(context.TODO())
+	assert.Equal(t, http.StatusOK, livenessStatus(liveness), "a single failure is below the threshold")
+	checker.refresh(context.TODO())
+	assert.Equal(t, http.StatusInternalServerError, livenessStatus(liveness))
+	assert.EqualError(t, checker.Check(nil), "2 consecutive checks failed, last error: Unauthorized")
+
+	check.setErr(nil)
+	checker.refresh(context.TODO())
+	assert.Equal(t, http.StatusOK, livenessStatus(liveness), "a successful check resets the failures")
+
+	check.setErr(errors.New("Unauthorized"))
+	checker.refresh(context.TODO())
+	assert.Equal(t, http.StatusOK, livenessStatus(liveness), "the failures must be consecutive")
+
+	disabled := NewAPIServerChecker(check.check, time.Hour, 0)
+	for i := 0; i < 5; i++ {
+		disabled.refresh(context.TODO())
+	}
+	assert.NoError(t, disabled.Check(nil), "a zero threshold never fails")
+}
+
+func TestAPIServerCheckerCaching(t *testing.T) {
+	check := &fakeCheck{err: errors.New("connection refused")}
+	checker := NewAPIServerChecker(check.check, time.Hour, 1)
+	liveness := NewLiveness(checker)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		checker.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return livenessStatus(liveness) == http.StatusInternalServerError
+	}, 5*time.Second, 10*time.Millisecond, "the first check runs on start")
+	for i := 0; i < 10; i++ {
+		livenessStatus(liveness)
+	}
+	assert.Equal(t, 1, check.callCount(), "the probes are served the cached result")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the checker to stop")
+	}
+}
+
+func TestVersionCheck(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/version", r.URL.Path)
+		w.WriteHeader(status)
+		w.Write([]byte(`{"major":"1","minor":"31"}`))
+	}))
+	defer server.Close()
+	client, err := discovery.NewDiscoveryClientForConfig(&rest.Config{Host: server.URL})
+	require.NoError(t, err)
+	check := VersionCheck(client.RESTClient())
+
+	assert.NoError(t, check(context.TODO()))
+	status = http.StatusUnauthorized
+	assert.Error(t, check(context.TODO()))
+}
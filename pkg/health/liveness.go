@@ -0,0 +1,38 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Checker is a single liveness check. It is satisfied by the client-go
+// leaderelection.HealthzAdaptor.
+type Checker interface {
+	// Name returns the name of the check, used when reporting a failure.
+	Name() string
+	// Check returns an error if the process is not healthy.
+	Check(req *http.Request) error
+}
+
+// Liveness serves the liveness endpoint of the operator. It reports healthy
+// unless one of its checks fails, in which case kubelet restarts the process.
+type Liveness struct {
+	checkers []Checker
+}
+
+// NewLiveness returns a Liveness handler that runs the given checks.
+func NewLiveness(checkers ...Checker) *Liveness {
+	return &Liveness{checkers: checkers}
+}
+
+// ServeHTTP writes a 200 if every check passes and a 500 naming the first
+// failing check otherwise.
+func (l *Liveness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, checker := range l.checkers {
+		if err := checker.Check(r); err != nil {
+			http.Error(w, fmt.Sprintf("%s check failed: %v", checker.Name(), err), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// fakeLock is an in memory resource lock whose updates can be made to hang,
+// simulating a leader that is unable to reach the API server.
+type fakeLock struct {
+	identity string
+	wedged   chan struct{}
+	release  chan struct{}
+
+	mutex  sync.Mutex
+	record *resourcelock.LeaderElectionRecord
+}
+
+func (l *fakeLock) Get(_ context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.record == nil {
+		return nil, nil, apierrors.NewNotFound(coordinationv1.Resource("leases"), l.identity)
+	}
+	record := *l.record
+	return &record, nil, nil
+}
+
+func (l *fakeLock) Create(_ context.Context, ler resourcelock.LeaderElectionRecord) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.record = &ler
+	return nil
+}
+
+func (l *fakeLock) Update(_ context.Context, ler resourcelock.LeaderElectionRecord) error {
+	select {
+	case <-l.wedged:
+		// Ignore the context like a hung connection would.
+		<-l.release
+		return errors.New("released")
+	default:
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.record = &ler
+	return nil
+}
+
+func (l *fakeLock) RecordEvent(string) {}
+
+func (l *fakeLock) Identity() string { return l.identity }
+
+func (l *fakeLock) Describe() string { return "fake/" + l.identity }
+
+func TestLivenessLeaderElection(t *testing.T) {
+	lock := &fakeLock{identity: "test", wedged: make(chan struct{}), release: make(chan struct{})}
+	defer close(lock.release)
+
+	adaptor := leaderelection.NewLeaderHealthzAdaptor(200 * time.Millisecond)
+	liveness := NewLiveness(adaptor)
+	status := func() int {
+		rec := httptest.NewRecorder()
+		liveness.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, status(), "the check is inactive before joining the election")
+
+	leading := make(chan struct{})
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: time.Second,
+		RenewDeadline: 500 * time.Millisecond,
+		RetryPeriod:   100 * time.Millisecond,
+		WatchDog:      adaptor,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) { close(leading) },
+			OnStoppedLeading: func() {},
+		},
+	})
+	require.NoError(t, err)
+	adaptor.SetLeaderElection(elector)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go elector.Run(ctx)
+
+	select {
+	case <-leading:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting to acquire the lock")
+	}
+	assert.Equal(t, http.StatusOK, status(), "the leader is healthy while it renews its lease")
+
+	close(lock.wedged)
+	assert.Eventually(t, func() bool {
+		return status() == http.StatusInternalServerError
+	}, 5*time.Second, 50*time.Millisecond, "a leader that can not renew its lease is unhealthy")
+}
@@ -0,0 +1,100 @@
+package health
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Readiness tracks the state that determines whether this replica of the
+// operator should report itself as ready. Only the replica that holds the
+// leader lock, whose manager cache has synced and that meets every registered
+// Precondition, is considered ready. It is safe for concurrent use by the
+// leader election callbacks and the HTTP handler.
+type Readiness struct {
+	leader      atomic.Bool
+	cacheSynced atomic.Bool
+
+	mutex         sync.Mutex
+	preconditions []*Precondition
+}
+
+// Precondition is a condition, registered with the Readiness, that the
+// replica must meet before it reports ready.
+type Precondition struct {
+	name string
+	met  atomic.Bool
+}
+
+// Set records whether the precondition is met.
+func (p *Precondition) Set(met bool) {
+	p.met.Store(met)
+}
+
+// Register returns a new Precondition, not met, that the replica must meet
+// before it reports ready. name identifies the precondition in the response
+// of the readiness endpoint while it is pending.
+func (r *Readiness) Register(name string) *Precondition {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	p := &Precondition{name: name}
+	r.preconditions = append(r.preconditions, p)
+	return p
+}
+
+// SetLeader records whether this replica currently holds the leader lock.
+// Losing the lock also resets the cache sync state as the cache is stopped
+// along with the manager.
+func (r *Readiness) SetLeader(leader bool) {
+	r.leader.Store(leader)
+	if !leader {
+		r.cacheSynced.Store(false)
+	}
+}
+
+// SetCacheSynced records whether the manager cache has synced.
+func (r *Readiness) SetCacheSynced(synced bool) {
+	r.cacheSynced.Store(synced)
+}
+
+// IsLeader returns true if this replica holds the leader lock.
+func (r *Readiness) IsLeader() bool {
+	return r.leader.Load()
+}
+
+// IsReady returns true if this replica holds the leader lock, its cache has
+// synced and every registered precondition is met.
+func (r *Readiness) IsReady() bool {
+	return r.leader.Load() && r.cacheSynced.Load() && len(r.pending()) == 0
+}
+
+// pending returns the names of the registered preconditions that are not
+// met, in the order they were registered.
+func (r *Readiness) pending() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var names []string
+	for _, p := range r.preconditions {
+		if !p.met.Load() {
+			names = append(names, p.name)
+		}
+	}
+	return names
+}
+
+// ServeHTTP writes a 200 if the replica is ready and a 503 naming what it is
+// waiting for otherwise.
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	pending := r.pending()
+	switch {
+	case !r.leader.Load():
+		http.Error(w, "not the leader", http.StatusServiceUnavailable)
+	case !r.cacheSynced.Load():
+		http.Error(w, "cache not synced", http.StatusServiceUnavailable)
+	case len(pending) > 0:
+		http.Error(w, "pending: "+strings.Join(pending, ", "), http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
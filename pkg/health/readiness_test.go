@@ -0,0 +1,72 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadiness(t *testing.T) {
+	readiness := &Readiness{}
+	status := func() int {
+		rec := httptest.NewRecorder()
+		readiness.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, status(), "a replica that is not leading is not ready")
+
+	readiness.SetLeader(true)
+	assert.Equal(t, http.StatusServiceUnavailable, status(), "the leader is not ready until the cache has synced")
+
+	readiness.SetCacheSynced(true)
+	assert.Equal(t, http.StatusOK, status())
+
+	readiness.SetLeader(false)
+	assert.Equal(t, http.StatusServiceUnavailable, status(), "losing the lock makes the replica unready")
+
+	readiness.SetLeader(true)
+	assert.Equal(t, http.StatusServiceUnavailable, status(), "the cache must sync again after reacquiring the lock")
+}
+
+func TestReadinessPreconditions(t *testing.T) {
+	readiness := &Readiness{}
+	readiness.SetLeader(true)
+	readiness.SetCacheSynced(true)
+	populated := readiness.Register("defaults-populated")
+	reported := readiness.Register("clusteroperator-reported")
+	serve := func() (int, string) {
+		rec := httptest.NewRecorder()
+		readiness.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code, strings.TrimSpace(rec.Body.String())
+	}
+
+	code, body := serve()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "pending: defaults-populated, clusteroperator-reported", body)
+
+	populated.Set(true)
+	code, body = serve()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "pending: clusteroperator-reported", body)
+	assert.False(t, readiness.IsReady())
+
+	reported.Set(true)
+	code, _ = serve()
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, readiness.IsReady())
+
+	populated.Set(false)
+	code, body = serve()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "pending: defaults-populated", body)
+
+	populated.Set(true)
+	readiness.SetCacheSynced(false)
+	code, body = serve()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "cache not synced", body)
+}
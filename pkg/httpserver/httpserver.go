@@ -0,0 +1,91 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// ReadTimeout is the time given to the clients to send a request.
+	ReadTimeout = 10 * time.Second
+
+	// WriteTimeout is the time given to a handler to write its response.
+	WriteTimeout = 30 * time.Second
+
+	// IdleTimeout is how long a keep-alive connection is kept open between
+	// requests.
+	IdleTimeout = 2 * time.Minute
+
+	// ShutdownTimeout is the time given to the in-flight requests to
+	// complete once the server is shut down.
+	ShutdownTimeout = 10 * time.Second
+)
+
+// New returns an http.Server serving handler on addr, with the read, write
+// and idle timeouts set.
+func New(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: ReadTimeout,
+		ReadTimeout:       ReadTimeout,
+		WriteTimeout:      WriteTimeout,
+		IdleTimeout:       IdleTimeout,
+	}
+}
+
+// Server is an http.Server bound to its address.
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+}
+
+// Listen binds the address of server, so that an address already in use is
+// reported to the caller rather than by the goroutine serving the requests.
+func Listen(server *http.Server) (*Server, error) {
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{server: server, listener: listener}, nil
+}
+
+// Addr returns the address the server is bound to.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve serves the requests until ctx is done, over https if the server has
+// a TLSConfig providing its certificates. The server is then shut down so
+// that the in-flight requests complete, the connections still open after
+// ShutdownTimeout are closed. It returns nil once the server is shut down, or
+// the error serving failed with.
+func (s *Server) Serve(ctx context.Context) error {
+	served := make(chan error, 1)
+	go func() {
+		if s.server.TLSConfig != nil {
+			served <- s.server.ServeTLS(s.listener, "", "")
+			return
+		}
+		served <- s.server.Serve(s.listener)
+	}()
+
+	select {
+	case err := <-served:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(shutdownCtx); err != nil {
+		s.server.Close()
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package httpserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenAddressInUse(t *testing.T) {
+	bound, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer bound.Close()
+
+	_, err = Listen(New(bound.Addr().String(), http.NotFoundHandler()))
+	assert.Error(t, err)
+}
+
+func TestServeShutsDownGracefully(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+	server, err := Listen(New("127.0.0.1:0", handler))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(ctx)
+	}()
+
+	type response struct {
+		body string
+		err  error
+	}
+	responses := make(chan response, 1)
+	go func() {
+		resp, err := http.Get("http://" + server.Addr().String())
+		if err != nil {
+			responses <- response{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- response{body: string(body), err: err}
+	}()
+	<-started
+
+	// The in-flight request completes once the server is shut down.
+	cancel()
+	select {
+	case err := <-served:
+		t.Fatalf("the server stopped before the in-flight request completed: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+
+	r := <-responses
+	require.NoError(t, r.err)
+	assert.Equal(t, "done", r.body)
+	assert.NoError(t, <-served)
+
+	_, err = net.Dial("tcp", server.Addr().String())
+	assert.Error(t, err, "the server no longer accepts connections")
+}
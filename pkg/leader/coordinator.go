@@ -0,0 +1,82 @@
+package leader
+
+import (
+	"context"
+	"sync"
+)
+
+// Coordinator orders the shutdown of a leader so that the leader lock is only
+// released once the work started when leadership was acquired has returned.
+// Without it, cancelling the context the leader election runs with releases
+// the lock immediately, and a new leader may start reconciling while this
+// replica is still flushing in-flight reconciles.
+type Coordinator struct {
+	shutdown       context.Context
+	election       context.Context
+	cancelElection context.CancelFunc
+
+	mutex    sync.Mutex
+	leading  bool
+	stopping bool
+}
+
+// NewCoordinator returns a Coordinator for a process that shuts down when the
+// given context is cancelled.
+func NewCoordinator(shutdown context.Context) *Coordinator {
+	election, cancelElection := context.WithCancel(context.Background())
+	c := &Coordinator{
+		shutdown:       shutdown,
+		election:       election,
+		cancelElection: cancelElection,
+	}
+	go c.waitForShutdown()
+	return c
+}
+
+// Context returns the context the leader election should be run with. It is
+// cancelled on shutdown, either right away if this replica is not leading, or
+// once the work passed to Lead has returned.
+func (c *Coordinator) Context() context.Context {
+	return c.election
+}
+
+// Lead runs fn and is meant to be called from the OnStartedLeading callback
+// with the context that callback receives. The context passed to fn is
+// cancelled when leadership is lost or on shutdown. Once fn returns the leader
+// election is stopped, releasing the lock.
+func (c *Coordinator) Lead(ctx context.Context, fn func(ctx context.Context)) {
+	defer c.cancelElection()
+
+	c.mutex.Lock()
+	if c.stopping {
+		c.mutex.Unlock()
+		return
+	}
+	c.leading = true
+	c.mutex.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(c.shutdown, cancel)
+	defer stop()
+
+	fn(ctx)
+}
+
+// waitForShutdown stops the leader election on shutdown unless the work
+// passed to Lead is running, in which case Lead stops it once that work
+// returns.
+func (c *Coordinator) waitForShutdown() {
+	select {
+	case <-c.shutdown.Done():
+	case <-c.election.Done():
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.stopping = true
+	if !c.leading {
+		c.cancelElection()
+	}
+}
@@ -0,0 +1,150 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// steps records the order in which the shutdown steps happened.
+type steps struct {
+	mutex sync.Mutex
+	names []string
+}
+
+func (s *steps) add(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.names = append(s.names, name)
+}
+
+func (s *steps) get() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.names...)
+}
+
+// fakeLock is an in memory resource lock that records when it is released.
+type fakeLock struct {
+	identity string
+	steps    *steps
+
+	mutex  sync.Mutex
+	record *resourcelock.LeaderElectionRecord
+}
+
+func (l *fakeLock) Get(_ context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.record == nil {
+		return nil, nil, apierrors.NewNotFound(coordinationv1.Resource("leases"), l.identity)
+	}
+	record := *l.record
+	return &record, nil, nil
+}
+
+func (l *fakeLock) Create(_ context.Context, ler resourcelock.LeaderElectionRecord) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.record = &ler
+	return nil
+}
+
+func (l *fakeLock) Update(_ context.Context, ler resourcelock.LeaderElectionRecord) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if ler.HolderIdentity == "" {
+		l.steps.add("lock released")
+	}
+	l.record = &ler
+	return nil
+}
+
+func (l *fakeLock) RecordEvent(string) {}
+
+func (l *fakeLock) Identity() string { return l.identity }
+
+func (l *fakeLock) Describe() string { return "fake/" + l.identity }
+
+// fakeManager mimics a manager that takes a while to drain its in-flight
+// reconciles once its context is cancelled.
+type fakeManager struct {
+	steps   *steps
+	started chan struct{}
+}
+
+func (m *fakeManager) Start(ctx context.Context) error {
+	close(m.started)
+	<-ctx.Done()
+	time.Sleep(200 * time.Millisecond)
+	m.steps.add("manager stopped")
+	return nil
+}
+
+func TestCoordinatorReleasesLockAfterManagerStops(t *testing.T) {
+	recorded := &steps{}
+	lock := &fakeLock{identity: "test", steps: recorded}
+	mgr := &fakeManager{steps: recorded, started: make(chan struct{})}
+
+	shutdown, triggerShutdown := context.WithCancel(context.Background())
+	defer triggerShutdown()
+	coordinator := NewCoordinator(shutdown)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		leaderelection.RunOrDie(coordinator.Context(), leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			ReleaseOnCancel: true,
+			LeaseDuration:   time.Second,
+			RenewDeadline:   500 * time.Millisecond,
+			RetryPeriod:     100 * time.Millisecond,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					coordinator.Lead(ctx, func(ctx context.Context) {
+						require.NoError(t, mgr.Start(ctx))
+					})
+				},
+				OnStoppedLeading: func() {},
+			},
+		})
+	}()
+
+	select {
+	case <-mgr.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the manager to start")
+	}
+
+	triggerShutdown()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the leader election to stop")
+	}
+	assert.Equal(t, []string{"manager stopped", "lock released"}, recorded.get())
+}
+
+func TestCoordinatorStopsElectionWhenNotLeading(t *testing.T) {
+	shutdown, triggerShutdown := context.WithCancel(context.Background())
+	coordinator := NewCoordinator(shutdown)
+
+	triggerShutdown()
+	select {
+	case <-coordinator.Context().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the leader election to stop")
+	}
+
+	ran := false
+	coordinator.Lead(context.Background(), func(context.Context) { ran = true })
+	assert.False(t, ran, "no work is started once shutting down")
+}
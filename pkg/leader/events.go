@@ -0,0 +1,90 @@
+package leader
+
+import (
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// LeaderElectedReason is the reason of the Event emitted when this
+	// replica acquires leadership.
+	LeaderElectedReason = "LeaderElected"
+
+	// LeaderLostReason is the reason of the Event emitted when this replica
+	// loses leadership.
+	LeaderLostReason = "LeaderLost"
+
+	// unknownLeader is used in Event messages when the identity of a leader
+	// has not been observed.
+	unknownLeader = "unknown"
+)
+
+// TransitionRecorder emits Kubernetes Events on the leader election lock
+// object whenever this replica acquires or loses leadership. It keeps track of
+// the leader observed through the leader election callbacks so that Events
+// can include the identity of both the old and the new leader.
+type TransitionRecorder struct {
+	recorder record.EventRecorder
+	lock     *corev1.ObjectReference
+	identity string
+
+	mutex          sync.Mutex
+	observedLeader string
+}
+
+// NewTransitionRecorder returns a TransitionRecorder that emits Events using
+// the given recorder on the lock object on behalf of identity.
+func NewTransitionRecorder(recorder record.EventRecorder, lock *corev1.ObjectReference, identity string) *TransitionRecorder {
+	return &TransitionRecorder{
+		recorder: recorder,
+		lock:     lock,
+		identity: identity,
+	}
+}
+
+// NewLeader records the identity of the leader as observed by the
+// OnNewLeader callback.
+func (t *TransitionRecorder) NewLeader(identity string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if identity != t.identity {
+		t.observedLeader = identity
+	}
+}
+
+// StartedLeading emits a Normal Event stating that this replica acquired
+// leadership from the previously observed leader.
+func (t *TransitionRecorder) StartedLeading() {
+	t.mutex.Lock()
+	previous := t.observedLeaderLocked()
+	// Forget the previous leader so that only a leader observed from now on
+	// is reported as the new leader when leadership is lost.
+	t.observedLeader = ""
+	t.mutex.Unlock()
+
+	t.recorder.Event(t.lock, corev1.EventTypeNormal, LeaderElectedReason,
+		fmt.Sprintf("%s became leader, previous leader was %s", t.identity, previous))
+}
+
+// StoppedLeading emits a Warning Event stating that this replica lost
+// leadership, along with the new leader if it has already been observed.
+func (t *TransitionRecorder) StoppedLeading() {
+	t.mutex.Lock()
+	next := t.observedLeaderLocked()
+	t.mutex.Unlock()
+
+	t.recorder.Event(t.lock, corev1.EventTypeWarning, LeaderLostReason,
+		fmt.Sprintf("%s stopped leading, new leader is %s", t.identity, next))
+}
+
+// observedLeaderLocked returns the last observed leader other than this
+// replica. The mutex must be held by the caller.
+func (t *TransitionRecorder) observedLeaderLocked() string {
+	if t.observedLeader == "" {
+		return unknownLeader
+	}
+	return t.observedLeader
+}
@@ -0,0 +1,35 @@
+package leader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestTransitionRecorder(t *testing.T) {
+	fakeRecorder := record.NewFakeRecorder(10)
+	lock := &corev1.ObjectReference{Kind: "Lease", Namespace: "openshift-marketplace", Name: "marketplace-operator-lock"}
+	recorder := NewTransitionRecorder(fakeRecorder, lock, "pod-b")
+
+	recorder.NewLeader("pod-a")
+	recorder.NewLeader("pod-b")
+	recorder.StartedLeading()
+	assert.Equal(t, "Normal LeaderElected pod-b became leader, previous leader was pod-a", <-fakeRecorder.Events)
+
+	recorder.StoppedLeading()
+	assert.Equal(t, "Warning LeaderLost pod-b stopped leading, new leader is unknown", <-fakeRecorder.Events)
+
+	recorder.NewLeader("pod-c")
+	recorder.StoppedLeading()
+	assert.Equal(t, "Warning LeaderLost pod-b stopped leading, new leader is pod-c", <-fakeRecorder.Events)
+}
+
+func TestTransitionRecorderWithoutPreviousLeader(t *testing.T) {
+	fakeRecorder := record.NewFakeRecorder(10)
+	recorder := NewTransitionRecorder(fakeRecorder, &corev1.ObjectReference{}, "pod-a")
+
+	recorder.StartedLeading()
+	assert.Equal(t, "Normal LeaderElected pod-a became leader, previous leader was unknown", <-fakeRecorder.Events)
+}
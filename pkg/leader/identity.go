@@ -0,0 +1,57 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// podNameEnv and podUIDEnv are the environment variables the downward API
+	// exposes the name and the UID of the operator pod through.
+	podNameEnv = "POD_NAME"
+	podUIDEnv  = "POD_UID"
+
+	// randomSuffixLength is the length of the suffix that makes the hostname
+	// based identity unique.
+	randomSuffixLength = 8
+)
+
+// hostname returns the hostname of the machine, it is replaced in tests.
+var hostname = os.Hostname
+
+// Identity returns the identity this replica holds the leader lock with. It
+// is composed of the pod name and the pod UID, so that it is stable across
+// container restarts while two pods ending up with the same name still have
+// different identities. The UID is read from the environment, or from the pod
+// object if it is not exposed there. If neither is available, the hostname
+// with a random suffix is used instead.
+func Identity(ctx context.Context, pods typedcorev1.PodsGetter, namespace string) (string, error) {
+	name := os.Getenv(podNameEnv)
+	if name != "" {
+		uid := os.Getenv(podUIDEnv)
+		if uid == "" {
+			pod, err := pods.Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				logrus.Warnf("[leader] Unable to get pod %s/%s to determine its UID: %v", namespace, name, err)
+			} else {
+				uid = string(pod.UID)
+			}
+		}
+		if uid != "" {
+			return fmt.Sprintf("%s_%s", name, uid), nil
+		}
+	}
+
+	logrus.Warnf("[leader] Unable to determine the pod name and UID, falling back to the hostname")
+	host, err := hostname()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s_%s", host, rand.String(randomSuffixLength)), nil
+}
@@ -0,0 +1,98 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// fakePods serves a single pod, or an error, through the PodsGetter
+// interface.
+type fakePods struct {
+	typedcorev1.PodInterface
+	pod *corev1.Pod
+	err error
+}
+
+func (f *fakePods) Pods(string) typedcorev1.PodInterface {
+	return f
+}
+
+func (f *fakePods) Get(_ context.Context, name string, _ metav1.GetOptions) (*corev1.Pod, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.pod == nil || f.pod.Name != name {
+		return nil, errors.New("not found")
+	}
+	return f.pod, nil
+}
+
+// setHostname replaces the hostname for the duration of the test.
+func setHostname(t *testing.T, name string, err error) {
+	t.Helper()
+	previous := hostname
+	hostname = func() (string, error) { return name, err }
+	t.Cleanup(func() { hostname = previous })
+}
+
+func TestIdentityFromEnvironment(t *testing.T) {
+	t.Setenv(podNameEnv, "marketplace-operator-abc")
+	t.Setenv(podUIDEnv, "1234")
+
+	id, err := Identity(context.TODO(), &fakePods{err: errors.New("unexpected call")}, "openshift-marketplace")
+	require.NoError(t, err)
+	assert.Equal(t, "marketplace-operator-abc_1234", id)
+}
+
+func TestIdentityFromPod(t *testing.T) {
+	t.Setenv(podNameEnv, "marketplace-operator-abc")
+	t.Setenv(podUIDEnv, "")
+	pods := &fakePods{pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "marketplace-operator-abc", UID: types.UID("5678")}}}
+
+	id, err := Identity(context.TODO(), pods, "openshift-marketplace")
+	require.NoError(t, err)
+	assert.Equal(t, "marketplace-operator-abc_5678", id)
+}
+
+func TestIdentityFallsBackToHostname(t *testing.T) {
+	setHostname(t, "host", nil)
+	suffixed := regexp.MustCompile(`^host_[a-z0-9]{8}$`)
+
+	t.Run("pod UID unavailable", func(t *testing.T) {
+		t.Setenv(podNameEnv, "marketplace-operator-abc")
+		t.Setenv(podUIDEnv, "")
+
+		id, err := Identity(context.TODO(), &fakePods{err: errors.New("forbidden")}, "openshift-marketplace")
+		require.NoError(t, err)
+		assert.Regexp(t, suffixed, id)
+	})
+
+	t.Run("pod name unavailable", func(t *testing.T) {
+		t.Setenv(podNameEnv, "")
+		t.Setenv(podUIDEnv, "1234")
+
+		first, err := Identity(context.TODO(), &fakePods{}, "openshift-marketplace")
+		require.NoError(t, err)
+		assert.Regexp(t, suffixed, first)
+		second, err := Identity(context.TODO(), &fakePods{}, "openshift-marketplace")
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second, "the suffix tells replicas sharing a hostname apart")
+	})
+}
+
+func TestIdentityHostnameError(t *testing.T) {
+	t.Setenv(podNameEnv, "")
+	setHostname(t, "", errors.New("no hostname"))
+
+	_, err := Identity(context.TODO(), &fakePods{}, "openshift-marketplace")
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+const (
+	// ControllerKey, NamespaceKey and RequestKey are the fields identifying the
+	// reconcile a line was logged from.
+	ControllerKey = "controller"
+	NamespaceKey  = "namespace"
+	RequestKey    = "name"
+
+	// ReconcileIDKey is the field holding the random ID of the reconcile a
+	// line was logged from, so that the lines of concurrent reconciles of
+	// the same object can be told apart.
+	ReconcileIDKey = "reconcileID"
+
+	// reconcileIDLength is the length of the reconcile IDs.
+	reconcileIDLength = 8
+)
+
+// NewReconcileContext returns a copy of ctx carrying a logger that tags the
+// lines logged through FromContext with the controller name, the namespace and
+// name of the request and a new reconcile ID.
+func NewReconcileContext(ctx context.Context, controllerName string, request types.NamespacedName) context.Context {
+	logger := NewLogger(logrus.StandardLogger()).WithValues(
+		ControllerKey, controllerName,
+		NamespaceKey, request.Namespace,
+		RequestKey, request.Name,
+		ReconcileIDKey, utilrand.String(reconcileIDLength),
+	)
+	return logr.NewContext(ctx, logger)
+}
+
+// FromContext returns the logrus entry the operator logs through in ctx. The
+// lines are tagged with the fields of the logger ctx carries, if it was
+// returned by NewLogger, and logged through the standard logrus logger
+// otherwise.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if logger, err := logr.FromContext(ctx); err == nil {
+		if sink, ok := logger.GetSink().(*logSink); ok {
+			return sink.entry(nil)
+		}
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	previous := logrus.StandardLogger().Out
+	logrus.SetOutput(&buf)
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+	t.Cleanup(func() {
+		logrus.SetOutput(previous)
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	})
+
+	ctx := NewReconcileContext(context.TODO(), "catalogsource-controller", types.NamespacedName{Namespace: "openshift-marketplace", Name: "redhat-operators"})
+	FromContext(ctx).Infof("[defaults] Creating CatalogSource %s", "redhat-operators")
+	// The lines logged without a reconcile context are not tagged.
+	FromContext(context.TODO()).Info("[status] Created ClusterOperator")
+
+	lines := entries(t, &buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "[defaults] Creating CatalogSource redhat-operators", lines[0]["msg"])
+	assert.Equal(t, "catalogsource-controller", lines[0][ControllerKey])
+	assert.Equal(t, "openshift-marketplace", lines[0][NamespaceKey])
+	assert.Equal(t, "redhat-operators", lines[0][RequestKey])
+	assert.Len(t, lines[0][ReconcileIDKey], reconcileIDLength)
+	assert.NotContains(t, lines[1], ReconcileIDKey)
+}
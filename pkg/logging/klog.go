@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/klog/v2"
+)
+
+// klogName is the logger name of the lines logged through klog.
+const klogName = "klog"
+
+// RedirectKlog routes the lines logged through klog, such as the ones of
+// client-go, through logger. klog only logs the V(n) lines up to the given
+// verbosity, which the level of logger filters further.
+func RedirectKlog(logger *logrus.Logger, verbosity int) error {
+	klog.SetLogger(NewLogger(logger).WithName(klogName))
+	return SetKlogVerbosity(verbosity)
+}
+
+// SetKlogVerbosity sets the verbosity up to which the V(n) lines of klog are
+// logged.
+func SetKlogVerbosity(verbosity int) error {
+	flags := flag.NewFlagSet(klogName, flag.ContinueOnError)
+	klog.InitFlags(flags)
+	return flags.Set("v", strconv.Itoa(verbosity))
+}
+
+// KlogVerbosity returns the klog verbosity matching the given logrus level:
+// the V(n) lines are only logged at the debug and trace levels.
+func KlogVerbosity(level logrus.Level) int {
+	switch {
+	case level >= logrus.TraceLevel:
+		return 10
+	case level >= logrus.DebugLevel:
+		return 4
+	default:
+		return 0
+	}
+}
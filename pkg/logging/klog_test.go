@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/klog/v2"
+)
+
+// redirectKlog routes klog through a logrus logger at the given level and
+// returns the buffer it writes to.
+func redirectKlog(t *testing.T, level logrus.Level, verbosity int) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	logger := newLogger(&buf, &logrus.TextFormatter{DisableTimestamp: true})
+	logger.SetLevel(level)
+	require.NoError(t, RedirectKlog(logger, verbosity))
+	t.Cleanup(func() {
+		klog.ClearLogger()
+		require.NoError(t, SetKlogVerbosity(0))
+	})
+	return &buf
+}
+
+func TestRedirectKlog(t *testing.T) {
+	buf := redirectKlog(t, logrus.InfoLevel, 0)
+
+	klog.Info("Waited before sending request")
+	klog.Flush()
+	assert.Equal(t, "level=info msg=\"Waited before sending request\" logger=klog\n", buf.String())
+
+	buf.Reset()
+	klog.Error("Failed to watch")
+	klog.Flush()
+	assert.Equal(t, "level=error msg=\"Failed to watch\" logger=klog\n", buf.String())
+}
+
+func TestRedirectKlogLevel(t *testing.T) {
+	// The verbose lines klog lets through are suppressed below the debug
+	// level.
+	buf := redirectKlog(t, logrus.InfoLevel, 10)
+	klog.V(2).Info("Listing and watching")
+	klog.Flush()
+	assert.Empty(t, buf.String())
+
+	buf = redirectKlog(t, logrus.DebugLevel, 10)
+	klog.V(2).Info("Listing and watching")
+	klog.V(6).Info("Response body")
+	klog.Flush()
+	assert.Equal(t, "level=debug msg=\"Listing and watching\" logger=klog\n", buf.String())
+
+	// The lines more verbose than the klog verbosity are never logged.
+	buf = redirectKlog(t, logrus.TraceLevel, 4)
+	klog.V(4).Info("Listing and watching")
+	klog.V(6).Info("Response body")
+	klog.Flush()
+	assert.Equal(t, "level=debug msg=\"Listing and watching\" logger=klog\n", buf.String())
+}
+
+func TestKlogVerbosity(t *testing.T) {
+	assert.Equal(t, 0, KlogVerbosity(logrus.WarnLevel))
+	assert.Equal(t, 0, KlogVerbosity(logrus.InfoLevel))
+	assert.Equal(t, 4, KlogVerbosity(logrus.DebugLevel))
+	assert.Equal(t, 10, KlogVerbosity(logrus.TraceLevel))
+}
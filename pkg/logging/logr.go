@@ -0,0 +1,131 @@
+// Package logging routes the logs of the libraries the operator depends on
+// through the logrus logger of the operator, so that every line of the pod
+// log has the same format.
+package logging
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+)
+
+// NameKey is the field holding the name of the logger a line was logged
+// through.
+const NameKey = "logger"
+
+// NewLogger returns a logr.Logger that logs through logger. The lines logged at
+// V(0) are logged at the info level, the ones up to V(4) at the debug level
+// and the more verbose ones at the trace level.
+func NewLogger(logger *logrus.Logger) logr.Logger {
+	return logr.New(&logSink{logger: logger})
+}
+
+// logSink is a logr.LogSink backed by a logrus logger.
+type logSink struct {
+	logger *logrus.Logger
+	name   string
+	fields logrus.Fields
+}
+
+var _ logr.LogSink = &logSink{}
+
+// Init implements logr.LogSink. The caller is not recorded.
+func (s *logSink) Init(logr.RuntimeInfo) {}
+
+// Enabled implements logr.LogSink.
+func (s *logSink) Enabled(level int) bool {
+	return s.logger.IsLevelEnabled(logrusLevel(level))
+}
+
+// Info implements logr.LogSink.
+func (s *logSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.entry(keysAndValues).Log(logrusLevel(level), msg)
+}
+
+// Error implements logr.LogSink.
+func (s *logSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	entry := s.entry(keysAndValues)
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Error(msg)
+}
+
+// WithValues implements logr.LogSink.
+func (s *logSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &logSink{logger: s.logger, name: s.name, fields: s.withFields(keysAndValues)}
+}
+
+// WithName implements logr.LogSink. The names are joined with a dot.
+func (s *logSink) WithName(name string) logr.LogSink {
+	if s.name != "" {
+		name = s.name + "." + name
+	}
+	return &logSink{logger: s.logger, name: name, fields: s.fields}
+}
+
+// entry returns the logrus entry of a line logged with the given key/value
+// pairs.
+func (s *logSink) entry(keysAndValues []interface{}) *logrus.Entry {
+	fields := s.withFields(keysAndValues)
+	if s.name != "" {
+		fields[NameKey] = s.name
+	}
+	return s.logger.WithFields(fields)
+}
+
+// withFields returns a copy of the fields of the sink along with the given
+// key/value pairs. A key without a value is given a nil value, and a key that
+// is not a string is formatted.
+func (s *logSink) withFields(keysAndValues []interface{}) logrus.Fields {
+	fields := make(logrus.Fields, len(s.fields)+len(keysAndValues)/2+1)
+	for key, value := range s.fields {
+		fields[key] = value
+	}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		var value interface{}
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+// logrusLevel returns the logrus level the lines logged at the given logr
+// verbosity are logged at.
+func logrusLevel(level int) logrus.Level {
+	switch {
+	case level > 4:
+		return logrus.TraceLevel
+	case level > 0:
+		return logrus.DebugLevel
+	default:
+		return logrus.InfoLevel
+	}
+}
+
+// NameHook is a logrus hook that names the lines logged without a logger
+// name, so that the lines of the operator can be told apart from the ones of
+// its libraries.
+type NameHook string
+
+var _ logrus.Hook = NameHook("")
+
+// Levels implements logrus.Hook.
+func (h NameHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook.
+func (h NameHook) Fire(entry *logrus.Entry) error {
+	if _, ok := entry.Data[NameKey]; !ok {
+		entry.Data[NameKey] = string(h)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/klog/v2"
+)
+
+// newLogger returns a logrus logger writing to buf in the given format.
+func newLogger(buf *bytes.Buffer, formatter logrus.Formatter) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(buf)
+	logger.SetFormatter(formatter)
+	return logger
+}
+
+// entries decodes the JSON lines of buf.
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var decoded []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		entry := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), "line %q is not JSON", line)
+		decoded = append(decoded, entry)
+	}
+	return decoded
+}
+
+func TestLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(newLogger(&buf, &logrus.JSONFormatter{})).WithName("controller-runtime")
+
+	logger.WithName("manager").WithValues("controller", "catalogsource").Info("Starting workers", "count", 1)
+	logger.Error(errors.New("denied"), "Reconciler error", "name")
+
+	decoded := entries(t, &buf)
+	require.Len(t, decoded, 2)
+	assert.Equal(t, "info", decoded[0]["level"])
+	assert.Equal(t, "Starting workers", decoded[0]["msg"])
+	assert.Equal(t, "controller-runtime.manager", decoded[0][NameKey])
+	assert.Equal(t, "catalogsource", decoded[0]["controller"])
+	assert.Equal(t, 1.0, decoded[0]["count"])
+	assert.NotEmpty(t, decoded[0]["time"])
+
+	assert.Equal(t, "error", decoded[1]["level"])
+	assert.Equal(t, "controller-runtime", decoded[1][NameKey])
+	assert.Equal(t, "denied", decoded[1][logrus.ErrorKey])
+	assert.Contains(t, decoded[1], "name", "a key without a value is kept")
+}
+
+func TestLoggerText(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(newLogger(&buf, &logrus.TextFormatter{DisableTimestamp: true})).WithName("klog")
+
+	logger.Info("Waited for request", "delay", "1s")
+	assert.Equal(t, "level=info msg=\"Waited for request\" delay=1s logger=klog\n", buf.String())
+	assert.False(t, json.Valid(buf.Bytes()))
+}
+
+func TestLoggerVerbosity(t *testing.T) {
+	var buf bytes.Buffer
+	base := newLogger(&buf, &logrus.JSONFormatter{})
+	logger := NewLogger(base)
+
+	assert.True(t, logger.Enabled())
+	assert.False(t, logger.V(1).Enabled(), "the verbose lines are logged at the debug level")
+	logger.V(1).Info("hidden")
+	assert.Empty(t, buf.String())
+
+	base.SetLevel(logrus.DebugLevel)
+	logger.V(2).Info("shown")
+	require.Len(t, entries(t, &buf), 1)
+	assert.Equal(t, "debug", entries(t, &buf)[0]["level"])
+}
+
+func TestKlogBridge(t *testing.T) {
+	var buf bytes.Buffer
+	klog.SetLogger(NewLogger(newLogger(&buf, &logrus.JSONFormatter{})).WithName("klog"))
+	t.Cleanup(klog.ClearLogger)
+
+	klog.InfoS("Throttling request", "verb", "GET")
+	klog.Flush()
+
+	decoded := entries(t, &buf)
+	require.Len(t, decoded, 1)
+	assert.Equal(t, "Throttling request", decoded[0]["msg"])
+	assert.Equal(t, "klog", decoded[0][NameKey])
+	assert.Equal(t, "GET", decoded[0]["verb"])
+}
+
+func TestNameHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, &logrus.JSONFormatter{})
+	logger.AddHook(NameHook("marketplace-operator"))
+
+	logger.Info("operator")
+	NewLogger(logger).WithName("klog").Info("library")
+
+	decoded := entries(t, &buf)
+	require.Len(t, decoded, 2)
+	assert.Equal(t, "marketplace-operator", decoded[0][NameKey])
+	assert.Equal(t, "klog", decoded[1][NameKey])
+}
@@ -0,0 +1,275 @@
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// AggregatedMetricsPath is the path that the leader exposes the metrics
+	// of all the marketplace replicas at.
+	AggregatedMetricsPath = "/metrics/aggregated"
+
+	// podLabel is the label added to every aggregated metric to identify the
+	// replica it was scraped from.
+	podLabel = "pod"
+
+	// scrapeTimeout is the maximum time spent scraping a single replica.
+	scrapeTimeout = 10 * time.Second
+
+	// serviceCAFile is the service CA bundle OpenShift mounts in every pod.
+	// It signs the serving certificates of the replicas.
+	serviceCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+)
+
+// Aggregator is an HTTP handler that scrapes the metrics endpoint of every
+// marketplace replica behind the metrics Service and merges the results into
+// a single Prometheus response. Each metric is labeled with the pod it was
+// scraped from. Only the leader serves aggregated metrics.
+type Aggregator struct {
+	endpoints   typedcorev1.EndpointsGetter
+	namespace   string
+	serviceName string
+	portName    string
+	scheme      string
+	authToken   string
+	httpClient  *http.Client
+	isLeader    func() bool
+}
+
+// NewAggregator returns an Aggregator that discovers the replicas through the
+// Endpoints of the given Service. The replicas are scraped over https if both
+// certPath and keyPath are set, verifying their serving certificate against
+// the service CA and presenting the serving key pair as client certificate
+// for the replicas requiring one, and over http otherwise. If authToken is not
+// empty it is sent as a bearer token. isLeader reports whether this replica
+// holds the leader lock.
+func NewAggregator(endpoints typedcorev1.EndpointsGetter, namespace, serviceName, certPath, keyPath, authToken string, isLeader func() bool) (*Aggregator, error) {
+	portName, scheme := "metrics", "http"
+	httpClient := &http.Client{Timeout: scrapeTimeout}
+	if certPath != "" && keyPath != "" {
+		// The serving certificate is reloaded when it is rotated on disk.
+		reloader, err := newCertificateReloader(certPath, keyPath)
+		if err != nil {
+			return nil, err
+		}
+		portName, scheme = "https-metrics", "https"
+		httpClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				// The replicas are addressed by IP, but their serving
+				// certificate is issued for the Service.
+				ServerName: fmt.Sprintf("%s.%s.svc", serviceName, namespace),
+				RootCAs:    serviceCAPool(),
+				GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+					return reloader.GetCertificate(nil)
+				},
+			},
+		}
+	}
+	return &Aggregator{
+		endpoints:   endpoints,
+		namespace:   namespace,
+		serviceName: serviceName,
+		portName:    portName,
+		scheme:      scheme,
+		authToken:   authToken,
+		httpClient:  httpClient,
+		isLeader:    isLeader,
+	}, nil
+}
+
+// ServeHTTP scrapes all the replicas and writes the merged metrics.
+func (a *Aggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !a.isLeader() {
+		http.Error(w, "aggregated metrics are only served by the leader", http.StatusServiceUnavailable)
+		return
+	}
+
+	targets, err := a.targets(r.Context())
+	if err != nil {
+		logrus.Errorf("[metrics] Unable to discover marketplace replicas: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	scraped := a.scrapeAll(r.Context(), targets)
+	pods := make([]string, 0, len(scraped))
+	for pod := range scraped {
+		pods = append(pods, pod)
+	}
+	sort.Strings(pods)
+	merged := map[string]*dto.MetricFamily{}
+	for _, pod := range pods {
+		mergeFamilies(merged, scraped[pod], pod)
+	}
+
+	format := expfmt.Negotiate(r.Header)
+	w.Header().Set("Content-Type", string(format))
+	encoder := expfmt.NewEncoder(w, format)
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := encoder.Encode(merged[name]); err != nil {
+			logrus.Errorf("[metrics] Unable to encode aggregated metrics: %v", err)
+			return
+		}
+	}
+}
+
+// scrapeAll scrapes the targets concurrently and returns the metrics of the
+// replicas that could be scraped, keyed by pod name.
+func (a *Aggregator) scrapeAll(ctx context.Context, targets map[string]string) map[string][]*dto.MetricFamily {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		scraped = make(map[string][]*dto.MetricFamily, len(targets))
+	)
+	for pod, url := range targets {
+		wg.Add(1)
+		go func(pod, url string) {
+			defer wg.Done()
+			families, err := a.scrape(ctx, url)
+			if err != nil {
+				// A replica that can not be scraped should not prevent the
+				// others from being reported.
+				logrus.Warnf("[metrics] Unable to scrape replica %s: %v", pod, err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			scraped[pod] = families
+		}(pod, url)
+	}
+	wg.Wait()
+	return scraped
+}
+
+// targets returns the metrics URL of every replica behind the metrics
+// Service, keyed by pod name. The replicas that are not ready are scraped too,
+// as only the leader reports ready.
+func (a *Aggregator) targets(ctx context.Context) (map[string]string, error) {
+	endpoints, err := a.endpoints.Endpoints(a.namespace).Get(ctx, a.serviceName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	targets := map[string]string{}
+	for _, subset := range endpoints.Subsets {
+		port := findPort(subset.Ports, a.portName)
+		if port == 0 {
+			continue
+		}
+		for _, address := range append(subset.Addresses, subset.NotReadyAddresses...) {
+			pod := address.IP
+			if address.TargetRef != nil {
+				pod = address.TargetRef.Name
+			}
+			host := net.JoinHostPort(address.IP, strconv.Itoa(int(port)))
+			targets[pod] = fmt.Sprintf("%s://%s%s", a.scheme, host, metricsPath)
+		}
+	}
+	return targets, nil
+}
+
+// scrape fetches and decodes the metrics served at url.
+func (a *Aggregator) scrape(ctx context.Context, url string) ([]*dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if a.authToken != "" {
+		req.Header.Set("Authorization", bearerPrefix+a.authToken)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var families []*dto.MetricFamily
+	decoder := expfmt.NewDecoder(resp.Body, expfmt.ResponseFormat(resp.Header))
+	for {
+		family := &dto.MetricFamily{}
+		if err := decoder.Decode(family); err != nil {
+			if errors.Is(err, io.EOF) {
+				return families, nil
+			}
+			return nil, err
+		}
+		families = append(families, family)
+	}
+}
+
+// mergeFamilies adds the metrics of families, labeled with the given pod, to
+// merged. Families whose type does not match the one already merged under the
+// same name are dropped.
+func mergeFamilies(merged map[string]*dto.MetricFamily, families []*dto.MetricFamily, pod string) {
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			metric.Label = append(metric.Label, &dto.LabelPair{
+				Name:  proto.String(podLabel),
+				Value: proto.String(pod),
+			})
+		}
+
+		existing, present := merged[family.GetName()]
+		if !present {
+			merged[family.GetName()] = family
+			continue
+		}
+		if existing.GetType() != family.GetType() {
+			logrus.Warnf("[metrics] Dropping metric %s from %s, its type does not match the other replicas", family.GetName(), pod)
+			continue
+		}
+		existing.Metric = append(existing.Metric, family.Metric...)
+	}
+}
+
+// serviceCAPool returns a pool containing the service CA if it is mounted,
+// and nil otherwise so that the system roots are used.
+func serviceCAPool() *x509.CertPool {
+	ca, err := os.ReadFile(serviceCAFile)
+	if err != nil {
+		logrus.Warnf("[metrics] Unable to read the service CA, using the system roots to verify replicas: %v", err)
+		return nil
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+	return pool
+}
+
+// findPort returns the port with the given name, or zero if it is not found.
+func findPort(ports []corev1.EndpointPort, name string) int32 {
+	for _, port := range ports {
+		if port.Name == name {
+			return port.Port
+		}
+	}
+	return 0
+}
@@ -0,0 +1,225 @@
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// fakeEndpoints serves the Endpoints of a single Service.
+type fakeEndpoints struct {
+	typedcorev1.EndpointsInterface
+	endpoints *corev1.Endpoints
+}
+
+func (f *fakeEndpoints) Endpoints(string) typedcorev1.EndpointsInterface {
+	return f
+}
+
+func (f *fakeEndpoints) Get(_ context.Context, name string, _ metav1.GetOptions) (*corev1.Endpoints, error) {
+	if f.endpoints == nil || f.endpoints.Name != name {
+		return nil, apierrors.NewNotFound(corev1.Resource("endpoints"), name)
+	}
+	return f.endpoints.DeepCopy(), nil
+}
+
+// peerHandler serves the given metrics in the text format.
+func peerHandler(metrics string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != metricsPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, metrics)
+	})
+}
+
+// peer returns the host and port the started server listens on, and closes
+// it once the test ends.
+func peer(t *testing.T, server *httptest.Server) (string, int32) {
+	t.Helper()
+	t.Cleanup(server.Close)
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+	return host, int32(port)
+}
+
+// endpointsOf returns the Endpoints of the metrics Service with the given
+// subsets, keyed by pod, whose ports are named portName.
+func endpointsOf(portName string, pods map[string]corev1.EndpointSubset) *corev1.Endpoints {
+	endpoints := &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: "marketplace-operator-metrics", Namespace: "openshift-marketplace"}}
+	for _, subset := range pods {
+		for i := range subset.Ports {
+			subset.Ports[i].Name = portName
+		}
+		endpoints.Subsets = append(endpoints.Subsets, subset)
+	}
+	return endpoints
+}
+
+func address(pod, ip string) corev1.EndpointAddress {
+	return corev1.EndpointAddress{IP: ip, TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: pod}}
+}
+
+func TestAggregatorTargets(t *testing.T) {
+	fake := &fakeEndpoints{endpoints: &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "marketplace-operator-metrics", Namespace: "openshift-marketplace"},
+		Subsets: []corev1.EndpointSubset{
+			{
+				Addresses:         []corev1.EndpointAddress{address("leader", "10.0.0.1")},
+				NotReadyAddresses: []corev1.EndpointAddress{address("standby", "10.0.0.2"), {IP: "10.0.0.3"}},
+				Ports:             []corev1.EndpointPort{{Name: "metrics", Port: 8383}, {Name: "https-metrics", Port: 8081}},
+			},
+			// The subsets without the metrics port are not scraped.
+			{
+				Addresses: []corev1.EndpointAddress{address("other", "10.0.0.4")},
+				Ports:     []corev1.EndpointPort{{Name: "webhook", Port: 9443}},
+			},
+		},
+	}}
+	a, err := NewAggregator(fake, "openshift-marketplace", "marketplace-operator-metrics", "", "", "", func() bool { return true })
+	require.NoError(t, err)
+
+	targets, err := a.targets(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"leader":   "http://10.0.0.1:8383/metrics",
+		"standby":  "http://10.0.0.2:8383/metrics",
+		"10.0.0.3": "http://10.0.0.3:8383/metrics",
+	}, targets, "the replicas are scraped whether they are ready or not")
+
+	a.serviceName = "missing"
+	_, err = a.targets(context.TODO())
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestMergeFamilies(t *testing.T) {
+	counter := func(name string, value float64) *dto.MetricFamily {
+		return &dto.MetricFamily{
+			Name:   proto.String(name),
+			Type:   dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{{Counter: &dto.Counter{Value: proto.Float64(value)}}},
+		}
+	}
+	gauge := func(name string, value float64) *dto.MetricFamily {
+		return &dto.MetricFamily{
+			Name:   proto.String(name),
+			Type:   dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(value)}}},
+		}
+	}
+
+	merged := map[string]*dto.MetricFamily{}
+	mergeFamilies(merged, []*dto.MetricFamily{counter("reconciles_total", 3), gauge("leader", 1)}, "leader")
+	mergeFamilies(merged, []*dto.MetricFamily{counter("reconciles_total", 1), counter("leader", 0)}, "standby")
+
+	require.Len(t, merged, 2)
+	reconciles := merged["reconciles_total"].GetMetric()
+	require.Len(t, reconciles, 2)
+	pods := []string{}
+	for _, metric := range reconciles {
+		require.Len(t, metric.GetLabel(), 1)
+		assert.Equal(t, podLabel, metric.GetLabel()[0].GetName())
+		pods = append(pods, metric.GetLabel()[0].GetValue())
+	}
+	assert.Equal(t, []string{"leader", "standby"}, pods)
+	assert.Len(t, merged["leader"].GetMetric(), 1, "the family whose type does not match is dropped")
+	assert.Equal(t, dto.MetricType_GAUGE, merged["leader"].GetType())
+}
+
+func TestAggregatorServeHTTP(t *testing.T) {
+	leaderHost, leaderPort := peer(t, httptest.NewServer(peerHandler("# TYPE marketplace_test_total counter\nmarketplace_test_total 3\n")))
+	standbyHost, standbyPort := peer(t, httptest.NewServer(peerHandler("# TYPE marketplace_test_total counter\nmarketplace_test_total 1\n")))
+	fake := &fakeEndpoints{endpoints: endpointsOf("metrics", map[string]corev1.EndpointSubset{
+		"leader":  {Addresses: []corev1.EndpointAddress{address("leader", leaderHost)}, Ports: []corev1.EndpointPort{{Port: leaderPort}}},
+		"standby": {NotReadyAddresses: []corev1.EndpointAddress{address("standby", standbyHost)}, Ports: []corev1.EndpointPort{{Port: standbyPort}}},
+		// A replica that can not be scraped is skipped.
+		"gone": {NotReadyAddresses: []corev1.EndpointAddress{address("gone", "127.0.0.1")}, Ports: []corev1.EndpointPort{{Port: 1}}},
+	})}
+	leader := true
+	a, err := NewAggregator(fake, "openshift-marketplace", "marketplace-operator-metrics", "", "", "", func() bool { return leader })
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	a.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, AggregatedMetricsPath, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `marketplace_test_total{pod="leader"} 3`)
+	assert.Contains(t, recorder.Body.String(), `marketplace_test_total{pod="standby"} 1`)
+	assert.NotContains(t, recorder.Body.String(), `pod="gone"`)
+
+	leader = false
+	recorder = httptest.NewRecorder()
+	a.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, AggregatedMetricsPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+func TestAggregatorScrapesConcurrently(t *testing.T) {
+	release := make(chan struct{})
+	blocking := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			<-release
+		}))
+	}
+	targets := map[string]string{}
+	for _, pod := range []string{"a", "b", "c"} {
+		server := blocking()
+		t.Cleanup(server.Close)
+		targets[pod] = server.URL + metricsPath
+	}
+	a, err := NewAggregator(&fakeEndpoints{}, "openshift-marketplace", "marketplace-operator-metrics", "", "", "", func() bool { return true })
+	require.NoError(t, err)
+	a.httpClient.Timeout = 200 * time.Millisecond
+
+	// The replicas all time out, concurrently rather than one after another.
+	start := time.Now()
+	assert.Empty(t, a.scrapeAll(context.TODO(), targets))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	close(release)
+}
+
+func TestAggregatorPresentsClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeKeyPair(t, certPath, keyPath, "marketplace-operator-metrics", time.Now())
+
+	// The replica requires a client certificate, as with -tls-client-ca.
+	server := httptest.NewUnstartedServer(peerHandler("# TYPE marketplace_test_total counter\nmarketplace_test_total 1\n"))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	host, port := peer(t, server)
+
+	fake := &fakeEndpoints{endpoints: endpointsOf("https-metrics", map[string]corev1.EndpointSubset{
+		"leader": {Addresses: []corev1.EndpointAddress{address("leader", host)}, Ports: []corev1.EndpointPort{{Port: port}}},
+	})}
+	a, err := NewAggregator(fake, "openshift-marketplace", "marketplace-operator-metrics", certPath, keyPath, "", func() bool { return true })
+	require.NoError(t, err)
+	// The test server certificate is not signed by the service CA.
+	a.httpClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = true
+
+	recorder := httptest.NewRecorder()
+	a.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, AggregatedMetricsPath, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `marketplace_test_total{pod="leader"} 1`)
+
+	_, err = NewAggregator(fake, "openshift-marketplace", "marketplace-operator-metrics", filepath.Join(dir, "missing.crt"), keyPath, "", func() bool { return true })
+	assert.Error(t, err, "the serving key pair must load")
+}
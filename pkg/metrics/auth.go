@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// bearerPrefix is the prefix of the Authorization header value that carries
+// a bearer token.
+const bearerPrefix = "Bearer "
+
+// NewAuthMiddleware returns a handler that only forwards requests to next if
+// they carry the given token in an `Authorization: Bearer <token>` header.
+// All other requests are rejected with a 401.
+func NewAuthMiddleware(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, bearerPrefix) ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, bearerPrefix)), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="marketplace-metrics"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
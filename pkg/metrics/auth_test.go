@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAuthMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := NewAuthMiddleware("secret", next)
+
+	tests := []struct {
+		name          string
+		authorization string
+		expected      int
+	}{
+		{name: "valid token", authorization: "Bearer secret", expected: http.StatusOK},
+		{name: "missing header", authorization: "", expected: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer wrong", expected: http.StatusUnauthorized},
+		{name: "wrong scheme", authorization: "Basic secret", expected: http.StatusUnauthorized},
+		{name: "token prefix", authorization: "Bearer secre", expected: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, metricsPath, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.expected, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/operator-framework/operator-marketplace/pkg/version"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// buildInfo identifies the marketplace build that is running. Its value is
+// always 1, the build is described by its labels.
+var buildInfo = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "marketplace_build_info",
+		Help: "A metric with a constant '1' value labeled by the source commit, release version and Go version marketplace was built with.",
+	},
+	[]string{"commit", "release_version", "go_version"},
+)
+
+// recordBuildInfo sets the labels of buildInfo from pkg/version and the
+// RELEASE_VERSION environment variable.
+func recordBuildInfo() {
+	buildInfo.Reset()
+	buildInfo.WithLabelValues(version.GitCommit, os.Getenv("RELEASE_VERSION"), runtime.Version()).Set(1)
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-marketplace/pkg/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildInfo(t *testing.T) {
+	previous := version.GitCommit
+	version.GitCommit = "0123456789abcdef"
+	t.Cleanup(func() { version.GitCommit = previous })
+	t.Setenv("RELEASE_VERSION", "4.18.0")
+	require.NoError(t, RegisterMetrics())
+
+	recorder := httptest.NewRecorder()
+	metricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, metricsPath, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+
+	// The commit label is the one pkg/version reports.
+	assert.Equal(t, "Marketplace source git commit: 0123456789abcdef\n", version.String())
+	metric := fmt.Sprintf(`marketplace_build_info{commit="0123456789abcdef",go_version="%s",release_version="4.18.0"} 1`, runtime.Version())
+	assert.True(t, strings.Contains(string(body), metric), "metric %s is served", metric)
+}
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// certCheckInterval is the minimum time between two checks of the serving
+// certificate files, so that handshakes do not hit the filesystem every time.
+const certCheckInterval = 10 * time.Second
+
+// fileVersion identifies the content of a file on disk.
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// certificateReloader serves the certificate and key at the given paths,
+// reloading them when they change on disk. This keeps the metrics endpoint
+// serving a valid certificate after it is rotated, e.g. by the service CA
+// operator.
+type certificateReloader struct {
+	certPath      string
+	keyPath       string
+	checkInterval time.Duration
+	now           func() time.Time
+
+	mutex       sync.Mutex
+	cert        *tls.Certificate
+	certVersion fileVersion
+	keyVersion  fileVersion
+	lastCheck   time.Time
+}
+
+// newCertificateReloader returns a certificateReloader for the given key pair.
+// An error is returned if the key pair can not be loaded initially.
+func newCertificateReloader(certPath, keyPath string) (*certificateReloader, error) {
+	r := &certificateReloader{
+		certPath:      certPath,
+		keyPath:       keyPath,
+		checkInterval: certCheckInterval,
+		now:           time.Now,
+	}
+	certVersion, keyVersion, err := r.versions()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(certVersion, keyVersion); err != nil {
+		return nil, err
+	}
+	r.lastCheck = r.now()
+	return r, nil
+}
+
+// GetCertificate returns the current certificate. It is meant to be used as
+// the GetCertificate function of a tls.Config. If the files changed since they
+// were last loaded the key pair is reloaded, and on failure the previous
+// certificate keeps being served.
+func (r *certificateReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.now()
+	if now.Sub(r.lastCheck) < r.checkInterval {
+		return r.cert, nil
+	}
+	r.lastCheck = now
+
+	certVersion, keyVersion, err := r.versions()
+	if err != nil {
+		logrus.Errorf("[metrics] Unable to check the serving certificate, serving the previous one: %v", err)
+		return r.cert, nil
+	}
+	if certVersion == r.certVersion && keyVersion == r.keyVersion {
+		return r.cert, nil
+	}
+	if err := r.load(certVersion, keyVersion); err != nil {
+		// The certificate and the key may not have been updated together
+		// yet, the load is retried on the next check.
+		logrus.Errorf("[metrics] Unable to reload the serving certificate, serving the previous one: %v", err)
+		return r.cert, nil
+	}
+	logrus.Info("[metrics] Reloaded the serving certificate")
+	return r.cert, nil
+}
+
+// load loads the key pair and records the versions of the files it was loaded
+// from.
+func (r *certificateReloader) load(certVersion, keyVersion fileVersion) error {
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return err
+	}
+	r.cert = &cert
+	r.certVersion = certVersion
+	r.keyVersion = keyVersion
+	return nil
+}
+
+// versions returns the versions of the certificate and key files.
+func (r *certificateReloader) versions() (fileVersion, fileVersion, error) {
+	certVersion, err := statVersion(r.certPath)
+	if err != nil {
+		return fileVersion{}, fileVersion{}, err
+	}
+	keyVersion, err := statVersion(r.keyPath)
+	if err != nil {
+		return fileVersion{}, fileVersion{}, err
+	}
+	return certVersion, keyVersion, nil
+}
+
+// statVersion returns the version of the file at path. Symlinks are followed
+// so that the atomic swaps of Secret and ConfigMap mounts are detected.
+func statVersion(path string) (fileVersion, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}, err
+	}
+	return fileVersion{modTime: info.ModTime(), size: info.Size()}, nil
+}
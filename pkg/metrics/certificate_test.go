@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyPair writes a self-signed certificate with the given common name,
+// and its key, to the given paths. The modification time of the files is set
+// to modTime so that successive writes are always detected.
+func writeKeyPair(t *testing.T, certPath, keyPath, commonName string, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	require.NoError(t, os.Chtimes(certPath, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyPath, modTime, modTime))
+}
+
+// listenTLS accepts TLS connections with the given configuration until the
+// test ends, and returns the address they are accepted on.
+func listenTLS(t *testing.T, config *tls.Config) string {
+	t.Helper()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// servedCommonName opens a new TLS connection to addr and returns the common
+// name of the certificate presented by the server.
+func servedCommonName(t *testing.T, addr string) string {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	modTime := time.Now().Add(-time.Hour)
+	writeKeyPair(t, certPath, keyPath, "old", modTime)
+
+	reloader, err := newCertificateReloader(certPath, keyPath)
+	require.NoError(t, err)
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	addr := listenTLS(t, &tls.Config{GetCertificate: reloader.GetCertificate})
+	assert.Equal(t, "old", servedCommonName(t, addr))
+
+	writeKeyPair(t, certPath, keyPath, "new", modTime.Add(time.Minute))
+	assert.Equal(t, "old", servedCommonName(t, addr), "the files are not checked again within the check interval")
+
+	now = now.Add(certCheckInterval)
+	assert.Equal(t, "new", servedCommonName(t, addr), "new connections are served the rotated certificate")
+
+	// A certificate that can not be loaded does not replace the current one.
+	require.NoError(t, os.WriteFile(certPath, []byte("not a certificate"), 0600))
+	now = now.Add(certCheckInterval)
+	assert.Equal(t, "new", servedCommonName(t, addr))
+
+	require.NoError(t, os.Remove(keyPath))
+	now = now.Add(certCheckInterval)
+	assert.Equal(t, "new", servedCommonName(t, addr))
+}
+
+func TestCertificateReloaderInitialLoad(t *testing.T) {
+	dir := t.TempDir()
+	_, err := newCertificateReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// clientCAReloader serves the pool of the CA certificates in the bundle at
+// the given path, reloading it when it changes on disk like the serving
+// certificate.
+type clientCAReloader struct {
+	path          string
+	checkInterval time.Duration
+	now           func() time.Time
+
+	mutex     sync.Mutex
+	pool      *x509.CertPool
+	version   fileVersion
+	lastCheck time.Time
+}
+
+// newClientCAReloader returns a clientCAReloader for the given CA bundle. An
+// error is returned if the bundle can not be loaded initially.
+func newClientCAReloader(path string) (*clientCAReloader, error) {
+	r := &clientCAReloader{
+		path:          path,
+		checkInterval: certCheckInterval,
+		now:           time.Now,
+	}
+	version, err := statVersion(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(version); err != nil {
+		return nil, err
+	}
+	r.lastCheck = r.now()
+	return r, nil
+}
+
+// ClientCAs returns the current pool of client CA certificates. If the bundle
+// changed since it was last loaded it is reloaded, and on failure the
+// previous pool keeps being used.
+func (r *clientCAReloader) ClientCAs() *x509.CertPool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.now()
+	if now.Sub(r.lastCheck) < r.checkInterval {
+		return r.pool
+	}
+	r.lastCheck = now
+
+	version, err := statVersion(r.path)
+	if err != nil {
+		logrus.Errorf("[metrics] Unable to check the client CA bundle, using the previous one: %v", err)
+		return r.pool
+	}
+	if version == r.version {
+		return r.pool
+	}
+	if err := r.load(version); err != nil {
+		logrus.Errorf("[metrics] Unable to reload the client CA bundle, using the previous one: %v", err)
+		return r.pool
+	}
+	logrus.Info("[metrics] Reloaded the client CA bundle")
+	return r.pool
+}
+
+// load loads the CA bundle and records the version of the file it was loaded
+// from.
+func (r *clientCAReloader) load(version fileVersion) error {
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return fmt.Errorf("no CA certificate found in %s", r.path)
+	}
+	r.pool = pool
+	r.version = version
+	return nil
+}
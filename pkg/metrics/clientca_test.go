@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA is a certificate authority issuing client certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, commonName string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// write writes the CA certificate to path with the given modification time.
+func (ca *testCA) write(t *testing.T, path string, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+// issue returns a client certificate signed by the CA.
+func (ca *testCA) issue(t *testing.T, commonName string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestClientCertificateAuthentication(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath, caPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	writeKeyPair(t, certPath, keyPath, "metrics", time.Now())
+	modTime := time.Now().Add(-time.Hour)
+	prometheusCA := newTestCA(t, "prometheus-ca")
+	prometheusCA.write(t, caPath, modTime)
+
+	reloader, err := newCertificateReloader(certPath, keyPath)
+	require.NoError(t, err)
+	clientCAs, err := newClientCAReloader(caPath)
+	require.NoError(t, err)
+	now := time.Now()
+	clientCAs.now = func() time.Time { return now }
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{
+		Handler:   promhttp.Handler(),
+		TLSConfig: ServeOptions{TLSMinVersion: tls.VersionTLS12}.tlsConfig(reloader, clientCAs),
+	}
+	go server.ServeTLS(listener, "", "")
+	t.Cleanup(func() { server.Close() })
+	url := "https://" + listener.Addr().String() + metricsPath
+
+	scrape := func(certificates ...tls.Certificate) (int, error) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: certificates},
+		}}
+		defer client.CloseIdleConnections()
+		response, err := client.Get(url)
+		if err != nil {
+			return 0, err
+		}
+		defer response.Body.Close()
+		return response.StatusCode, nil
+	}
+
+	prometheus := prometheusCA.issue(t, "prometheus")
+	status, err := scrape(prometheus)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status, "a client certificate signed by the CA is accepted")
+
+	_, err = scrape()
+	assert.Error(t, err, "a connection without a client certificate is rejected")
+
+	intruder := newTestCA(t, "intruder-ca").issue(t, "intruder")
+	_, err = scrape(intruder)
+	assert.Error(t, err, "a client certificate signed by another CA is rejected")
+
+	response, err := http.Get("http://" + listener.Addr().String() + metricsPath)
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode, "plain http is rejected")
+
+	// The rotated CA bundle is used by the new connections.
+	rotatedCA := newTestCA(t, "rotated-ca")
+	rotatedCA.write(t, caPath, modTime.Add(time.Minute))
+	now = now.Add(certCheckInterval)
+	_, err = scrape(prometheus)
+	assert.Error(t, err, "a client certificate signed by the previous CA is rejected after the rotation")
+	status, err = scrape(rotatedCA.issue(t, "prometheus"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	// A bundle that can not be loaded does not replace the current one.
+	require.NoError(t, os.WriteFile(caPath, []byte("not a certificate"), 0600))
+	now = now.Add(certCheckInterval)
+	status, err = scrape(rotatedCA.issue(t, "prometheus"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestServePrometheusClientCARequiresTLS(t *testing.T) {
+	_, err := ServePrometheus(ServeOptions{Addr: ":0", ClientCAPath: "/etc/prometheus/ca.crt"})
+	assert.Error(t, err)
+}
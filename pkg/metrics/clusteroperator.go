@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// clusterOperatorStatusUpdateFailures counts the attempts to report the
+// status of the marketplace ClusterOperator that failed.
+var clusterOperatorStatusUpdateFailures = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "marketplace_clusteroperator_status_update_failures_total",
+		Help: "Number of attempts to update the status of the marketplace ClusterOperator that failed.",
+	},
+)
+
+// clusterOperatorLastSuccessfulUpdate reports when the status of the
+// marketplace ClusterOperator was last successfully reported.
+var clusterOperatorLastSuccessfulUpdate = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "marketplace_clusteroperator_last_successful_update_timestamp_seconds",
+		Help: "Unix time at which the status of the marketplace ClusterOperator was last successfully updated.",
+	},
+)
+
+// RecordClusterOperatorStatusUpdate records the outcome of an attempt, made
+// at the given time, to update the status of the marketplace ClusterOperator.
+// The attempt failed if err is not nil.
+func RecordClusterOperatorStatusUpdate(at time.Time, err error) {
+	if err != nil {
+		clusterOperatorStatusUpdateFailures.Inc()
+		return
+	}
+	clusterOperatorLastSuccessfulUpdate.Set(float64(at.Unix()))
+}
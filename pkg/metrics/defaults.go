@@ -0,0 +1,183 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	// DefaultCatalogSourceApplied is the status of default CatalogSources
+	// that are present on the cluster.
+	DefaultCatalogSourceApplied = "applied"
+
+	// DefaultCatalogSourceFailed is the status of default CatalogSources
+	// that could not be processed.
+	DefaultCatalogSourceFailed = "failed"
+
+	// DefaultCatalogSourceDeleted is the status of default CatalogSources
+	// that have been disabled and removed from the cluster.
+	DefaultCatalogSourceDeleted = "deleted"
+
+	// CanarySucceeded is the result of the canaries that became ready, the
+	// default CatalogSource was upgraded to their image.
+	CanarySucceeded = "succeeded"
+
+	// CanaryFailed is the result of the canaries that did not become ready in
+	// time, the upgrade of the default CatalogSource to their image was
+	// aborted.
+	CanaryFailed = "failed"
+)
+
+// defaultCatalogSourceCount tracks the number of default CatalogSources
+// managed by marketplace per status.
+var defaultCatalogSourceCount = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "marketplace_default_catalogsources",
+		Help: "Number of default CatalogSources managed by marketplace, by status.",
+	},
+	[]string{"status"},
+)
+
+// SetDefaultCatalogSourceCount sets the number of default CatalogSources in
+// the given status.
+func SetDefaultCatalogSourceCount(status string, count int) {
+	defaultCatalogSourceCount.WithLabelValues(status).Set(float64(count))
+}
+
+// defaultCatalogSourcePopulation tracks the number of default CatalogSource
+// definitions per result of their last population.
+var defaultCatalogSourcePopulation = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "marketplace_default_catalogsources_populated",
+		Help: "Number of default CatalogSource definitions created, updated, skipped or failed when they were last populated, by result.",
+	},
+	[]string{"result"},
+)
+
+// SetDefaultCatalogSourcePopulation sets the number of default CatalogSource
+// definitions per result of their last population.
+func SetDefaultCatalogSourcePopulation(created, updated, skipped, failed int) {
+	defaultCatalogSourcePopulation.WithLabelValues("created").Set(float64(created))
+	defaultCatalogSourcePopulation.WithLabelValues("updated").Set(float64(updated))
+	defaultCatalogSourcePopulation.WithLabelValues("skipped").Set(float64(skipped))
+	defaultCatalogSourcePopulation.WithLabelValues("failed").Set(float64(failed))
+}
+
+// defaultCatalogSourceRecreations counts the times marketplace recreated a
+// default CatalogSource that was deleted from the cluster.
+var defaultCatalogSourceRecreations = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "marketplace_default_catalogsource_recreations_total",
+		Help: "Number of times marketplace recreated a deleted default CatalogSource, by name.",
+	},
+	[]string{"name"},
+)
+
+// unexpectedSpecMutations counts the modifications of the spec of a default
+// CatalogSource that were not made by marketplace.
+var unexpectedSpecMutations = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "marketplace_unexpected_spec_mutations_total",
+		Help: "Number of times the spec of a default CatalogSource was modified by someone other than marketplace, by name.",
+	},
+	[]string{"name"},
+)
+
+// fieldManagerConflicts counts the default CatalogSources found managed by
+// another marketplace operator instance than this one.
+var fieldManagerConflicts = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "marketplace_field_manager_conflicts_total",
+		Help: "Number of times a default CatalogSource was found managed by another marketplace operator instance, by name.",
+	},
+	[]string{"name"},
+)
+
+// catalogSourceCanaries counts the canaries of the default CatalogSources per
+// result.
+var catalogSourceCanaries = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "marketplace_catalogsource_canaries_total",
+		Help: "Number of canaries of the default CatalogSource image upgrades that succeeded or failed, by name and result.",
+	},
+	[]string{"name", "result"},
+)
+
+// catalogRestarts counts the restarts of the catalog pods of the default
+// CatalogSources that were serving an outdated image digest.
+var catalogRestarts = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "marketplace_catalog_restarts_total",
+		Help: "Number of times the catalog pod of a default CatalogSource was restarted to serve its pinned image digest, by name.",
+	},
+	[]string{"name"},
+)
+
+// defaultCatalogSourceReady reports whether the registry of each enabled
+// default CatalogSource is reachable.
+var defaultCatalogSourceReady = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "marketplace_default_catalogsource_ready",
+		Help: "Whether the connection to the registry of the default CatalogSource is ready (1) or not (0), by name.",
+	},
+	[]string{"name"},
+)
+
+// defaultCatalogSourceReloadFailures counts the reloads of the default
+// CatalogSource definitions that failed, keeping the previous definitions.
+var defaultCatalogSourceReloadFailures = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "marketplace_default_catalogsource_reload_failures_total",
+		Help: "Number of times the default CatalogSource definitions changed on disk could not be reloaded, the previous definitions being kept.",
+	},
+)
+
+// IncDefaultCatalogSourceReloadFailures records that the default CatalogSource
+// definitions could not be reloaded.
+func IncDefaultCatalogSourceReloadFailures() {
+	defaultCatalogSourceReloadFailures.Inc()
+}
+
+// IncDefaultCatalogSourceRecreations records that the default CatalogSource
+// with the given name was recreated.
+func IncDefaultCatalogSourceRecreations(name string) {
+	defaultCatalogSourceRecreations.WithLabelValues(name).Inc()
+}
+
+// IncCatalogRestarts records that the catalog pod of the default CatalogSource
+// with the given name was restarted.
+func IncCatalogRestarts(name string) {
+	catalogRestarts.WithLabelValues(name).Inc()
+}
+
+// IncUnexpectedSpecMutations records that the spec of the default
+// CatalogSource with the given name was modified by someone other than
+// marketplace.
+func IncUnexpectedSpecMutations(name string) {
+	unexpectedSpecMutations.WithLabelValues(name).Inc()
+}
+
+// IncFieldManagerConflicts records that the default CatalogSource with the
+// given name was found managed by another marketplace operator instance.
+func IncFieldManagerConflicts(name string) {
+	fieldManagerConflicts.WithLabelValues(name).Inc()
+}
+
+// IncCatalogSourceCanaries records the result of a canary of the default
+// CatalogSource with the given name.
+func IncCatalogSourceCanaries(name, result string) {
+	catalogSourceCanaries.WithLabelValues(name, result).Inc()
+}
+
+// SetDefaultCatalogSourceReady records whether the default CatalogSource with
+// the given name is ready.
+func SetDefaultCatalogSourceReady(name string, ready bool) {
+	value := 0.0
+	if ready {
+		value = 1
+	}
+	defaultCatalogSourceReady.WithLabelValues(name).Set(value)
+}
+
+// DeleteDefaultCatalogSourceReady stops reporting the readiness of the default
+// CatalogSource with the given name, once it is disabled.
+func DeleteDefaultCatalogSourceReady(name string) {
+	defaultCatalogSourceReady.DeleteLabelValues(name)
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// runtimeMetricPrefixes are the prefixes of the metrics of the Go and process
+// collectors, which both the default and the controller-runtime registries
+// contain.
+var runtimeMetricPrefixes = []string{"go_", "process_"}
+
+// gatherer returns the Gatherer of the metrics served on the marketplace
+// metrics endpoint: the marketplace metrics registered in the default
+// registry and the controller-runtime metrics, such as the workqueue and
+// reconcile metrics. The Go and process metrics are only gathered once, from
+// the default registry, as gathering the same metric twice is an error.
+func gatherer() prometheus.Gatherer {
+	return prometheus.Gatherers{
+		prometheus.DefaultGatherer,
+		withoutRuntimeMetrics{ctrlmetrics.Registry},
+	}
+}
+
+// withoutRuntimeMetrics drops the Go and process metrics from the metrics
+// gathered by the Gatherer it wraps.
+type withoutRuntimeMetrics struct {
+	prometheus.Gatherer
+}
+
+// Gather implements prometheus.Gatherer.
+func (g withoutRuntimeMetrics) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	filtered := families[:0]
+	for _, family := range families {
+		if !isRuntimeMetric(family.GetName()) {
+			filtered = append(filtered, family)
+		}
+	}
+	return filtered, err
+}
+
+// isRuntimeMetric returns true if name is the name of a Go or process metric.
+func isRuntimeMetric(name string) bool {
+	for _, prefix := range runtimeMetricPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
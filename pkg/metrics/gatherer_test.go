@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/util/workqueue"
+	// The controller package sets controller-runtime as the provider of the
+	// workqueue metrics.
+	_ "sigs.k8s.io/controller-runtime/pkg/controller"
+)
+
+func TestMetricsHandlerServesControllerRuntimeMetrics(t *testing.T) {
+	require.NoError(t, RegisterMetrics())
+	RecordReconcileDuration("gatherer-test", time.Millisecond, nil)
+
+	// The workqueue metrics are provided by controller-runtime for every
+	// named queue.
+	queue := workqueue.NewTypedRateLimitingQueueWithConfig(
+		workqueue.DefaultTypedControllerRateLimiter[string](),
+		workqueue.TypedRateLimitingQueueConfig[string]{Name: "gatherer-test"},
+	)
+	defer queue.ShutDown()
+	queue.Add("item")
+
+	recorder := httptest.NewRecorder()
+	metricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, metricsPath, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+
+	for _, metric := range []string{
+		`workqueue_depth{controller="gatherer-test",name="gatherer-test"} 1`,
+		`marketplace_reconcile_duration_seconds_count{controller="gatherer-test"`,
+		"go_goroutines",
+	} {
+		assert.True(t, strings.Contains(string(body), metric), "metric %s is served", metric)
+	}
+}
+
+func TestGathererHasNoDuplicates(t *testing.T) {
+	require.NoError(t, RegisterMetrics())
+	families, err := gatherer().Gather()
+	require.NoError(t, err, "the Go and process metrics must only be gathered once")
+
+	names := map[string]int{}
+	for _, family := range families {
+		names[family.GetName()]++
+	}
+	for name, count := range names {
+		assert.Equal(t, 1, count, "metric %s", name)
+	}
+}
@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MetricsInfoPath is the path the documentation of the marketplace
+	// metrics is served at, on the metrics endpoint.
+	MetricsInfoPath = "/metrics-info"
+
+	// maxMetricExamples is the number of example values documented for each
+	// metric.
+	maxMetricExamples = 3
+)
+
+// descPattern matches the string representation of a prometheus.Desc, as it
+// does not expose its name, help and labels otherwise.
+var descPattern = regexp.MustCompile(`^Desc\{fqName: (".*"), help: (".*"), constLabels: \{(.*)\}, variableLabels: \{(.*)\}\}$`)
+
+// MetricInfo documents a marketplace metric.
+type MetricInfo struct {
+	Name string `json:"name"`
+	// Type is counter, gauge, histogram, summary or untyped.
+	Type   string   `json:"type"`
+	Help   string   `json:"help"`
+	Labels []string `json:"labels"`
+	// Examples are values of the metric currently exported, if any.
+	Examples []MetricExample `json:"examples"`
+}
+
+// MetricExample is a value of a metric.
+type MetricExample struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	// Value is the value of a counter or a gauge, or the number of
+	// observations of a histogram or a summary.
+	Value float64 `json:"value"`
+}
+
+// OperatorMetricsPage is an http.Handler documenting the marketplace metrics in
+// JSON, from the descriptions of their collectors, for writing dashboards
+// without reading the operator source.
+type OperatorMetricsPage struct {
+	collectors []prometheus.Collector
+}
+
+// NewOperatorMetricsPage returns an OperatorMetricsPage documenting the
+// metrics registered by RegisterMetrics.
+func NewOperatorMetricsPage() *OperatorMetricsPage {
+	return &OperatorMetricsPage{collectors: marketplaceCollectors()}
+}
+
+// ServeHTTP writes the MetricInfo of each metric, in the order of their names.
+func (p *OperatorMetricsPage) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	infos, err := p.metricInfos()
+	if err != nil {
+		logrus.Errorf("[metrics] Error documenting the marketplace metrics - %v", err)
+		http.Error(w, "failed to document the metrics", http.StatusInternalServerError)
+		return
+	}
+	body, err := json.MarshalIndent(infos, "", "  ")
+	if err != nil {
+		logrus.Errorf("[metrics] Error encoding the marketplace metrics documentation - %v", err)
+		http.Error(w, "failed to document the metrics", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// metricInfos returns the MetricInfo of each metric described by the
+// collectors, sorted by name.
+func (p *OperatorMetricsPage) metricInfos() ([]MetricInfo, error) {
+	var infos []MetricInfo
+	for _, collector := range p.collectors {
+		examples := collectExamples(collector)
+		for _, desc := range describe(collector) {
+			info, err := parseDesc(desc)
+			if err != nil {
+				return nil, err
+			}
+			info.Type = collectorType(collector)
+			for _, metric := range examples[desc] {
+				if len(info.Examples) == maxMetricExamples {
+					break
+				}
+				metricType, example := toExample(metric)
+				info.Type = metricType
+				info.Examples = append(info.Examples, example)
+			}
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// describe returns the descriptions of the metrics of collector.
+func describe(collector prometheus.Collector) []*prometheus.Desc {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		collector.Describe(ch)
+		close(ch)
+	}()
+	var descs []*prometheus.Desc
+	for desc := range ch {
+		descs = append(descs, desc)
+	}
+	return descs
+}
+
+// collectExamples returns the metrics currently exported by collector, keyed
+// by their description, as written by prometheus.Metric.Write and sorted by
+// their label values so that the same examples are documented every time.
+func collectExamples(collector prometheus.Collector) map[*prometheus.Desc][]*dto.Metric {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(ch)
+		close(ch)
+	}()
+	examples := make(map[*prometheus.Desc][]*dto.Metric)
+	for metric := range ch {
+		written := &dto.Metric{}
+		if err := metric.Write(written); err != nil {
+			continue
+		}
+		examples[metric.Desc()] = append(examples[metric.Desc()], written)
+	}
+	for _, metrics := range examples {
+		sort.Slice(metrics, func(i, j int) bool { return labelValues(metrics[i]) < labelValues(metrics[j]) })
+	}
+	return examples
+}
+
+// labelValues returns the label values of a written metric, in the order of
+// their names.
+func labelValues(metric *dto.Metric) string {
+	values := make([]string, 0, len(metric.GetLabel()))
+	for _, label := range metric.GetLabel() {
+		values = append(values, label.GetValue())
+	}
+	return strings.Join(values, "\xff")
+}
+
+// parseDesc returns the MetricInfo of the name, help and variable labels of
+// desc.
+func parseDesc(desc *prometheus.Desc) (MetricInfo, error) {
+	match := descPattern.FindStringSubmatch(desc.String())
+	if match == nil {
+		return MetricInfo{}, fmt.Errorf("invalid metric description %s", desc)
+	}
+	name, err := strconv.Unquote(match[1])
+	if err != nil {
+		return MetricInfo{}, fmt.Errorf("invalid name in metric description %s: %v", desc, err)
+	}
+	help, err := strconv.Unquote(match[2])
+	if err != nil {
+		return MetricInfo{}, fmt.Errorf("invalid help in metric description %s: %v", desc, err)
+	}
+	info := MetricInfo{Name: name, Help: help, Labels: []string{}, Examples: []MetricExample{}}
+	if match[4] != "" {
+		for _, label := range strings.Split(match[4], ",") {
+			// The constrained labels are written c(label).
+			info.Labels = append(info.Labels, strings.TrimSuffix(strings.TrimPrefix(label, "c("), ")"))
+		}
+	}
+	return info, nil
+}
+
+// typedCollector is a custom collector telling the type of its metrics.
+type typedCollector interface {
+	metricType() string
+}
+
+// collectorType returns the type of the metrics of collector, for the vectors
+// and the custom collectors whose type can not be told from the metrics they
+// export.
+func collectorType(collector prometheus.Collector) string {
+	switch c := collector.(type) {
+	case typedCollector:
+		return c.metricType()
+	case *prometheus.CounterVec:
+		return "counter"
+	case *prometheus.GaugeVec:
+		return "gauge"
+	case *prometheus.HistogramVec:
+		return "histogram"
+	case *prometheus.SummaryVec:
+		return "summary"
+	default:
+		return "untyped"
+	}
+}
+
+// toExample returns the type and the MetricExample of a written metric.
+func toExample(metric *dto.Metric) (string, MetricExample) {
+	example := MetricExample{}
+	if len(metric.GetLabel()) > 0 {
+		example.Labels = make(map[string]string, len(metric.GetLabel()))
+		for _, label := range metric.GetLabel() {
+			example.Labels[label.GetName()] = label.GetValue()
+		}
+	}
+	switch {
+	case metric.Counter != nil:
+		example.Value = metric.GetCounter().GetValue()
+		return "counter", example
+	case metric.Gauge != nil:
+		example.Value = metric.GetGauge().GetValue()
+		return "gauge", example
+	case metric.Histogram != nil:
+		example.Value = float64(metric.GetHistogram().GetSampleCount())
+		return "histogram", example
+	case metric.Summary != nil:
+		example.Value = float64(metric.GetSummary().GetSampleCount())
+		return "summary", example
+	default:
+		example.Value = metric.GetUntyped().GetValue()
+		return "untyped", example
+	}
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveMetricsInfo returns the MetricInfos served by page, keyed by name.
+func serveMetricsInfo(t *testing.T, page *OperatorMetricsPage) map[string]MetricInfo {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	page.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, MetricsInfoPath, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var infos []MetricInfo
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &infos))
+	byName := make(map[string]MetricInfo, len(infos))
+	for i, info := range infos {
+		if i > 0 {
+			assert.Less(t, infos[i-1].Name, info.Name, "the metrics are sorted by name")
+		}
+		byName[info.Name] = info
+	}
+	return byName
+}
+
+func TestOperatorMetricsPage(t *testing.T) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_changes_total", Help: "Number of \"changes\"."}, []string{"kind", "result"})
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_duration_seconds", Help: "Duration."}, []string{"controller"})
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_enabled", Help: "Whether it is enabled."})
+	page := &OperatorMetricsPage{collectors: []prometheus.Collector{counter, histogram, gauge}}
+
+	infos := serveMetricsInfo(t, page)
+	assert.Equal(t, MetricInfo{
+		Name:     "test_changes_total",
+		Type:     "counter",
+		Help:     `Number of "changes".`,
+		Labels:   []string{"kind", "result"},
+		Examples: []MetricExample{},
+	}, infos["test_changes_total"], "a vector without values is documented")
+	assert.Equal(t, MetricInfo{
+		Name:     "test_enabled",
+		Type:     "gauge",
+		Help:     "Whether it is enabled.",
+		Labels:   []string{},
+		Examples: []MetricExample{{Value: 0}},
+	}, infos["test_enabled"])
+
+	for _, kind := range []string{"a", "b", "c", "d"} {
+		counter.WithLabelValues(kind, "success").Add(2)
+	}
+	histogram.WithLabelValues("catalogsource").Observe(0.5)
+	histogram.WithLabelValues("catalogsource").Observe(1.5)
+	gauge.Set(1)
+
+	infos = serveMetricsInfo(t, page)
+	assert.Len(t, infos["test_changes_total"].Examples, maxMetricExamples)
+	assert.Equal(t, MetricExample{Labels: map[string]string{"kind": "a", "result": "success"}, Value: 2}, infos["test_changes_total"].Examples[0])
+	assert.Equal(t, "histogram", infos["test_duration_seconds"].Type)
+	assert.Equal(t, []MetricExample{{Labels: map[string]string{"controller": "catalogsource"}, Value: 2}}, infos["test_duration_seconds"].Examples,
+		"the example of a histogram is its number of observations")
+	assert.Equal(t, []MetricExample{{Value: 1}}, infos["test_enabled"].Examples)
+}
+
+func TestOperatorMetricsPageDocumentsMarketplaceMetrics(t *testing.T) {
+	SetRegistryTokenExpiry("registry.example.com", time.Now().Add(time.Hour))
+	t.Cleanup(func() {
+		registryTokenExpiry.mutex.Lock()
+		defer registryTokenExpiry.mutex.Unlock()
+		delete(registryTokenExpiry.expiries, "registry.example.com")
+	})
+
+	infos := serveMetricsInfo(t, NewOperatorMetricsPage())
+	for _, info := range infos {
+		assert.NotEmpty(t, info.Help, "metric %s is documented", info.Name)
+		assert.NotEqual(t, "untyped", info.Type, "metric %s is typed", info.Name)
+	}
+	assert.Equal(t, []string{"controller"}, infos["marketplace_reconcile_latency_p99_seconds"].Labels)
+	tokenExpiry := infos["marketplace_registry_token_expiry_seconds"]
+	assert.Equal(t, "gauge", tokenExpiry.Type)
+	assert.Equal(t, []string{"registry"}, tokenExpiry.Labels)
+	require.Len(t, tokenExpiry.Examples, 1)
+	assert.Equal(t, map[string]string{"registry": "registry.example.com"}, tokenExpiry.Examples[0].Labels)
+}
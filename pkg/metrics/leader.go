@@ -0,0 +1,34 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// leaderElectionStatus reports whether the marketplace replica with the given
+// identity currently holds the leader lock.
+var leaderElectionStatus = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "marketplace_leader_election_status",
+		Help: "Whether the marketplace replica is the leader (1) or not (0), by identity.",
+	},
+	[]string{"identity"},
+)
+
+// leaderElectionMasterStatus reports the same as leaderElectionStatus under
+// the name used by the dashboards of other OpenShift operators.
+var leaderElectionMasterStatus = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "marketplace_leader_election_master_status",
+		Help: "Whether the marketplace replica is the leader (1) or not (0), by identity.",
+	},
+	[]string{"identity"},
+)
+
+// SetLeaderStatus records whether the replica with the given identity holds
+// the leader lock.
+func SetLeaderStatus(identity string, isLeader bool) {
+	value := 0.0
+	if isLeader {
+		value = 1
+	}
+	leaderElectionStatus.WithLabelValues(identity).Set(value)
+	leaderElectionMasterStatus.WithLabelValues(identity).Set(value)
+}
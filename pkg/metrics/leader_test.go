@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// leaderStatus returns the value of the given leader election gauge for the
+// given identity from the registry the metrics are served from.
+func leaderStatus(t *testing.T, name, identity string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "identity" && label.GetValue() == identity {
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	t.Fatalf("no leader election status found for identity %s", identity)
+	return 0
+}
+
+func TestSetLeaderStatus(t *testing.T) {
+	require.NoError(t, RegisterMetrics())
+
+	for _, name := range []string{"marketplace_leader_election_status", "marketplace_leader_election_master_status"} {
+		t.Run(name, func(t *testing.T) {
+			// The replica starts as a follower, becomes the leader and
+			// then loses the lock.
+			SetLeaderStatus("marketplace-operator-a", false)
+			SetLeaderStatus("marketplace-operator-b", false)
+			assert.Equal(t, 0.0, leaderStatus(t, name, "marketplace-operator-a"))
+
+			SetLeaderStatus("marketplace-operator-a", true)
+			assert.Equal(t, 1.0, leaderStatus(t, name, "marketplace-operator-a"))
+			assert.Equal(t, 0.0, leaderStatus(t, name, "marketplace-operator-b"))
+
+			SetLeaderStatus("marketplace-operator-a", false)
+			assert.Equal(t, 0.0, leaderStatus(t, name, "marketplace-operator-a"))
+		})
+	}
+}
@@ -1,12 +1,14 @@
 package metrics
 
 import (
-	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
 
-	"github.com/operator-framework/operator-marketplace/pkg/filemonitor"
-
+	"github.com/operator-framework/operator-marketplace/pkg/httpserver"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 )
@@ -20,68 +22,211 @@ const (
 
 	// metricsTLSPort is the port that marketplace exposes its metrics over https.
 	metricsTLSPort = 8081
+
+	// maxMetricsPort is the highest port the metrics can be served on.
+	maxMetricsPort = 65535
+)
+
+var (
+	// registerOnce ensures the marketplace metrics are only registered once.
+	registerOnce sync.Once
+
+	// registerErr is the result of registering the marketplace metrics.
+	registerErr error
+
+	// handlersOnce ensures the metrics handlers are only registered on
+	// serveMux once, as registering the same pattern twice panics.
+	handlersOnce sync.Once
+
+	// serveMux is the mux the metrics endpoints are served on. The
+	// http.DefaultServeMux is not used, as net/http/pprof registers its
+	// handlers on it.
+	serveMux = http.NewServeMux()
 )
 
-// ServePrometheus enables marketplace to serve prometheus metrics.
-func ServePrometheus(cert, key string) error {
+// ServeOptions configures the marketplace metrics endpoint.
+type ServeOptions struct {
+	// CertPath and KeyPath are the paths of the serving certificate and key.
+	// The metrics are served over https if both are set.
+	CertPath string
+	KeyPath  string
+	// AuthToken, if not empty, is the bearer token scrapes are required to
+	// present.
+	AuthToken string
+	// Addr is the host:port pair the metrics are served on, where a zero port
+	// selects the default port for http or https. If Addr is empty the
+	// metrics listener is disabled.
+	Addr string
+	// TLSMinVersion is the minimum TLS version accepted over https.
+	TLSMinVersion uint16
+	// TLSCipherSuites are the cipher suites accepted over https, up to TLS
+	// 1.2. If empty the Go recommended cipher suites are used.
+	TLSCipherSuites []uint16
+	// ClientCAPath, if not empty, is the path of the CA bundle the client
+	// certificates must be signed by. It requires https.
+	ClientCAPath string
+}
+
+// ServePrometheus registers the marketplace metrics and binds the listener
+// they are served on, over https if a certificate is provided. The metrics
+// are served once Serve is called on the returned Server, which is nil if the
+// listener is disabled. It is safe to call more than once, the handlers are
+// registered with the AuthToken of the first call.
+func ServePrometheus(o ServeOptions) (*httpserver.Server, error) {
+	tlsEnabled := useTLS(o.CertPath, o.KeyPath)
+	listenAddr, err := metricsListenAddr(o.Addr, tlsEnabled)
+	if err != nil {
+		return nil, err
+	}
+	if o.ClientCAPath != "" && !tlsEnabled {
+		return nil, fmt.Errorf("client certificate authentication requires both --tls-key and --tls-cert")
+	}
+
 	// Register metrics for the operator with the prometheus.
 	logrus.Info("[metrics] Registering marketplace metrics")
 
-	err := registerMetrics()
+	err = RegisterMetrics()
 	if err != nil {
 		logrus.Infof("[metrics] Unable to register marketplace metrics: %v", err)
-		return err
+		return nil, err
 	}
 
-	// Start the server and expose the registered metrics.
-	logrus.Info("[metrics] Serving marketplace metrics")
-	http.Handle(metricsPath, promhttp.Handler())
+	if o.AuthToken != "" {
+		logrus.Info("[metrics] Bearer token authentication enabled for metrics")
+	}
+	handlersOnce.Do(func() {
+		serveMux.Handle(metricsPath, withAuth(metricsHandler(), o.AuthToken))
+		serveMux.Handle(MetricsInfoPath, withAuth(NewOperatorMetricsPage(), o.AuthToken))
+	})
+
+	if listenAddr == "" {
+		logrus.Info("[metrics] Metrics address is empty, the metrics listener is disabled")
+		return nil, nil
+	}
 
-	if useTLS(cert, key) {
-		tlsGetCertFn, err := filemonitor.OLMGetCertRotationFn(logrus.New(), cert, key)
+	server := httpserver.New(listenAddr, serveMux)
+	if tlsEnabled {
+		// The certificate is reloaded when it is rotated on disk.
+		reloader, err := newCertificateReloader(o.CertPath, o.KeyPath)
 		if err != nil {
-			logrus.Errorf("Certificate monitoring for metrics (https) failed: %v", err)
-			return err
+			logrus.Errorf("Certificate loading for metrics (https) failed: %v", err)
+			return nil, err
 		}
-
-		go func() {
-			httpsServer := &http.Server{
-				Addr:    fmt.Sprintf(":%d", metricsTLSPort),
-				Handler: nil,
-				TLSConfig: &tls.Config{
-					GetCertificate: tlsGetCertFn,
-				},
-			}
-			err := httpsServer.ListenAndServeTLS("", "")
-			if err != nil {
-				if err == http.ErrServerClosed {
-					logrus.Errorf("Metrics (https) server closed")
-					return
-				}
-				logrus.Errorf("Metrics (https) serving failed: %v", err)
+		var clientCAs *clientCAReloader
+		if o.ClientCAPath != "" {
+			if clientCAs, err = newClientCAReloader(o.ClientCAPath); err != nil {
+				logrus.Errorf("Client CA loading for metrics (https) failed: %v", err)
+				return nil, err
 			}
-		}()
-		return nil
+			logrus.Info("[metrics] Client certificate authentication enabled for metrics")
+		}
+		server.TLSConfig = o.tlsConfig(reloader, clientCAs)
 	}
 
-	go func() {
-		err := http.ListenAndServe(fmt.Sprintf(":%d", metricsPort), nil)
-		if err != nil {
-			if err == http.ErrServerClosed {
-				logrus.Errorf("Metrics (http) server closed")
+	listener, err := httpserver.Listen(server)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen on the metrics address %s: %v", listenAddr, err)
+	}
+	logrus.Infof("[metrics] Serving marketplace metrics on %s", listenAddr)
+	return listener, nil
+}
+
+// metricsHandler returns the handler serving the metrics of gatherer(),
+// instrumented like promhttp.Handler.
+func metricsHandler() http.Handler {
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(gatherer(), promhttp.HandlerOpts{}))
+}
+
+// metricsListenAddr validates the host:port pair the metrics are served on
+// and returns the address to listen on. A zero port is replaced with the
+// default port for http or https, and an empty addr is returned as is.
+func metricsListenAddr(addr string, tlsEnabled bool) (string, error) {
+	if addr == "" {
+		return "", nil
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid metrics address %q: %v", addr, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid metrics address %q: port must be a number", addr)
+	}
+	if port == 0 {
+		port = metricsPort
+		if tlsEnabled {
+			port = metricsTLSPort
+		}
+	}
+	if port < 1 || port > maxMetricsPort {
+		return "", fmt.Errorf("invalid metrics port %d, must be between 1 and %d", port, maxMetricsPort)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// ServeAggregated exposes the metrics of all the marketplace replicas, as
+// gathered by the given Aggregator, on the marketplace metrics endpoint. If
+// authToken is not empty, scrapes are required to present it as a bearer
+// token.
+func ServeAggregated(aggregator *Aggregator, authToken string) {
+	serveMux.Handle(AggregatedMetricsPath, withAuth(aggregator, authToken))
+}
+
+// withAuth wraps handler with NewAuthMiddleware if authToken is not empty.
+func withAuth(handler http.Handler, authToken string) http.Handler {
+	if authToken == "" {
+		return handler
+	}
+	return NewAuthMiddleware(authToken, handler)
+}
+
+// RegisterMetrics registers marketplace prometheus metrics. It is safe to
+// call more than once, the metrics are only registered on the first call while
+// the build info is recorded on every call.
+func RegisterMetrics() error {
+	registerOnce.Do(func() {
+		// Register all of the metrics in the standard registry.
+		for _, collector := range marketplaceCollectors() {
+			if registerErr = prometheus.Register(collector); registerErr != nil {
 				return
 			}
-			logrus.Errorf("Metrics (http) serving failed: %v", err)
 		}
-	}()
-
-	return nil
+	})
+	if registerErr == nil {
+		recordBuildInfo()
+	}
+	return registerErr
 }
 
-// registerMetrics registers marketplace prometheus metrics.
-func registerMetrics() error {
-	// Register all of the metrics in the standard registry.
-	return nil
+// marketplaceCollectors returns the collectors of the marketplace metrics.
+func marketplaceCollectors() []prometheus.Collector {
+	collectors := []prometheus.Collector{
+		reconcileDuration,
+		reconcileErrors,
+		defaultCatalogSourceCount,
+		defaultCatalogSourcePopulation,
+		defaultCatalogSourceRecreations,
+		defaultCatalogSourceReloadFailures,
+		unexpectedSpecMutations,
+		fieldManagerConflicts,
+		catalogSourceCanaries,
+		catalogRestarts,
+		registryTokenExpiry,
+		defaultCatalogSourceReady,
+		operatorHubSourceDisabled,
+		operatorHubDisableAllDefaultSources,
+		leaderElectionStatus,
+		leaderElectionMasterStatus,
+		clusterOperatorStatusUpdateFailures,
+		clusterOperatorLastSuccessfulUpdate,
+		buildInfo,
+	}
+	for _, gauge := range reconcileLatencyPercentiles {
+		collectors = append(collectors, gauge)
+	}
+	return collectors
 }
 
 func useTLS(certPath, keyPath string) bool {
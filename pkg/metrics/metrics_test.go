@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServePrometheusInvalidPort(t *testing.T) {
+	for _, addr := range []string{":-1", ":65536", "localhost", ":metrics"} {
+		// An invalid address is rejected before the listener is started.
+		_, err := ServePrometheus(ServeOptions{Addr: addr})
+		assert.Error(t, err, "address %s", addr)
+	}
+}
+
+func TestServePrometheusAddressInUse(t *testing.T) {
+	bound, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer bound.Close()
+
+	server, err := ServePrometheus(ServeOptions{Addr: bound.Addr().String()})
+	assert.Error(t, err)
+	assert.Nil(t, server)
+}
+
+func TestServePrometheusTwice(t *testing.T) {
+	// The handlers are only registered once, a second call does not panic
+	// on the duplicate patterns and serves the same handlers.
+	for i := 0; i < 2; i++ {
+		free, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := free.Addr().String()
+		require.NoError(t, free.Close())
+
+		server, err := ServePrometheus(ServeOptions{Addr: addr})
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() { served <- server.Serve(ctx) }()
+
+		resp, err := http.Get("http://" + addr + metricsPath)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		cancel()
+		require.NoError(t, <-served)
+	}
+}
+
+func TestMetricsListenAddr(t *testing.T) {
+	tests := []struct {
+		addr       string
+		tlsEnabled bool
+		expected   string
+	}{
+		{addr: ":0", expected: ":8383"},
+		{addr: ":0", tlsEnabled: true, expected: ":8081"},
+		{addr: "127.0.0.1:9090", expected: "127.0.0.1:9090"},
+		{addr: ":443", tlsEnabled: true, expected: ":443"},
+		{addr: ":1", expected: ":1"},
+		{addr: ":65535", expected: ":65535"},
+		{addr: "[::]:8383", expected: "[::]:8383"},
+		{addr: "[::1]:0", tlsEnabled: true, expected: "[::1]:8081"},
+		{addr: "", expected: ""},
+	}
+	for _, tt := range tests {
+		listenAddr, err := metricsListenAddr(tt.addr, tt.tlsEnabled)
+		require.NoError(t, err, "address %s", tt.addr)
+		assert.Equal(t, tt.expected, listenAddr, "address %s", tt.addr)
+	}
+}
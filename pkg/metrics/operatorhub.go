@@ -0,0 +1,41 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// operatorHubSourceDisabled reports whether each source of the OperatorHub
+// configuration is disabled.
+var operatorHubSourceDisabled = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "marketplace_operatorhub_source_disabled",
+		Help: "Whether the source is disabled (1) or not (0) by the OperatorHub configuration, by name.",
+	},
+	[]string{"name"},
+)
+
+// operatorHubDisableAllDefaultSources reports the disableAllDefaultSources
+// field of the OperatorHub configuration.
+var operatorHubDisableAllDefaultSources = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "marketplace_operatorhub_disable_all_default_sources",
+		Help: "Whether the OperatorHub configuration disables all the default sources (1) or not (0).",
+	},
+)
+
+// SetOperatorHubConfig records the OperatorHub configuration, where disabled
+// tells whether each source is disabled. The sources missing from disabled
+// are no longer reported.
+func SetOperatorHubConfig(disableAllDefaultSources bool, disabled map[string]bool) {
+	operatorHubDisableAllDefaultSources.Set(boolToFloat(disableAllDefaultSources))
+	operatorHubSourceDisabled.Reset()
+	for name, isDisabled := range disabled {
+		operatorHubSourceDisabled.WithLabelValues(name).Set(boolToFloat(isDisabled))
+	}
+}
+
+// boolToFloat returns 1 if b is true and 0 otherwise.
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultLatencyPercentileUpdateInterval is the default interval at which the
+// reconcile latency percentiles are computed.
+const DefaultLatencyPercentileUpdateInterval = time.Minute
+
+// reconcileLatencyPercentiles are the percentiles of the reconcile duration
+// exported as gauges, keyed by their quantile.
+var reconcileLatencyPercentiles = map[float64]*prometheus.GaugeVec{
+	0.50: newLatencyPercentileGaugeVec("p50"),
+	0.95: newLatencyPercentileGaugeVec("p95"),
+	0.99: newLatencyPercentileGaugeVec("p99"),
+}
+
+func newLatencyPercentileGaugeVec(percentile string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "marketplace_reconcile_latency_" + percentile + "_seconds",
+			Help: "The " + percentile + " of the time taken by the marketplace controllers to reconcile an object, estimated from marketplace_reconcile_duration_seconds.",
+		},
+		[]string{"controller"},
+	)
+}
+
+// LatencyPercentileGauge periodically exports the P50, P95 and P99 of the
+// reconcile duration histogram as gauges, for the monitoring systems that
+// display gauges more easily than histogram queries.
+type LatencyPercentileGauge struct {
+	interval time.Duration
+}
+
+// NewLatencyPercentileGauge returns a LatencyPercentileGauge updating the
+// percentiles every interval.
+func NewLatencyPercentileGauge(interval time.Duration) *LatencyPercentileGauge {
+	return &LatencyPercentileGauge{interval: interval}
+}
+
+// Start updates the percentiles every interval until ctx is done.
+func (g *LatencyPercentileGauge) Start(ctx context.Context) error {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			g.update()
+		}
+	}
+}
+
+// update computes the percentiles of the reconcile duration of each
+// controller, across the results of the reconciles.
+func (g *LatencyPercentileGauge) update() {
+	histograms, err := reconcileHistograms()
+	if err != nil {
+		logrus.Warnf("[metrics] Unable to compute the reconcile latency percentiles: %v", err)
+		return
+	}
+	for controller, buckets := range histograms {
+		for quantile, gauge := range reconcileLatencyPercentiles {
+			if value := bucketQuantile(quantile, buckets); !math.IsNaN(value) {
+				gauge.WithLabelValues(controller).Set(value)
+			}
+		}
+	}
+}
+
+// bucket is a cumulative bucket of a histogram.
+type bucket struct {
+	upperBound float64
+	count      float64
+}
+
+// reconcileHistograms returns the cumulative buckets of the reconcile duration
+// histogram of each controller, summed across the results and sorted by upper
+// bound. The +Inf bucket is included.
+func reconcileHistograms() (map[string][]bucket, error) {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		reconcileDuration.Collect(ch)
+		close(ch)
+	}()
+
+	counts := make(map[string]map[float64]float64)
+	var err error
+	for metric := range ch {
+		m := &dto.Metric{}
+		if writeErr := metric.Write(m); writeErr != nil {
+			err = writeErr
+			continue
+		}
+		var controller string
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "controller" {
+				controller = label.GetValue()
+			}
+		}
+		if counts[controller] == nil {
+			counts[controller] = make(map[float64]float64)
+		}
+		histogram := m.GetHistogram()
+		for _, b := range histogram.GetBucket() {
+			counts[controller][b.GetUpperBound()] += float64(b.GetCumulativeCount())
+		}
+		counts[controller][math.Inf(1)] += float64(histogram.GetSampleCount())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	histograms := make(map[string][]bucket, len(counts))
+	for controller, byUpperBound := range counts {
+		buckets := make([]bucket, 0, len(byUpperBound))
+		for upperBound, count := range byUpperBound {
+			buckets = append(buckets, bucket{upperBound: upperBound, count: count})
+		}
+		sort.Slice(buckets, func(i, j int) bool { return buckets[i].upperBound < buckets[j].upperBound })
+		histograms[controller] = buckets
+	}
+	return histograms, nil
+}
+
+// bucketQuantile estimates the quantile q from the cumulative buckets, sorted
+// by upper bound and ending with the +Inf bucket, by linear interpolation
+// within the bucket the quantile falls in. It follows the histogram_quantile
+// function of Prometheus: a quantile falling in the +Inf bucket is the upper
+// bound of the last finite bucket and NaN is returned without observations.
+func bucketQuantile(q float64, buckets []bucket) float64 {
+	if len(buckets) < 2 || !math.IsInf(buckets[len(buckets)-1].upperBound, 1) {
+		return math.NaN()
+	}
+	total := buckets[len(buckets)-1].count
+	if total == 0 {
+		return math.NaN()
+	}
+
+	rank := q * total
+	b := sort.Search(len(buckets)-1, func(i int) bool { return buckets[i].count >= rank })
+	if b == len(buckets)-1 {
+		return buckets[len(buckets)-2].upperBound
+	}
+	if b == 0 && buckets[0].upperBound <= 0 {
+		return buckets[0].upperBound
+	}
+
+	bucketStart, bucketEnd, count := 0.0, buckets[b].upperBound, buckets[b].count
+	if b > 0 {
+		bucketStart = buckets[b-1].upperBound
+		count -= buckets[b-1].count
+		rank -= buckets[b-1].count
+	}
+	return bucketStart + (bucketEnd-bucketStart)*(rank/count)
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// percentile returns the value of the percentile gauge of the given quantile
+// for the given controller.
+func percentile(t *testing.T, quantile float64, controller string) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	require.NoError(t, reconcileLatencyPercentiles[quantile].WithLabelValues(controller).Write(m))
+	return m.GetGauge().GetValue()
+}
+
+func TestBucketQuantile(t *testing.T) {
+	buckets := []bucket{
+		{upperBound: 0.1, count: 50},
+		{upperBound: 0.5, count: 90},
+		{upperBound: 1, count: 100},
+		{upperBound: math.Inf(1), count: 100},
+	}
+	assert.InDelta(t, 0.1, bucketQuantile(0.5, buckets), 1e-9)
+	assert.InDelta(t, 0.5+0.5*0.5, bucketQuantile(0.95, buckets), 1e-9)
+	assert.InDelta(t, 0.5+0.5*0.9, bucketQuantile(0.99, buckets), 1e-9)
+	assert.InDelta(t, 0.04, bucketQuantile(0.2, buckets), 1e-9, "the first bucket starts at zero")
+
+	buckets[3].count = 200
+	assert.Equal(t, 1.0, bucketQuantile(0.99, buckets), "a quantile in the +Inf bucket is the last finite upper bound")
+
+	assert.True(t, math.IsNaN(bucketQuantile(0.5, []bucket{{upperBound: 1}, {upperBound: math.Inf(1)}})), "there is no quantile without observations")
+	assert.True(t, math.IsNaN(bucketQuantile(0.5, []bucket{{upperBound: 1, count: 1}})))
+}
+
+func TestLatencyPercentileGauge(t *testing.T) {
+	require.NoError(t, RegisterMetrics())
+	// Half the reconciles take 10ms and half 2s, across the results, so
+	// that the P50 falls in the (0.005, 0.01] bucket and the P95 and P99 in
+	// the (1, 2.5] bucket.
+	for i := 0; i < 50; i++ {
+		RecordReconcileDuration("percentile-test", 10*time.Millisecond, nil)
+		RecordReconcileDuration("percentile-test", 2*time.Second, assert.AnError)
+	}
+
+	NewLatencyPercentileGauge(time.Minute).update()
+	assert.InDelta(t, 0.01, percentile(t, 0.50, "percentile-test"), 1e-9)
+	assert.InDelta(t, 1+1.5*0.9, percentile(t, 0.95, "percentile-test"), 1e-9)
+	assert.InDelta(t, 1+1.5*0.98, percentile(t, 0.99, "percentile-test"), 1e-9)
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// resultSuccess is the result label value of a reconcile that succeeded.
+	resultSuccess = "success"
+
+	// resultError is the result label value of a reconcile that failed.
+	resultError = "error"
+
+	// resultRequeue is the result label value of a reconcile that succeeded
+	// but asked for the object to be reconciled again.
+	resultRequeue = "requeue"
+)
+
+// reconcileDurationBuckets are the buckets of the reconcile duration
+// histogram, from 1ms to 30s.
+var reconcileDurationBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// reconcileDuration observes how long each reconcile of the marketplace
+// controllers takes.
+var reconcileDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "marketplace_reconcile_duration_seconds",
+		Help:    "Time taken by the marketplace controllers to reconcile an object.",
+		Buckets: reconcileDurationBuckets,
+	},
+	[]string{"controller", "result"},
+)
+
+// reconcileErrors counts the reconciles of the marketplace controllers that
+// returned an error.
+var reconcileErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "marketplace_reconcile_errors_total",
+		Help: "Number of reconciles of the marketplace controllers that returned an error.",
+	},
+	[]string{"controller"},
+)
+
+// RecordReconcileDuration records the duration of a reconcile performed by the
+// given controller. The reconcile is labeled as an error if err is not nil.
+func RecordReconcileDuration(controllerName string, duration time.Duration, err error) {
+	RecordReconcile(controllerName, duration, reconcile.Result{}, err)
+}
+
+// RecordReconcile records the duration and the outcome of a reconcile
+// performed by the given controller. The reconcile is labeled as an error if
+// err is not nil, and as a requeue if result asks for the object to be
+// reconciled again.
+func RecordReconcile(controllerName string, duration time.Duration, result reconcile.Result, err error) {
+	label := resultSuccess
+	switch {
+	case err != nil:
+		label = resultError
+		reconcileErrors.WithLabelValues(controllerName).Inc()
+	case result.Requeue || result.RequeueAfter > 0:
+		label = resultRequeue
+	}
+	reconcileDuration.WithLabelValues(controllerName, label).Observe(duration.Seconds())
+}
+
+// NewInstrumentedReconciler returns a reconcile.Reconciler that records the
+// duration and the outcome of every reconcile performed by r. Each reconcile
+// is given a context carrying a logger tagged with the controller name, the
+// request and a reconcile ID, retrieved with logging.FromContext.
+func NewInstrumentedReconciler(controllerName string, r reconcile.Reconciler) reconcile.Reconciler {
+	return &instrumentedReconciler{
+		controllerName: controllerName,
+		reconciler:     r,
+	}
+}
+
+type instrumentedReconciler struct {
+	controllerName string
+	reconciler     reconcile.Reconciler
+}
+
+// Reconcile calls the wrapped reconciler and records how long it took and
+// whether it succeeded, failed or requeued the object.
+func (i *instrumentedReconciler) Reconcile(ctx context.Context, request reconcile.Request) (result reconcile.Result, err error) {
+	ctx = logging.NewReconcileContext(ctx, i.controllerName, request.NamespacedName)
+	start := time.Now()
+	defer func() {
+		RecordReconcile(i.controllerName, time.Since(start), result, err)
+	}()
+	return i.reconciler.Reconcile(ctx, request)
+}
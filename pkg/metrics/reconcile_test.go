@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/operator-framework/operator-marketplace/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// fakeReconciler returns the given result and error on every reconcile.
+type fakeReconciler struct {
+	result reconcile.Result
+	err    error
+}
+
+func (f *fakeReconciler) Reconcile(context.Context, reconcile.Request) (reconcile.Result, error) {
+	return f.result, f.err
+}
+
+// histogram returns the reconcile duration histogram of the given controller
+// and result.
+func histogram(t *testing.T, controller, result string) *dto.Histogram {
+	t.Helper()
+	m := &dto.Metric{}
+	observer, err := reconcileDuration.GetMetricWithLabelValues(controller, result)
+	require.NoError(t, err)
+	require.NoError(t, observer.(prometheus.Metric).Write(m))
+	return m.GetHistogram()
+}
+
+// observations returns the number of reconciles observed for the given
+// controller and result.
+func observations(t *testing.T, controller, result string) uint64 {
+	t.Helper()
+	return histogram(t, controller, result).GetSampleCount()
+}
+
+func reconcileErrorCount(t *testing.T, controller string) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	require.NoError(t, reconcileErrors.WithLabelValues(controller).Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func TestInstrumentedReconciler(t *testing.T) {
+	const controller = "instrumented-test"
+	fake := &fakeReconciler{}
+	r := NewInstrumentedReconciler(controller, fake)
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), observations(t, controller, resultSuccess))
+
+	fake.result = reconcile.Result{RequeueAfter: time.Second}
+	result, err := r.Reconcile(context.TODO(), reconcile.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, result.RequeueAfter, "the result of the wrapped reconciler is returned")
+	fake.result = reconcile.Result{Requeue: true}
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), observations(t, controller, resultRequeue))
+
+	fake.result, fake.err = reconcile.Result{}, errors.New("conflict")
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{})
+	assert.EqualError(t, err, "conflict")
+	assert.Equal(t, uint64(1), observations(t, controller, resultError))
+	assert.Equal(t, 1.0, reconcileErrorCount(t, controller))
+
+	assert.Equal(t, uint64(1), observations(t, controller, resultSuccess))
+}
+
+func TestReconcileDurationBuckets(t *testing.T) {
+	assert.Equal(t, 0.001, reconcileDurationBuckets[0])
+	assert.Equal(t, 30.0, reconcileDurationBuckets[len(reconcileDurationBuckets)-1])
+
+	RecordReconcileDuration("buckets-test", 20*time.Second, nil)
+	buckets := histogram(t, "buckets-test", resultSuccess).GetBucket()
+	require.Len(t, buckets, len(reconcileDurationBuckets))
+	assert.Equal(t, uint64(0), buckets[len(buckets)-2].GetCumulativeCount(), "a 20s reconcile is above the 10s bucket")
+	assert.Equal(t, uint64(1), buckets[len(buckets)-1].GetCumulativeCount(), "a 20s reconcile is within the 30s bucket")
+}
+
+// loggingReconciler logs a line through the logger of the reconcile context,
+// once every reconciler has started.
+type loggingReconciler struct {
+	started *sync.WaitGroup
+}
+
+func (l *loggingReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	l.started.Done()
+	l.started.Wait()
+	logging.FromContext(ctx).Infof("[defaults] Ensuring CatalogSource %s", request.Name)
+	return reconcile.Result{}, nil
+}
+
+func TestInstrumentedReconcilerContextualLogging(t *testing.T) {
+	hook := logtest.NewGlobal()
+	t.Cleanup(func() { hook.Reset() })
+
+	started := &sync.WaitGroup{}
+	started.Add(2)
+	r := NewInstrumentedReconciler("operatorhub-controller", &loggingReconciler{started: started})
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "cluster"}}
+	var done sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			_, err := r.Reconcile(context.TODO(), request)
+			assert.NoError(t, err)
+		}()
+	}
+	done.Wait()
+
+	entries := hook.AllEntries()
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.Equal(t, "operatorhub-controller", entry.Data[logging.ControllerKey])
+		assert.Equal(t, "cluster", entry.Data[logging.RequestKey])
+		assert.NotEmpty(t, entry.Data[logging.ReconcileIDKey])
+	}
+	assert.NotEqual(t, entries[0].Data[logging.ReconcileIDKey], entries[1].Data[logging.ReconcileIDKey],
+		"concurrent reconciles are given distinct IDs")
+}
+
+func TestRecordReconcileDuration(t *testing.T) {
+	const controller = "record-duration-test"
+
+	RecordReconcileDuration(controller, 20*time.Millisecond, nil)
+	assert.EqualValues(t, 1, observations(t, controller, resultSuccess))
+	assert.InDelta(t, 0.02, histogram(t, controller, resultSuccess).GetSampleSum(), 1e-9)
+	assert.EqualValues(t, 0, observations(t, controller, resultError))
+
+	RecordReconcileDuration(controller, time.Second, errors.New("failed"))
+	assert.EqualValues(t, 1, observations(t, controller, resultSuccess))
+	assert.EqualValues(t, 1, observations(t, controller, resultError))
+	assert.InDelta(t, 1, histogram(t, controller, resultError).GetSampleSum(), 1e-9)
+	assert.Equal(t, float64(1), reconcileErrorCount(t, controller))
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// registryTokenExpiryCollector reports the time remaining before the OAuth
+// token of each registry expires, computed when the metrics are scraped.
+type registryTokenExpiryCollector struct {
+	desc *prometheus.Desc
+	now  func() time.Time
+
+	mutex    sync.Mutex
+	expiries map[string]time.Time
+}
+
+// registryTokenExpiry holds the expiry of the OAuth token of each registry.
+var registryTokenExpiry = &registryTokenExpiryCollector{
+	desc: prometheus.NewDesc(
+		"marketplace_registry_token_expiry_seconds",
+		"Seconds remaining before the OAuth token of the registry expires, negative once it expired.",
+		[]string{"registry"}, nil,
+	),
+	now:      time.Now,
+	expiries: make(map[string]time.Time),
+}
+
+// Describe implements prometheus.Collector.
+func (c *registryTokenExpiryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector.
+func (c *registryTokenExpiryCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := c.now()
+	for registry, expiry := range c.expiries {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, expiry.Sub(now).Seconds(), registry)
+	}
+}
+
+// metricType implements typedCollector.
+func (c *registryTokenExpiryCollector) metricType() string {
+	return "gauge"
+}
+
+// SetRegistryTokenExpiry records the expiry of the OAuth token of the given
+// registry.
+func SetRegistryTokenExpiry(registry string, expiry time.Time) {
+	registryTokenExpiry.mutex.Lock()
+	defer registryTokenExpiry.mutex.Unlock()
+	registryTokenExpiry.expiries[registry] = expiry
+}
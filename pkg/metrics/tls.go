@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultTLSMinVersion is the name of the minimum TLS version accepted by the
+// metrics endpoint by default.
+const DefaultTLSMinVersion = "VersionTLS12"
+
+// tlsVersions maps the TLS version names used by the OpenShift components to
+// their value.
+var tlsVersions = map[string]uint16{
+	"VersionTLS10": tls.VersionTLS10,
+	"VersionTLS11": tls.VersionTLS11,
+	"VersionTLS12": tls.VersionTLS12,
+	"VersionTLS13": tls.VersionTLS13,
+}
+
+// TLSVersion returns the TLS version with the given name, e.g. VersionTLS12.
+func TLSVersion(name string) (uint16, error) {
+	if version, ok := tlsVersions[name]; ok {
+		return version, nil
+	}
+	var names []string
+	for name := range tlsVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return 0, fmt.Errorf("unsupported TLS version %q, supported versions are %s", name, strings.Join(names, ", "))
+}
+
+// CipherSuites returns the cipher suites with the given IANA names, e.g.
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, which are the names used by the
+// OpenShift components. The cipher suites Go considers insecure are rejected.
+// An empty list selects the Go recommended cipher suites, and is returned as
+// nil.
+func CipherSuites(names []string) ([]uint16, error) {
+	supported := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		supported[suite.Name] = suite.ID
+	}
+
+	var suites []uint16
+	for _, name := range names {
+		id, ok := supported[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %q, supported cipher suites are %s",
+				name, strings.Join(supportedCipherSuiteNames(), ", "))
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// supportedCipherSuiteNames returns the sorted names of the cipher suites
+// accepted by CipherSuites.
+func supportedCipherSuiteNames() []string {
+	var names []string
+	for _, suite := range tls.CipherSuites() {
+		names = append(names, suite.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tlsConfig returns the configuration of the https metrics server, serving the
+// certificate of reloader. If clientCAs is not nil, clients are required to
+// present a certificate signed by one of its CAs.
+func (o ServeOptions) tlsConfig(reloader *certificateReloader, clientCAs *clientCAReloader) *tls.Config {
+	config := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     o.TLSMinVersion,
+		CipherSuites:   o.TLSCipherSuites,
+	}
+	if clientCAs == nil {
+		return config
+	}
+
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	config.ClientCAs = clientCAs.ClientCAs()
+	// The CA bundle is looked up on every handshake so that its rotation is
+	// picked up by the new connections.
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		handshakeConfig := config.Clone()
+		handshakeConfig.GetConfigForClient = nil
+		handshakeConfig.ClientCAs = clientCAs.ClientCAs()
+		return handshakeConfig, nil
+	}
+	return config
+}
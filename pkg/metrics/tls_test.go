@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSVersion(t *testing.T) {
+	version, err := TLSVersion(DefaultTLSMinVersion)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), version)
+
+	version, err = TLSVersion("VersionTLS13")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), version)
+
+	_, err = TLSVersion("TLSv1.2")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "VersionTLS10, VersionTLS11, VersionTLS12, VersionTLS13")
+}
+
+func TestCipherSuites(t *testing.T) {
+	suites, err := CipherSuites(nil)
+	require.NoError(t, err)
+	assert.Nil(t, suites, "no cipher suites selects the Go recommended ones")
+
+	suites, err = CipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", " TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384}, suites)
+
+	for _, name := range []string{"ECDHE-RSA-AES128-GCM-SHA256", "TLS_RSA_WITH_RC4_128_SHA", ""} {
+		_, err = CipherSuites([]string{name})
+		require.Error(t, err, "cipher suite %q", name)
+		assert.Contains(t, err.Error(), "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256")
+	}
+}
+
+func TestTLSConfigHandshakes(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeKeyPair(t, certPath, keyPath, "metrics", time.Now())
+	reloader, err := newCertificateReloader(certPath, keyPath)
+	require.NoError(t, err)
+
+	handshake := func(addr string, client *tls.Config) error {
+		client.InsecureSkipVerify = true
+		conn, err := tls.Dial("tcp", addr, client)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	addr := listenTLS(t, ServeOptions{TLSMinVersion: tls.VersionTLS12}.tlsConfig(reloader, nil))
+	assert.Error(t, handshake(addr, &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11}), "handshakes below the minimum version are refused")
+	assert.NoError(t, handshake(addr, &tls.Config{MaxVersion: tls.VersionTLS12}))
+	assert.NoError(t, handshake(addr, &tls.Config{MinVersion: tls.VersionTLS13}))
+
+	addr = listenTLS(t, ServeOptions{
+		TLSMinVersion:   tls.VersionTLS12,
+		TLSCipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+	}.tlsConfig(reloader, nil))
+	assert.Error(t, handshake(addr, &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	}), "handshakes with a cipher suite that is not allowed are refused")
+	assert.NoError(t, handshake(addr, &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+	}))
+}
@@ -5,6 +5,7 @@ import (
 
 	configv1 "github.com/openshift/api/config/v1"
 	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
 	"github.com/sirupsen/logrus"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -36,6 +37,8 @@ func (h *confighandler) Handle(ctx context.Context, in *configv1.OperatorHub) er
 	current := GetSingleton()
 	current.Set(in.Spec)
 	currentConfig := current.Get()
+	// The sources removed from the spec are reported in their default state.
+	metrics.SetOperatorHubConfig(in.Spec.DisableAllDefaultSources, currentConfig)
 
 	// Apply the configuration to the default CatalogSources
 	catsrcDefinitions := defaults.GetGlobalCatalogSourceDefinitions()
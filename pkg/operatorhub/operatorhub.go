@@ -24,7 +24,9 @@ func init() {
 // operatorhub implements OperatorHub
 type operatorhub struct {
 	current map[string]bool
-	lock    sync.Mutex
+	// spec is the spec the current configuration was last set from.
+	spec configv1.OperatorHubSpec
+	lock sync.Mutex
 }
 
 // OperatorHub is the interface to interact with the OperatorHub configuration in
@@ -32,6 +34,7 @@ type operatorhub struct {
 type OperatorHub interface {
 	Get() map[string]bool
 	Set(spec configv1.OperatorHubSpec)
+	Refresh()
 	Disabled() bool
 }
 
@@ -67,6 +70,21 @@ func (o *operatorhub) Disabled() bool {
 func (o *operatorhub) Set(spec configv1.OperatorHubSpec) {
 	o.lock.Lock()
 	defer o.lock.Unlock()
+	o.set(spec)
+}
+
+// Refresh sets the current configuration again from the spec it was last set
+// from, so that it covers the defaults as they were reloaded since.
+func (o *operatorhub) Refresh() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.set(o.spec)
+}
+
+// set sets the current configuration based on the spec, see Set. The lock
+// must be held.
+func (o *operatorhub) set(spec configv1.OperatorHubSpec) {
+	o.spec = *spec.DeepCopy()
 
 	// Reset to the defaults. If DisableAllDefaultSources, mark all defaults
 	// as disabled.
@@ -0,0 +1,149 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	mktconfig "github.com/operator-framework/operator-marketplace/pkg/apis/config/v1"
+	apiutils "github.com/operator-framework/operator-marketplace/pkg/apis/operators/shared"
+	"github.com/sirupsen/logrus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// permission is a set of verbs the operator needs on a resource.
+type permission struct {
+	group    string
+	resource string
+	// namespaced is true if the verbs are only needed in the operator
+	// namespace rather than cluster wide.
+	namespaced bool
+	verbs      []string
+}
+
+// catalogSourcePermissions are the verbs the operator needs on CatalogSources.
+// They are watched in all namespaces to recreate the default CatalogSources,
+// which only live in the operator namespace.
+var catalogSourcePermissions = []permission{
+	{group: olmv1alpha1.GroupName, resource: "catalogsources", verbs: []string{"get", "list", "watch"}},
+	{group: olmv1alpha1.GroupName, resource: "catalogsources", namespaced: true, verbs: []string{"create", "update", "patch", "delete"}},
+}
+
+// resourceLister lists the resources served by the apiserver. It is
+// implemented by discovery.DiscoveryInterface.
+type resourceLister interface {
+	ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error)
+}
+
+// accessReviewer reviews the access of the current user. It is implemented by
+// the SelfSubjectAccessReviews client.
+type accessReviewer interface {
+	Create(ctx context.Context, review *authorizationv1.SelfSubjectAccessReview, opts metav1.CreateOptions) (*authorizationv1.SelfSubjectAccessReview, error)
+}
+
+// RunChecks verifies that the cluster provides what the operator needs to
+// start: the APIs of the types it manages, the Lease API used for leader
+// election and the permissions of its service account on CatalogSources. The
+// returned error identifies the missing resource or permission.
+func RunChecks(cfg *rest.Config, scheme *runtime.Scheme) error {
+	namespace, err := apiutils.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	return runChecks(context.TODO(), clientset.Discovery(), clientset.AuthorizationV1().SelfSubjectAccessReviews(), scheme, namespace)
+}
+
+func runChecks(ctx context.Context, lister resourceLister, reviewer accessReviewer, scheme *runtime.Scheme, namespace string) error {
+	required := []client.Object{&olmv1alpha1.CatalogSource{}}
+	if mktconfig.IsAPIAvailable() {
+		required = append(required, &configv1.OperatorHub{}, &configv1.ClusterOperator{})
+	}
+	for _, obj := range required {
+		gvk, err := apiutil.GVKForObject(obj, scheme)
+		if err != nil {
+			return fmt.Errorf("preflight: %v", err)
+		}
+		if err := checkKind(lister, gvk); err != nil {
+			return fmt.Errorf("preflight: %v, is its CustomResourceDefinition installed?", err)
+		}
+	}
+
+	if err := checkKind(lister, coordinationv1.SchemeGroupVersion.WithKind("Lease")); err != nil {
+		return fmt.Errorf("preflight: %v, it is required for leader election", err)
+	}
+
+	// The permissions are checked in each of the watched namespaces
+	for _, ns := range strings.Split(namespace, ",") {
+		for _, p := range catalogSourcePermissions {
+			if err := checkPermission(ctx, reviewer, p, ns); err != nil {
+				return fmt.Errorf("preflight: %v", err)
+			}
+		}
+	}
+	logrus.Info("[preflight] All pre-flight checks passed")
+	return nil
+}
+
+// checkKind returns an error if the apiserver does not serve the given kind.
+func checkKind(lister resourceLister, gvk schema.GroupVersionKind) error {
+	resources, err := lister.ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+	if err != nil {
+		return fmt.Errorf("the %s API is not available: %v", gvk.GroupVersion(), err)
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Kind == gvk.Kind {
+			return nil
+		}
+	}
+	return fmt.Errorf("the %s kind is not served by the %s API", gvk.Kind, gvk.GroupVersion())
+}
+
+// checkPermission returns an error listing the verbs of p that are not
+// allowed.
+func checkPermission(ctx context.Context, reviewer accessReviewer, p permission, namespace string) error {
+	scope := "cluster wide"
+	if !p.namespaced {
+		namespace = ""
+	} else {
+		scope = "in namespace " + namespace
+	}
+
+	var denied []string
+	for _, verb := range p.verbs {
+		review, err := reviewer.Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: namespace,
+					Verb:      verb,
+					Group:     p.group,
+					Resource:  p.resource,
+				},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to review the access to %s.%s: %v", p.resource, p.group, err)
+		}
+		if !review.Status.Allowed {
+			denied = append(denied, verb)
+		}
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("the service account is not allowed to %s %s.%s %s",
+			strings.Join(denied, ", "), p.resource, p.group, scope)
+	}
+	return nil
+}
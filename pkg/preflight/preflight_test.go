@@ -0,0 +1,121 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// fakeLister serves the kinds of each group version.
+type fakeLister map[string][]string
+
+func (f fakeLister) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	kinds, ok := f[groupVersion]
+	if !ok {
+		return nil, errors.New("the server could not find the requested resource")
+	}
+	list := &metav1.APIResourceList{GroupVersion: groupVersion}
+	for _, kind := range kinds {
+		list.APIResources = append(list.APIResources, metav1.APIResource{Kind: kind})
+	}
+	return list, nil
+}
+
+// fakeReviewer denies the verbs it holds, in the namespace they map to.
+type fakeReviewer map[string]string
+
+func (f fakeReviewer) Create(_ context.Context, review *authorizationv1.SelfSubjectAccessReview, _ metav1.CreateOptions) (*authorizationv1.SelfSubjectAccessReview, error) {
+	attributes := review.Spec.ResourceAttributes
+	namespace, denied := f[attributes.Verb]
+	review.Status.Allowed = !denied || namespace != attributes.Namespace
+	return review, nil
+}
+
+func TestRunChecks(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	available := fakeLister{
+		"operators.coreos.com/v1alpha1": {"CatalogSource", "Subscription"},
+		"coordination.k8s.io/v1":        {"Lease"},
+	}
+
+	tests := []struct {
+		description string
+		lister      fakeLister
+		reviewer    fakeReviewer
+		scheme      *runtime.Scheme
+		namespace   string
+		expected    string
+	}{
+		{
+			description: "everything is available",
+			lister:      available,
+		},
+		{
+			description: "the CatalogSource CRD is missing",
+			lister:      fakeLister{"coordination.k8s.io/v1": {"Lease"}},
+			expected:    "preflight: the operators.coreos.com/v1alpha1 API is not available: the server could not find the requested resource, is its CustomResourceDefinition installed?",
+		},
+		{
+			description: "the CatalogSource kind is not served",
+			lister:      fakeLister{"operators.coreos.com/v1alpha1": {"Subscription"}, "coordination.k8s.io/v1": {"Lease"}},
+			expected:    "preflight: the CatalogSource kind is not served by the operators.coreos.com/v1alpha1 API, is its CustomResourceDefinition installed?",
+		},
+		{
+			description: "the Lease API is missing",
+			lister:      fakeLister{"operators.coreos.com/v1alpha1": {"CatalogSource"}},
+			expected:    "preflight: the coordination.k8s.io/v1 API is not available: the server could not find the requested resource, it is required for leader election",
+		},
+		{
+			description: "CatalogSources can not be watched",
+			lister:      available,
+			reviewer:    fakeReviewer{"watch": ""},
+			expected:    "preflight: the service account is not allowed to watch catalogsources.operators.coreos.com cluster wide",
+		},
+		{
+			description: "CatalogSources can not be created nor deleted",
+			lister:      available,
+			reviewer:    fakeReviewer{"create": "openshift-marketplace", "delete": "openshift-marketplace"},
+			expected:    "preflight: the service account is not allowed to create, delete catalogsources.operators.coreos.com in namespace openshift-marketplace",
+		},
+		{
+			description: "CatalogSources can not be created in a selected namespace",
+			lister:      available,
+			reviewer:    fakeReviewer{"create": "tenant-a"},
+			namespace:   "openshift-marketplace,tenant-a",
+			expected:    "preflight: the service account is not allowed to create catalogsources.operators.coreos.com in namespace tenant-a",
+		},
+		{
+			description: "the type is not registered",
+			lister:      available,
+			scheme:      runtime.NewScheme(),
+			expected:    "preflight: no kind is registered for the type v1alpha1.CatalogSource",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			s := scheme
+			if tt.scheme != nil {
+				s = tt.scheme
+			}
+			namespace := "openshift-marketplace"
+			if tt.namespace != "" {
+				namespace = tt.namespace
+			}
+			err := runChecks(context.TODO(), tt.lister, tt.reviewer, s, namespace)
+			if tt.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
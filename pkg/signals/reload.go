@@ -0,0 +1,72 @@
+package signals
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogLevelEnv is the environment variable the log level is read from when a
+// SIGHUP is received.
+const LogLevelEnv = "MARKETPLACE_LOG_LEVEL"
+
+var (
+	reloadSignals = []os.Signal{syscall.SIGHUP}
+	reloadOnce    sync.Once
+	reloadLock    sync.Mutex
+	reloadHooks   []func()
+)
+
+// OnReload registers hook to be called whenever a SIGHUP is received, after
+// the log level of the standard logger has been reloaded from
+// MARKETPLACE_LOG_LEVEL. The first call starts handling SIGHUP, which would
+// otherwise terminate the process.
+func OnReload(hook func()) {
+	reloadLock.Lock()
+	reloadHooks = append(reloadHooks, hook)
+	reloadLock.Unlock()
+
+	reloadOnce.Do(func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, reloadSignals...)
+		go func() {
+			for range c {
+				logrus.Info("received the reload signal")
+				reload()
+			}
+		}()
+	})
+}
+
+// reload reloads the log level and calls the registered hooks.
+func reload() {
+	reloadLogLevel()
+
+	reloadLock.Lock()
+	hooks := append([]func(){}, reloadHooks...)
+	reloadLock.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// reloadLogLevel sets the level of the standard logger to the one in
+// MARKETPLACE_LOG_LEVEL. The level is left unchanged if it is not set or is
+// invalid.
+func reloadLogLevel() {
+	value := os.Getenv(LogLevelEnv)
+	if value == "" {
+		logrus.Infof("%s is not set, keeping log level %s", LogLevelEnv, logrus.GetLevel())
+		return
+	}
+	level, err := logrus.ParseLevel(value)
+	if err != nil {
+		logrus.Errorf("invalid %s, keeping log level %s: %v", LogLevelEnv, logrus.GetLevel(), err)
+		return
+	}
+	logrus.SetLevel(level)
+	logrus.Infof("log level set to %s", level)
+}
@@ -0,0 +1,44 @@
+//go:build !windows
+
+package signals
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadLogLevel(t *testing.T) {
+	previous := logrus.GetLevel()
+	t.Cleanup(func() { logrus.SetLevel(previous) })
+	logrus.SetLevel(logrus.InfoLevel)
+
+	reloaded := make(chan logrus.Level, 1)
+	OnReload(func() {
+		reloaded <- logrus.GetLevel()
+	})
+
+	t.Setenv(LogLevelEnv, "debug")
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	select {
+	case level := <-reloaded:
+		assert.Equal(t, logrus.DebugLevel, level, "the level is reloaded before the hooks are called")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the reload hook")
+	}
+
+	t.Setenv(LogLevelEnv, "verbose")
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	select {
+	case level := <-reloaded:
+		assert.Equal(t, logrus.DebugLevel, level, "an invalid level is ignored")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the reload hook")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -40,3 +41,22 @@ func Context() context.Context {
 
 	return signalCtx
 }
+
+// ShutdownContext returns a Context that is cancelled timeout after ctx is
+// done. It bounds the time given to the work stopped by ctx to return. The
+// returned CancelFunc releases the resources of the Context.
+func ShutdownContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	shutdown, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-shutdown.Done():
+			return
+		}
+		deadline, cancelDeadline := context.WithTimeout(shutdown, timeout)
+		defer cancelDeadline()
+		<-deadline.Done()
+		cancel()
+	}()
+	return shutdown, cancel
+}
@@ -0,0 +1,41 @@
+package signals
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownContext(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	timeout := 100 * time.Millisecond
+	shutdown, cancel := ShutdownContext(parent, timeout)
+	defer cancel()
+
+	select {
+	case <-shutdown.Done():
+		t.Fatal("the context was cancelled before the parent was done")
+	case <-time.After(2 * timeout):
+	}
+
+	cancelParent()
+	cancelledAt := time.Now()
+	select {
+	case <-shutdown.Done():
+		assert.GreaterOrEqual(t, time.Since(cancelledAt), timeout, "the context is only cancelled once the timeout elapsed")
+	case <-time.After(10 * timeout):
+		t.Fatal("the context was not cancelled within the timeout")
+	}
+}
+
+func TestShutdownContextCancel(t *testing.T) {
+	shutdown, cancel := ShutdownContext(context.Background(), time.Hour)
+	cancel()
+	select {
+	case <-shutdown.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the context was not cancelled")
+	}
+}
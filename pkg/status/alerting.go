@@ -0,0 +1,282 @@
+package status
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	configclient "github.com/openshift/client-go/config/clientset/versioned/typed/config/v1"
+	cohelpers "github.com/openshift/library-go/pkg/config/clusteroperator/v1helpers"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultAlertAfter is the default time the ClusterOperator has to be
+	// Degraded for before an alert is sent.
+	DefaultAlertAfter = 5 * time.Minute
+
+	// WebhookFormatSlack and WebhookFormatPagerDuty are the supported alert
+	// webhook formats, a Slack incoming webhook and the PagerDuty Events API
+	// v2.
+	WebhookFormatSlack     = "slack"
+	WebhookFormatPagerDuty = "pagerduty"
+
+	// alertCheckInterval is the interval at which the Degraded condition of
+	// the ClusterOperator is checked.
+	alertCheckInterval = coStatusReportInterval
+
+	// alertTimeout is the time given to the webhook to accept an alert.
+	alertTimeout = 10 * time.Second
+)
+
+// Alert is sent when the ClusterOperator has been Degraded for longer than the
+// alerting delay, and again once it recovers.
+type Alert struct {
+	// ClusterOperator is the name of the ClusterOperator.
+	ClusterOperator string
+	// Resolved is true if the ClusterOperator is no longer Degraded.
+	Resolved bool
+	// Since is when the ClusterOperator became Degraded.
+	Since time.Time
+	// Reason and Message are the ones of the Degraded condition.
+	Reason  string
+	Message string
+	// DedupKey identifies the Degraded period, the alert sent on recovery
+	// has the same key as the one sent when the period started.
+	DedupKey string
+}
+
+// summary returns a one-line description of the alert.
+func (a Alert) summary() string {
+	if a.Resolved {
+		return fmt.Sprintf("ClusterOperator %s is no longer Degraded, it was Degraded since %s", a.ClusterOperator, a.Since.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("ClusterOperator %s has been Degraded since %s: %s - %s", a.ClusterOperator, a.Since.UTC().Format(time.RFC3339), a.Reason, a.Message)
+}
+
+// AlertSender sends an Alert.
+type AlertSender interface {
+	Send(ctx context.Context, alert Alert) error
+}
+
+// NewWebhookSender returns an AlertSender posting the alerts to url in the
+// given format. routingKey is the integration key of a PagerDuty service, it
+// is only used by the pagerduty format.
+func NewWebhookSender(format, url, routingKey string) (AlertSender, error) {
+	if url == "" {
+		return nil, errors.New("the alert webhook URL is required")
+	}
+	client := &http.Client{Timeout: alertTimeout}
+	switch format {
+	case WebhookFormatSlack:
+		return &slackSender{url: url, client: client}, nil
+	case WebhookFormatPagerDuty:
+		if routingKey == "" {
+			return nil, errors.New("the PagerDuty routing key is required")
+		}
+		return &pagerDutySender{url: url, routingKey: routingKey, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported alert webhook format %q, must be %s or %s", format, WebhookFormatSlack, WebhookFormatPagerDuty)
+	}
+}
+
+// slackSender posts the alerts to a Slack incoming webhook.
+type slackSender struct {
+	url    string
+	client *http.Client
+}
+
+// Send implements AlertSender.
+func (s *slackSender) Send(ctx context.Context, alert Alert) error {
+	return postJSON(ctx, s.client, s.url, map[string]string{"text": alert.summary()})
+}
+
+// pagerDutySender sends the alerts to the PagerDuty Events API v2, as the
+// trigger and resolve events of an incident keyed by the DedupKey.
+type pagerDutySender struct {
+	url        string
+	routingKey string
+	client     *http.Client
+}
+
+// pagerDutyEvent is an event of the PagerDuty Events API v2.
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary  string `json:"summary"`
+	Source   string `json:"source"`
+	Severity string `json:"severity"`
+}
+
+// Send implements AlertSender.
+func (s *pagerDutySender) Send(ctx context.Context, alert Alert) error {
+	event := pagerDutyEvent{RoutingKey: s.routingKey, EventAction: "resolve", DedupKey: alert.DedupKey}
+	if !alert.Resolved {
+		event.EventAction = "trigger"
+		event.Payload = &pagerDutyPayload{Summary: alert.summary(), Source: alert.ClusterOperator, Severity: "critical"}
+	}
+	return postJSON(ctx, s.client, s.url, event)
+}
+
+// postJSON posts body, encoded in JSON, to url and returns an error if the
+// response is not a success.
+func postJSON(ctx context.Context, client *http.Client, url string, body interface{}) error {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("the alert webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// AlertingReporter wraps a Reporter and sends an alert when the
+// ClusterOperator stays Degraded for longer than its alerting delay. A single
+// alert is sent per Degraded period, followed by another once the
+// ClusterOperator recovers.
+type AlertingReporter struct {
+	Reporter
+	configClient        configclient.ClusterOperatorsGetter
+	clusterOperatorName string
+	alertAfter          time.Duration
+	sender              AlertSender
+	stopCh              <-chan struct{}
+	clock               clock.WithTicker
+
+	// degraded is the Degraded period an alert was sent for, nil if no alert
+	// needs to be resolved.
+	degraded *Alert
+}
+
+// NewAlertingReporter returns an AlertingReporter wrapping reporter, which
+// sends its alerts through sender once the ClusterOperator with the given name
+// has been Degraded for alertAfter. The alerts are no longer checked once
+// stopCh is closed.
+func NewAlertingReporter(cfg *rest.Config, reporter Reporter, name string, alertAfter time.Duration, sender AlertSender, stopCh <-chan struct{}) (*AlertingReporter, error) {
+	configClient, err := configclient.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config v1 client: %s", err.Error())
+	}
+	return newAlertingReporter(configClient, reporter, name, alertAfter, sender, stopCh), nil
+}
+
+func newAlertingReporter(configClient configclient.ClusterOperatorsGetter, reporter Reporter, name string, alertAfter time.Duration, sender AlertSender, stopCh <-chan struct{}) *AlertingReporter {
+	return &AlertingReporter{
+		Reporter:            reporter,
+		configClient:        configClient,
+		clusterOperatorName: name,
+		alertAfter:          alertAfter,
+		sender:              sender,
+		stopCh:              stopCh,
+		clock:               clock.RealClock{},
+	}
+}
+
+// StartReporting starts the wrapped Reporter and checks the Degraded
+// condition of the ClusterOperator until stopCh is closed. The channel
+// returned is closed once both have stopped.
+func (a *AlertingReporter) StartReporting() <-chan struct{} {
+	reporting := a.Reporter.StartReporting()
+	done := make(chan struct{})
+	ticker := a.clock.NewTicker(alertCheckInterval)
+	go func() {
+		defer close(done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.stopCh:
+				<-reporting
+				return
+			case <-ticker.C():
+				a.check(context.TODO())
+			}
+		}
+	}()
+	return done
+}
+
+// check sends an alert if the ClusterOperator has been Degraded for longer
+// than the alerting delay and no alert was sent for the Degraded period yet,
+// or resolves the alert sent if the ClusterOperator is no longer Degraded. An
+// alert that fails to be sent is sent again on the next check.
+func (a *AlertingReporter) check(ctx context.Context) {
+	clusterOperator, err := a.configClient.ClusterOperators().Get(ctx, a.clusterOperatorName, metav1.GetOptions{})
+	if err != nil {
+		log.Errorf("[status] Error getting ClusterOperator %s to check whether it is Degraded - %v", a.clusterOperatorName, err)
+		return
+	}
+
+	condition := cohelpers.FindStatusCondition(clusterOperator.Status.Conditions, configv1.OperatorDegraded)
+	if condition == nil || condition.Status != configv1.ConditionTrue {
+		a.resolve(ctx)
+		return
+	}
+
+	since := condition.LastTransitionTime.Time
+	if a.degraded != nil {
+		if a.degraded.Since.Equal(since) {
+			return
+		}
+		// The ClusterOperator recovered and became Degraded again between
+		// two checks.
+		if !a.resolve(ctx) {
+			return
+		}
+	}
+	if a.clock.Since(since) < a.alertAfter {
+		return
+	}
+	alert := Alert{
+		ClusterOperator: a.clusterOperatorName,
+		Since:           since,
+		Reason:          condition.Reason,
+		Message:         condition.Message,
+		DedupKey:        fmt.Sprintf("%s-degraded-%d", a.clusterOperatorName, since.Unix()),
+	}
+	if err := a.sender.Send(ctx, alert); err != nil {
+		log.Errorf("[status] Error sending the Degraded alert of ClusterOperator %s - %v", a.clusterOperatorName, err)
+		return
+	}
+	log.Warnf("[status] Sent the Degraded alert of ClusterOperator %s", a.clusterOperatorName)
+	a.degraded = &alert
+}
+
+// resolve resolves the alert sent, if any, and returns false if it failed to.
+func (a *AlertingReporter) resolve(ctx context.Context) bool {
+	if a.degraded == nil {
+		return true
+	}
+	resolved := *a.degraded
+	resolved.Resolved = true
+	if err := a.sender.Send(ctx, resolved); err != nil {
+		log.Errorf("[status] Error resolving the Degraded alert of ClusterOperator %s - %v", a.clusterOperatorName, err)
+		return false
+	}
+	log.Infof("[status] Resolved the Degraded alert of ClusterOperator %s", a.clusterOperatorName)
+	a.degraded = nil
+	return true
+}
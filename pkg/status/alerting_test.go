@@ -0,0 +1,206 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// recordingSender records the alerts sent, and fails to send them while err
+// is not nil.
+type recordingSender struct {
+	mu     sync.Mutex
+	alerts []Alert
+	err    error
+}
+
+func (r *recordingSender) Send(_ context.Context, alert Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+// sent returns the number of alerts sent.
+func (r *recordingSender) sent() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.alerts)
+}
+
+func degradedClusterOperator(status configv1.ConditionStatus, since time.Time) *configv1.ClusterOperator {
+	return &configv1.ClusterOperator{
+		ObjectMeta: metav1.ObjectMeta{Name: "marketplace"},
+		Status: configv1.ClusterOperatorStatus{Conditions: []configv1.ClusterOperatorStatusCondition{{
+			Type:               configv1.OperatorDegraded,
+			Status:             status,
+			Reason:             "CatalogSourcesUnavailable",
+			Message:            "redhat-operators is not ready",
+			LastTransitionTime: metav1.NewTime(since),
+		}}},
+	}
+}
+
+func TestAlertingReporterCheck(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-time.Minute)
+	fake := &fakeClusterOperators{clusterOperator: degradedClusterOperator(configv1.ConditionTrue, since)}
+	sender := &recordingSender{}
+	fakeClock := clocktesting.NewFakeClock(now)
+	a := newAlertingReporter(fake, &NoOpReporter{}, "marketplace", DefaultAlertAfter, sender, nil)
+	a.clock = fakeClock
+
+	// No alert is sent before the alerting delay.
+	a.check(context.TODO())
+	assert.Empty(t, sender.alerts)
+
+	// An alert that fails to be sent is sent on the next check.
+	now = since.Add(DefaultAlertAfter)
+	fakeClock.SetTime(now)
+	sender.err = errors.New("unavailable")
+	a.check(context.TODO())
+	assert.Empty(t, sender.alerts)
+	sender.err = nil
+	a.check(context.TODO())
+	require.Len(t, sender.alerts, 1)
+	assert.False(t, sender.alerts[0].Resolved)
+	assert.Equal(t, since, sender.alerts[0].Since)
+	assert.Equal(t, "CatalogSourcesUnavailable", sender.alerts[0].Reason)
+
+	// A single alert is sent per Degraded period.
+	now = now.Add(time.Hour)
+	fakeClock.SetTime(now)
+	a.check(context.TODO())
+	assert.Len(t, sender.alerts, 1)
+
+	// The alert is resolved on recovery.
+	fake.clusterOperator = degradedClusterOperator(configv1.ConditionFalse, now)
+	a.check(context.TODO())
+	require.Len(t, sender.alerts, 2)
+	assert.True(t, sender.alerts[1].Resolved)
+	assert.Equal(t, sender.alerts[0].DedupKey, sender.alerts[1].DedupKey)
+	a.check(context.TODO())
+	assert.Len(t, sender.alerts, 2)
+
+	// A new Degraded period is alerted on again.
+	fake.clusterOperator = degradedClusterOperator(configv1.ConditionTrue, now)
+	now = now.Add(DefaultAlertAfter)
+	fakeClock.SetTime(now)
+	a.check(context.TODO())
+	require.Len(t, sender.alerts, 3)
+	assert.False(t, sender.alerts[2].Resolved)
+	assert.NotEqual(t, sender.alerts[0].DedupKey, sender.alerts[2].DedupKey)
+}
+
+func TestAlertingReporterResolvesPreviousPeriod(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fake := &fakeClusterOperators{clusterOperator: degradedClusterOperator(configv1.ConditionTrue, now.Add(-time.Hour))}
+	sender := &recordingSender{}
+	a := newAlertingReporter(fake, &NoOpReporter{}, "marketplace", DefaultAlertAfter, sender, nil)
+	a.clock = clocktesting.NewFakeClock(now)
+	a.check(context.TODO())
+	require.Len(t, sender.alerts, 1)
+
+	// The ClusterOperator recovered and became Degraded again between two
+	// checks.
+	fake.clusterOperator = degradedClusterOperator(configv1.ConditionTrue, now.Add(-time.Second))
+	a.check(context.TODO())
+	require.Len(t, sender.alerts, 2)
+	assert.True(t, sender.alerts[1].Resolved)
+	assert.Equal(t, sender.alerts[0].DedupKey, sender.alerts[1].DedupKey)
+}
+
+func TestAlertingReporterChecksEveryInterval(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fake := &fakeClusterOperators{clusterOperator: degradedClusterOperator(configv1.ConditionTrue, now)}
+	sender := &recordingSender{}
+	stopCh := make(chan struct{})
+	fakeClock := clocktesting.NewFakeClock(now)
+	wrapped := &countingReporter{done: make(chan struct{})}
+	a := newAlertingReporter(fake, wrapped, "marketplace", DefaultAlertAfter, sender, stopCh)
+	a.clock = fakeClock
+
+	done := a.StartReporting()
+	assert.EqualValues(t, 1, wrapped.starts.Load(), "the wrapped reporter is started")
+
+	// The ClusterOperator is checked at each interval, the alert is sent
+	// once it has been Degraded for the alerting delay.
+	fakeClock.Step(alertCheckInterval)
+	assert.Never(t, func() bool { return sender.sent() > 0 }, 100*time.Millisecond, 10*time.Millisecond,
+		"no alert is sent before the alerting delay")
+	fakeClock.Step(DefaultAlertAfter)
+	require.Eventually(t, func() bool { return sender.sent() == 1 }, time.Second, 10*time.Millisecond)
+
+	close(stopCh)
+	close(wrapped.done)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the reporter is not done once stopped")
+	}
+}
+
+func TestWebhookSenders(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	alert := Alert{
+		ClusterOperator: "marketplace",
+		Since:           time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Reason:          "CatalogSourcesUnavailable",
+		Message:         "redhat-operators is not ready",
+		DedupKey:        "marketplace-degraded-1714564800",
+	}
+
+	slack, err := NewWebhookSender(WebhookFormatSlack, server.URL, "")
+	require.NoError(t, err)
+	require.NoError(t, slack.Send(context.TODO(), alert))
+	assert.Equal(t, "ClusterOperator marketplace has been Degraded since 2024-05-01T12:00:00Z: CatalogSourcesUnavailable - redhat-operators is not ready", received["text"])
+
+	pagerDuty, err := NewWebhookSender(WebhookFormatPagerDuty, server.URL, "routing-key")
+	require.NoError(t, err)
+	require.NoError(t, pagerDuty.Send(context.TODO(), alert))
+	assert.Equal(t, "trigger", received["event_action"])
+	assert.Equal(t, "routing-key", received["routing_key"])
+	assert.Equal(t, alert.DedupKey, received["dedup_key"])
+	assert.Equal(t, "critical", received["payload"].(map[string]interface{})["severity"])
+
+	alert.Resolved = true
+	require.NoError(t, pagerDuty.Send(context.TODO(), alert))
+	assert.Equal(t, "resolve", received["event_action"])
+	assert.NotContains(t, received, "payload")
+
+	_, err = NewWebhookSender(WebhookFormatPagerDuty, server.URL, "")
+	assert.Error(t, err, "the routing key is required")
+	_, err = NewWebhookSender("email", server.URL, "")
+	assert.Error(t, err)
+}
+
+func TestWebhookSenderFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	slack, err := NewWebhookSender(WebhookFormatSlack, server.URL, "")
+	require.NoError(t, err)
+	assert.Error(t, slack.Send(context.TODO(), Alert{ClusterOperator: "marketplace"}))
+}
@@ -0,0 +1,71 @@
+package status
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MarketplaceStatusExtension is the status of the marketplace operator
+// reported in the Extension of the ClusterOperator status.
+type MarketplaceStatusExtension struct {
+	// ManagedCatalogSources is the number of CatalogSources of the operator
+	// namespace managed by the operator.
+	ManagedCatalogSources int `json:"managedCatalogSources"`
+	// FailingCatalogSources is the number of CatalogSources failing to sync.
+	FailingCatalogSources int `json:"failingCatalogSources"`
+}
+
+// ParseMarketplaceStatusExtension returns the MarketplaceStatusExtension
+// reported in the status of co, or nil if none is reported.
+func ParseMarketplaceStatusExtension(co *configv1.ClusterOperator) (*MarketplaceStatusExtension, error) {
+	if len(co.Status.Extension.Raw) == 0 {
+		return nil, nil
+	}
+	extension := &MarketplaceStatusExtension{}
+	if err := json.Unmarshal(co.Status.Extension.Raw, extension); err != nil {
+		return nil, fmt.Errorf("invalid status extension of ClusterOperator %s: %v", co.Name, err)
+	}
+	return extension, nil
+}
+
+// setStatusExtension sets the MarketplaceStatusExtension of the
+// ClusterOperator status. The managed CatalogSources are counted if rawClient
+// is set, the count previously reported is kept if they can not be listed.
+func (r *reporter) setStatusExtension() {
+	extension := MarketplaceStatusExtension{}
+	if previous, err := ParseMarketplaceStatusExtension(r.clusterOperator); err == nil && previous != nil {
+		extension.ManagedCatalogSources = previous.ManagedCatalogSources
+	}
+	if r.rawClient != nil {
+		managed := &olmv1alpha1.CatalogSourceList{}
+		err := r.rawClient.List(context.TODO(), managed, client.InNamespace(r.namespace), client.MatchingLabels{defaults.ManagedByLabelKey: defaults.ManagedByLabelValue})
+		if err != nil {
+			log.Errorf("[status] Error counting the managed CatalogSources - %v", err)
+		} else {
+			extension.ManagedCatalogSources = len(managed.Items)
+		}
+	}
+	extension.FailingCatalogSources = r.failureCount()
+
+	raw, err := json.Marshal(extension)
+	if err != nil {
+		log.Errorf("[status] Error encoding the ClusterOperator status extension - %v", err)
+		return
+	}
+	r.clusterOperator.Status.Extension = runtime.RawExtension{Raw: raw}
+}
+
+// extensionChanged returns true if the status extension of the
+// ClusterOperator differs from the one of previousStatus.
+func (r *reporter) extensionChanged(previousStatus *configv1.ClusterOperatorStatus) bool {
+	return !bytes.Equal(previousStatus.Extension.Raw, r.clusterOperator.Status.Extension.Raw)
+}
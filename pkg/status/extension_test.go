@@ -0,0 +1,66 @@
+package status
+
+import (
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseMarketplaceStatusExtension(t *testing.T) {
+	co := &configv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: "marketplace"}}
+	extension, err := ParseMarketplaceStatusExtension(co)
+	require.NoError(t, err)
+	assert.Nil(t, extension, "no extension is reported")
+
+	co.Status.Extension = runtime.RawExtension{Raw: []byte(`{"managedCatalogSources":4,"failingCatalogSources":1}`)}
+	extension, err = ParseMarketplaceStatusExtension(co)
+	require.NoError(t, err)
+	assert.Equal(t, &MarketplaceStatusExtension{ManagedCatalogSources: 4, FailingCatalogSources: 1}, extension)
+
+	co.Status.Extension = runtime.RawExtension{Raw: []byte(`[]`)}
+	_, err = ParseMarketplaceStatusExtension(co)
+	assert.ErrorContains(t, err, "invalid status extension of ClusterOperator marketplace")
+}
+
+func TestSetStatusReportsExtension(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	managed := func(namespace, name string) *olmv1alpha1.CatalogSource {
+		return &olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{defaults.ManagedByLabelKey: defaults.ManagedByLabelValue},
+		}}
+	}
+	rawClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		managed("openshift-marketplace", "redhat-operators"),
+		managed("openshift-marketplace", "community-operators"),
+		// Neither the CatalogSources of other namespaces nor the ones not
+		// managed by the operator are counted.
+		managed("tenant", "redhat-operators"),
+		&olmv1alpha1.CatalogSource{ObjectMeta: metav1.ObjectMeta{Name: "custom", Namespace: "openshift-marketplace"}},
+	).Build()
+
+	fake := &fakeClusterOperators{}
+	r := &reporter{configClient: fake, rawClient: rawClient, namespace: "openshift-marketplace", clusterOperatorName: "marketplace", version: "4.18.0"}
+	r.RegisterFailure("community-operators", "openshift-marketplace", "image pull failed")
+	require.NoError(t, r.setStatus(availableConditions("available")))
+
+	extension, err := ParseMarketplaceStatusExtension(fake.clusterOperator)
+	require.NoError(t, err)
+	assert.Equal(t, &MarketplaceStatusExtension{ManagedCatalogSources: 2, FailingCatalogSources: 1}, extension)
+
+	// The status is updated when only the extension changes.
+	r.ClearFailure("community-operators", "openshift-marketplace")
+	require.NoError(t, r.setStatus(availableConditions("available")))
+	extension, err = ParseMarketplaceStatusExtension(fake.clusterOperator)
+	require.NoError(t, err)
+	assert.Equal(t, &MarketplaceStatusExtension{ManagedCatalogSources: 2}, extension)
+}
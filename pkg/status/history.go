@@ -0,0 +1,152 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultConditionHistorySize is the number of transitions of each
+	// ClusterOperator condition kept by default.
+	DefaultConditionHistorySize = 10
+
+	// ConditionHistoryConfigMapName is the name of the ConfigMap, in the
+	// operator namespace, the condition history is persisted in so that it
+	// survives restarts.
+	ConditionHistoryConfigMapName = "marketplace-condition-history"
+
+	// conditionHistoryKey is the data key of the condition history in its
+	// ConfigMap.
+	conditionHistoryKey = "history.json"
+)
+
+// ConditionTransition is a transition of a ClusterOperator condition.
+type ConditionTransition struct {
+	Status  configv1.ConditionStatus `json:"status"`
+	Reason  string                   `json:"reason,omitempty"`
+	Message string                   `json:"message,omitempty"`
+	Time    metav1.Time              `json:"time"`
+}
+
+// ConditionHistory stores the latest transitions of each ClusterOperator
+// condition, up to its size, the oldest transition being dropped first. It is
+// safe for concurrent use.
+type ConditionHistory struct {
+	mu          sync.RWMutex
+	size        int
+	transitions map[configv1.ClusterStatusConditionType][]ConditionTransition
+}
+
+// NewConditionHistory returns an empty ConditionHistory keeping the latest
+// size transitions of each condition.
+func NewConditionHistory(size int) *ConditionHistory {
+	return &ConditionHistory{
+		size:        size,
+		transitions: make(map[configv1.ClusterStatusConditionType][]ConditionTransition),
+	}
+}
+
+// Record records the transition of each condition whose status is not the
+// status last recorded for its type, at its LastTransitionTime. It returns true
+// if a transition was recorded.
+func (h *ConditionHistory) Record(conditions []configv1.ClusterOperatorStatusCondition) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	recorded := false
+	for _, condition := range conditions {
+		transitions := h.transitions[condition.Type]
+		if len(transitions) > 0 && transitions[len(transitions)-1].Status == condition.Status {
+			continue
+		}
+		transitions = append(transitions, ConditionTransition{
+			Status:  condition.Status,
+			Reason:  condition.Reason,
+			Message: condition.Message,
+			Time:    condition.LastTransitionTime,
+		})
+		if len(transitions) > h.size {
+			transitions = transitions[len(transitions)-h.size:]
+		}
+		h.transitions[condition.Type] = transitions
+		recorded = true
+	}
+	return recorded
+}
+
+// Get returns the transitions recorded for the given condition type, oldest
+// first.
+func (h *ConditionHistory) Get(conditionType configv1.ClusterStatusConditionType) []ConditionTransition {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]ConditionTransition(nil), h.transitions[conditionType]...)
+}
+
+// Load replaces the transitions of the history with the ones persisted in the
+// condition history ConfigMap in namespace, if it exists.
+func (h *ConditionHistory) Load(ctx context.Context, c client.Reader, namespace string) error {
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ConditionHistoryConfigMapName}, configMap)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get the condition history ConfigMap: %v", err)
+	}
+
+	transitions := make(map[configv1.ClusterStatusConditionType][]ConditionTransition)
+	if err := json.Unmarshal([]byte(configMap.Data[conditionHistoryKey]), &transitions); err != nil {
+		return fmt.Errorf("invalid condition history in ConfigMap %s/%s: %v", namespace, ConditionHistoryConfigMapName, err)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for conditionType, recorded := range transitions {
+		if len(recorded) > h.size {
+			transitions[conditionType] = recorded[len(recorded)-h.size:]
+		}
+	}
+	h.transitions = transitions
+	return nil
+}
+
+// Save persists the transitions of the history in the condition history
+// ConfigMap in namespace.
+func (h *ConditionHistory) Save(ctx context.Context, c client.Client, namespace string) error {
+	h.mu.RLock()
+	data, err := json.Marshal(h.transitions)
+	h.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err = c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ConditionHistoryConfigMapName}, configMap)
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: ConditionHistoryConfigMapName, Namespace: namespace},
+			Data:       map[string]string{conditionHistoryKey: string(data)},
+		}
+		if err := c.Create(ctx, configMap); err != nil {
+			return fmt.Errorf("failed to create the condition history ConfigMap: %v", err)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get the condition history ConfigMap: %v", err)
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[conditionHistoryKey] = string(data)
+	if err := c.Update(ctx, configMap); err != nil {
+		return fmt.Errorf("failed to update the condition history ConfigMap: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// degradedCondition returns the Degraded condition with the given status,
+// transitioned the given number of minutes after a fixed time.
+func degradedCondition(status configv1.ConditionStatus, minutes int) configv1.ClusterOperatorStatusCondition {
+	return configv1.ClusterOperatorStatusCondition{
+		Type:               configv1.OperatorDegraded,
+		Status:             status,
+		Reason:             "Reason" + string(status),
+		LastTransitionTime: metav1.NewTime(time.Date(2024, 5, 1, 12, minutes, 0, 0, time.UTC)),
+	}
+}
+
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func TestConditionHistoryRotation(t *testing.T) {
+	h := NewConditionHistory(3)
+	assert.True(t, h.Record([]configv1.ClusterOperatorStatusCondition{degradedCondition(configv1.ConditionFalse, 0)}))
+	assert.False(t, h.Record([]configv1.ClusterOperatorStatusCondition{degradedCondition(configv1.ConditionFalse, 1)}),
+		"a condition whose status did not change is not a transition")
+	for minutes := 2; minutes < 5; minutes++ {
+		status := configv1.ConditionTrue
+		if minutes%2 == 1 {
+			status = configv1.ConditionFalse
+		}
+		assert.True(t, h.Record([]configv1.ClusterOperatorStatusCondition{degradedCondition(status, minutes)}))
+	}
+
+	transitions := h.Get(configv1.OperatorDegraded)
+	require.Len(t, transitions, 3, "the oldest transition is dropped at capacity")
+	assert.Equal(t, 2, transitions[0].Time.Minute())
+	assert.Equal(t, configv1.ConditionTrue, transitions[0].Status)
+	assert.Equal(t, 4, transitions[2].Time.Minute())
+	assert.Empty(t, h.Get(configv1.OperatorAvailable))
+}
+
+func TestConditionHistoryReloaded(t *testing.T) {
+	c := newFakeClient(t)
+	h := NewConditionHistory(2)
+	h.Record([]configv1.ClusterOperatorStatusCondition{degradedCondition(configv1.ConditionTrue, 0)})
+	require.NoError(t, h.Save(context.TODO(), c, "openshift-marketplace"))
+	h.Record([]configv1.ClusterOperatorStatusCondition{degradedCondition(configv1.ConditionFalse, 5)})
+	require.NoError(t, h.Save(context.TODO(), c, "openshift-marketplace"))
+
+	// The history of a restarted operator is loaded from the ConfigMap.
+	reloaded := NewConditionHistory(2)
+	require.NoError(t, reloaded.Load(context.TODO(), c, "openshift-marketplace"))
+	transitions := reloaded.Get(configv1.OperatorDegraded)
+	require.Len(t, transitions, 2)
+	assert.Equal(t, configv1.ConditionTrue, transitions[0].Status)
+	assert.Equal(t, "ReasonFalse", transitions[1].Reason)
+	assert.True(t, transitions[1].Time.Equal(&metav1.Time{Time: time.Date(2024, 5, 1, 12, 5, 0, 0, time.UTC)}))
+
+	// A smaller history only keeps the latest transitions.
+	smaller := NewConditionHistory(1)
+	require.NoError(t, smaller.Load(context.TODO(), c, "openshift-marketplace"))
+	require.Len(t, smaller.Get(configv1.OperatorDegraded), 1)
+	assert.Equal(t, configv1.ConditionFalse, smaller.Get(configv1.OperatorDegraded)[0].Status)
+
+	// A missing ConfigMap leaves the history empty.
+	empty := NewConditionHistory(2)
+	require.NoError(t, empty.Load(context.TODO(), newFakeClient(t), "openshift-marketplace"))
+	assert.Empty(t, empty.Get(configv1.OperatorDegraded))
+}
+
+func TestSetStatusRecordsConditionHistory(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+	c := newFakeClient(t)
+	fake := &fakeClusterOperators{updateErr: errors.New("forbidden")}
+	r := &reporter{configClient: fake, rawClient: c, namespace: "openshift-marketplace", clusterOperatorName: "marketplace",
+		history: NewConditionHistory(DefaultConditionHistorySize)}
+
+	assert.Error(t, r.setStatus(availableConditions("available")))
+	assert.Empty(t, r.GetConditionHistory(configv1.OperatorAvailable), "a failed write is not recorded")
+
+	fake.updateErr = nil
+	require.NoError(t, r.setStatus(availableConditions("available")))
+	require.NoError(t, r.setStatus(availableConditions("still available")))
+	transitions := r.GetConditionHistory(configv1.OperatorAvailable)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, configv1.ConditionTrue, transitions[0].Status)
+
+	reloaded := NewConditionHistory(DefaultConditionHistorySize)
+	require.NoError(t, reloaded.Load(context.TODO(), c, "openshift-marketplace"))
+	persisted := reloaded.Get(configv1.OperatorAvailable)
+	require.Len(t, persisted, 1)
+	assert.Equal(t, transitions[0].Status, persisted[0].Status)
+	assert.Equal(t, transitions[0].Message, persisted[0].Message)
+	assert.Equal(t, transitions[0].Time.Unix(), persisted[0].Time.Unix(), "the transition time is persisted to the second")
+}
@@ -0,0 +1,57 @@
+package status
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/utils/clock"
+)
+
+// PeriodicReporter wraps a Reporter and calls its StartReporting every resync
+// interval, so that the ClusterOperator status is restored soon after it is
+// edited by hand, such as when one of its conditions is removed. The wrapped
+// Reporter must re-sync the status when StartReporting is called once it
+// has started, as the Reporter returned by NewReporter does.
+type PeriodicReporter struct {
+	Reporter
+	resyncInterval time.Duration
+	stopCh         <-chan struct{}
+	clock          clock.WithTicker
+}
+
+// NewPeriodicReporter returns a PeriodicReporter re-syncing the status of
+// reporter every resyncInterval until stopCh is closed.
+func NewPeriodicReporter(reporter Reporter, resyncInterval time.Duration, stopCh <-chan struct{}) *PeriodicReporter {
+	return &PeriodicReporter{
+		Reporter:       reporter,
+		resyncInterval: resyncInterval,
+		stopCh:         stopCh,
+		clock:          clock.RealClock{},
+	}
+}
+
+// StartReporting starts the wrapped Reporter and re-syncs its status every
+// resync interval until stopCh is closed. The channel returned is closed once
+// both have stopped.
+func (p *PeriodicReporter) StartReporting() <-chan struct{} {
+	reporting := p.Reporter.StartReporting()
+	// The ticker is created before returning so that the first re-sync is
+	// due one interval after the reporting started.
+	ticker := p.clock.NewTicker(p.resyncInterval)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stopCh:
+				<-reporting
+				return
+			case <-ticker.C():
+				log.Debug("[status] Re-syncing the ClusterOperator status")
+				p.Reporter.StartReporting()
+			}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,67 @@
+package status
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// countingReporter counts the calls made to StartReporting.
+type countingReporter struct {
+	NoOpReporter
+	starts atomic.Int32
+	done   chan struct{}
+}
+
+func (r *countingReporter) StartReporting() <-chan struct{} {
+	r.starts.Add(1)
+	return r.done
+}
+
+func TestPeriodicReporterResyncs(t *testing.T) {
+	wrapped := &countingReporter{done: make(chan struct{})}
+	stopCh := make(chan struct{})
+	fakeClock := clocktesting.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	p := NewPeriodicReporter(wrapped, 5*time.Minute, stopCh)
+	p.clock = fakeClock
+
+	done := p.StartReporting()
+	assert.EqualValues(t, 1, wrapped.starts.Load(), "the wrapped reporter is started")
+
+	fakeClock.Step(4 * time.Minute)
+	assert.Never(t, func() bool { return wrapped.starts.Load() > 1 }, 100*time.Millisecond, 10*time.Millisecond,
+		"no re-sync is due before the interval elapses")
+
+	fakeClock.Step(time.Minute)
+	require.Eventually(t, func() bool { return wrapped.starts.Load() == 2 }, time.Second, 10*time.Millisecond)
+
+	fakeClock.Step(5 * time.Minute)
+	require.Eventually(t, func() bool { return wrapped.starts.Load() == 3 }, time.Second, 10*time.Millisecond)
+
+	close(stopCh)
+	select {
+	case <-done:
+		t.Fatal("the reporter is done before the wrapped reporter")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(wrapped.done)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the reporter is not done once stopped")
+	}
+}
+
+func TestStartReportingRequestsResync(t *testing.T) {
+	r := &reporter{resyncCh: make(chan struct{}, 1), monitorDoneCh: make(chan struct{})}
+	// The reporting is marked as started without running the monitor.
+	r.once.Do(func() {})
+
+	r.StartReporting()
+	r.StartReporting()
+	assert.Len(t, r.resyncCh, 1, "a single re-sync is pending")
+}
@@ -2,8 +2,11 @@ package status
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,9 +16,12 @@ import (
 	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
 	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
 	mktconfig "github.com/operator-framework/operator-marketplace/pkg/apis/config/v1"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
 	log "github.com/sirupsen/logrus"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -28,24 +34,162 @@ const (
 	upgradeable = "Marketplace is upgradeable"
 
 	operatorAvailable = "OperatorAvailable"
+
+	// catalogSourcesFailing is the reason of the Degraded condition while
+	// CatalogSources fail to sync.
+	catalogSourcesFailing = "CatalogSourcesFailing"
+
+	// defaultsPopulationFailed is the reason of the Degraded condition while
+	// the default CatalogSources can not be populated.
+	defaultsPopulationFailed = "DefaultsPopulationFailed"
+
+	// maxListedFailures is the number of failing CatalogSources listed in the
+	// Degraded condition message.
+	maxListedFailures = 10
+
+	// VersionAnnotation is the annotation of the ClusterOperator holding the
+	// version of the operator.
+	VersionAnnotation = "operator.openshift.io/marketplace-version"
+
+	// CommitAnnotation is the annotation of the ClusterOperator holding the
+	// git commit the operator was built from.
+	CommitAnnotation = "operator.openshift.io/marketplace-commit"
+
+	// DefaultStatusUpdateRetries is the number of times a ClusterOperator
+	// status update failing with a conflict or because of rate limiting is
+	// retried by default.
+	DefaultStatusUpdateRetries = 5
+
+	// DefaultConditionDecayTimeout is the time after which, by default, a
+	// failure condition is cleared if no new failure was observed.
+	DefaultConditionDecayTimeout = 30 * time.Minute
+
+	// statusUpdateRetryInterval is the interval before the first retry of a
+	// ClusterOperator status update, doubled on each retry.
+	statusUpdateRetryInterval = 500 * time.Millisecond
 )
 
+// FailureReporter aggregates the CatalogSources failing to sync into the
+// Degraded condition of the ClusterOperator.
+type FailureReporter interface {
+	// RegisterFailure records that the CatalogSource name in namespace
+	// fails to sync for reason, replacing its previous reason. It is listed
+	// in the Degraded condition until ClearFailure is called.
+	RegisterFailure(name, namespace, reason string)
+	// ClearFailure removes the failure of the CatalogSource name in
+	// namespace, if any.
+	ClearFailure(name, namespace string)
+}
+
 type Reporter interface {
+	FailureReporter
+	// SetDefaultsError records that the default CatalogSources can not be
+	// populated because of err, the ClusterOperator is Degraded until it is
+	// called with a nil error.
+	SetDefaultsError(err error)
 	StartReporting() <-chan struct{}
+	// GetConditionHistory returns the latest transitions of the given
+	// ClusterOperator condition, oldest first.
+	GetConditionHistory(conditionType configv1.ClusterStatusConditionType) []ConditionTransition
 }
 
 type reporter struct {
-	configClient    *configclient.ConfigV1Client
+	configClient    configclient.ClusterOperatorsGetter
 	rawClient       client.Client
 	namespace       string
 	clusterOperator *configv1.ClusterOperator
 	version         string
+	// commit is the git commit the operator was built from.
+	commit string
 	// stopCh is used to signal that threads should stop reporting ClusterOperator status
 	stopCh <-chan struct{}
 	// monitorDoneCh is used to signal that threads are done reporting ClusterOperator status
-	monitorDoneCh       chan struct{}
+	monitorDoneCh chan struct{}
+	// resyncCh requests a report of the ClusterOperator status without
+	// waiting for the report interval.
+	resyncCh            chan struct{}
 	clusterOperatorName string
 	once                sync.Once
+	// onReported is called once the status is first set successfully.
+	onReported   func()
+	reportedOnce sync.Once
+	// history holds the latest transitions of each condition, persisted
+	// through rawClient.
+	history     *ConditionHistory
+	historySize int
+	// statusUpdateRetries is the number of times a status update failing
+	// with a conflict or because of rate limiting is retried.
+	statusUpdateRetries int
+	retryInterval       time.Duration
+	// degradedState holds the causes of the Degraded condition.
+	degradedState
+}
+
+// degradedState holds the causes of the Degraded condition: the failing
+// CatalogSources and the error populating the default CatalogSources. It is
+// safe for concurrent use.
+type degradedState struct {
+	mu sync.Mutex
+	// failures holds the reason each failing CatalogSource fails to sync
+	// for.
+	failures map[types.NamespacedName]string
+	// observed holds when each failing CatalogSource was last registered.
+	observed map[types.NamespacedName]time.Time
+	// decayTimeout, if positive, is the time after which a failure that was
+	// not registered again is dropped.
+	decayTimeout time.Duration
+	// now returns the current time, time.Now is used if it is nil.
+	now func() time.Time
+	// defaultsErr is the error populating the default CatalogSources.
+	defaultsErr error
+}
+
+// ReporterOption configures the Reporter returned by NewReporter.
+type ReporterOption func(*reporter)
+
+// WithOnReported returns a ReporterOption calling fn once, the first time the
+// ClusterOperator status is set successfully, either because it was written or
+// because it was already up to date.
+func WithOnReported(fn func()) ReporterOption {
+	return func(r *reporter) {
+		r.onReported = fn
+	}
+}
+
+// WithConditionHistorySize returns a ReporterOption keeping the latest size
+// transitions of each ClusterOperator condition rather than
+// DefaultConditionHistorySize.
+func WithConditionHistorySize(size int) ReporterOption {
+	return func(r *reporter) {
+		r.historySize = size
+	}
+}
+
+// WithStatusUpdateRetries returns a ReporterOption retrying a ClusterOperator
+// status update failing with a conflict or because of rate limiting up to
+// retries times rather than DefaultStatusUpdateRetries, with an exponential
+// backoff.
+func WithStatusUpdateRetries(retries int) ReporterOption {
+	return func(r *reporter) {
+		r.statusUpdateRetries = retries
+	}
+}
+
+// WithConditionDecayTimeout returns a ReporterOption dropping a failing
+// CatalogSource from the Degraded condition once its failure was not
+// registered again for timeout. The failures never decay if timeout is zero.
+func WithConditionDecayTimeout(timeout time.Duration) ReporterOption {
+	return func(r *reporter) {
+		r.decayTimeout = timeout
+	}
+}
+
+// WithCommit returns a ReporterOption annotating the ClusterOperator with the
+// git commit the operator was built from, along with its version.
+func WithCommit(commit string) ReporterOption {
+	return func(r *reporter) {
+		r.commit = commit
+	}
 }
 
 // ensureClusterOperator ensures that a ClusterOperator CR is present on the
@@ -58,42 +202,289 @@ func (r *reporter) ensureClusterOperator() error {
 		return nil
 	}
 	if !apierrors.IsNotFound(err) {
-		return fmt.Errorf("Error %v getting ClusterOperator", err)
+		return fmt.Errorf("Error %w getting ClusterOperator", err)
 	}
 
 	clusterOperator := &configv1.ClusterOperator{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      r.clusterOperatorName,
-			Namespace: r.namespace,
+			Name:        r.clusterOperatorName,
+			Namespace:   r.namespace,
+			Annotations: r.annotations(),
 		},
 	}
 	r.setRelatedObjects()
 
 	r.clusterOperator, err = r.configClient.ClusterOperators().Create(context.TODO(), clusterOperator, metav1.CreateOptions{})
 	if err != nil {
-		return fmt.Errorf("Error %v creating ClusterOperator", err)
+		return fmt.Errorf("Error %w creating ClusterOperator", err)
 	}
 	log.Info("[status] Created ClusterOperator")
 	return nil
 }
 
 // setStatus handles setting all the required fields for the given
-// ClusterStatusConditionType
-func (r *reporter) setStatus(statusConditions []configv1.ClusterOperatorStatusCondition) error {
-	err := r.ensureClusterOperator()
+// ClusterStatusConditionType. The outcome of every attempt is recorded in the
+// ClusterOperator status metrics.
+func (r *reporter) setStatus(statusConditions []configv1.ClusterOperatorStatusCondition) (err error) {
+	defer func() {
+		metrics.RecordClusterOperatorStatusUpdate(time.Now(), err)
+	}()
+	err = r.ensureClusterOperator()
 	if err != nil {
 		return err
 	}
+	r.ensureAnnotations()
 	previousStatus := r.clusterOperator.Status.DeepCopy()
 	for _, statusCondition := range statusConditions {
 		r.setStatusCondition(statusCondition)
 	}
+	r.setStatusExtension()
 	if err := r.updateStatus(previousStatus); err != nil {
 		return err
 	}
+	r.recordHistory()
+	if r.onReported != nil {
+		r.reportedOnce.Do(r.onReported)
+	}
 	return nil
 }
 
+// reportStatus sets the ClusterOperator status from the causes of the
+// Degraded condition. A status update failing with a conflict or because of
+// rate limiting is retried up to statusUpdateRetries times, with an
+// exponential backoff, so that a transient failure does not leave the status
+// stale until the next report.
+func (r *reporter) reportStatus(ctx context.Context) error {
+	backoff := wait.Backoff{Duration: r.retryInterval, Factor: 2, Jitter: 0.1, Steps: r.statusUpdateRetries + 1}
+	attempts := 0
+	var statusErr error
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func(context.Context) (bool, error) {
+		attempts++
+		statusErr = r.setStatus(clusterOperatorConditions(r.version, &r.degradedState))
+		if statusErr == nil {
+			return true, nil
+		}
+		if !isRetryableStatusError(statusErr) || attempts > r.statusUpdateRetries {
+			return false, statusErr
+		}
+		log.Warnf("[status] Retrying the ClusterOperator status update - %v", statusErr)
+		return false, nil
+	})
+	if err != nil && statusErr != nil {
+		// The error of the last attempt is more telling than the
+		// interruption of the backoff.
+		return statusErr
+	}
+	return err
+}
+
+// isRetryableStatusError returns true if err is a conflict or a rate limiting
+// error, which a later attempt may not run into.
+func isRetryableStatusError(err error) bool {
+	return apierrors.IsConflict(err) || apierrors.IsTooManyRequests(err)
+}
+
+// annotations returns the annotations of the ClusterOperator identifying the
+// build of the operator.
+func (r *reporter) annotations() map[string]string {
+	annotations := map[string]string{VersionAnnotation: r.version}
+	if r.commit != "" {
+		annotations[CommitAnnotation] = r.commit
+	}
+	return annotations
+}
+
+// ensureAnnotations sets the version and commit annotations on the
+// ClusterOperator so that they show in oc describe. A failure is logged rather
+// than returned as the annotations must not prevent the conditions from being
+// reported.
+func (r *reporter) ensureAnnotations() {
+	annotations := r.annotations()
+	upToDate := true
+	for key, value := range annotations {
+		if r.clusterOperator.Annotations[key] != value {
+			upToDate = false
+		}
+	}
+	if upToDate {
+		return
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+	})
+	if err != nil {
+		log.Errorf("[status] Error encoding the ClusterOperator annotations - %v", err)
+		return
+	}
+	clusterOperator, err := r.configClient.ClusterOperators().Patch(context.TODO(), r.clusterOperatorName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		log.Errorf("[status] Error annotating the ClusterOperator with the operator version - %v", err)
+		return
+	}
+	r.clusterOperator = clusterOperator
+}
+
+// recordHistory records the transitions of the ClusterOperator conditions in
+// the condition history, and persists it if any was recorded.
+func (r *reporter) recordHistory() {
+	if r.history == nil || !r.history.Record(r.clusterOperator.Status.Conditions) {
+		return
+	}
+	if err := r.history.Save(context.TODO(), r.rawClient, r.namespace); err != nil {
+		log.Errorf("[status] Error persisting the condition history - %v", err)
+	}
+}
+
+// GetConditionHistory returns the latest transitions of the given
+// ClusterOperator condition, oldest first.
+func (r *reporter) GetConditionHistory(conditionType configv1.ClusterStatusConditionType) []ConditionTransition {
+	if r.history == nil {
+		return nil
+	}
+	return r.history.Get(conditionType)
+}
+
+// RegisterFailure records that the CatalogSource name in namespace fails to
+// sync for reason. The Degraded condition is updated on the next report.
+func (s *degradedState) RegisterFailure(name, namespace, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures == nil {
+		s.failures = make(map[types.NamespacedName]string)
+		s.observed = make(map[types.NamespacedName]time.Time)
+	}
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	s.failures[key] = reason
+	s.observed[key] = s.clock()
+}
+
+// ClearFailure removes the failure of the CatalogSource name in namespace.
+func (s *degradedState) ClearFailure(name, namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	delete(s.failures, key)
+	delete(s.observed, key)
+}
+
+// clock returns the current time.
+func (s *degradedState) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// decayFailures drops the failures that were not registered again for the
+// decay timeout, as the CatalogSources that still fail are registered again
+// on each retry. The caller must hold mu.
+func (s *degradedState) decayFailures() {
+	if s.decayTimeout <= 0 {
+		return
+	}
+	now := s.clock()
+	for key, observed := range s.observed {
+		if now.Sub(observed) >= s.decayTimeout {
+			log.Infof("[status] Clearing the failure of CatalogSource %s, it was not observed for %s", key, s.decayTimeout)
+			delete(s.failures, key)
+			delete(s.observed, key)
+		}
+	}
+}
+
+// SetDefaultsError records the error populating the default CatalogSources,
+// or clears it if err is nil. The Degraded condition is updated on the next
+// report.
+func (s *degradedState) SetDefaultsError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultsErr = err
+}
+
+// degradedCondition returns the status, message and reason of the Degraded
+// condition: True if the default CatalogSources can not be populated or any
+// CatalogSource fails to sync, listing the failing CatalogSources, and False
+// with the available message otherwise.
+func (s *degradedState) degradedCondition(availableMessage string) (configv1.ConditionStatus, string, string) {
+	s.mu.Lock()
+	s.decayFailures()
+	defaultsErr := s.defaultsErr
+	message := failuresMessage(s.failures)
+	s.mu.Unlock()
+	if defaultsErr != nil {
+		defaultsMessage := fmt.Sprintf("Unable to populate the default CatalogSources: %v", defaultsErr)
+		if message != "" {
+			defaultsMessage += ". " + message
+		}
+		return configv1.ConditionTrue, defaultsMessage, defaultsPopulationFailed
+	}
+	if message == "" {
+		return configv1.ConditionFalse, availableMessage, operatorAvailable
+	}
+	return configv1.ConditionTrue, message, catalogSourcesFailing
+}
+
+// failureCount returns the number of failing CatalogSources.
+func (s *degradedState) failureCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.decayFailures()
+	return len(s.failures)
+}
+
+// failureList returns the failing CatalogSources and their reason, as
+// namespace/name: reason, ordered by namespace and name.
+func (s *degradedState) failureList() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.decayFailures()
+	failures := make([]string, 0, len(s.failures))
+	for _, key := range sortedFailures(s.failures) {
+		failures = append(failures, fmt.Sprintf("%s: %s", key, s.failures[key]))
+	}
+	return failures
+}
+
+// sortedFailures returns the keys of failures ordered by namespace and name.
+func sortedFailures(failures map[types.NamespacedName]string) []types.NamespacedName {
+	keys := make([]types.NamespacedName, 0, len(failures))
+	for key := range failures {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Namespace != keys[j].Namespace {
+			return keys[i].Namespace < keys[j].Namespace
+		}
+		return keys[i].Name < keys[j].Name
+	})
+	return keys
+}
+
+// failuresMessage returns a message listing the failing CatalogSources and
+// their reason, ordered by namespace and name so that it only changes with the
+// failures. Only the first maxListedFailures are listed. The empty string is
+// returned if there is no failure.
+func failuresMessage(failures map[types.NamespacedName]string) string {
+	if len(failures) == 0 {
+		return ""
+	}
+	keys := sortedFailures(failures)
+	listed := make([]string, 0, maxListedFailures+1)
+	for i, key := range keys {
+		if i == maxListedFailures {
+			listed = append(listed, fmt.Sprintf("and %d more", len(keys)-maxListedFailures))
+			break
+		}
+		listed = append(listed, fmt.Sprintf("%s: %s", key, failures[key]))
+	}
+	noun := "CatalogSources"
+	if len(keys) == 1 {
+		noun = "CatalogSource"
+	}
+	return fmt.Sprintf("%d %s failing to sync: %s", len(keys), noun, strings.Join(listed, "; "))
+}
+
 // setOperandVersion sets the operator version in the ClusterOperator Status
 // Per instructions from the CVO team, setOperandVersion should only be called
 // when the operator becomes available
@@ -117,7 +508,7 @@ func (r *reporter) setStatusCondition(statusCondition configv1.ClusterOperatorSt
 
 // updateStatus makes the API call to update the ClusterOperator if the status has changed.
 func (r *reporter) updateStatus(previousStatus *configv1.ClusterOperatorStatus) error {
-	if compareClusterOperatorStatusConditionArrays(previousStatus.Conditions, r.clusterOperator.Status.Conditions) {
+	if compareClusterOperatorStatusConditionArrays(previousStatus.Conditions, r.clusterOperator.Status.Conditions) && !r.extensionChanged(previousStatus) {
 		log.Debugf("[status] Previous and current ClusterOperator Status are the same, the ClusterOperator Status will not be updated.")
 		return nil
 	}
@@ -144,7 +535,7 @@ func (r *reporter) updateStatus(previousStatus *configv1.ClusterOperatorStatus)
 	// Always update RelatedObjects to account for the upgrade case.
 	r.setRelatedObjects()
 	if _, err := r.configClient.ClusterOperators().UpdateStatus(context.TODO(), r.clusterOperator, metav1.UpdateOptions{}); err != nil {
-		return fmt.Errorf("Error %v updating ClusterOperator", err)
+		return fmt.Errorf("Error %w updating ClusterOperator", err)
 	}
 	log.Info("[status] ClusterOperator status conditions updated.")
 	return nil
@@ -170,24 +561,31 @@ func (r *reporter) setRelatedObjects() {
 	r.clusterOperator.Status.RelatedObjects = objectReferences
 }
 
+// clusterOperatorConditions returns the ClusterOperator conditions reported
+// for the given release version: marketplace is available, and Degraded
+// following degraded.
+func clusterOperatorConditions(version string, degraded *degradedState) []configv1.ClusterOperatorStatusCondition {
+	msg := fmt.Sprintf("Available release version: %s", version)
+	conditionListBuilder := clusterStatusListBuilder()
+	conditionListBuilder(configv1.OperatorProgressing, configv1.ConditionFalse, fmt.Sprintf("Successfully progressed to release version: %s", version), operatorAvailable)
+	conditionListBuilder(configv1.OperatorAvailable, configv1.ConditionTrue, msg, operatorAvailable)
+	conditionListBuilder(configv1.OperatorUpgradeable, configv1.ConditionTrue, upgradeable, operatorAvailable)
+	degradedStatus, degradedMessage, degradedReason := degraded.degradedCondition(msg)
+	return conditionListBuilder(configv1.OperatorDegraded, degradedStatus, degradedMessage, degradedReason)
+}
+
 // monitorClusterStatus updates the ClusterOperator's status based on
 // the number of successful syncs / total syncs
 func (r *reporter) monitorClusterStatus() {
-	msg := fmt.Sprintf("Available release version: %s", r.version)
 	// Signal to the main channel that we have stopped reporting status.
 	defer func() {
 		close(r.monitorDoneCh)
 	}()
 	// Create the ClusterOperator in the available state if it does not exist
 	// and it is the first report.
+	ctx := wait.ContextForChannel(r.stopCh)
 	if r.clusterOperator == nil {
-		conditionListBuilder := clusterStatusListBuilder()
-		conditionListBuilder(configv1.OperatorProgressing, configv1.ConditionFalse, fmt.Sprintf("Successfully progressed to release version: %s", r.version), operatorAvailable)
-		conditionListBuilder(configv1.OperatorAvailable, configv1.ConditionTrue, msg, operatorAvailable)
-		conditionListBuilder(configv1.OperatorUpgradeable, configv1.ConditionTrue, upgradeable, operatorAvailable)
-		statusConditions := conditionListBuilder(configv1.OperatorDegraded, configv1.ConditionFalse, msg, operatorAvailable)
-		statusErr := r.setStatus(statusConditions)
-		if statusErr != nil {
+		if statusErr := r.reportStatus(ctx); statusErr != nil {
 			log.Error("[status] " + statusErr.Error())
 		}
 	}
@@ -199,25 +597,19 @@ func (r *reporter) monitorClusterStatus() {
 		// Attempt to update the ClusterOperator status whenever the seconds
 		// number of seconds defined by coStatusReportInterval passes.
 		case <-time.After(coStatusReportInterval):
-			// Log any status update errors after exit.
-			var statusErr error
-			defer func() {
-				if statusErr != nil {
-					log.Error("[status] " + statusErr.Error())
-				}
-			}()
 			// Report that marketplace is available
-			conditionListBuilder := clusterStatusListBuilder()
-			conditionListBuilder(configv1.OperatorProgressing, configv1.ConditionFalse, fmt.Sprintf("Successfully progressed to release version: %s", r.version), operatorAvailable)
-			conditionListBuilder(configv1.OperatorDegraded, configv1.ConditionFalse, msg, operatorAvailable)
-			conditionListBuilder(configv1.OperatorUpgradeable, configv1.ConditionTrue, upgradeable, operatorAvailable)
-			statusConditions := conditionListBuilder(configv1.OperatorAvailable, configv1.ConditionTrue, msg, operatorAvailable)
-			statusErr = r.setStatus(statusConditions)
+			if statusErr := r.reportStatus(ctx); statusErr != nil {
+				log.Error("[status] " + statusErr.Error())
+			}
+		case <-r.resyncCh:
+			if statusErr := r.reportStatus(ctx); statusErr != nil {
+				log.Error("[status] " + statusErr.Error())
+			}
 		}
 	}
 }
 
-func NewReporter(cfg *rest.Config, mgr manager.Manager, namespace string, name string, version string, stopCh <-chan struct{}) (Reporter, error) {
+func NewReporter(cfg *rest.Config, mgr manager.Manager, namespace string, name string, version string, stopCh <-chan struct{}, opts ...ReporterOption) (Reporter, error) {
 	if !mktconfig.IsAPIAvailable() {
 		return nil, errors.New("[status] ClusterOperator API not present")
 	}
@@ -238,35 +630,86 @@ func NewReporter(cfg *rest.Config, mgr manager.Manager, namespace string, name s
 		version = "OpenShift Independent Version"
 	}
 
-	return &reporter{
+	r := &reporter{
 		configClient:        configClient,
 		rawClient:           rawClient,
 		namespace:           namespace,
 		version:             version,
 		stopCh:              stopCh,
 		monitorDoneCh:       make(chan struct{}),
+		resyncCh:            make(chan struct{}, 1),
 		clusterOperatorName: name,
-	}, nil
+		historySize:         DefaultConditionHistorySize,
+		statusUpdateRetries: DefaultStatusUpdateRetries,
+		retryInterval:       statusUpdateRetryInterval,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.historySize < 1 {
+		return nil, fmt.Errorf("invalid condition history size %d, must be at least 1", r.historySize)
+	}
+	if r.statusUpdateRetries < 0 {
+		return nil, fmt.Errorf("invalid status update retries %d, must not be negative", r.statusUpdateRetries)
+	}
+	r.history = NewConditionHistory(r.historySize)
+	if err := r.history.Load(context.TODO(), rawClient, namespace); err != nil {
+		log.Warnf("[status] Starting with an empty condition history - %v", err)
+	}
+	return r, nil
 }
 
 // StartReporting ensures that the cluster supports reporting ClusterOperator status
 // and returns a channel that reports if it is actively reporting.
+// StartReporting starts reporting the ClusterOperator status until stopCh is
+// closed. Once started, each call requests the status to be reported again
+// without waiting for the report interval.
 func (r *reporter) StartReporting() <-chan struct{} {
+	started := false
 	// ensure each goroutine is only started once.
 	r.once.Do(func() {
+		started = true
 		// start reporting ClusterOperator status
 		go r.monitorClusterStatus()
 	})
+	if !started {
+		select {
+		case r.resyncCh <- struct{}{}:
+		default:
+			// A report is already pending.
+		}
+	}
 	return r.monitorDoneCh
 }
 
-type NoOpReporter struct{}
+// NoOpReporter does not report the ClusterOperator status. It records the
+// causes of the Degraded condition it is given instead, so that tests can
+// assert what would have been reported.
+type NoOpReporter struct {
+	degradedState
+}
+
+func (*NoOpReporter) SendSyncMessage(err error) {
+}
 
-func (NoOpReporter) SendSyncMessage(err error) {
+func (*NoOpReporter) GetConditionHistory(configv1.ClusterStatusConditionType) []ConditionTransition {
+	return nil
 }
 
-func (NoOpReporter) StartReporting() <-chan struct{} {
+func (*NoOpReporter) StartReporting() <-chan struct{} {
 	ch := make(chan struct{})
 	close(ch)
 	return ch
 }
+
+// RecordedConditions returns the ClusterOperator conditions that would be
+// reported following the calls made to the NoOpReporter.
+func (r *NoOpReporter) RecordedConditions() []configv1.ClusterOperatorStatusCondition {
+	return clusterOperatorConditions("", &r.degradedState)
+}
+
+// RecordedFailures returns the failing CatalogSources registered and not
+// cleared, as namespace/name: reason, ordered by namespace and name.
+func (r *NoOpReporter) RecordedFailures() []string {
+	return r.failureList()
+}
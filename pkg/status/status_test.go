@@ -0,0 +1,351 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	configclient "github.com/openshift/client-go/config/clientset/versioned/typed/config/v1"
+	cohelpers "github.com/openshift/library-go/pkg/config/clusteroperator/v1helpers"
+	"github.com/operator-framework/operator-marketplace/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// fakeClusterOperators is an in-memory ClusterOperator client whose status
+// updates fail with the errors of updateErrs, in order, then with updateErr if
+// it is not nil.
+type fakeClusterOperators struct {
+	configclient.ClusterOperatorInterface
+	clusterOperator *configv1.ClusterOperator
+	updateErrs      []error
+	updateErr       error
+	// updates counts the status updates of the ClusterOperator, failed or
+	// not.
+	updates int
+	// patches counts the patches of the ClusterOperator.
+	patches int
+}
+
+func (f *fakeClusterOperators) ClusterOperators() configclient.ClusterOperatorInterface {
+	return f
+}
+
+func (f *fakeClusterOperators) Get(_ context.Context, name string, _ metav1.GetOptions) (*configv1.ClusterOperator, error) {
+	if f.clusterOperator == nil {
+		// Like the generated clients, an empty object is returned on error.
+		return &configv1.ClusterOperator{}, apierrors.NewNotFound(configv1.Resource("clusteroperators"), name)
+	}
+	return f.clusterOperator.DeepCopy(), nil
+}
+
+func (f *fakeClusterOperators) Create(_ context.Context, clusterOperator *configv1.ClusterOperator, _ metav1.CreateOptions) (*configv1.ClusterOperator, error) {
+	f.clusterOperator = clusterOperator.DeepCopy()
+	return clusterOperator, nil
+}
+
+func (f *fakeClusterOperators) UpdateStatus(_ context.Context, clusterOperator *configv1.ClusterOperator, _ metav1.UpdateOptions) (*configv1.ClusterOperator, error) {
+	f.updates++
+	if len(f.updateErrs) > 0 {
+		err := f.updateErrs[0]
+		f.updateErrs = f.updateErrs[1:]
+		return nil, err
+	}
+	if f.updateErr != nil {
+		return nil, f.updateErr
+	}
+	f.clusterOperator = clusterOperator.DeepCopy()
+	return clusterOperator, nil
+}
+
+// Patch applies the annotations of a merge patch of the ClusterOperator
+// metadata.
+func (f *fakeClusterOperators) Patch(_ context.Context, name string, pt types.PatchType, data []byte, _ metav1.PatchOptions, _ ...string) (*configv1.ClusterOperator, error) {
+	if f.clusterOperator == nil {
+		return nil, apierrors.NewNotFound(configv1.Resource("clusteroperators"), name)
+	}
+	if pt != types.MergePatchType {
+		return nil, fmt.Errorf("unexpected patch type %s", pt)
+	}
+	patch := &configv1.ClusterOperator{}
+	if err := json.Unmarshal(data, patch); err != nil {
+		return nil, err
+	}
+	if f.clusterOperator.Annotations == nil {
+		f.clusterOperator.Annotations = map[string]string{}
+	}
+	for key, value := range patch.Annotations {
+		f.clusterOperator.Annotations[key] = value
+	}
+	f.patches++
+	return f.clusterOperator.DeepCopy(), nil
+}
+
+// metricValue returns the value of the given unlabeled metric from the
+// registry the metrics are served from.
+func metricValue(t *testing.T, name string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		metric := family.GetMetric()[0]
+		if metric.GetCounter() != nil {
+			return metric.GetCounter().GetValue()
+		}
+		return metric.GetGauge().GetValue()
+	}
+	t.Fatalf("no metric %s found", name)
+	return 0
+}
+
+func availableConditions(message string) []configv1.ClusterOperatorStatusCondition {
+	conditionListBuilder := clusterStatusListBuilder()
+	return conditionListBuilder(configv1.OperatorAvailable, configv1.ConditionTrue, message, operatorAvailable)
+}
+
+func TestSetStatusRecordsMetrics(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+	fake := &fakeClusterOperators{updateErr: errors.New("forbidden")}
+	r := &reporter{configClient: fake, namespace: "openshift-marketplace", clusterOperatorName: "marketplace"}
+
+	failures := metricValue(t, "marketplace_clusteroperator_status_update_failures_total")
+	assert.Error(t, r.setStatus(availableConditions("available")))
+	assert.Error(t, r.setStatus(availableConditions("still available")))
+	assert.Equal(t, failures+2, metricValue(t, "marketplace_clusteroperator_status_update_failures_total"))
+
+	before := time.Now().Unix()
+	fake.updateErr = nil
+	require.NoError(t, r.setStatus(availableConditions("available again")))
+	assert.Equal(t, failures+2, metricValue(t, "marketplace_clusteroperator_status_update_failures_total"))
+	assert.GreaterOrEqual(t, metricValue(t, "marketplace_clusteroperator_last_successful_update_timestamp_seconds"), float64(before))
+	require.NotNil(t, fake.clusterOperator)
+	assert.Equal(t, "available again", fake.clusterOperator.Status.Conditions[0].Message)
+}
+
+func TestSetStatusOnReported(t *testing.T) {
+	require.NoError(t, metrics.RegisterMetrics())
+	fake := &fakeClusterOperators{updateErr: errors.New("forbidden")}
+	reported := 0
+	r := &reporter{configClient: fake, namespace: "openshift-marketplace", clusterOperatorName: "marketplace"}
+	WithOnReported(func() { reported++ })(r)
+
+	assert.Error(t, r.setStatus(availableConditions("available")))
+	assert.Equal(t, 0, reported, "a failed write is not reported")
+
+	fake.updateErr = nil
+	require.NoError(t, r.setStatus(availableConditions("available")))
+	require.NoError(t, r.setStatus(availableConditions("still available")))
+	assert.Equal(t, 1, reported, "only the first successful write is reported")
+}
+
+func TestFailuresMessage(t *testing.T) {
+	assert.Empty(t, failuresMessage(nil))
+	assert.Equal(t, "1 CatalogSource failing to sync: openshift-marketplace/redhat-operators: forbidden",
+		failuresMessage(map[types.NamespacedName]string{{Namespace: "openshift-marketplace", Name: "redhat-operators"}: "forbidden"}))
+
+	failures := map[types.NamespacedName]string{
+		{Namespace: "tenant", Name: "community-operators"}:                "timeout",
+		{Namespace: "openshift-marketplace", Name: "redhat-operators"}:    "forbidden",
+		{Namespace: "openshift-marketplace", Name: "certified-operators"}: "invalid image",
+	}
+	assert.Equal(t, "3 CatalogSources failing to sync: "+
+		"openshift-marketplace/certified-operators: invalid image; "+
+		"openshift-marketplace/redhat-operators: forbidden; "+
+		"tenant/community-operators: timeout", failuresMessage(failures), "the failures are ordered by namespace and name")
+
+	for i := 0; i < 12; i++ {
+		failures[types.NamespacedName{Namespace: "tenant", Name: fmt.Sprintf("source-%02d", i)}] = "unavailable"
+	}
+	message := failuresMessage(failures)
+	assert.Contains(t, message, "15 CatalogSources failing to sync: ")
+	assert.Contains(t, message, "tenant/source-06: unavailable; and 5 more")
+	assert.NotContains(t, message, "source-07", "only the first failures are listed")
+	assert.Contains(t, message, "tenant/community-operators: timeout; tenant/source-00")
+}
+
+func TestRegisterFailureDegraded(t *testing.T) {
+	r := &reporter{}
+	status, message, reason := r.degradedCondition("available")
+	assert.Equal(t, configv1.ConditionFalse, status)
+	assert.Equal(t, "available", message)
+	assert.Equal(t, operatorAvailable, reason)
+
+	r.RegisterFailure("redhat-operators", "openshift-marketplace", "forbidden")
+	r.RegisterFailure("redhat-operators", "openshift-marketplace", "timeout")
+	r.RegisterFailure("certified-operators", "openshift-marketplace", "invalid image")
+	status, message, reason = r.degradedCondition("available")
+	assert.Equal(t, configv1.ConditionTrue, status)
+	assert.Equal(t, catalogSourcesFailing, reason)
+	assert.Equal(t, "2 CatalogSources failing to sync: openshift-marketplace/certified-operators: invalid image; "+
+		"openshift-marketplace/redhat-operators: timeout", message, "a failure registered again replaces its reason")
+
+	r.ClearFailure("certified-operators", "openshift-marketplace")
+	r.ClearFailure("community-operators", "openshift-marketplace")
+	_, message, _ = r.degradedCondition("available")
+	assert.Equal(t, "1 CatalogSource failing to sync: openshift-marketplace/redhat-operators: timeout", message)
+
+	r.ClearFailure("redhat-operators", "openshift-marketplace")
+	status, message, _ = r.degradedCondition("available")
+	assert.Equal(t, configv1.ConditionFalse, status)
+	assert.Equal(t, "available", message)
+}
+
+func TestRegisterFailureDecays(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &reporter{}
+	WithConditionDecayTimeout(30 * time.Minute)(r)
+	r.now = func() time.Time { return now }
+
+	r.RegisterFailure("redhat-operators", "openshift-marketplace", "forbidden")
+	now = now.Add(20 * time.Minute)
+	r.RegisterFailure("certified-operators", "openshift-marketplace", "timeout")
+	now = now.Add(10 * time.Minute)
+	status, message, _ := r.degradedCondition("available")
+	assert.Equal(t, configv1.ConditionTrue, status)
+	assert.Equal(t, "1 CatalogSource failing to sync: openshift-marketplace/certified-operators: timeout", message,
+		"a failure not registered again for the decay timeout is cleared")
+
+	// A failure registered again does not decay.
+	now = now.Add(15 * time.Minute)
+	r.RegisterFailure("certified-operators", "openshift-marketplace", "timeout")
+	now = now.Add(15 * time.Minute)
+	assert.Equal(t, []string{"openshift-marketplace/certified-operators: timeout"}, r.failureList())
+
+	now = now.Add(15 * time.Minute)
+	assert.Zero(t, r.failureCount())
+	status, message, _ = r.degradedCondition("available")
+	assert.Equal(t, configv1.ConditionFalse, status)
+	assert.Equal(t, "available", message)
+}
+
+func TestSetDefaultsErrorDegraded(t *testing.T) {
+	fake := &fakeClusterOperators{}
+	r := &reporter{configClient: fake, namespace: "openshift-marketplace", clusterOperatorName: "marketplace"}
+	report := func() *configv1.ClusterOperatorStatusCondition {
+		status, message, reason := r.degradedCondition("available")
+		conditions := []configv1.ClusterOperatorStatusCondition{{Type: configv1.OperatorDegraded, Status: status, Message: message, Reason: reason}}
+		require.NoError(t, r.setStatus(conditions))
+		for i := range fake.clusterOperator.Status.Conditions {
+			if fake.clusterOperator.Status.Conditions[i].Type == configv1.OperatorDegraded {
+				return &fake.clusterOperator.Status.Conditions[i]
+			}
+		}
+		t.Fatal("the Degraded condition is not reported")
+		return nil
+	}
+
+	r.SetDefaultsError(errors.New("ValidationError in /defaults/redhat-operators.yaml: error converting YAML to JSON"))
+	r.RegisterFailure("certified-operators", "openshift-marketplace", "forbidden")
+	degraded := report()
+	assert.Equal(t, configv1.ConditionTrue, degraded.Status)
+	assert.Equal(t, defaultsPopulationFailed, degraded.Reason)
+	assert.Equal(t, "Unable to populate the default CatalogSources: ValidationError in /defaults/redhat-operators.yaml: error converting YAML to JSON. "+
+		"1 CatalogSource failing to sync: openshift-marketplace/certified-operators: forbidden", degraded.Message)
+
+	// The condition clears once the defaults are populated.
+	r.ClearFailure("certified-operators", "openshift-marketplace")
+	r.SetDefaultsError(nil)
+	degraded = report()
+	assert.Equal(t, configv1.ConditionFalse, degraded.Status)
+	assert.Equal(t, operatorAvailable, degraded.Reason)
+	assert.Equal(t, "available", degraded.Message)
+}
+
+func TestNoOpReporterRecorders(t *testing.T) {
+	r := &NoOpReporter{}
+	assert.Empty(t, r.RecordedFailures())
+	conditions := r.RecordedConditions()
+	require.Len(t, conditions, 4)
+	for _, condition := range conditions {
+		if condition.Type == configv1.OperatorDegraded {
+			assert.Equal(t, configv1.ConditionFalse, condition.Status)
+		}
+	}
+
+	r.RegisterFailure("redhat-operators", "openshift-marketplace", "timeout")
+	r.RegisterFailure("certified-operators", "openshift-marketplace", "forbidden")
+	r.ClearFailure("community-operators", "openshift-marketplace")
+	assert.Equal(t, []string{
+		"openshift-marketplace/certified-operators: forbidden",
+		"openshift-marketplace/redhat-operators: timeout",
+	}, r.RecordedFailures())
+
+	r.SetDefaultsError(errors.New("no default CatalogSource found"))
+	for _, condition := range r.RecordedConditions() {
+		if condition.Type == configv1.OperatorDegraded {
+			assert.Equal(t, configv1.ConditionTrue, condition.Status)
+			assert.Equal(t, defaultsPopulationFailed, condition.Reason)
+			assert.Equal(t, "Unable to populate the default CatalogSources: no default CatalogSource found. "+
+				"2 CatalogSources failing to sync: openshift-marketplace/certified-operators: forbidden; "+
+				"openshift-marketplace/redhat-operators: timeout", condition.Message)
+		}
+	}
+
+	r.ClearFailure("redhat-operators", "openshift-marketplace")
+	assert.Equal(t, []string{"openshift-marketplace/certified-operators: forbidden"}, r.RecordedFailures())
+}
+
+func TestSetStatusAnnotatesVersion(t *testing.T) {
+	fake := &fakeClusterOperators{}
+	r := &reporter{configClient: fake, namespace: "openshift-marketplace", clusterOperatorName: "marketplace", version: "4.18.0", commit: "0123abcd"}
+
+	// The ClusterOperator is created annotated.
+	require.NoError(t, r.setStatus(availableConditions("available")))
+	assert.Equal(t, "4.18.0", fake.clusterOperator.Annotations[VersionAnnotation])
+	assert.Equal(t, "0123abcd", fake.clusterOperator.Annotations[CommitAnnotation])
+	assert.Zero(t, fake.patches)
+
+	// An existing ClusterOperator is annotated once with the new version,
+	// keeping its other annotations.
+	fake.clusterOperator.Annotations = map[string]string{VersionAnnotation: "4.17.0", "include.release.openshift.io/self-managed-high-availability": "true"}
+	require.NoError(t, r.setStatus(availableConditions("available")))
+	require.NoError(t, r.setStatus(availableConditions("available")))
+	assert.Equal(t, 1, fake.patches)
+	assert.Equal(t, map[string]string{
+		VersionAnnotation: "4.18.0",
+		CommitAnnotation:  "0123abcd",
+		"include.release.openshift.io/self-managed-high-availability": "true",
+	}, fake.clusterOperator.Annotations)
+}
+
+func TestReportStatusRetriesConflicts(t *testing.T) {
+	conflict := apierrors.NewConflict(configv1.Resource("clusteroperators"), "marketplace", errors.New("the object has been modified"))
+	fake := &fakeClusterOperators{updateErrs: []error{conflict, conflict, conflict}}
+	r := &reporter{configClient: fake, namespace: "openshift-marketplace", clusterOperatorName: "marketplace", version: "4.18.0",
+		statusUpdateRetries: DefaultStatusUpdateRetries, retryInterval: time.Millisecond}
+
+	require.NoError(t, r.reportStatus(context.Background()))
+	assert.Equal(t, 4, fake.updates, "the status is updated once the conflicts are retried")
+	available := cohelpers.FindStatusCondition(fake.clusterOperator.Status.Conditions, configv1.OperatorAvailable)
+	require.NotNil(t, available)
+	assert.Equal(t, configv1.ConditionTrue, available.Status)
+}
+
+func TestReportStatusRetryLimit(t *testing.T) {
+	tooManyRequests := apierrors.NewTooManyRequests("slow down", 1)
+	fake := &fakeClusterOperators{updateErr: tooManyRequests}
+	r := &reporter{configClient: fake, namespace: "openshift-marketplace", clusterOperatorName: "marketplace", version: "4.18.0",
+		statusUpdateRetries: 2, retryInterval: time.Millisecond}
+
+	err := r.reportStatus(context.Background())
+	assert.True(t, apierrors.IsTooManyRequests(err), "the error of the last attempt is returned, got %v", err)
+	assert.Equal(t, 3, fake.updates)
+
+	// Other errors are not retried.
+	fake.updates = 0
+	fake.updateErr = apierrors.NewForbidden(configv1.Resource("clusteroperators"), "marketplace", errors.New("denied"))
+	err = r.reportStatus(context.Background())
+	assert.True(t, apierrors.IsForbidden(err), "got %v", err)
+	assert.Equal(t, 1, fake.updates)
+}
@@ -0,0 +1,238 @@
+package status
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// statusPageConditionTypes are the ClusterOperator conditions whose history is
+// shown on the status page, in display order.
+var statusPageConditionTypes = []configv1.ClusterStatusConditionType{
+	configv1.OperatorAvailable,
+	configv1.OperatorProgressing,
+	configv1.OperatorDegraded,
+	configv1.OperatorUpgradeable,
+}
+
+// StatusPage is an http.Handler rendering the status of the operator as an
+// HTML page: the ClusterOperator conditions and their recent history, the
+// health of the default CatalogSources, the operator version and whether this
+// replica is the leader.
+type StatusPage struct {
+	// reader reads from the apiserver, as the manager cache only runs on
+	// the leader.
+	reader              client.Reader
+	namespace           string
+	clusterOperatorName string
+	version             string
+	isLeader            func() bool
+	now                 func() time.Time
+}
+
+// NewStatusPage returns a StatusPage reading the status of the operator
+// running in namespace through reader. The ClusterOperator conditions are not
+// shown if clusterOperatorName is empty.
+func NewStatusPage(reader client.Reader, namespace, clusterOperatorName, version string, isLeader func() bool) *StatusPage {
+	if version == "" {
+		version = "OpenShift Independent Version"
+	}
+	return &StatusPage{
+		reader:              reader,
+		namespace:           namespace,
+		clusterOperatorName: clusterOperatorName,
+		version:             version,
+		isLeader:            isLeader,
+		now:                 time.Now,
+	}
+}
+
+// statusPageData is the data the status page template is rendered with.
+type statusPageData struct {
+	Version         string
+	Leader          bool
+	Time            string
+	ClusterOperator string
+	Conditions      []configv1.ClusterOperatorStatusCondition
+	CatalogSources  []catalogSourceHealth
+	History         []conditionTransitions
+	// Errors are the sections that could not be read.
+	Errors []string
+}
+
+// catalogSourceHealth is the health of a default CatalogSource.
+type catalogSourceHealth struct {
+	Name      string
+	Namespace string
+	Image     string
+	State     string
+	Message   string
+}
+
+// conditionTransitions are the recent transitions of a condition, latest
+// first.
+type conditionTransitions struct {
+	Type        configv1.ClusterStatusConditionType
+	Transitions []ConditionTransition
+}
+
+// ServeHTTP renders the status page. The sections that cannot be read are
+// replaced by an error, so that the page is still served while the apiserver
+// is degraded.
+func (p *StatusPage) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	data := statusPageData{
+		Version:         p.version,
+		Leader:          p.isLeader(),
+		Time:            p.now().UTC().Format(time.RFC3339),
+		ClusterOperator: p.clusterOperatorName,
+	}
+
+	if p.clusterOperatorName != "" {
+		clusterOperator := &configv1.ClusterOperator{}
+		if err := p.reader.Get(req.Context(), client.ObjectKey{Name: p.clusterOperatorName}, clusterOperator); err != nil && !apierrors.IsNotFound(err) {
+			data.Errors = append(data.Errors, fmt.Sprintf("Unable to read ClusterOperator %s: %v", p.clusterOperatorName, err))
+		} else {
+			data.Conditions = clusterOperator.Status.Conditions
+		}
+
+		history := NewConditionHistory(DefaultConditionHistorySize)
+		if err := history.Load(req.Context(), p.reader, p.namespace); err != nil {
+			data.Errors = append(data.Errors, fmt.Sprintf("Unable to read the condition history: %v", err))
+		}
+		for _, conditionType := range statusPageConditionTypes {
+			transitions := history.Get(conditionType)
+			for i, j := 0, len(transitions)-1; i < j; i, j = i+1, j-1 {
+				transitions[i], transitions[j] = transitions[j], transitions[i]
+			}
+			if len(transitions) > 0 {
+				data.History = append(data.History, conditionTransitions{Type: conditionType, Transitions: transitions})
+			}
+		}
+	}
+
+	definitions := defaults.GetGlobalCatalogSourceDefinitions()
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		def := definitions[name]
+		health := catalogSourceHealth{Name: name, Namespace: def.Namespace, Image: def.Spec.Image, State: "Unknown"}
+		catsrc := &olmv1alpha1.CatalogSource{}
+		err := p.reader.Get(req.Context(), client.ObjectKey{Namespace: def.Namespace, Name: name}, catsrc)
+		switch {
+		case apierrors.IsNotFound(err):
+			health.State = "Missing"
+		case err != nil:
+			health.Message = fmt.Sprintf("Unable to read the CatalogSource: %v", err)
+		default:
+			if catsrc.Status.GRPCConnectionState != nil && catsrc.Status.GRPCConnectionState.LastObservedState != "" {
+				health.State = catsrc.Status.GRPCConnectionState.LastObservedState
+			}
+			health.Message = catsrc.Status.Message
+		}
+		data.CatalogSources = append(data.CatalogSources, health)
+	}
+
+	var page bytes.Buffer
+	if err := statusPageTemplate.Execute(&page, data); err != nil {
+		log.Errorf("[status] Error rendering the status page - %v", err)
+		http.Error(w, "failed to render the status page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page.Bytes())
+}
+
+// conditionClass returns the Bootstrap class of the table row of a condition:
+// success if the condition is in its healthy state, danger if the operator is
+// Degraded and warning otherwise.
+func conditionClass(conditionType configv1.ClusterStatusConditionType, status configv1.ConditionStatus) string {
+	healthy := configv1.ConditionTrue
+	if conditionType == configv1.OperatorDegraded || conditionType == configv1.OperatorProgressing {
+		healthy = configv1.ConditionFalse
+	}
+	switch {
+	case status == healthy:
+		return "table-success"
+	case conditionType == configv1.OperatorDegraded:
+		return "table-danger"
+	default:
+		return "table-warning"
+	}
+}
+
+// catalogSourceClass returns the Bootstrap class of the table row of a
+// CatalogSource in the given connection state.
+func catalogSourceClass(state string) string {
+	switch state {
+	case "READY":
+		return "table-success"
+	case "Missing", "TRANSIENT_FAILURE", "SHUTDOWN":
+		return "table-danger"
+	default:
+		return "table-warning"
+	}
+}
+
+var statusPageTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"conditionClass":     conditionClass,
+	"catalogSourceClass": catalogSourceClass,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Marketplace operator status</title>
+<link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/bootstrap@5.3.3/dist/css/bootstrap.min.css">
+</head>
+<body>
+<main class="container py-4">
+<h1 class="h3">Marketplace operator</h1>
+<p class="text-body-secondary">Version {{.Version}} &middot;
+{{if .Leader}}<span class="badge text-bg-success">Leader</span>{{else}}<span class="badge text-bg-secondary">Standby</span>{{end}}
+&middot; Rendered at {{.Time}}</p>
+{{range .Errors}}<div class="alert alert-warning" role="alert">{{.}}</div>
+{{end}}
+{{if .ClusterOperator}}<h2 class="h5 mt-4">ClusterOperator {{.ClusterOperator}}</h2>
+{{if .Conditions}}<table class="table table-sm">
+<thead><tr><th>Type</th><th>Status</th><th>Reason</th><th>Message</th><th>Last transition</th></tr></thead>
+<tbody>
+{{range .Conditions}}<tr class="{{conditionClass .Type .Status}}"><td>{{.Type}}</td><td>{{.Status}}</td><td>{{.Reason}}</td><td>{{.Message}}</td><td>{{.LastTransitionTime.UTC.Format "2006-01-02T15:04:05Z07:00"}}</td></tr>
+{{end}}</tbody>
+</table>
+{{else}}<p class="text-body-secondary">No condition is reported yet.</p>
+{{end}}{{end}}
+<h2 class="h5 mt-4">Default CatalogSources</h2>
+{{if .CatalogSources}}<table class="table table-sm">
+<thead><tr><th>Name</th><th>Namespace</th><th>Image</th><th>State</th><th>Message</th></tr></thead>
+<tbody>
+{{range .CatalogSources}}<tr class="{{catalogSourceClass .State}}"><td>{{.Name}}</td><td>{{.Namespace}}</td><td><code>{{.Image}}</code></td><td>{{.State}}</td><td>{{.Message}}</td></tr>
+{{end}}</tbody>
+</table>
+{{else}}<p class="text-body-secondary">No default CatalogSource is defined.</p>
+{{end}}
+{{if .History}}<h2 class="h5 mt-4">Condition history</h2>
+{{range .History}}{{$type := .Type}}<h3 class="h6 mt-3">{{.Type}}</h3>
+<table class="table table-sm">
+<thead><tr><th>Time</th><th>Status</th><th>Reason</th><th>Message</th></tr></thead>
+<tbody>
+{{range .Transitions}}<tr class="{{conditionClass $type .Status}}"><td>{{.Time.UTC.Format "2006-01-02T15:04:05Z07:00"}}</td><td>{{.Status}}</td><td>{{.Reason}}</td><td>{{.Message}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}{{end}}
+</main>
+</body>
+</html>
+`))
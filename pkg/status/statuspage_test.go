@@ -0,0 +1,115 @@
+package status
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults/defaultstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const statusPageCatalogSources = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+metadata:
+  name: redhat-operators
+  namespace: openshift-marketplace
+spec:
+  sourceType: grpc
+  image: registry.redhat.io/redhat/redhat-operator-index:v4.18
+---
+`
+
+func TestStatusPage(t *testing.T) {
+	defaultstest.Populate(t, map[string]string{
+		"redhat-operators.yaml":    statusPageCatalogSources,
+		"certified-operators.yaml": strings.NewReplacer("redhat-operators", "certified-operators", "redhat-operator-index", "certified-operator-index").Replace(statusPageCatalogSources),
+	})
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	require.NoError(t, configv1.AddToScheme(scheme))
+	transitionTime := metav1.NewTime(time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&configv1.ClusterOperator{
+			ObjectMeta: metav1.ObjectMeta{Name: "marketplace"},
+			Status: configv1.ClusterOperatorStatus{Conditions: []configv1.ClusterOperatorStatusCondition{
+				{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue, Reason: operatorAvailable, Message: "Available release version: 4.18", LastTransitionTime: transitionTime},
+				{Type: configv1.OperatorDegraded, Status: configv1.ConditionTrue, Reason: catalogSourcesFailing, Message: "1 CatalogSource failing to sync: <script>", LastTransitionTime: transitionTime},
+			}},
+		},
+		&olmv1alpha1.CatalogSource{
+			ObjectMeta: metav1.ObjectMeta{Name: "redhat-operators", Namespace: "openshift-marketplace"},
+			Status: olmv1alpha1.CatalogSourceStatus{
+				GRPCConnectionState: &olmv1alpha1.GRPCConnectionState{LastObservedState: "READY"},
+			},
+		},
+	).Build()
+	history := NewConditionHistory(DefaultConditionHistorySize)
+	history.Record([]configv1.ClusterOperatorStatusCondition{degradedCondition(configv1.ConditionFalse, 0)})
+	history.Record([]configv1.ClusterOperatorStatusCondition{degradedCondition(configv1.ConditionTrue, 15)})
+	require.NoError(t, history.Save(context.TODO(), c, "openshift-marketplace"))
+
+	leader := true
+	page := NewStatusPage(c, "openshift-marketplace", "marketplace", "", func() bool { return leader })
+	render := func(t *testing.T) string {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		page.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+		return recorder.Body.String()
+	}
+
+	body := render(t)
+	assert.Contains(t, body, "bootstrap.min.css")
+	assert.Contains(t, body, "Version OpenShift Independent Version")
+	assert.Contains(t, body, ">Leader</span>")
+	assert.Contains(t, body, "ClusterOperator marketplace")
+	assert.Contains(t, body, `<tr class="table-success"><td>Available</td><td>True</td><td>OperatorAvailable</td>`)
+	assert.Contains(t, body, `<tr class="table-danger"><td>Degraded</td><td>True</td><td>CatalogSourcesFailing</td><td>1 CatalogSource failing to sync: &lt;script&gt;</td><td>2026-10-14T08:00:00Z</td>`)
+	assert.NotContains(t, body, "<script>", "the messages are escaped")
+
+	// The CatalogSources are listed by name.
+	certified := strings.Index(body, "<td>certified-operators</td>")
+	redhat := strings.Index(body, "<td>redhat-operators</td>")
+	require.NotEqual(t, -1, certified)
+	require.NotEqual(t, -1, redhat)
+	assert.Less(t, certified, redhat)
+	assert.Contains(t, body, `<tr class="table-danger"><td>certified-operators</td><td>openshift-marketplace</td><td><code>registry.redhat.io/redhat/certified-operator-index:v4.18</code></td><td>Missing</td>`)
+	assert.Contains(t, body, `<tr class="table-success"><td>redhat-operators</td><td>openshift-marketplace</td><td><code>registry.redhat.io/redhat/redhat-operator-index:v4.18</code></td><td>READY</td>`)
+
+	// The latest transition is shown first.
+	latest := strings.Index(body, "<td>2024-05-01T12:15:00Z</td><td>True</td><td>ReasonTrue</td>")
+	oldest := strings.Index(body, "<td>2024-05-01T12:00:00Z</td><td>False</td><td>ReasonFalse</td>")
+	require.NotEqual(t, -1, latest)
+	require.NotEqual(t, -1, oldest)
+	assert.Less(t, latest, oldest)
+
+	leader = false
+	assert.Contains(t, render(t), ">Standby</span>")
+}
+
+func TestStatusPageWithoutClusterOperator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	page := NewStatusPage(fake.NewClientBuilder().WithScheme(scheme).Build(), "openshift-marketplace", "", "4.18.0", func() bool { return false })
+
+	recorder := httptest.NewRecorder()
+	page.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "Version 4.18.0")
+	assert.NotContains(t, recorder.Body.String(), "ClusterOperator")
+	assert.NotContains(t, recorder.Body.String(), "alert-warning", "the ClusterOperator is not read")
+}
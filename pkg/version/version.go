@@ -1,11 +1,213 @@
 package version
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
-// GitCommit indicates which git commit the binary was built from
-var GitCommit string
+var (
+	// GitCommit indicates which git commit the binary was built from
+	GitCommit string
+
+	// SemVer is the semantic version the binary was built as, set through
+	// the ldflags of the build. It is empty in development builds.
+	SemVer string
+)
 
 // String returns a pretty string concatenation of GitCommit
 func String() string {
 	return fmt.Sprintf("Marketplace source git commit: %s\n", GitCommit)
 }
+
+// Version is a semantic version, as defined by https://semver.org.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	// Prerelease holds the dot-separated pre-release identifiers, as in
+	// rc.1 for 4.17.0-rc.1. It is empty for a release.
+	Prerelease string
+	// BuildMetadata holds the dot-separated build identifiers, as in
+	// 20240101 for 4.17.0+20240101. It is ignored by the comparisons.
+	BuildMetadata string
+}
+
+// Current returns the Version the binary was built as, parsed from SemVer. An
+// error is returned if SemVer is empty or not a semantic version.
+func Current() (Version, error) {
+	if SemVer == "" {
+		return Version{}, fmt.Errorf("the version of the build is not set")
+	}
+	return Parse(SemVer)
+}
+
+// Parse returns the Version s is the string of, as MAJOR.MINOR.PATCH followed
+// by optional -PRERELEASE and +BUILD parts. A leading v, as in git tags, is
+// accepted.
+func Parse(s string) (Version, error) {
+	rest := strings.TrimPrefix(s, "v")
+	var v Version
+	var found bool
+	if rest, v.BuildMetadata, found = strings.Cut(rest, "+"); found {
+		if err := validateIdentifiers(v.BuildMetadata, false); err != nil {
+			return Version{}, fmt.Errorf("invalid build metadata of version %q: %v", s, err)
+		}
+	}
+	if rest, v.Prerelease, found = strings.Cut(rest, "-"); found {
+		if err := validateIdentifiers(v.Prerelease, true); err != nil {
+			return Version{}, fmt.Errorf("invalid pre-release of version %q: %v", s, err)
+		}
+	}
+
+	numbers := strings.Split(rest, ".")
+	if len(numbers) != 3 {
+		return Version{}, fmt.Errorf("invalid version %q, must be MAJOR.MINOR.PATCH", s)
+	}
+	for i, field := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		n, err := parseNumber(numbers[i])
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q: %v", s, err)
+		}
+		*field = n
+	}
+	return v, nil
+}
+
+// String returns the semantic version string of v, without a leading v.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.BuildMetadata != "" {
+		s += "+" + v.BuildMetadata
+	}
+	return s
+}
+
+// Less returns true if v has a lower precedence than other: the major, minor
+// and patch versions are compared numerically, a pre-release has a lower
+// precedence than the release, and the pre-releases are compared identifier
+// by identifier. The build metadata is ignored.
+func (v Version) Less(other Version) bool {
+	return v.compare(other) < 0
+}
+
+// Compatible returns true if v and other are expected to be compatible: they
+// have the same major version, and the same minor version too if the major
+// version is 0, as anything may change before 1.0.0.
+func (v Version) Compatible(other Version) bool {
+	if v.Major != other.Major {
+		return false
+	}
+	return v.Major != 0 || v.Minor == other.Minor
+}
+
+// compare returns -1, 0 or 1 if v has a lower, the same or a higher precedence
+// than other.
+func (v Version) compare(other Version) int {
+	for _, pair := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if c := compareInts(pair[0], pair[1]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	}
+
+	ids, otherIDs := strings.Split(v.Prerelease, "."), strings.Split(other.Prerelease, ".")
+	for i := 0; i < len(ids) && i < len(otherIDs); i++ {
+		if c := compareIdentifiers(ids[i], otherIDs[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(ids), len(otherIDs))
+}
+
+// compareIdentifiers compares two pre-release identifiers: the numeric ones
+// numerically, the others lexically in ASCII order, a numeric identifier
+// having a lower precedence than an alphanumeric one.
+func compareIdentifiers(a, b string) int {
+	aNumeric, bNumeric := isNumeric(a), isNumeric(b)
+	switch {
+	case aNumeric && bNumeric:
+		// The identifiers have no leading zero, the longer is the
+		// greater, which holds for the numbers that overflow an int.
+		if c := compareInts(len(a), len(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// parseNumber parses a major, minor or patch version, which must be a
+// non-negative integer without leading zeros.
+func parseNumber(s string) (int, error) {
+	if !isNumeric(s) {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("%q has a leading zero", s)
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is out of range", s)
+	}
+	return n, nil
+}
+
+// validateIdentifiers returns an error if s is not a dot-separated list of
+// non-empty identifiers of ASCII letters, digits and hyphens. The numeric
+// identifiers of a pre-release must not have leading zeros.
+func validateIdentifiers(s string, prerelease bool) error {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return fmt.Errorf("empty identifier")
+		}
+		for _, r := range id {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+				return fmt.Errorf("invalid character %q in identifier %q", r, id)
+			}
+		}
+		if prerelease && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return fmt.Errorf("numeric identifier %q has a leading zero", id)
+		}
+	}
+	return nil
+}
+
+// isNumeric returns true if s is a non-empty string of ASCII digits.
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		version string
+		want    Version
+		wantErr bool
+	}{
+		{version: "4.17.0", want: Version{Major: 4, Minor: 17}},
+		{version: "v4.17.2", want: Version{Major: 4, Minor: 17, Patch: 2}},
+		{version: "0.0.0", want: Version{}},
+		{version: "1.0.0-rc.1", want: Version{Major: 1, Prerelease: "rc.1"}},
+		{version: "1.0.0-alpha-beta.0", want: Version{Major: 1, Prerelease: "alpha-beta.0"}},
+		{version: "1.0.0+20240101.sha-0abc", want: Version{Major: 1, BuildMetadata: "20240101.sha-0abc"}},
+		{version: "1.0.0-rc.1+001", want: Version{Major: 1, Prerelease: "rc.1", BuildMetadata: "001"}},
+		{version: "1.0.0+build-1.rc-2", want: Version{Major: 1, BuildMetadata: "build-1.rc-2"}},
+		{version: "", wantErr: true},
+		{version: "4.17", wantErr: true},
+		{version: "4.17.0.1", wantErr: true},
+		{version: "4.x.0", wantErr: true},
+		{version: "-1.0.0", wantErr: true},
+		{version: "01.0.0", wantErr: true},
+		{version: "1.0.00", wantErr: true},
+		{version: "1.0.0-", wantErr: true},
+		{version: "1.0.0-rc..1", wantErr: true},
+		{version: "1.0.0-01", wantErr: true},
+		{version: "1.0.0-rc_1", wantErr: true},
+		{version: "1.0.0+", wantErr: true},
+		{version: "1.0.0+a+b", wantErr: true},
+		{version: "99999999999999999999.0.0", wantErr: true},
+	} {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := Parse(tt.version)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVersionString(t *testing.T) {
+	for _, s := range []string{"4.17.0", "1.0.0-rc.1", "1.0.0+001", "1.0.0-rc.1+001"} {
+		v, err := Parse(s)
+		require.NoError(t, err)
+		assert.Equal(t, s, v.String())
+	}
+	v, err := Parse("v4.17.0")
+	require.NoError(t, err)
+	assert.Equal(t, "4.17.0", v.String(), "the leading v is dropped")
+}
+
+func TestLess(t *testing.T) {
+	// The versions are in increasing precedence, as in the example of the
+	// specification.
+	ordered := []string{
+		"0.9.9",
+		"1.0.0-0.3.7",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-beta.99999999999999999999",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.2.0",
+		"1.10.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, err := Parse(ordered[i])
+			require.NoError(t, err)
+			b, err := Parse(ordered[j])
+			require.NoError(t, err)
+			assert.Equal(t, i < j, a.Less(b), "%s < %s", ordered[i], ordered[j])
+		}
+	}
+}
+
+func TestLessIgnoresBuildMetadata(t *testing.T) {
+	a, err := Parse("1.0.0+001")
+	require.NoError(t, err)
+	b, err := Parse("1.0.0+002")
+	require.NoError(t, err)
+	assert.False(t, a.Less(b))
+	assert.False(t, b.Less(a))
+}
+
+func TestCompatible(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want bool
+	}{
+		{a: "4.17.0", b: "4.18.3", want: true},
+		{a: "4.17.0", b: "4.17.0-rc.1", want: true},
+		{a: "4.17.0", b: "5.0.0", want: false},
+		{a: "0.3.0", b: "0.3.9", want: true},
+		{a: "0.3.0", b: "0.4.0", want: false},
+		{a: "0.3.0", b: "1.3.0", want: false},
+	} {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			a, err := Parse(tt.a)
+			require.NoError(t, err)
+			b, err := Parse(tt.b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, a.Compatible(b))
+			assert.Equal(t, tt.want, b.Compatible(a), "compatibility is symmetric")
+		})
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	previous := SemVer
+	t.Cleanup(func() { SemVer = previous })
+
+	SemVer = ""
+	_, err := Current()
+	assert.Error(t, err)
+
+	SemVer = "v4.17.1"
+	v, err := Current()
+	require.NoError(t, err)
+	assert.Equal(t, Version{Major: 4, Minor: 17, Patch: 1}, v)
+}
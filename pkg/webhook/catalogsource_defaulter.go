@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	apiutils "github.com/operator-framework/operator-marketplace/pkg/apis/operators/shared"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// DefaultRegistryPollInterval is the interval at which OLM polls the
+	// registry of the CatalogSources created without an update strategy.
+	DefaultRegistryPollInterval = "1h"
+
+	// DefaultedAnnotationKey is the annotation of the CatalogSources the
+	// webhook set defaults on, its value is the field defaulted.
+	DefaultedAnnotationKey = "marketplace.operator.openshift.io/defaulted"
+)
+
+// CatalogSourceDefaulter sets the update strategy of the CatalogSources
+// created without one, so that their registry is polled at a known interval
+// rather than the one OLM picks.
+type CatalogSourceDefaulter struct{}
+
+var _ admission.CustomDefaulter = &CatalogSourceDefaulter{}
+
+// Default implements admission.CustomDefaulter. Only the CatalogSources being
+// created are defaulted, and the ones the operator manages are left as the
+// operator applies them.
+func (d *CatalogSourceDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	catsrc, ok := obj.(*olmv1alpha1.CatalogSource)
+	if !ok {
+		return fmt.Errorf("expected a CatalogSource, got %T", obj)
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if req.Operation != admissionv1.Create || catsrc.Labels[apiutils.ManagedLabelKey] == "true" {
+		return nil
+	}
+
+	if catsrc.Spec.UpdateStrategy == nil {
+		catsrc.Spec.UpdateStrategy = &olmv1alpha1.UpdateStrategy{
+			RegistryPoll: &olmv1alpha1.RegistryPoll{
+				RawInterval: DefaultRegistryPollInterval,
+				Interval:    &metav1.Duration{Duration: time.Hour},
+			},
+		}
+		if catsrc.Annotations == nil {
+			catsrc.Annotations = make(map[string]string)
+		}
+		catsrc.Annotations[DefaultedAnnotationKey] = "spec.updateStrategy"
+	}
+	return nil
+}
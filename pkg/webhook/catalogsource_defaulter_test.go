@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	apiutils "github.com/operator-framework/operator-marketplace/pkg/apis/operators/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// withOperation returns a context holding an admission request of the given
+// operation, as the webhook passes to the defaulter.
+func withOperation(operation admissionv1.Operation) context.Context {
+	return admission.NewContextWithRequest(context.TODO(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: operation}})
+}
+
+func TestDefaultOnCreate(t *testing.T) {
+	catsrc := catalogSource("custom-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: "quay.io/example/custom:latest"})
+	require.NoError(t, (&CatalogSourceDefaulter{}).Default(withOperation(admissionv1.Create), catsrc))
+	require.NotNil(t, catsrc.Spec.UpdateStrategy)
+	require.NotNil(t, catsrc.Spec.UpdateStrategy.RegistryPoll)
+	assert.Equal(t, "1h", catsrc.Spec.UpdateStrategy.RegistryPoll.RawInterval)
+	assert.Equal(t, "spec.updateStrategy", catsrc.Annotations[DefaultedAnnotationKey])
+
+	// An update strategy set on creation is kept.
+	catsrc = catalogSource("custom-operators", olmv1alpha1.CatalogSourceSpec{
+		SourceType:     olmv1alpha1.SourceTypeGrpc,
+		Image:          "quay.io/example/custom:latest",
+		UpdateStrategy: &olmv1alpha1.UpdateStrategy{RegistryPoll: &olmv1alpha1.RegistryPoll{RawInterval: "10m"}},
+	})
+	require.NoError(t, (&CatalogSourceDefaulter{}).Default(withOperation(admissionv1.Create), catsrc))
+	assert.Equal(t, "10m", catsrc.Spec.UpdateStrategy.RegistryPoll.RawInterval)
+	assert.NotContains(t, catsrc.Annotations, DefaultedAnnotationKey)
+}
+
+func TestDefaultSkipsUpdatesAndManagedCatalogSources(t *testing.T) {
+	catsrc := catalogSource("custom-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: "quay.io/example/custom:latest"})
+	require.NoError(t, (&CatalogSourceDefaulter{}).Default(withOperation(admissionv1.Update), catsrc))
+	assert.Nil(t, catsrc.Spec.UpdateStrategy, "an update is not defaulted")
+
+	managed := catalogSource("redhat-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: "quay.io/example/redhat-operators:latest"})
+	managed.Labels = apiutils.ManagedLabels("marketplace-operator", "")
+	require.NoError(t, (&CatalogSourceDefaulter{}).Default(withOperation(admissionv1.Create), managed))
+	assert.Nil(t, managed.Spec.UpdateStrategy, "a CatalogSource managed by the operator is not defaulted")
+}
+
+func TestDefaultingWebhookPatches(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	webhook := admission.WithCustomDefaulter(scheme, &olmv1alpha1.CatalogSource{}, &CatalogSourceDefaulter{})
+	raw, err := json.Marshal(catalogSource("custom-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: "quay.io/example/custom:latest"}))
+	require.NoError(t, err)
+
+	handle := func(operation admissionv1.Operation) admission.Response {
+		return webhook.Handle(context.TODO(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+		}})
+	}
+
+	response := handle(admissionv1.Create)
+	assert.True(t, response.Allowed)
+	paths := map[string]interface{}{}
+	for _, patch := range response.Patches {
+		paths[patch.Path] = patch.Value
+	}
+	assert.Equal(t, map[string]interface{}{"registryPoll": map[string]interface{}{"interval": "1h"}}, paths["/spec/updateStrategy"])
+	assert.Contains(t, paths, "/metadata/annotations")
+
+	response = handle(admissionv1.Update)
+	assert.True(t, response.Allowed)
+	assert.Empty(t, response.Patches)
+}
@@ -0,0 +1,126 @@
+// Package webhook serves the admission webhooks of the CatalogSources, which
+// reject the CatalogSources OLM could not serve before they are reconciled and
+// default the fields OLM would otherwise pick a value of its own for.
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/controller/options"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// maxNameLength is the maximum length of the name of a CatalogSource.
+const maxNameLength = 253
+
+// Add registers the CatalogSource webhooks with the webhook server of the
+// Manager if the webhooks are enabled.
+func Add(mgr manager.Manager, o options.ControllerOptions) error {
+	if !o.EnableWebhooks {
+		return nil
+	}
+	return builder.WebhookManagedBy(mgr).
+		For(&olmv1alpha1.CatalogSource{}).
+		WithValidator(&CatalogSourceValidator{}).
+		WithDefaulter(&CatalogSourceDefaulter{}).
+		Complete()
+}
+
+// CatalogSourceValidator rejects the CatalogSources whose spec OLM can not
+// serve with a 400 Bad Request.
+type CatalogSourceValidator struct{}
+
+var _ admission.CustomValidator = &CatalogSourceValidator{}
+
+// ValidateCreate implements admission.CustomValidator.
+func (v *CatalogSourceValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	catsrc, ok := obj.(*olmv1alpha1.CatalogSource)
+	if !ok {
+		return nil, k8sErrors.NewBadRequest(fmt.Sprintf("expected a CatalogSource, got %T", obj))
+	}
+	return nil, validate(catsrc)
+}
+
+// ValidateUpdate implements admission.CustomValidator. Only the updates of the
+// spec are validated, so that the metadata of a CatalogSource created before
+// the webhook, such as its finalizers, can still be updated.
+func (v *CatalogSourceValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	previous, ok := oldObj.(*olmv1alpha1.CatalogSource)
+	if !ok {
+		return nil, k8sErrors.NewBadRequest(fmt.Sprintf("expected a CatalogSource, got %T", oldObj))
+	}
+	catsrc, ok := newObj.(*olmv1alpha1.CatalogSource)
+	if !ok {
+		return nil, k8sErrors.NewBadRequest(fmt.Sprintf("expected a CatalogSource, got %T", newObj))
+	}
+	if equality.Semantic.DeepEqual(previous.Spec, catsrc.Spec) {
+		return nil, nil
+	}
+	return nil, validate(catsrc)
+}
+
+// ValidateDelete implements admission.CustomValidator. The deletions are
+// always allowed.
+func (v *CatalogSourceValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate returns a BadRequest error listing the invalid fields of catsrc, or
+// nil if it is valid.
+func validate(catsrc *olmv1alpha1.CatalogSource) error {
+	errs := validateCatalogSource(catsrc)
+	if len(errs) == 0 {
+		return nil
+	}
+	return k8sErrors.NewBadRequest(fmt.Sprintf("CatalogSource %q is invalid: %v", catsrc.Name, errs.ToAggregate()))
+}
+
+// validateCatalogSource returns the invalid fields of catsrc:
+//   - a name longer than 253 characters
+//   - a grpc CatalogSource with neither an image to serve nor the address of a
+//     registry
+//   - an address that is not a host:port
+func validateCatalogSource(catsrc *olmv1alpha1.CatalogSource) field.ErrorList {
+	var errs field.ErrorList
+	if len(catsrc.Name) > maxNameLength {
+		errs = append(errs, field.TooLong(field.NewPath("metadata", "name"), catsrc.Name, maxNameLength))
+	}
+
+	spec := field.NewPath("spec")
+	if catsrc.Spec.SourceType == olmv1alpha1.SourceTypeGrpc && catsrc.Spec.Image == "" && catsrc.Spec.Address == "" {
+		errs = append(errs, field.Required(spec.Child("image"), "an image is required by the grpc sourceType unless an address is set"))
+	}
+	if catsrc.Spec.Address != "" {
+		if err := validateHostPort(catsrc.Spec.Address); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("address"), catsrc.Spec.Address, err.Error()))
+		}
+	}
+	return errs
+}
+
+// validateHostPort returns an error if address is not a host and a port
+// between 1 and 65535.
+func validateHostPort(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("must be a host:port: %v", err)
+	}
+	if host == "" {
+		return fmt.Errorf("must be a host:port: missing host")
+	}
+	number, err := strconv.Atoi(port)
+	if err != nil || number < 1 || number > 65535 {
+		return fmt.Errorf("must be a host:port: invalid port %q", port)
+	}
+	return nil
+}
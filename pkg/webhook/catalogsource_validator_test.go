@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func catalogSource(name string, spec olmv1alpha1.CatalogSourceSpec) *olmv1alpha1.CatalogSource {
+	return &olmv1alpha1.CatalogSource{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "operators.coreos.com/v1alpha1", Kind: "CatalogSource"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openshift-marketplace"},
+		Spec:       spec,
+	}
+}
+
+func TestValidateCreate(t *testing.T) {
+	tests := []struct {
+		description string
+		catsrc      *olmv1alpha1.CatalogSource
+		expected    string
+	}{
+		{
+			description: "grpc with an image",
+			catsrc:      catalogSource("redhat-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: "quay.io/example/redhat-operators:latest"}),
+		},
+		{
+			description: "grpc with an address",
+			catsrc:      catalogSource("redhat-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Address: "registry.example.com:50051"}),
+		},
+		{
+			description: "configmap without an image",
+			catsrc:      catalogSource("redhat-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeConfigmap, ConfigMap: "catalog"}),
+		},
+		{
+			description: "grpc without an image",
+			catsrc:      catalogSource("redhat-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc}),
+			expected:    "spec.image: Required value",
+		},
+		{
+			description: "address without a port",
+			catsrc:      catalogSource("redhat-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Address: "registry.example.com"}),
+			expected:    `spec.address: Invalid value: "registry.example.com": must be a host:port`,
+		},
+		{
+			description: "address without a host",
+			catsrc:      catalogSource("redhat-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Address: ":50051"}),
+			expected:    "must be a host:port: missing host",
+		},
+		{
+			description: "address with an invalid port",
+			catsrc:      catalogSource("redhat-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Address: "registry.example.com:70000"}),
+			expected:    `must be a host:port: invalid port "70000"`,
+		},
+		{
+			description: "name of 253 characters",
+			catsrc:      catalogSource(strings.Repeat("a", 253), olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: "quay.io/example/catalog:latest"}),
+		},
+		{
+			description: "name longer than 253 characters",
+			catsrc:      catalogSource(strings.Repeat("a", 254), olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc, Image: "quay.io/example/catalog:latest"}),
+			expected:    "metadata.name: Too long",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			_, err := (&CatalogSourceValidator{}).ValidateCreate(context.TODO(), tt.catsrc)
+			if tt.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	invalid := catalogSource("redhat-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc})
+	validator := &CatalogSourceValidator{}
+
+	// The metadata of an invalid CatalogSource can still be updated.
+	updated := invalid.DeepCopy()
+	updated.Finalizers = nil
+	updated.Labels = map[string]string{"team": "catalogs"}
+	_, err := validator.ValidateUpdate(context.TODO(), invalid, updated)
+	assert.NoError(t, err)
+
+	// Its spec can only be updated to a valid one.
+	updated.Spec.Address = "registry.example.com"
+	_, err = validator.ValidateUpdate(context.TODO(), invalid, updated)
+	assert.Error(t, err)
+	updated.Spec.Address = "registry.example.com:50051"
+	_, err = validator.ValidateUpdate(context.TODO(), invalid, updated)
+	assert.NoError(t, err)
+
+	_, err = validator.ValidateDelete(context.TODO(), invalid)
+	assert.NoError(t, err)
+}
+
+func TestValidatingWebhookRejectsWithBadRequest(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, olmv1alpha1.AddToScheme(scheme))
+	webhook := admission.WithCustomValidator(scheme, &olmv1alpha1.CatalogSource{}, &CatalogSourceValidator{})
+
+	raw, err := json.Marshal(catalogSource("redhat-operators", olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc}))
+	require.NoError(t, err)
+	response := webhook.Handle(context.TODO(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}})
+	assert.False(t, response.Allowed)
+	require.NotNil(t, response.Result)
+	assert.Equal(t, int32(http.StatusBadRequest), response.Result.Code)
+	assert.Contains(t, response.Result.Message, "spec.image: Required value")
+}
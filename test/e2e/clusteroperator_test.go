@@ -8,6 +8,7 @@ import (
 
 	configv1 "github.com/openshift/api/config/v1"
 	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/status"
 	"k8s.io/apimachinery/pkg/types"
 )
 
@@ -52,4 +53,23 @@ var _ = Describe("clusteroperator", func() {
 		}
 		Expect(co.Status.RelatedObjects).To(ContainElements(expectedRelatedObjects))
 	})
+
+	It("Should be annotated with the operator version and commit", func() {
+		Eventually(func() (map[string]string, error) {
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: clusterOperatorName}, co)
+			return co.GetAnnotations(), err
+		}).Should(SatisfyAll(
+			HaveKeyWithValue(status.VersionAnnotation, Not(BeEmpty())),
+			HaveKeyWithValue(status.CommitAnnotation, Not(BeEmpty())),
+		))
+	})
+
+	It("Should report the managed CatalogSources in the status extension", func() {
+		Eventually(func() (*status.MarketplaceStatusExtension, error) {
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: clusterOperatorName}, co); err != nil {
+				return nil, err
+			}
+			return status.ParseMarketplaceStatusExtension(co)
+		}).Should(Not(BeNil()))
+	})
 })
@@ -0,0 +1,34 @@
+package e2e
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/operator-marketplace/pkg/leader"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("leader election", func() {
+	var (
+		ctx       = context.Background()
+		namespace = "openshift-marketplace"
+	)
+
+	It("Should record an Event when the operator became leader", func() {
+		events := &corev1.EventList{}
+		err := k8sClient.List(ctx, events, client.InNamespace(namespace))
+		Expect(err).ToNot(HaveOccurred())
+
+		var elected []corev1.Event
+		for _, event := range events.Items {
+			if event.Reason == leader.LeaderElectedReason && event.InvolvedObject.Kind == "Lease" {
+				elected = append(elected, event)
+			}
+		}
+		Expect(elected).ToNot(BeEmpty())
+		Expect(elected[0].Type).To(Equal(corev1.EventTypeNormal))
+	})
+})
@@ -0,0 +1,70 @@
+package e2e
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/defaults"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("obsolete default catalogsources", func() {
+	var (
+		ctx             = context.Background()
+		globalNamespace = "openshift-marketplace"
+		nn              = types.NamespacedName{Name: "cluster"}
+		obsoleteNN      = types.NamespacedName{Name: "obsolete-operators", Namespace: globalNamespace}
+	)
+
+	AfterEach(func() {
+		Eventually(func() error {
+			og := &configv1.OperatorHub{}
+			if err := k8sClient.Get(ctx, nn, og); err != nil {
+				return err
+			}
+			og.Spec = configv1.OperatorHubSpec{}
+			return k8sClient.Update(ctx, og)
+		}, defaultTimeout, defaultPoll).Should(BeNil())
+	})
+
+	It("should delete a catalogsource managed by the operator that is no longer a default", func() {
+		By("creating a catalogsource labeled as managed by the operator, as left over by a removed defaults file")
+		obsolete := &olmv1alpha1.CatalogSource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      obsoleteNN.Name,
+				Namespace: obsoleteNN.Namespace,
+				Labels:    map[string]string{defaults.ManagedByLabelKey: defaults.ManagedByLabelValue},
+			},
+			Spec: olmv1alpha1.CatalogSourceSpec{
+				SourceType: olmv1alpha1.SourceTypeGrpc,
+				Image:      "quay.io/operator-framework/obsolete-operators:latest",
+			},
+		}
+		Expect(k8sClient.Create(ctx, obsolete)).To(Succeed())
+
+		By("triggering a reconciliation of the operatorhub resource")
+		Eventually(func() error {
+			og := &configv1.OperatorHub{}
+			if err := k8sClient.Get(ctx, nn, og); err != nil {
+				return err
+			}
+			og.Spec = configv1.OperatorHubSpec{
+				Sources: []configv1.HubSource{{Name: "redhat-operators", Disabled: false}},
+			}
+			return k8sClient.Update(ctx, og)
+		}, defaultTimeout, defaultPoll).Should(BeNil())
+
+		By("checking the obsolete catalogsource has been deleted")
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, obsoleteNN, &olmv1alpha1.CatalogSource{})
+			return apierrors.IsNotFound(err)
+		}, defaultTimeout, defaultPoll).Should(BeTrue())
+	})
+})
@@ -0,0 +1,54 @@
+package e2e
+
+import (
+	"context"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// syncPeriod returns the -sync-period the operator under test runs with, as
+// set by E2E_SYNC_PERIOD, or the default of the operator.
+func syncPeriod() time.Duration {
+	if period, err := time.ParseDuration(os.Getenv("E2E_SYNC_PERIOD")); err == nil {
+		return period
+	}
+	return 10 * time.Minute
+}
+
+var _ = Describe("periodic resync", func() {
+	var (
+		ctx = context.Background()
+		nn  = types.NamespacedName{Name: "redhat-operators", Namespace: "openshift-marketplace"}
+	)
+
+	It("should restore a default catalogsource modified out-of-band", func() {
+		catsrc := &olmv1alpha1.CatalogSource{}
+		Expect(k8sClient.Get(ctx, nn, catsrc)).To(Succeed())
+		desiredDisplayName := catsrc.Spec.DisplayName
+
+		By("modifying the spec of the catalogsource directly")
+		Expect(retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			catsrc := &olmv1alpha1.CatalogSource{}
+			if err := k8sClient.Get(ctx, nn, catsrc); err != nil {
+				return err
+			}
+			catsrc.Spec.DisplayName = "Modified out-of-band"
+			return k8sClient.Update(ctx, catsrc)
+		})).To(Succeed())
+
+		By("waiting for the catalogsource to be restored, at the latest on the next resync")
+		Eventually(func() (string, error) {
+			catsrc := &olmv1alpha1.CatalogSource{}
+			err := k8sClient.Get(ctx, nn, catsrc)
+			return catsrc.Spec.DisplayName, err
+		}, syncPeriod()+defaultTimeout, defaultPoll).Should(Equal(desiredDisplayName))
+	})
+})
@@ -0,0 +1,77 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	olmv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-marketplace/pkg/webhook"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// webhookConfigurationName is the name of the ValidatingWebhookConfiguration
+// and MutatingWebhookConfiguration registering the CatalogSource webhooks in
+// manifests/13_webhook.yaml.
+const webhookConfigurationName = "marketplace-operator-catalogsources"
+
+var _ = Describe("catalogsource webhooks", func() {
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		// The webhooks are only served if the operator under test is deployed
+		// with its webhook configurations.
+		for _, configuration := range []client.Object{
+			&admissionregistrationv1.ValidatingWebhookConfiguration{},
+			&admissionregistrationv1.MutatingWebhookConfiguration{},
+		} {
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: webhookConfigurationName}, configuration)
+			if k8sErrors.IsNotFound(err) {
+				Skip(fmt.Sprintf("the %T %s is not deployed", configuration, webhookConfigurationName))
+			}
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("should reject a grpc catalogsource without an image with a 400", func() {
+		catsrc := &olmv1alpha1.CatalogSource{
+			ObjectMeta: metav1.ObjectMeta{Name: "webhook-invalid", Namespace: "openshift-marketplace"},
+			Spec:       olmv1alpha1.CatalogSourceSpec{SourceType: olmv1alpha1.SourceTypeGrpc},
+		}
+		err := k8sClient.Create(ctx, catsrc)
+		Expect(err).To(HaveOccurred())
+
+		var status k8sErrors.APIStatus
+		Expect(errors.As(err, &status)).To(BeTrue())
+		Expect(status.Status().Code).To(Equal(int32(http.StatusBadRequest)))
+		Expect(err.Error()).To(ContainSubstring("spec.image"))
+	})
+
+	It("should default the update strategy of a minimal catalogsource", func() {
+		catsrc := &olmv1alpha1.CatalogSource{
+			ObjectMeta: metav1.ObjectMeta{Name: "webhook-defaulted", Namespace: "openshift-marketplace"},
+			Spec: olmv1alpha1.CatalogSourceSpec{
+				SourceType: olmv1alpha1.SourceTypeGrpc,
+				Image:      "quay.io/operatorhubio/catalog:latest",
+			},
+		}
+		Expect(k8sClient.Create(ctx, catsrc)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, catsrc))).To(Succeed())
+		})
+
+		created := &olmv1alpha1.CatalogSource{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(catsrc), created)).To(Succeed())
+		Expect(created.Annotations).To(HaveKeyWithValue(webhook.DefaultedAnnotationKey, "spec.updateStrategy"))
+		Expect(created.Spec.UpdateStrategy).NotTo(BeNil())
+		Expect(created.Spec.UpdateStrategy.RegistryPoll.RawInterval).To(Equal(webhook.DefaultRegistryPollInterval))
+	})
+})